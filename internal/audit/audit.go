@@ -0,0 +1,190 @@
+// Package audit lists the shell commands sessions ran through the Bash
+// tool: what ran, where and when, and what came back.
+package audit
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+// MaxOutput is how much of a command's output is kept, in bytes
+const MaxOutput = 4096
+
+// Status is how a command ended
+type Status string
+
+const (
+	StatusOK          Status = "ok"
+	StatusError       Status = "error"
+	StatusInterrupted Status = "interrupted"
+	StatusUnknown     Status = "unknown" // No result: still running, or the session stopped first
+)
+
+// Command is one Bash tool call
+type Command struct {
+	Project     string    `json:"project"`
+	SessionID   string    `json:"session_id"`
+	MessageUUID string    `json:"message_uuid"`
+	ToolID      string    `json:"tool_id"`
+	Timestamp   time.Time `json:"timestamp"`
+	CWD         string    `json:"cwd,omitempty"`
+	Command     string    `json:"command"`
+	Description string    `json:"description,omitempty"`
+	Background  bool      `json:"run_in_background,omitempty"`
+	Agent       bool      `json:"agent,omitempty"` // Run by a subagent
+	Status      Status    `json:"status"`
+	ExitCode    int       `json:"exit_code,omitempty"` // Set when a failed command's output reports it
+	Output      string    `json:"output"`
+	Truncated   bool      `json:"output_truncated,omitempty"` // Output was cut to MaxOutput bytes
+}
+
+// Matches reports whether re matches the command or its description
+func (c *Command) Matches(re *regexp.Regexp) bool {
+	return re == nil || re.MatchString(c.Command) || re.MatchString(c.Description)
+}
+
+var exitCodeRe = regexp.MustCompile(`^(?:Error: )?Exit code (\d+)`)
+
+// Session lists the Bash calls of a parsed session of project, agents
+// included, in the order they were made. Every branch counts: a rewound
+// command still ran.
+func Session(project string, s *parser.Session) []Command {
+	var msgs []*parser.Message
+	var walk func([]*parser.Message)
+	walk = func(list []*parser.Message) {
+		for _, msg := range list {
+			msgs = append(msgs, msg)
+			walk(msg.Children)
+		}
+	}
+	walk(s.RootMessages)
+	for _, sc := range s.Sidechains {
+		walk(sc.RootMessages)
+	}
+
+	type result struct {
+		block parser.ContentBlock
+		meta  map[string]any
+	}
+	results := make(map[string]result)
+	for _, msg := range msgs {
+		for _, block := range msg.Content {
+			if block.Type == "tool_result" && block.ToolID != "" {
+				meta, _ := msg.ToolUseResult().(map[string]any)
+				results[block.ToolID] = result{block, meta}
+			}
+		}
+	}
+
+	var out []Command
+	seen := make(map[string]bool)
+	for _, msg := range msgs {
+		for _, block := range msg.Content {
+			if block.Type != "tool_use" || block.ToolName != "Bash" || seen[block.ToolID] {
+				continue
+			}
+			seen[block.ToolID] = true
+			input, _ := block.ToolInput.(map[string]any)
+			c := Command{
+				Project:     project,
+				SessionID:   s.ID,
+				MessageUUID: msg.UUID,
+				ToolID:      block.ToolID,
+				Timestamp:   msg.Timestamp,
+				CWD:         msg.CWD(),
+				Agent:       msg.IsSidechain,
+				Status:      StatusUnknown,
+			}
+			c.Command, _ = input["command"].(string)
+			c.Description, _ = input["description"].(string)
+			c.Background, _ = input["run_in_background"].(bool)
+			if c.CWD == "" {
+				c.CWD = s.CWD
+			}
+
+			if res, ok := results[block.ToolID]; ok {
+				c.Output = res.block.ResultText()
+				interrupted, _ := res.meta["interrupted"].(bool)
+				switch {
+				case interrupted:
+					c.Status = StatusInterrupted
+				case res.block.IsError:
+					c.Status = StatusError
+					if m := exitCodeRe.FindStringSubmatch(c.Output); m != nil {
+						c.ExitCode, _ = strconv.Atoi(m[1])
+					}
+				default:
+					c.Status = StatusOK
+				}
+			}
+			if len(c.Output) > MaxOutput {
+				cut := MaxOutput
+				for cut > 0 && !utf8.RuneStart(c.Output[cut]) {
+					cut--
+				}
+				c.Output, c.Truncated = c.Output[:cut], true
+			}
+			out = append(out, c)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Timestamp.Before(out[j].Timestamp) })
+	return out
+}
+
+// Collect lists the Bash calls of every session under projectsDir, or of
+// one project's sessions when project is set, oldest first
+func Collect(projectsDir, project string) ([]Command, error) {
+	projects, err := parser.SelectProjects(projectsDir, project)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []Command
+	for _, p := range projects {
+		for _, s := range p.Sessions {
+			full, err := parser.ParseSession(s.FilePath)
+			if err != nil {
+				continue
+			}
+			out = append(out, Session(p.EncodedName, full)...)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Timestamp.Before(out[j].Timestamp) })
+	return out, nil
+}
+
+// Filter keeps the commands that match re and, with errorsOnly, didn't
+// succeed
+func Filter(cmds []Command, re *regexp.Regexp, errorsOnly bool) []Command {
+	var out []Command
+	for _, c := range cmds {
+		if !c.Matches(re) {
+			continue
+		}
+		if errorsOnly && (c.Status == StatusOK || c.Status == StatusUnknown) {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// OutputLines is the first n lines of the command's output, and how many
+// more there are
+func (c *Command) OutputLines(n int) ([]string, int) {
+	text := strings.TrimRight(c.Output, "\n")
+	if text == "" || n <= 0 {
+		return nil, 0
+	}
+	lines := strings.Split(text, "\n")
+	if len(lines) <= n {
+		return lines, 0
+	}
+	return lines[:n], len(lines) - n
+}
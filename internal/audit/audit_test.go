@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/thevibeworks/ccx/internal/testutil"
+)
+
+var auditSession = `{"type":"user","timestamp":"2026-01-01T10:00:00Z","uuid":"u1","cwd":"/repo","message":{"content":"Run the tests"}}
+{"type":"assistant","timestamp":"2026-01-01T10:00:01Z","uuid":"a1","parentUuid":"u1","cwd":"/repo","message":{"content":[{"type":"tool_use","id":"b1","name":"Bash","input":{"command":"go test ./...","description":"Run tests"}},{"type":"tool_use","id":"r1","name":"Read","input":{"file_path":"/repo/go.mod"}}]}}
+{"type":"user","timestamp":"2026-01-01T10:00:05Z","uuid":"t1","parentUuid":"a1","cwd":"/repo","message":{"content":[{"type":"tool_result","tool_use_id":"b1","content":"Error: Exit code 1\nFAIL\tgithub.com/x/y","is_error":true}]}}
+{"type":"assistant","timestamp":"2026-01-01T10:00:06Z","uuid":"a2","parentUuid":"t1","cwd":"/repo/sub","message":{"content":[{"type":"tool_use","id":"b2","name":"Bash","input":{"command":"npm run dev","run_in_background":true}},{"type":"tool_use","id":"b3","name":"Bash","input":{"command":"sleep 600"}}]}}
+{"type":"user","timestamp":"2026-01-01T10:00:07Z","uuid":"t2","parentUuid":"a2","message":{"content":[{"type":"tool_result","tool_use_id":"b2","content":"Command running in background with ID: 1"}]}}
+{"type":"user","timestamp":"2026-01-01T10:00:08Z","uuid":"t3","parentUuid":"t2","toolUseResult":{"stdout":"","stderr":"","interrupted":true},"message":{"content":[{"type":"tool_result","tool_use_id":"b3","content":"[Request interrupted by user for tool use]","is_error":true}]}}
+{"type":"assistant","timestamp":"2026-01-01T10:00:09Z","uuid":"s1","parentUuid":"t3","isSidechain":true,"message":{"content":[{"type":"tool_use","id":"b4","name":"Bash","input":{"command":"ls -la"}}]}}
+`
+
+func TestSession(t *testing.T) {
+	session := testutil.ParseSession(t, auditSession)
+
+	cmds := Session("-repo", session)
+	if len(cmds) != 4 {
+		t.Fatalf("got %d commands, want 4: %+v", len(cmds), cmds)
+	}
+	test, dev, sleep, ls := cmds[0], cmds[1], cmds[2], cmds[3]
+	if test.Command != "go test ./..." || test.Description != "Run tests" || test.CWD != "/repo" ||
+		test.Status != StatusError || test.ExitCode != 1 || !strings.Contains(test.Output, "FAIL") || test.Project != "-repo" {
+		t.Errorf("go test = %+v", test)
+	}
+	if !dev.Background || dev.Status != StatusOK || dev.CWD != "/repo/sub" {
+		t.Errorf("npm run dev = %+v", dev)
+	}
+	if sleep.Status != StatusInterrupted || sleep.ExitCode != 0 {
+		t.Errorf("sleep = %+v", sleep)
+	}
+	if !ls.Agent || ls.Status != StatusUnknown || ls.CWD != "/repo" {
+		t.Errorf("ls = %+v", ls)
+	}
+
+	if got := Filter(cmds, regexp.MustCompile("(?i)TESTS"), false); len(got) != 1 || got[0].ToolID != "b1" {
+		t.Errorf("grep matched %+v", got)
+	}
+	if got := Filter(cmds, nil, true); len(got) != 2 {
+		t.Errorf("errors only matched %d, want 2", len(got))
+	}
+	if lines, more := test.OutputLines(1); len(lines) != 1 || more != 1 {
+		t.Errorf("OutputLines(1) = %q, %d more", lines, more)
+	}
+}
+
+func TestSession_TruncatesOutput(t *testing.T) {
+	out := strings.Repeat("é", MaxOutput)
+	content := `{"type":"assistant","timestamp":"2026-01-01T10:00:01Z","uuid":"a1","message":{"content":[{"type":"tool_use","id":"b1","name":"Bash","input":{"command":"cat big"}}]}}
+{"type":"user","timestamp":"2026-01-01T10:00:02Z","uuid":"t1","parentUuid":"a1","message":{"content":[{"type":"tool_result","tool_use_id":"b1","content":"` + out + `"}]}}
+`
+	session := testutil.ParseSession(t, content)
+
+	c := Session("p", session)[0]
+	if !c.Truncated || len(c.Output) > MaxOutput || !strings.HasPrefix(out, c.Output) {
+		t.Errorf("output of %d bytes, truncated %v", len(c.Output), c.Truncated)
+	}
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/thevibeworks/ccx/internal/audit"
+	"github.com/thevibeworks/ccx/internal/config"
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/tui"
+)
+
+var commandsCmd = &cobra.Command{
+	Use:   "commands [session]",
+	Short: "List the shell commands sessions ran",
+	Long: `List every command run through the Bash tool, agents included, with its
+time, working directory, how it ended and the start of its output.
+
+Without SESSION, pick one with the fuzzy finder; --project lists every
+session of a project and --all every session there is. Commands on
+rewound branches are listed too: they still ran.
+
+Examples:
+  ccx commands e38536              # One session
+  ccx commands --all --grep 'rm|curl|ssh'
+  ccx commands -p myproject --errors
+  ccx commands --all --json | jq -r '.[].command'`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runCommands,
+}
+
+var (
+	commandsProject string
+	commandsAll     bool
+	commandsGrep    string
+	commandsErrors  bool
+	commandsLines   int
+	commandsJSON    bool
+)
+
+func init() {
+	commandsCmd.Flags().StringVarP(&commandsProject, "project", "p", "", "project name")
+	commandsCmd.Flags().BoolVar(&commandsAll, "all", false, "commands of every session in every project")
+	commandsCmd.Flags().StringVar(&commandsGrep, "grep", "", "only commands or descriptions matching this regexp (case-insensitive)")
+	commandsCmd.Flags().BoolVar(&commandsErrors, "errors", false, "only commands that failed or were interrupted")
+	commandsCmd.Flags().IntVarP(&commandsLines, "lines", "n", 3, "output lines to show per command (0 = none)")
+	commandsCmd.Flags().BoolVar(&commandsJSON, "json", false, "output as JSON")
+
+	rootCmd.AddCommand(commandsCmd)
+}
+
+func runCommands(cmd *cobra.Command, args []string) error {
+	projectsDir := config.ProjectsDir()
+
+	var re *regexp.Regexp
+	if commandsGrep != "" {
+		var err error
+		if re, err = regexp.Compile("(?i)" + commandsGrep); err != nil {
+			return fmt.Errorf("invalid --grep: %w", err)
+		}
+	}
+
+	var cmds []audit.Command
+	switch {
+	case len(args) > 0 || (!commandsAll && commandsProject == ""):
+		var session *parser.Session
+		var err error
+		if len(args) == 0 {
+			session, err = selectSession(projectsDir, "", "Commands of session")
+		} else {
+			projectName, sessionID := parseSessionArg(args[0])
+			if commandsProject != "" {
+				projectName = commandsProject
+			}
+			session, err = parser.FindSession(projectsDir, projectName, sessionID)
+		}
+		if errors.Is(err, tui.ErrCancelled) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to find session: %w", err)
+		}
+		if session == nil {
+			return fmt.Errorf("session not found")
+		}
+		full, err := parser.ParseSession(session.FilePath)
+		if err != nil {
+			return fmt.Errorf("failed to parse session: %w", err)
+		}
+		cmds = audit.Session(session.ProjectName, full)
+	default:
+		project := commandsProject
+		if commandsAll {
+			project = ""
+		}
+		var err error
+		if cmds, err = audit.Collect(projectsDir, project); err != nil {
+			return fmt.Errorf("failed to collect commands: %w", err)
+		}
+	}
+	cmds = audit.Filter(cmds, re, commandsErrors)
+
+	if commandsJSON {
+		if cmds == nil {
+			cmds = []audit.Command{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(cmds)
+	}
+
+	if len(cmds) == 0 {
+		fmt.Println("No commands found")
+		return nil
+	}
+	printCommands(cmds, len(args) == 0 && (commandsAll || commandsProject != ""))
+	return nil
+}
+
+// printCommands writes one block per command; withSession adds the
+// project and session each ran in
+func printCommands(cmds []audit.Command, withSession bool) {
+	color := term.IsTerminal(int(os.Stdout.Fd()))
+	paint := func(code, s string) string {
+		if !color {
+			return s
+		}
+		return "\033[" + code + "m" + s + "\033[0m"
+	}
+	home, _ := os.UserHomeDir()
+
+	for i, c := range cmds {
+		if i > 0 {
+			fmt.Println()
+		}
+		head := []string{c.Timestamp.Local().Format("2006-01-02 15:04:05")}
+		if withSession {
+			head = append(head, parser.GetProjectDisplayName(c.Project)+":"+truncateID(c.SessionID, 8))
+		}
+		if c.CWD != "" {
+			cwd := c.CWD
+			if home != "" && strings.HasPrefix(cwd, home) {
+				cwd = "~" + strings.TrimPrefix(cwd, home)
+			}
+			head = append(head, cwd)
+		}
+		if c.Agent {
+			head = append(head, "[agent]")
+		}
+		if c.Background {
+			head = append(head, "[background]")
+		}
+		status := string(c.Status)
+		switch c.Status {
+		case audit.StatusError:
+			if c.ExitCode != 0 {
+				status = fmt.Sprintf("exit %d", c.ExitCode)
+			}
+			status = paint("31", status)
+		case audit.StatusInterrupted, audit.StatusUnknown:
+			status = paint("33", status)
+		default:
+			status = paint("32", status)
+		}
+		fmt.Println(paint("2", strings.Join(head, "  ")) + "  " + status)
+
+		for j, line := range strings.Split(c.Command, "\n") {
+			prefix := "  $ "
+			if j > 0 {
+				prefix = "    "
+			}
+			fmt.Println(prefix + paint("1", line))
+		}
+		if c.Description != "" {
+			fmt.Println(paint("36", "  # "+c.Description))
+		}
+		lines, more := c.OutputLines(commandsLines)
+		for _, line := range lines {
+			fmt.Println(paint("2", "  │ ") + truncate(line, 160))
+		}
+		if more > 0 {
+			fmt.Println(paint("2", fmt.Sprintf("  │ … %d more lines", more)))
+		} else if c.Truncated && len(lines) > 0 {
+			fmt.Println(paint("2", "  │ …"))
+		}
+	}
+}
@@ -27,6 +27,7 @@ var configShowCmd = &cobra.Command{
 		fmt.Printf("rendering.show_thinking: %s\n", config.ShowThinking())
 		fmt.Printf("rendering.code_theme: %s\n", config.CodeTheme())
 		fmt.Printf("export.default_format: %s\n", config.DefaultExportFormat())
+		fmt.Printf("export.patch_author: %s\n", config.PatchAuthor())
 		return nil
 	},
 }
@@ -87,6 +88,7 @@ export:
   default_format: html
   include_thinking: false
   include_images: true
+  # patch_author: "Jane Doe <jane@example.com>"  # Signs --format patch commits; default: git user.name and user.email
 
 filters:
   exclude_tools: []
@@ -94,6 +96,23 @@ filters:
   min_messages: 1
 
 # skills_dir: ~/.config/ccx/skills
+
+# pricing:                 # Override or add rates, USD per million tokens
+#   - model: claude-sonnet-4-5
+#     since: 2026-01-01    # Optional; the rate applies from this UTC date
+#     input: 3
+#     output: 15
+#     cache_read: 0.3
+#     cache_write: 3.75
+
+# redact:                  # Masking for ccx export --redact and ccx web --redact
+#   web: false             # Always mask what the web server serves
+#   disable: [email]       # Built-in detectors to turn off
+#   patterns:              # Extra detectors; with a group, only the group is masked
+#     - name: internal-host
+#       pattern: '\b[a-z0-9-]+\.corp\.example\.com\b'
+#     - name: customer-id
+#       pattern: 'customer_id=(\d+)'
 `
 
 		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
@@ -0,0 +1,368 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/thevibeworks/ccx/internal/compare"
+	"github.com/thevibeworks/ccx/internal/config"
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/pricing"
+	"github.com/thevibeworks/ccx/internal/tui"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [session-a] [session-b]",
+	Short: "Compare two sessions or two branches of one",
+	Long: `Line up two sessions by user prompt and show where they diverge, the
+tools each called, the files each read or changed, and the differences in
+tokens, estimated cost and duration.
+
+With one session, compare two of its branches: pick each with --leaf-a and
+--leaf-b, the UUID (or a prefix) of the branch's last message. Without a
+leaf, the branch ending at the most recent message is used.
+
+Rows are marked:
+  =  the same messages on both sides (before two branches fork)
+     the same prompt, with the same tools and files
+  ~  the same prompt, with different tools or files
+  <  only in A
+  >  only in B
+
+Examples:
+  ccx diff e38536 f1a2b3
+  ccx diff myproject:e38536 otherproject:f1a2b3
+  ccx diff e38536 --leaf-a 7c1d --leaf-b 9e02
+  ccx diff e38536 f1a2b3 -f json
+
+Without arguments, pick both sessions with the fuzzy finder; cancel the
+second pick to compare branches of the first.`,
+	Args: cobra.MaximumNArgs(2),
+	RunE: runDiff,
+}
+
+var (
+	diffProject string
+	diffLeafA   string
+	diffLeafB   string
+	diffFormat  string
+)
+
+func init() {
+	diffCmd.Flags().StringVarP(&diffProject, "project", "p", "", "project name")
+	diffCmd.Flags().StringVar(&diffLeafA, "leaf-a", "", "last message of the branch to compare on side A")
+	diffCmd.Flags().StringVar(&diffLeafB, "leaf-b", "", "last message of the branch to compare on side B")
+	diffCmd.Flags().StringVarP(&diffFormat, "format", "f", "table", "output format: table, json")
+
+	rootCmd.AddCommand(diffCmd)
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		picked, err := pickDiffSessions()
+		if errors.Is(err, tui.ErrCancelled) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		args = picked
+	}
+	if len(args) == 1 {
+		args = append(args, args[0])
+	}
+
+	a, err := loadDiffSession(args[0])
+	if err != nil {
+		return err
+	}
+	b := a
+	if args[1] != args[0] {
+		if b, err = loadDiffSession(args[1]); err != nil {
+			return err
+		}
+	}
+
+	prices, err := config.Pricing()
+	if err != nil {
+		return err
+	}
+	res, err := compare.Sessions(a, diffLeafA, b, diffLeafB, prices)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(diffFormat) {
+	case "table", "":
+		return printDiffTable(res)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diffToJSON(res))
+	default:
+		return fmt.Errorf("unsupported format: %s (want table or json)", diffFormat)
+	}
+}
+
+// pickDiffSessions asks for session A, then B, as project:id arguments
+func pickDiffSessions() ([]string, error) {
+	projectsDir := config.ProjectsDir()
+	a, err := selectSession(projectsDir, diffProject, "Compare session A")
+	if err != nil {
+		return nil, err
+	}
+	args := []string{a.ProjectName + ":" + a.ID}
+	b, err := selectSession(projectsDir, diffProject, "with session B (cancel for A's branches)")
+	if errors.Is(err, tui.ErrCancelled) {
+		return args, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(args, b.ProjectName+":"+b.ID), nil
+}
+
+func loadDiffSession(arg string) (*parser.Session, error) {
+	projectName, sessionID := parseSessionArg(arg)
+	if diffProject != "" && projectName == "" {
+		projectName = diffProject
+	}
+	session, err := parser.FindSession(config.ProjectsDir(), projectName, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find session: %w", err)
+	}
+	if session == nil {
+		return nil, fmt.Errorf("session not found: %s", arg)
+	}
+	full, err := parser.ParseSession(session.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse session: %w", err)
+	}
+	full.ProjectName = filepath.Base(filepath.Dir(full.FilePath))
+	return full, nil
+}
+
+func printDiffTable(res *compare.Result) error {
+	for _, side := range []struct {
+		name string
+		s    *compare.Side
+	}{{"A", res.A}, {"B", res.B}} {
+		fmt.Printf("%s: %s  %s  %s", side.name, truncateID(side.s.Session.ID, 8),
+			parser.GetProjectDisplayName(side.s.Session.ProjectName), truncate(side.s.Session.Summary, 50))
+		if res.A.Session == res.B.Session {
+			fmt.Printf("  [branch %s]", truncateID(side.s.Leaf, 8))
+		}
+		if len(side.s.Models) > 0 {
+			fmt.Printf("  (%s)", strings.Join(side.s.Models, ", "))
+		}
+		fmt.Println()
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\n\tA\tB\tDIFF")
+	fmt.Fprintf(w, "Turns\t%d\t%d\t%s\n", len(res.A.Turns), len(res.B.Turns), signed(len(res.B.Turns)-len(res.A.Turns)))
+	fmt.Fprintf(w, "Responses\t%d\t%d\t%s\n", res.A.Responses, res.B.Responses, signed(res.B.Responses-res.A.Responses))
+	fmt.Fprintf(w, "Tool calls\t%d\t%d\t%s\n", res.A.ToolCalls(), res.B.ToolCalls(), signed(res.B.ToolCalls()-res.A.ToolCalls()))
+	fmt.Fprintf(w, "Files\t%d\t%d\t%s\n", len(res.A.Files), len(res.B.Files), signed(len(res.B.Files)-len(res.A.Files)))
+	fmt.Fprintf(w, "Tokens\t%d\t%d\t%s\n", res.A.Usage.Total(), res.B.Usage.Total(), signed(res.B.Usage.Total()-res.A.Usage.Total()))
+	fmt.Fprintf(w, "  output\t%d\t%d\t%s\n", res.A.Usage.OutputTokens, res.B.Usage.OutputTokens, signed(res.B.Usage.OutputTokens-res.A.Usage.OutputTokens))
+	fmt.Fprintf(w, "Est. cost\t%s\t%s\t%s\n", costString(res.A.Cost), costString(res.B.Cost), signedUSD(res.B.Cost.USD-res.A.Cost.USD))
+	fmt.Fprintf(w, "Duration\t%s\t%s\t%s\n", res.A.Duration().Round(time.Second), res.B.Duration().Round(time.Second),
+		signedDuration(res.B.Duration()-res.A.Duration()))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if res.Diverge < 0 {
+		fmt.Println("\nNo divergence: same prompts, tools and files.")
+	} else {
+		fmt.Printf("\nDiverges at turn %d.\n", res.Diverge+1)
+	}
+
+	fmt.Fprintln(w, "\n#\t\tA\tTOOLS A\tB\tTOOLS B")
+	for i, row := range res.Rows {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", i+1, diffMarker(row),
+			turnText(row.A), toolSummary(row.A), turnText(row.B), toolSummary(row.B))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if names := compare.ToolNames(res.A.Tools, res.B.Tools); len(names) > 0 {
+		fmt.Fprintln(w, "\nTOOL\tA\tB\tDIFF")
+		for _, name := range names {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", name, res.A.Tools[name], res.B.Tools[name], signed(res.B.Tools[name]-res.A.Tools[name]))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	onlyA, onlyB, both := compare.FileDiff(res.A.Files, res.B.Files)
+	if len(onlyA)+len(onlyB)+len(both) > 0 {
+		fmt.Fprintln(w, "\nFILE\tA\tB")
+		for _, group := range [][]string{onlyA, onlyB, both} {
+			for _, f := range group {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", f, fileUse(res.A.Files, f), fileUse(res.B.Files, f))
+			}
+		}
+		return w.Flush()
+	}
+	return nil
+}
+
+func diffMarker(row compare.Row) string {
+	switch {
+	case row.Shared:
+		return "="
+	case row.B == nil:
+		return "<"
+	case row.A == nil:
+		return ">"
+	case row.Differs():
+		return "~"
+	}
+	return ""
+}
+
+func turnText(t *compare.Turn) string {
+	if t == nil {
+		return "-"
+	}
+	text := t.Text()
+	if text == "" {
+		return "(before first prompt)"
+	}
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i]
+	}
+	return truncate(text, 40)
+}
+
+// toolSummary lists a turn's tool calls, most called first: "Edit×3 Read"
+func toolSummary(t *compare.Turn) string {
+	if t == nil || len(t.Tools) == 0 {
+		return "-"
+	}
+	names := compare.ToolNames(t.Tools, nil)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		if n := t.Tools[name]; n > 1 {
+			parts = append(parts, fmt.Sprintf("%s×%d", name, n))
+		} else {
+			parts = append(parts, name)
+		}
+	}
+	return truncate(strings.Join(parts, " "), 40)
+}
+
+func fileUse(files map[string]bool, f string) string {
+	changed, ok := files[f]
+	switch {
+	case !ok:
+		return "-"
+	case changed:
+		return "changed"
+	}
+	return "read"
+}
+
+func signed(n int) string {
+	if n > 0 {
+		return fmt.Sprintf("+%d", n)
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+func signedUSD(usd float64) string {
+	if usd >= 0 {
+		return "+" + pricing.FormatUSD(usd)
+	}
+	return "-" + pricing.FormatUSD(-usd)
+}
+
+func signedDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d >= 0 {
+		return "+" + d.String()
+	}
+	return d.String()
+}
+
+type diffSideJSON struct {
+	Project    string           `json:"project"`
+	SessionID  string           `json:"session_id"`
+	Leaf       string           `json:"leaf"`
+	Models     []string         `json:"models"`
+	Turns      int              `json:"turns"`
+	Responses  int              `json:"responses"`
+	Tools      map[string]int   `json:"tools"`
+	Files      map[string]bool  `json:"files"` // true if changed
+	Tokens     parser.Usage     `json:"tokens"`
+	Cost       pricing.Estimate `json:"estimated_cost"`
+	DurationMS int64            `json:"duration_ms"`
+}
+
+type diffTurnJSON struct {
+	UUID   string          `json:"uuid,omitempty"`
+	Prompt string          `json:"prompt"`
+	Tools  map[string]int  `json:"tools"`
+	Files  map[string]bool `json:"files"`
+	Tokens parser.Usage    `json:"tokens"`
+}
+
+type diffRowJSON struct {
+	A       *diffTurnJSON `json:"a"`
+	B       *diffTurnJSON `json:"b"`
+	Shared  bool          `json:"shared"`
+	Differs bool          `json:"differs"`
+}
+
+type diffJSON struct {
+	A       diffSideJSON  `json:"a"`
+	B       diffSideJSON  `json:"b"`
+	Diverge int           `json:"diverge"` // Row index; -1 if none differs
+	Rows    []diffRowJSON `json:"rows"`
+}
+
+func diffToJSON(res *compare.Result) diffJSON {
+	side := func(s *compare.Side) diffSideJSON {
+		return diffSideJSON{
+			Project:    s.Session.ProjectName,
+			SessionID:  s.Session.ID,
+			Leaf:       s.Leaf,
+			Models:     append([]string{}, s.Models...),
+			Turns:      len(s.Turns),
+			Responses:  s.Responses,
+			Tools:      s.Tools,
+			Files:      s.Files,
+			Tokens:     s.Usage,
+			Cost:       s.Cost,
+			DurationMS: s.Duration().Milliseconds(),
+		}
+	}
+	turn := func(t *compare.Turn) *diffTurnJSON {
+		if t == nil {
+			return nil
+		}
+		out := &diffTurnJSON{Prompt: t.Text(), Tools: t.Tools, Files: t.Files, Tokens: t.Usage}
+		if t.Prompt != nil {
+			out.UUID = t.Prompt.UUID
+		}
+		return out
+	}
+
+	out := diffJSON{A: side(res.A), B: side(res.B), Diverge: res.Diverge, Rows: []diffRowJSON{}}
+	for _, row := range res.Rows {
+		out.Rows = append(out.Rows, diffRowJSON{A: turn(row.A), B: turn(row.B), Shared: row.Shared, Differs: row.Differs()})
+	}
+	return out
+}
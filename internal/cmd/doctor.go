@@ -58,6 +58,10 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if _, err := os.Stat(config.SessionCacheFile()); err == nil {
+		fmt.Printf("[OK] Session cache: %s\n", config.SessionCacheFile())
+	}
+
 	if _, err := os.Stat(claudeHome + "/settings.json"); err == nil {
 		fmt.Println("[OK] Claude Code settings.json: found")
 	}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,8 +10,10 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/thevibeworks/ccx/internal/config"
+	"github.com/thevibeworks/ccx/internal/db"
 	"github.com/thevibeworks/ccx/internal/parser"
 	"github.com/thevibeworks/ccx/internal/render"
+	"github.com/thevibeworks/ccx/internal/tui"
 )
 
 var exportCmd = &cobra.Command{
@@ -18,10 +21,57 @@ var exportCmd = &cobra.Command{
 	Short: "Export session to file",
 	Long: `Export a Claude Code session to HTML, Markdown, or Org-mode.
 
+--format patch writes the files the session changed as a git format-patch
+series instead, one commit per prompt that changed files, with paths
+relative to the session's working directory. Apply it there with git am.
+Commits are signed by --author, else export.patch_author in config.yaml,
+else your git user.name and user.email.
+Edits whose line numbers can't be recovered are left out and listed in the
+commit message.
+
+--redact masks secrets and personal data before rendering: AWS keys,
+GitHub tokens, API keys, JWTs, private keys, password assignments, email
+addresses and random-looking tokens, plus the patterns under redact in
+config.yaml. What was masked is reported on stderr. Masking is best
+effort: read what you publish.
+
+--template renders with a Go template instead: text/template, or
+html/template for --format html, which escapes what it prints. Name a
+file, or a template in ~/.config/ccx/templates (.tmpl optional). Dropping
+html.tmpl, md.tmpl or org.tmpl in that directory replaces the built-in
+format for every export. With a template, --format may be anything, such
+as csv, and names the output extension. Templates are executed with:
+
+  .Session          the parsed session: .ID, .Summary, .StartTime,
+                    .EndTime, .Stats, .GitBranch, .CWD, ...
+  .Project          project display name
+  .Messages         messages depth first; each has .Type, .Kind,
+                    .Timestamp, .Model, .Usage, .Content blocks (.Type,
+                    .Text, .ToolName, .ToolInput, .ToolResult, .IsError).
+                    With --include-agents, agent transcripts follow the
+                    message that spawned them (.IsSidechain, .AgentID)
+  .Annotations      notes on the session
+  .Cost             cost estimate, empty when nothing was priced
+  .Format, .Theme, .IncludeThinking, .IncludeAgents, .Generated
+
+and these functions besides the builtins:
+
+  markdown TEXT     Markdown to HTML
+  text MSG          a message's text blocks
+  toolPreview NAME INPUT, toolInput INPUT, toolResult RESULT
+  tokens N, duration SECONDS
+  time T, date T, formatTime LAYOUT T
+  shortID ID, truncate N S, json V, notes UUID
+
 Examples:
   ccx export e38536 --format=html
   ccx export myproject:e38536 -f md -o session.md
-  ccx export @1 --format=org`,
+  ccx export @1 --format=org
+  ccx export e38536 -f patch -o - | git am
+  ccx export e38536 -f md --redact -o share.md
+  ccx export e38536 -f csv --template tools.csv.tmpl
+
+If SESSION is omitted, pick one with the fuzzy finder.`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runExport,
 }
@@ -34,16 +84,20 @@ var (
 	exportIncludeThinking bool
 	exportIncludeAgents   bool
 	exportTemplate        string
+	exportRedact          bool
+	exportAuthor          string
 )
 
 func init() {
-	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", "", "output format: html, md, org (default from config)")
+	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", "", "output format: html, md, org, patch (default from config)")
 	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "output file path (default: session.<ext>)")
 	exportCmd.Flags().StringVarP(&exportProject, "project", "p", "", "project name")
 	exportCmd.Flags().StringVar(&exportTheme, "theme", "", "theme: dark, light (default from config)")
 	exportCmd.Flags().BoolVar(&exportIncludeThinking, "include-thinking", false, "include thinking blocks")
 	exportCmd.Flags().BoolVar(&exportIncludeAgents, "include-agents", false, "include agent sidechains")
-	exportCmd.Flags().StringVar(&exportTemplate, "template", "", "custom template path")
+	exportCmd.Flags().StringVar(&exportTemplate, "template", "", "Go template to render with, a path or a name in ~/.config/ccx/templates")
+	exportCmd.Flags().BoolVar(&exportRedact, "redact", false, "mask secrets and personal data")
+	exportCmd.Flags().StringVar(&exportAuthor, "author", "", `"Name <email>" signing patch commits (default from config or git)`)
 }
 
 func runExport(cmd *cobra.Command, args []string) error {
@@ -53,7 +107,7 @@ func runExport(cmd *cobra.Command, args []string) error {
 	var err error
 
 	if len(args) == 0 {
-		session, err = selectSession(projectsDir)
+		session, err = selectSession(projectsDir, exportProject, "Export session")
 	} else {
 		sessionArg := args[0]
 		projectName, sessionID := parseSessionArg(sessionArg)
@@ -63,6 +117,9 @@ func runExport(cmd *cobra.Command, args []string) error {
 		session, err = parser.FindSession(projectsDir, projectName, sessionID)
 	}
 
+	if errors.Is(err, tui.ErrCancelled) {
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to find session: %w", err)
 	}
@@ -95,18 +152,40 @@ func runExport(cmd *cobra.Command, args []string) error {
 		output = fmt.Sprintf("session-%s%s", id, ext)
 	}
 
+	prices, err := config.Pricing()
+	if err != nil {
+		return err
+	}
+
 	opts := render.ExportOptions{
 		Format:          format,
 		Theme:           theme,
 		IncludeThinking: exportIncludeThinking,
 		IncludeAgents:   exportIncludeAgents,
 		TemplatePath:    exportTemplate,
+		TemplatesDir:    config.TemplatesDir(),
+		Annotations:     loadSessionAnnotations(session.ID),
+		Pricing:         prices,
+	}
+	if strings.EqualFold(format, "patch") {
+		opts.PatchAuthor = exportAuthor
+		if opts.PatchAuthor == "" {
+			opts.PatchAuthor = config.PatchAuthor()
+		}
+	}
+	if exportRedact {
+		if opts.Redactor, err = config.Redactor(); err != nil {
+			return err
+		}
 	}
 
 	content, err := render.Export(fullSession, opts)
 	if err != nil {
 		return fmt.Errorf("failed to render: %w", err)
 	}
+	if opts.Redactor != nil {
+		fmt.Fprintf(os.Stderr, "Redacted: %s\n", opts.Redactor.Report())
+	}
 
 	if output == "-" {
 		fmt.Print(content)
@@ -128,6 +207,17 @@ func runExport(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// loadSessionAnnotations returns a session's annotations for export, or
+// nil if the database cannot be opened
+func loadSessionAnnotations(sessionID string) []db.Annotation {
+	if err := db.Init(config.DataDir()); err != nil {
+		return nil
+	}
+	defer db.Close()
+	annotations, _ := db.GetSessionAnnotations(sessionID)
+	return annotations
+}
+
 func formatToExt(format string) string {
 	switch strings.ToLower(format) {
 	case "html":
@@ -136,7 +226,12 @@ func formatToExt(format string) string {
 		return ".md"
 	case "org":
 		return ".org"
-	default:
+	case "patch":
+		return ".patch"
+	case "":
 		return ".html"
+	default:
+		// Formats only a template renders, like csv
+		return "." + strings.ToLower(format)
 	}
 }
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/thevibeworks/ccx/internal/config"
+	"github.com/thevibeworks/ccx/internal/files"
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/tui"
+)
+
+var filesCmd = &cobra.Command{
+	Use:   "files [session]",
+	Short: "Show the files a session changed, with a diff per edit",
+	Long: `Replay a session's Write, Edit, MultiEdit and NotebookEdit calls to list
+every file it changed and the unified diff of each step, agents included.
+
+Diffs have real line numbers when the file's earlier content is known: the
+session wrote the whole file first, or Claude Code recorded the original
+with the edit. Otherwise only the edited text is shown, with "?" for line
+numbers. Failed edits are listed but change nothing.
+
+Examples:
+  ccx files e38536               # Change log with diffs
+  ccx files e38536 --stat        # Just files and line counts
+  ccx files e38536 --file api/   # Only paths containing api/
+  ccx files e38536 --json
+
+If SESSION is omitted, pick one with the fuzzy finder.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runFiles,
+}
+
+var (
+	filesProject string
+	filesStat    bool
+	filesFilter  string
+	filesJSON    bool
+)
+
+func init() {
+	filesCmd.Flags().StringVarP(&filesProject, "project", "p", "", "project name")
+	filesCmd.Flags().BoolVar(&filesStat, "stat", false, "only list files with added and removed line counts")
+	filesCmd.Flags().StringVar(&filesFilter, "file", "", "only files whose path contains this")
+	filesCmd.Flags().BoolVar(&filesJSON, "json", false, "output as JSON")
+
+	rootCmd.AddCommand(filesCmd)
+}
+
+func runFiles(cmd *cobra.Command, args []string) error {
+	projectsDir := config.ProjectsDir()
+
+	var session *parser.Session
+	var err error
+	if len(args) == 0 {
+		session, err = selectSession(projectsDir, filesProject, "Files changed in session")
+	} else {
+		projectName, sessionID := parseSessionArg(args[0])
+		if filesProject != "" {
+			projectName = filesProject
+		}
+		session, err = parser.FindSession(projectsDir, projectName, sessionID)
+	}
+	if errors.Is(err, tui.ErrCancelled) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find session: %w", err)
+	}
+	if session == nil {
+		return fmt.Errorf("session not found")
+	}
+
+	full, err := parser.ParseSession(session.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to parse session: %w", err)
+	}
+
+	var history []*files.File
+	for _, f := range files.History(full, "") {
+		if filesFilter == "" || strings.Contains(f.Path, filesFilter) {
+			history = append(history, f)
+		}
+	}
+
+	if filesJSON {
+		if history == nil {
+			history = []*files.File{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string]any{"session": full.ID, "cwd": full.CWD, "files": history})
+	}
+
+	if len(history) == 0 {
+		fmt.Println("No files changed")
+		return nil
+	}
+	if filesStat {
+		return printFilesStat(full, history)
+	}
+	printFileHistory(full, history)
+	return nil
+}
+
+func printFilesStat(session *parser.Session, history []*files.File) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tEDITS\tADDED\tREMOVED\t")
+	added, removed := 0, 0
+	for _, f := range history {
+		path := files.RelPath(session.CWD, f.Path)
+		if f.Created {
+			path += " (new)"
+		}
+		fmt.Fprintf(w, "%s\t%d\t+%d\t-%d\t\n", path, len(f.Steps), f.Added, f.Removed)
+		added += f.Added
+		removed += f.Removed
+	}
+	fmt.Fprintf(w, "%d files\t\t+%d\t-%d\t\n", len(history), added, removed)
+	return w.Flush()
+}
+
+func printFileHistory(session *parser.Session, history []*files.File) {
+	color := term.IsTerminal(int(os.Stdout.Fd()))
+	paint := func(code, s string) string {
+		if !color {
+			return s
+		}
+		return "\033[" + code + "m" + s + "\033[0m"
+	}
+
+	for i, f := range history {
+		if i > 0 {
+			fmt.Println()
+		}
+		path := files.RelPath(session.CWD, f.Path)
+		header := fmt.Sprintf("%s  +%d -%d, %d edits", path, f.Added, f.Removed, len(f.Steps))
+		if f.Created {
+			header += ", new"
+		}
+		fmt.Println(paint("1", header))
+
+		for _, s := range f.Steps {
+			line := fmt.Sprintf("%s  %s  %s", s.Timestamp.Local().Format("15:04:05"), s.Tool, truncateID(s.MessageUUID, 8))
+			if s.Agent {
+				line += "  [agent]"
+			}
+			if s.Failed {
+				line += "  [failed]"
+			}
+			fmt.Println(paint("36", line))
+			if s.Note != "" {
+				fmt.Println(paint("2", "  "+s.Note))
+			}
+			if len(s.Hunks) == 0 {
+				continue
+			}
+			for _, l := range strings.Split(strings.TrimSuffix(s.Unified(path), "\n"), "\n") {
+				switch {
+				case strings.HasPrefix(l, "+++"), strings.HasPrefix(l, "---"):
+					fmt.Println(paint("1", l))
+				case strings.HasPrefix(l, "@@"):
+					fmt.Println(paint("36", l))
+				case strings.HasPrefix(l, "+"):
+					fmt.Println(paint("32", l))
+				case strings.HasPrefix(l, "-"):
+					fmt.Println(paint("31", l))
+				default:
+					fmt.Println(l)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/thevibeworks/ccx/internal/config"
+	"github.com/thevibeworks/ccx/internal/db"
+	"github.com/thevibeworks/ccx/internal/mcp"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Serve sessions to agents over MCP (stdio)",
+	Long: `Run a Model Context Protocol server on stdin/stdout so agents can look up
+past sessions themselves.
+
+Tools:
+  list_projects     Projects with sessions, most recent first
+  list_sessions     A project's sessions with summaries
+  search_sessions   Full-text search (same query language as ccx search)
+  get_session       A session's messages in order, paged
+  get_message       One message with full tool inputs and results
+  session_stats     Tokens, estimated cost and tool usage
+
+Every session is also a Markdown resource at ccx://{project}/{session}.
+
+Register with Claude Code:
+  claude mcp add ccx -- ccx mcp`,
+	Args: cobra.NoArgs,
+	RunE: runMCP,
+}
+
+func init() {
+	rootCmd.AddCommand(mcpCmd)
+}
+
+func runMCP(cmd *cobra.Command, args []string) error {
+	// stdout carries the protocol; warnings go to stderr
+	if err := db.Init(config.DataDir()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: search unavailable: %v\n", err)
+	}
+	defer db.Close()
+
+	prices, err := config.Pricing()
+	if err != nil {
+		return err
+	}
+	return mcp.NewServer(config.ProjectsDir(), prices, version).Serve(os.Stdin, os.Stdout)
+}
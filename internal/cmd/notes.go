@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/thevibeworks/ccx/internal/config"
+	"github.com/thevibeworks/ccx/internal/db"
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+var notesCmd = &cobra.Command{
+	Use:     "notes [session]",
+	Aliases: []string{"annotations"},
+	Short:   "List message annotations",
+	Long: `List the notes and highlights added to messages in the web viewer.
+
+Without a session, lists the most recent annotations across all sessions.
+Annotations are included as footnotes by ccx export.
+
+Examples:
+  ccx notes                  Recent annotations everywhere
+  ccx notes e38536           Annotations of one session, in order
+  ccx notes -p myproject     Annotations in a project
+  ccx notes rm 12            Delete annotation 12`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runNotes,
+}
+
+var notesRmCmd = &cobra.Command{
+	Use:     "rm <id>...",
+	Aliases: []string{"remove"},
+	Short:   "Delete annotations by ID",
+	Args:    cobra.MinimumNArgs(1),
+	RunE:    runNotesRm,
+}
+
+var (
+	notesProject string
+	notesAuthor  string
+	notesLimit   int
+	notesJSON    bool
+)
+
+func init() {
+	notesCmd.Flags().StringVarP(&notesProject, "project", "p", "", "only annotations in this project")
+	notesCmd.Flags().StringVar(&notesAuthor, "author", "", "only annotations by this author")
+	notesCmd.Flags().IntVar(&notesLimit, "limit", 50, "limit number of annotations (0 = no limit)")
+	notesCmd.Flags().BoolVar(&notesJSON, "json", false, "output as JSON")
+
+	notesCmd.AddCommand(notesRmCmd)
+	rootCmd.AddCommand(notesCmd)
+}
+
+func runNotes(cmd *cobra.Command, args []string) error {
+	projectsDir := config.ProjectsDir()
+	filter := db.AnnotationFilter{Author: notesAuthor, Limit: notesLimit}
+
+	if notesProject != "" {
+		project, err := parser.FindProject(projectsDir, notesProject)
+		if err != nil {
+			return fmt.Errorf("failed to find project: %w", err)
+		}
+		if project == nil {
+			return fmt.Errorf("project not found: %s", notesProject)
+		}
+		filter.Project = project.EncodedName
+	}
+	if len(args) > 0 {
+		projectName, sessionID := parseSessionArg(args[0])
+		session, err := parser.FindSession(projectsDir, projectName, sessionID)
+		if err != nil {
+			return fmt.Errorf("failed to find session: %w", err)
+		}
+		if session == nil {
+			return fmt.Errorf("session not found: %s", args[0])
+		}
+		filter.SessionID = session.ID
+		filter.Limit = 0
+	}
+
+	if err := db.Init(config.DataDir()); err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	annotations, err := db.ListAnnotations(filter)
+	if err != nil {
+		return fmt.Errorf("failed to list annotations: %w", err)
+	}
+
+	if notesJSON {
+		if annotations == nil {
+			annotations = []db.Annotation{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(annotations)
+	}
+
+	if len(annotations) == 0 {
+		fmt.Println("No annotations yet. Add notes from the session viewer (ccx web).")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSESSION\tMESSAGE\tAUTHOR\tAGE\tNOTE")
+	for _, a := range annotations {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			a.ID,
+			truncateID(a.SessionID, 8),
+			truncateID(a.MessageUUID, 8),
+			a.Author,
+			formatAge(a.CreatedAt),
+			noteSummary(a),
+		)
+	}
+	return w.Flush()
+}
+
+// noteSummary is a one-line form of an annotation: the note, with the
+// highlighted quote in front
+func noteSummary(a db.Annotation) string {
+	text := strings.Join(strings.Fields(a.Note), " ")
+	if a.Quote != "" {
+		quote := fmt.Sprintf("%q", truncate(strings.Join(strings.Fields(a.Quote), " "), 30))
+		if text == "" {
+			return quote
+		}
+		text = quote + " " + text
+	}
+	return truncate(text, 70)
+}
+
+func runNotesRm(cmd *cobra.Command, args []string) error {
+	if err := db.Init(config.DataDir()); err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid annotation ID: %s", arg)
+		}
+		ok, err := db.DeleteAnnotation(id)
+		if err != nil {
+			return fmt.Errorf("failed to delete annotation %d: %w", id, err)
+		}
+		if !ok {
+			return fmt.Errorf("annotation not found: %d", id)
+		}
+		fmt.Printf("Deleted annotation %d\n", id)
+	}
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 	"text/tabwriter"
 	"time"
 
@@ -25,12 +27,14 @@ var (
 	projectsSort  string
 	projectsLimit int
 	projectsJSON  bool
+	projectsTag   string
 )
 
 func init() {
 	projectsCmd.Flags().StringVar(&projectsSort, "sort", "time", "sort by: name, time, sessions")
 	projectsCmd.Flags().IntVar(&projectsLimit, "limit", 0, "limit number of projects (0 = no limit)")
 	projectsCmd.Flags().BoolVar(&projectsJSON, "json", false, "output as JSON")
+	projectsCmd.Flags().StringVar(&projectsTag, "tag", "", "only projects tagged, or with sessions tagged, with this tag")
 }
 
 func runProjects(cmd *cobra.Command, args []string) error {
@@ -47,37 +51,63 @@ func runProjects(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	tags := loadItemTags("project")
+	if projectsTag != "" {
+		tag := strings.ToLower(projectsTag)
+		sessionTags := loadItemTags("session")
+		var filtered []*parser.Project
+		for _, p := range projects {
+			match := slices.Contains(tags[p.EncodedName], tag)
+			for _, s := range p.Sessions {
+				match = match || slices.Contains(sessionTags[s.ID], tag)
+			}
+			if match {
+				filtered = append(filtered, p)
+			}
+		}
+		projects = filtered
+		if len(projects) == 0 {
+			fmt.Println("No projects found.")
+			return nil
+		}
+	}
+
 	if projectsLimit > 0 && len(projects) > projectsLimit {
 		projects = projects[:projectsLimit]
 	}
 
 	if projectsJSON {
-		return printProjectsJSON(projects)
+		return printProjectsJSON(projects, tags)
 	}
 
-	return printProjectsTable(projects)
+	return printProjectsTable(projects, tags)
 }
 
-func printProjectsTable(projects []*parser.Project) error {
+func printProjectsTable(projects []*parser.Project, tags map[string][]string) error {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 	fmt.Fprintln(w, "PROJECT\tSESSIONS\tLAST MODIFIED")
 
 	for _, p := range projects {
 		age := formatAge(p.LastModified)
-		fmt.Fprintf(w, "%s\t%d\t%s\n", p.Name, len(p.Sessions), age)
+		name := p.Name
+		for _, t := range tags[p.EncodedName] {
+			name += " #" + t
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\n", name, len(p.Sessions), age)
 	}
 
 	return w.Flush()
 }
 
 type projectJSON struct {
-	Name         string `json:"name"`
-	EncodedName  string `json:"encoded_name"`
-	Sessions     int    `json:"sessions"`
-	LastModified string `json:"last_modified"`
+	Name         string   `json:"name"`
+	EncodedName  string   `json:"encoded_name"`
+	Sessions     int      `json:"sessions"`
+	LastModified string   `json:"last_modified"`
+	Tags         []string `json:"tags,omitempty"`
 }
 
-func printProjectsJSON(projects []*parser.Project) error {
+func printProjectsJSON(projects []*parser.Project, tags map[string][]string) error {
 	items := make([]projectJSON, len(projects))
 	for i, p := range projects {
 		items[i] = projectJSON{
@@ -85,6 +115,7 @@ func printProjectsJSON(projects []*parser.Project) error {
 			EncodedName:  p.EncodedName,
 			Sessions:     len(p.Sessions),
 			LastModified: p.LastModified.Format(time.RFC3339),
+			Tags:         tags[p.EncodedName],
 		}
 	}
 	enc := json.NewEncoder(os.Stdout)
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/thevibeworks/ccx/internal/config"
+	"github.com/thevibeworks/ccx/internal/db"
+	"github.com/thevibeworks/ccx/internal/web"
+)
+
+var publishCmd = &cobra.Command{
+	Use:   "publish [project...]",
+	Short: "Build a static website of sessions",
+	Long: `Build a static website of Claude Code sessions, to archive them on a wiki
+or any static file host.
+
+The site has an index of projects, a page listing each project's sessions
+and a page per session in the web UI's viewer, with notes as footnotes.
+The search box in the top bar searches session summaries and prompts from
+an index shipped with the site. Links are relative, so the site works from
+any static file server or opened straight from disk.
+
+Name projects as ccx projects lists them, or by part of the name; without
+any, every project is published. Files already in the output directory
+are overwritten, others are left alone.
+
+--redact masks secrets and personal data everywhere on the site, as
+ccx export --redact does. What was masked is reported on stderr.
+
+Examples:
+  ccx publish -o site/
+  ccx publish myproject otherproject -o sprint-42/
+  ccx publish myproject --redact -o site/`,
+	RunE: runPublish,
+}
+
+var (
+	publishOutput string
+	publishTheme  string
+	publishRedact bool
+)
+
+func init() {
+	publishCmd.Flags().StringVarP(&publishOutput, "output", "o", "site", "output directory")
+	publishCmd.Flags().StringVar(&publishTheme, "theme", "", "theme: dark, light (default from config)")
+	publishCmd.Flags().BoolVar(&publishRedact, "redact", false, "mask secrets and personal data")
+
+	rootCmd.AddCommand(publishCmd)
+}
+
+func runPublish(cmd *cobra.Command, args []string) error {
+	opts := web.PublishOptions{
+		Projects: args,
+		Theme:    publishTheme,
+	}
+	if opts.Theme == "" {
+		opts.Theme = config.Theme()
+	}
+	if publishRedact {
+		r, err := config.Redactor()
+		if err != nil {
+			return err
+		}
+		opts.Redactor = r
+	}
+
+	// Annotations are published as footnotes when the database is there
+	if err := db.Init(config.DataDir()); err == nil {
+		defer db.Close()
+	}
+
+	n, err := web.Publish(config.ProjectsDir(), publishOutput, opts)
+	if err != nil {
+		return fmt.Errorf("failed to publish: %w", err)
+	}
+	if opts.Redactor != nil {
+		fmt.Fprintf(os.Stderr, "Redacted: %s\n", opts.Redactor.Report())
+	}
+
+	fmt.Printf("Published %d sessions to: %s\n", n, filepath.Join(publishOutput, "index.html"))
+	return nil
+}
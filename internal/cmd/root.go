@@ -8,6 +8,7 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/thevibeworks/ccx/internal/config"
+	"github.com/thevibeworks/ccx/internal/parser"
 )
 
 var (
@@ -102,4 +103,6 @@ func initConfig() {
 	viper.SetDefault("export.default_format", "html")
 
 	_ = viper.ReadInConfig()
+
+	parser.SetCacheFile(config.SessionCacheFile())
 }
@@ -11,33 +11,61 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/thevibeworks/ccx/internal/config"
+	"github.com/thevibeworks/ccx/internal/db"
 	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/search"
 )
 
 var searchCmd = &cobra.Command{
 	Use:   "search QUERY",
 	Short: "Search across projects and sessions",
-	Long: `Search for projects and sessions by name or summary.
+	Long: `Search for projects and sessions by name or summary, and for
+messages by content (text, tool inputs, tool results).
+
+Message content is searched through a full-text index kept in the ccx
+database. The index is updated incrementally before each search, so only
+sessions that changed since the last run are reindexed.
+
+Queries accept qualifiers to narrow message and session results:
+
+  project:NAME   project name contains NAME
+  branch:NAME    session git branch
+  tag:NAME       session or its project is tagged NAME (see ccx tag)
+  model:ID       model ID contains ID (e.g. model:opus)
+  tool:NAME      message calls tool NAME
+  kind:KIND      user, assistant, command, meta or compact
+  is:error       a tool call in the message failed
+  after:DATE     on or after DATE (YYYY-MM-DD)
+  before:DATE    before DATE
+
+Use "quotes" for exact phrases, a trailing * for prefixes, a leading -
+to negate a word, phrase or qualifier, and key:a,b to match either value.
 
 Examples:
-  ccx search auth          # Find sessions about authentication
-  ccx search myproject     # Find project by name
-  ccx search "fix bug"     # Multi-word search
-  ccx search -t session    # Only search sessions`,
+  ccx search auth              # Find sessions about authentication
+  ccx search myproject         # Find project by name
+  ccx search "fix bug"         # Multi-word search
+  ccx search -t session auth   # Only search sessions
+  ccx search -t message EACCES # Only search message content
+  ccx search --reindex foo     # Rebuild the index before searching
+  ccx search 'tool:Bash is:error after:2026-09-01'
+  ccx search 'project:ccx "race condition" -test'`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: runSearch,
 }
 
 var (
-	searchType  string
-	searchLimit int
-	searchJSON  bool
+	searchType    string
+	searchLimit   int
+	searchJSON    bool
+	searchReindex bool
 )
 
 func init() {
-	searchCmd.Flags().StringVarP(&searchType, "type", "t", "", "filter by type: project, session")
-	searchCmd.Flags().IntVarP(&searchLimit, "limit", "n", 20, "max results")
+	searchCmd.Flags().StringVarP(&searchType, "type", "t", "", "filter by type: project, session, message")
+	searchCmd.Flags().IntVarP(&searchLimit, "limit", "n", 20, "max results (0 = no limit)")
 	searchCmd.Flags().BoolVar(&searchJSON, "json", false, "output as JSON")
+	searchCmd.Flags().BoolVar(&searchReindex, "reindex", false, "rebuild the message index from scratch")
 
 	rootCmd.AddCommand(searchCmd)
 }
@@ -46,13 +74,22 @@ type searchResult struct {
 	Type     string `json:"type"`
 	Project  string `json:"project"`
 	Session  string `json:"session,omitempty"`
+	Message  string `json:"message,omitempty"`
 	Summary  string `json:"summary"`
+	Snippet  string `json:"snippet,omitempty"`
 	Time     string `json:"time,omitempty"`
 	Priority int    `json:"-"`
 }
 
 func runSearch(cmd *cobra.Command, args []string) error {
-	query := strings.ToLower(strings.Join(args, " "))
+	q, err := search.Parse(strings.Join(args, " "))
+	if err != nil {
+		return fmt.Errorf("invalid query: %w", err)
+	}
+	if q.IsEmpty() {
+		return fmt.Errorf("empty query")
+	}
+	query := q.Text()
 	projectsDir := config.ProjectsDir()
 
 	projects, err := parser.DiscoverProjects(projectsDir)
@@ -60,14 +97,26 @@ func runSearch(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to discover projects: %w", err)
 	}
 
+	// The database holds tags (tag: qualifier) as well as the message index
+	dbErr := db.Init(config.DataDir())
+	if dbErr == nil {
+		defer db.Close()
+	}
+
 	var results []searchResult
 
+	// Name and ID matching only applies to plain queries
+	plain := query != "" && !q.HasFilters()
+
 	for _, p := range projects {
+		if !q.MatchProject(p) {
+			continue
+		}
 		projDisplay := parser.GetProjectDisplayName(p.EncodedName)
 		projPath := parser.DecodePath(p.EncodedName)
 
 		// Project name match (skip if filtering to sessions only)
-		if searchType != "session" {
+		if plain && searchType != "session" {
 			if strings.Contains(strings.ToLower(p.EncodedName), query) ||
 				strings.Contains(strings.ToLower(projPath), query) ||
 				strings.Contains(strings.ToLower(projDisplay), query) {
@@ -80,14 +129,14 @@ func runSearch(cmd *cobra.Command, args []string) error {
 			}
 		}
 
-		// Session search (skip if filtering to projects only)
-		if searchType == "project" {
+		// Session search (skip if filtering to projects or messages only)
+		if searchType == "project" || searchType == "message" {
 			continue
 		}
 
 		for _, s := range p.Sessions {
 			// Session ID match (high priority)
-			if strings.HasPrefix(strings.ToLower(s.ID), query) {
+			if plain && strings.HasPrefix(strings.ToLower(s.ID), query) {
 				results = append(results, searchResult{
 					Type:     "session",
 					Project:  projDisplay,
@@ -99,8 +148,8 @@ func runSearch(cmd *cobra.Command, args []string) error {
 				continue
 			}
 
-			// Summary match
-			if strings.Contains(strings.ToLower(s.Summary), query) {
+			// Summary and qualifier match
+			if q.MatchSession(p, s) {
 				results = append(results, searchResult{
 					Type:     "session",
 					Project:  projDisplay,
@@ -113,8 +162,21 @@ func runSearch(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Sort by priority
-	sort.Slice(results, func(i, j int) bool {
+	// Message content search via the full-text index
+	if (searchType == "" || searchType == "message") && q.SearchesMessages() {
+		var messages []searchResult
+		err := dbErr
+		if err == nil {
+			messages, err = searchMessages(projects, q)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: message search unavailable: %v\n", err)
+		}
+		results = append(results, messages...)
+	}
+
+	// Sort by priority; message hits keep their BM25 order
+	sort.SliceStable(results, func(i, j int) bool {
 		return results[i].Priority < results[j].Priority
 	})
 
@@ -137,6 +199,48 @@ func runSearch(cmd *cobra.Command, args []string) error {
 	return printSearchResults(results)
 }
 
+func searchMessages(projects []*parser.Project, q *search.Query) ([]searchResult, error) {
+	if searchReindex {
+		if err := db.ResetSearchIndex(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := search.Sync(projects); err != nil {
+		return nil, err
+	}
+
+	hits, err := search.Messages(projects, q, searchLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make(map[string]string)
+	for _, p := range projects {
+		for _, s := range p.Sessions {
+			summaries[p.EncodedName+"/"+s.ID] = s.Summary
+		}
+	}
+
+	var results []searchResult
+	for _, h := range hits {
+		summary, ok := summaries[h.Project+"/"+h.SessionID]
+		if !ok {
+			continue
+		}
+		results = append(results, searchResult{
+			Type:     "message",
+			Project:  parser.GetProjectDisplayName(h.Project),
+			Session:  truncateID(h.SessionID, 8),
+			Message:  h.UUID,
+			Summary:  truncate(summary, 60),
+			Snippet:  strings.Join(strings.Fields(h.Snippet), " "),
+			Time:     formatAge(h.Timestamp),
+			Priority: 3,
+		})
+	}
+	return results, nil
+}
+
 func printSearchResults(results []searchResult) error {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 	fmt.Fprintln(w, "TYPE\tPROJECT\tSESSION\tSUMMARY\tTIME")
@@ -150,8 +254,12 @@ func printSearchResults(results []searchResult) error {
 		if time == "" {
 			time = "-"
 		}
+		summary := r.Summary
+		if r.Snippet != "" {
+			summary = truncate(r.Snippet, 80)
+		}
 		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
-			r.Type, truncate(r.Project, 20), session, r.Summary, time)
+			r.Type, truncate(r.Project, 20), session, summary, time)
 	}
 
 	return w.Flush()
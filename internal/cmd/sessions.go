@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 	"text/tabwriter"
 	"time"
 
@@ -11,6 +13,7 @@ import (
 
 	"github.com/thevibeworks/ccx/internal/config"
 	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/pricing"
 )
 
 var sessionsCmd = &cobra.Command{
@@ -29,12 +32,14 @@ var (
 	sessionsSort  string
 	sessionsLimit int
 	sessionsJSON  bool
+	sessionsTag   string
 )
 
 func init() {
 	sessionsCmd.Flags().StringVar(&sessionsSort, "sort", "time", "sort by: time, messages")
 	sessionsCmd.Flags().IntVar(&sessionsLimit, "limit", 20, "limit number of sessions (0 = no limit)")
 	sessionsCmd.Flags().BoolVar(&sessionsJSON, "json", false, "output as JSON")
+	sessionsCmd.Flags().StringVar(&sessionsTag, "tag", "", "only sessions with this tag")
 }
 
 func runSessions(cmd *cobra.Command, args []string) error {
@@ -66,6 +71,18 @@ func runSessions(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	tags := loadItemTags("session")
+	if sessionsTag != "" {
+		tag := strings.ToLower(sessionsTag)
+		var filtered []*parser.Session
+		for _, s := range sessions {
+			if slices.Contains(tags[s.ID], tag) {
+				filtered = append(filtered, s)
+			}
+		}
+		sessions = filtered
+	}
+
 	if len(sessions) == 0 {
 		fmt.Println("No sessions found.")
 		return nil
@@ -75,22 +92,31 @@ func runSessions(cmd *cobra.Command, args []string) error {
 		sessions = sessions[:sessionsLimit]
 	}
 
+	prices, err := config.Pricing()
+	if err != nil {
+		return err
+	}
+
 	if sessionsJSON {
-		return printSessionsJSON(sessions)
+		return printSessionsJSON(sessions, tags, prices)
 	}
 
-	return printSessionsTable(sessions, projectName == "")
+	return printSessionsTable(sessions, projectName == "", tags, prices)
 }
 
-func printSessionsTable(sessions []*parser.Session, showProject bool) error {
+// printSessionsTable lists sessions with their estimated cost, and the
+// total of those listed at the end
+func printSessionsTable(sessions []*parser.Session, showProject bool, tags map[string][]string, prices *pricing.Table) error {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 
 	if showProject {
-		fmt.Fprintln(w, "PROJECT\tSESSION\tSTARTED\tSUMMARY")
+		fmt.Fprintln(w, "PROJECT\tSESSION\tSTARTED\tEST. COST\tSUMMARY")
 	} else {
-		fmt.Fprintln(w, "SESSION\tSTARTED\tSUMMARY")
+		fmt.Fprintln(w, "SESSION\tSTARTED\tEST. COST\tSUMMARY")
 	}
 
+	var total pricing.Estimate
+
 	for _, s := range sessions {
 		id := s.ID
 		if len(id) > 8 {
@@ -104,28 +130,43 @@ func printSessionsTable(sessions []*parser.Session, showProject bool) error {
 
 		age := formatAge(s.StartTime)
 
+		cost := prices.Session(s.Stats)
+		total.Add(cost)
+
+		for _, t := range tags[s.ID] {
+			summary += " #" + t
+		}
+
 		if showProject {
 			proj := s.ProjectName
 			if len(proj) > 20 {
 				proj = proj[:17] + "..."
 			}
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", proj, id, age, summary)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", proj, id, age, costString(cost), summary)
 		} else {
-			fmt.Fprintf(w, "%s\t%s\t%s\n", id, age, summary)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", id, age, costString(cost), summary)
 		}
 	}
 
-	return w.Flush()
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if !total.IsZero() {
+		fmt.Printf("\nEstimated cost of %d sessions: %s (from the pricing table; not billed amounts)\n", len(sessions), total)
+	}
+	return nil
 }
 
 type sessionJSON struct {
-	ID        string `json:"id"`
-	Project   string `json:"project"`
-	Summary   string `json:"summary"`
-	StartTime string `json:"start_time"`
+	ID        string           `json:"id"`
+	Project   string           `json:"project"`
+	Summary   string           `json:"summary"`
+	StartTime string           `json:"start_time"`
+	Cost      pricing.Estimate `json:"estimated_cost"`
+	Tags      []string         `json:"tags,omitempty"`
 }
 
-func printSessionsJSON(sessions []*parser.Session) error {
+func printSessionsJSON(sessions []*parser.Session, tags map[string][]string, prices *pricing.Table) error {
 	items := make([]sessionJSON, len(sessions))
 	for i, s := range sessions {
 		items[i] = sessionJSON{
@@ -133,6 +174,8 @@ func printSessionsJSON(sessions []*parser.Session) error {
 			Project:   s.ProjectName,
 			Summary:   s.Summary,
 			StartTime: s.StartTime.Format(time.RFC3339),
+			Cost:      prices.Session(s.Stats),
+			Tags:      tags[s.ID],
 		}
 	}
 	enc := json.NewEncoder(os.Stdout)
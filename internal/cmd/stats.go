@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/thevibeworks/ccx/internal/config"
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/pricing"
+	"github.com/thevibeworks/ccx/internal/stats"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Token, model and tool usage statistics",
+	Long: `Aggregate usage across sessions: tokens by model, tool calls and error
+rates, sessions per day, average session duration and compaction frequency.
+Costs are estimates from the pricing table (built in, overridable under
+"pricing" in config.yaml).
+
+Dates are YYYY-MM-DD (--until includes that day) or relative like 7d, 4w.
+
+Output formats:
+  table   human-readable summary and breakdowns (default)
+  json    the full report
+  csv     one row per value: section,key,metric,value
+
+Examples:
+  ccx stats --since 30d
+  ccx stats -p myproject --branch main
+  ccx stats --since 2026-09-01 --until 2026-09-30 -f csv > september.csv`,
+	Args: cobra.NoArgs,
+	RunE: runStats,
+}
+
+var (
+	statsProject string
+	statsBranch  string
+	statsSince   string
+	statsUntil   string
+	statsFormat  string
+)
+
+func init() {
+	statsCmd.Flags().StringVarP(&statsProject, "project", "p", "", "only sessions in projects matching this name")
+	statsCmd.Flags().StringVarP(&statsBranch, "branch", "b", "", "only sessions on this git branch")
+	statsCmd.Flags().StringVar(&statsSince, "since", "", "only messages at or after this date (YYYY-MM-DD or 7d)")
+	statsCmd.Flags().StringVar(&statsUntil, "until", "", "only messages up to this date (YYYY-MM-DD or 7d)")
+	statsCmd.Flags().StringVarP(&statsFormat, "format", "f", "table", "output format: table, json, csv")
+
+	rootCmd.AddCommand(statsCmd)
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	filter := stats.Filter{Project: statsProject, Branch: statsBranch}
+	var err error
+	if statsSince != "" {
+		if filter.After, err = stats.ParseDate(statsSince, false); err != nil {
+			return err
+		}
+	}
+	if statsUntil != "" {
+		if filter.Before, err = stats.ParseDate(statsUntil, true); err != nil {
+			return err
+		}
+	}
+
+	prices, err := config.Pricing()
+	if err != nil {
+		return err
+	}
+	report, err := stats.Collect(config.ProjectsDir(), filter, prices)
+	if err != nil {
+		return fmt.Errorf("failed to collect stats: %w", err)
+	}
+
+	switch strings.ToLower(statsFormat) {
+	case "table", "":
+		return printStatsTable(report, filter)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case "csv":
+		return printStatsCSV(report)
+	default:
+		return fmt.Errorf("unsupported format: %s (want table, json or csv)", statsFormat)
+	}
+}
+
+func printStatsTable(r *stats.Report, f stats.Filter) error {
+	if r.Sessions == 0 {
+		fmt.Println("No sessions found.")
+		return nil
+	}
+
+	duration := time.Duration(r.AvgDurationSeconds * float64(time.Second)).Round(time.Second)
+	fmt.Printf("Sessions:     %d in %d projects (%s)\n", r.Sessions, r.Projects, f.Scope())
+	fmt.Printf("Messages:     %d (%d prompts), avg session %s\n", r.Messages, r.UserPrompts, duration)
+	fmt.Printf("Tokens:       %d input, %d output, %d cache read, %d cache write\n",
+		r.Tokens.InputTokens, r.Tokens.OutputTokens, r.Tokens.CacheReadTokens, r.Tokens.CacheCreateTokens)
+	fmt.Printf("Est. cost:    %s (estimated from the pricing table)\n", costString(r.Cost))
+	fmt.Printf("Tool calls:   %d, %d failed (%s)\n", r.ToolCalls, r.ToolErrors, percent(r.ToolErrors, r.ToolCalls))
+	fmt.Printf("Compactions:  %d in %d sessions (%.2f per session)\n", r.Compactions, r.CompactedSessions, r.CompactionsPerSession())
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if len(r.ByModel) > 0 {
+		fmt.Fprintln(w, "\nMODEL\tRESPONSES\tINPUT\tOUTPUT\tCACHE READ\tCACHE WRITE\tEST. COST")
+		for _, m := range r.ByModel {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%s\n", m.Model, m.Messages,
+				m.Tokens.InputTokens, m.Tokens.OutputTokens, m.Tokens.CacheReadTokens, m.Tokens.CacheCreateTokens, costString(m.Cost))
+		}
+	}
+	if len(r.ByTool) > 0 {
+		fmt.Fprintln(w, "\nTOOL\tCALLS\tERRORS\tERROR RATE")
+		for _, t := range r.ByTool {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", t.Name, t.Calls, t.Errors, percent(t.Errors, t.Calls))
+		}
+	}
+	if len(r.ByProject) > 1 {
+		fmt.Fprintln(w, "\nPROJECT\tSESSIONS\tMESSAGES\tTOKENS\tEST. COST")
+		for _, p := range r.ByProject {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\n", truncate(p.Name, 40), p.Sessions, p.Messages, p.Tokens.Total(), costString(p.Cost))
+		}
+	}
+	fmt.Fprintln(w, "\nDATE\tSESSIONS\tMESSAGES\tTOKENS\tEST. COST")
+	for _, d := range r.ByDay {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\n", d.Date, d.Sessions, d.Messages, d.Tokens.Total(), costString(d.Cost))
+	}
+	return w.Flush()
+}
+
+// costString formats an estimate for a table cell, "-" for no usage
+func costString(e pricing.Estimate) string {
+	if e.IsZero() {
+		return "-"
+	}
+	return e.String()
+}
+
+func percent(n, of int) string {
+	if of == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(of))
+}
+
+// printStatsCSV writes the report in long form, one value per row, so
+// reports from several repos or periods can simply be concatenated
+func printStatsCSV(r *stats.Report) error {
+	w := csv.NewWriter(os.Stdout)
+	row := func(section, key, metric string, value any) {
+		_ = w.Write([]string{section, key, metric, fmt.Sprint(value)})
+	}
+	tokens := func(section, key string, t parser.Usage) {
+		row(section, key, "input_tokens", t.InputTokens)
+		row(section, key, "output_tokens", t.OutputTokens)
+		row(section, key, "cache_read_tokens", t.CacheReadTokens)
+		row(section, key, "cache_create_tokens", t.CacheCreateTokens)
+	}
+	cost := func(section, key string, e pricing.Estimate) {
+		row(section, key, "est_cost_usd", strconv.FormatFloat(e.USD, 'f', 4, 64))
+		row(section, key, "unpriced_tokens", e.Unpriced)
+	}
+
+	_ = w.Write([]string{"section", "key", "metric", "value"})
+	row("summary", "", "sessions", r.Sessions)
+	row("summary", "", "projects", r.Projects)
+	row("summary", "", "messages", r.Messages)
+	row("summary", "", "user_prompts", r.UserPrompts)
+	row("summary", "", "tool_calls", r.ToolCalls)
+	row("summary", "", "tool_errors", r.ToolErrors)
+	row("summary", "", "avg_duration_seconds", strconv.FormatFloat(r.AvgDurationSeconds, 'f', 1, 64))
+	row("summary", "", "compactions", r.Compactions)
+	row("summary", "", "compacted_sessions", r.CompactedSessions)
+	tokens("summary", "", r.Tokens)
+	cost("summary", "", r.Cost)
+
+	for _, m := range r.ByModel {
+		row("model", m.Model, "responses", m.Messages)
+		tokens("model", m.Model, m.Tokens)
+		cost("model", m.Model, m.Cost)
+	}
+	for _, t := range r.ByTool {
+		row("tool", t.Name, "calls", t.Calls)
+		row("tool", t.Name, "errors", t.Errors)
+		row("tool", t.Name, "error_rate", strconv.FormatFloat(t.ErrorRate, 'f', 4, 64))
+	}
+	for _, p := range r.ByProject {
+		row("project", p.Name, "sessions", p.Sessions)
+		row("project", p.Name, "messages", p.Messages)
+		tokens("project", p.Name, p.Tokens)
+		cost("project", p.Name, p.Cost)
+	}
+	for _, d := range r.ByDay {
+		row("day", d.Date, "sessions", d.Sessions)
+		row("day", d.Date, "messages", d.Messages)
+		tokens("day", d.Date, d.Tokens)
+		cost("day", d.Date, d.Cost)
+	}
+	for day, hours := range r.Activity {
+		for hour, n := range hours {
+			if n > 0 {
+				row("activity", fmt.Sprintf("%s %02d:00", time.Weekday(day).String()[:3], hour), "messages", n)
+			}
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
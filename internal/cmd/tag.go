@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/thevibeworks/ccx/internal/config"
+	"github.com/thevibeworks/ccx/internal/db"
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Tag sessions and projects",
+	Long: `Add, remove and list tags on sessions and projects.
+
+Tags show up as chips in the web UI and can be used to filter listings
+(ccx sessions --tag NAME, /project/NAME?tag=...) and searches (tag:NAME).
+
+Examples:
+  ccx tag add e38536 incident        Tag a session
+  ccx tag add -p myproject refactor  Tag a project
+  ccx tag rm e38536 incident         Remove a tag
+  ccx tag ls                         List all tags with counts
+  ccx tag ls e38536                  List a session's tags`,
+}
+
+var tagAddCmd = &cobra.Command{
+	Use:   "add [session] <tag>...",
+	Short: "Add tags to a session or project",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTagEdit(args, db.TagItemByName)
+	},
+}
+
+var tagRmCmd = &cobra.Command{
+	Use:     "rm [session] <tag>...",
+	Aliases: []string{"remove"},
+	Short:   "Remove tags from a session or project",
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTagEdit(args, db.UntagItemByName)
+	},
+}
+
+var tagLsCmd = &cobra.Command{
+	Use:     "ls [session]",
+	Aliases: []string{"list"},
+	Short:   "List all tags, or the tags of one session or project",
+	Args:    cobra.MaximumNArgs(1),
+	RunE:    runTagLs,
+}
+
+var tagProject string
+
+func init() {
+	for _, c := range []*cobra.Command{tagAddCmd, tagRmCmd, tagLsCmd} {
+		c.Flags().StringVarP(&tagProject, "project", "p", "", "tag a project instead of a session")
+		tagCmd.AddCommand(c)
+	}
+
+	rootCmd.AddCommand(tagCmd)
+}
+
+// resolveTagItem turns the --project flag or a session argument into an
+// item type and ID, returning the arguments left over
+func resolveTagItem(args []string) (itemType, itemID, label string, rest []string, err error) {
+	projectsDir := config.ProjectsDir()
+
+	if tagProject != "" {
+		project, err := parser.FindProject(projectsDir, tagProject)
+		if err != nil {
+			return "", "", "", nil, fmt.Errorf("failed to find project: %w", err)
+		}
+		if project == nil {
+			return "", "", "", nil, fmt.Errorf("project not found: %s", tagProject)
+		}
+		return "project", project.EncodedName, project.Name, args, nil
+	}
+
+	if len(args) == 0 {
+		return "", "", "", nil, fmt.Errorf("session argument required")
+	}
+	projectName, sessionID := parseSessionArg(args[0])
+	session, err := parser.FindSession(projectsDir, projectName, sessionID)
+	if err != nil {
+		return "", "", "", nil, fmt.Errorf("failed to find session: %w", err)
+	}
+	if session == nil {
+		return "", "", "", nil, fmt.Errorf("session not found: %s", args[0])
+	}
+	return "session", session.ID, truncateID(session.ID, 8), args[1:], nil
+}
+
+func runTagEdit(args []string, edit func(itemType, itemID, name string) error) error {
+	itemType, itemID, label, tags, err := resolveTagItem(args)
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return fmt.Errorf("no tags given")
+	}
+
+	if err := db.Init(config.DataDir()); err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	for _, tag := range tags {
+		if err := edit(itemType, itemID, tag); err != nil {
+			return fmt.Errorf("failed to update tag %q: %w", tag, err)
+		}
+	}
+	return printItemTags(itemType, itemID, label)
+}
+
+func runTagLs(cmd *cobra.Command, args []string) error {
+	var itemType, itemID, label string
+	if tagProject != "" || len(args) > 0 {
+		var err error
+		if itemType, itemID, label, _, err = resolveTagItem(args); err != nil {
+			return err
+		}
+	}
+
+	if err := db.Init(config.DataDir()); err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	if itemID != "" {
+		return printItemTags(itemType, itemID, label)
+	}
+
+	counts, err := db.GetTagCounts()
+	if err != nil {
+		return fmt.Errorf("failed to list tags: %w", err)
+	}
+	if len(counts) == 0 {
+		fmt.Println("No tags yet. Add one with: ccx tag add <session> <tag>")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TAG\tITEMS")
+	for _, c := range counts {
+		fmt.Fprintf(w, "%s\t%d\n", c.Name, c.Count)
+	}
+	return w.Flush()
+}
+
+func printItemTags(itemType, itemID, label string) error {
+	tags, err := db.GetItemTags(itemType, itemID)
+	if err != nil {
+		return fmt.Errorf("failed to read tags: %w", err)
+	}
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.Name
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		fmt.Printf("%s %s: no tags\n", itemType, label)
+		return nil
+	}
+	fmt.Printf("%s %s:", itemType, label)
+	for _, n := range names {
+		fmt.Printf(" #%s", n)
+	}
+	fmt.Println()
+	return nil
+}
+
+// loadItemTags returns item ID -> tag names, or nil if the database
+// cannot be opened; listings simply show no tags then
+func loadItemTags(itemType string) map[string][]string {
+	if err := db.Init(config.DataDir()); err != nil {
+		return nil
+	}
+	defer db.Close()
+	tags, _ := db.GetTagsByType(itemType)
+	return tags
+}
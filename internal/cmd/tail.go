@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/thevibeworks/ccx/internal/config"
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/render"
+	"github.com/thevibeworks/ccx/internal/watch"
+)
+
+var tailCmd = &cobra.Command{
+	Use:   "tail [session]",
+	Short: "Follow a live session in the terminal",
+	Long: `Print a session's messages as they are written, like tail -f.
+
+Without SESSION, follows the most recently modified session (in --project,
+if given). The last few messages are printed first; set --lines 0 to only
+show new ones.
+
+Examples:
+  ccx tail                      # Whatever is running now
+  ccx tail e38536 --tools-only  # Just the tool calls and their results
+  ccx tail --json | jq .type    # Raw JSONL lines`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runTail,
+}
+
+var (
+	tailProject    string
+	tailLines      int
+	tailToolsOnly  bool
+	tailNoThinking bool
+	tailJSON       bool
+)
+
+// tailBuffer is how many lines the terminal may fall behind the session
+// file, such as when a rewritten file is read again from the start
+const tailBuffer = 1 << 16
+
+func init() {
+	tailCmd.Flags().StringVarP(&tailProject, "project", "p", "", "project name")
+	tailCmd.Flags().IntVarP(&tailLines, "lines", "n", 10, "messages to print before following")
+	tailCmd.Flags().BoolVar(&tailToolsOnly, "tools-only", false, "only show tool calls and results")
+	tailCmd.Flags().BoolVar(&tailNoThinking, "no-thinking", false, "hide thinking blocks")
+	tailCmd.Flags().BoolVar(&tailJSON, "json", false, "pass raw JSONL lines through unchanged")
+
+	rootCmd.AddCommand(tailCmd)
+}
+
+func runTail(cmd *cobra.Command, args []string) error {
+	projectsDir := config.ProjectsDir()
+
+	var session *parser.Session
+	var err error
+	if len(args) > 0 {
+		projectName, sessionID := parseSessionArg(args[0])
+		if tailProject != "" {
+			projectName = tailProject
+		}
+		session, err = parser.FindSession(projectsDir, projectName, sessionID)
+	} else {
+		session, err = latestSession(projectsDir, tailProject)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find session: %w", err)
+	}
+	if session == nil {
+		return fmt.Errorf("session not found")
+	}
+
+	opts := render.TerminalOptions{
+		Thinking:   render.ThinkingShown,
+		ShowAgents: true,
+		ToolsOnly:  tailToolsOnly,
+		Theme:      config.Theme(),
+	}
+	if tailNoThinking {
+		opts.Thinking = render.ThinkingHidden
+	}
+	emit := func(line []byte) {
+		if tailJSON {
+			os.Stdout.Write(append(line, '\n'))
+			return
+		}
+		if msg, ok := parser.ParseLine(line); ok {
+			render.TerminalMessage(msg, opts)
+		}
+	}
+
+	// Status goes to stderr so --json output stays clean
+	fmt.Fprintf(os.Stderr, "Following %s  %s\n", truncateID(session.ID, 8), truncate(session.Summary, 60))
+
+	data, err := os.ReadFile(session.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read session: %w", err)
+	}
+	// A partial last line is delivered by the watcher once it is complete
+	end := bytes.LastIndexByte(data, '\n') + 1
+	var backlog [][]byte
+	for _, line := range bytes.Split(data[:end], []byte("\n")) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			backlog = append(backlog, line)
+		}
+	}
+	for _, line := range lastMessages(backlog, tailLines) {
+		emit(line)
+	}
+
+	events, cancel, err := watch.Follow(session.FilePath, int64(end), tailBuffer)
+	if err != nil {
+		return fmt.Errorf("failed to follow session: %w", err)
+	}
+	defer cancel()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-events:
+			if !ok {
+				return fmt.Errorf("fell behind the session; run ccx tail again")
+			}
+			if ev.Type == "reset" {
+				fmt.Fprintln(os.Stderr, "Session file was rewritten; reading from the start")
+				continue
+			}
+			emit([]byte(ev.Data))
+		}
+	}
+}
+
+// latestSession is the session whose file changed last
+func latestSession(projectsDir, project string) (*parser.Session, error) {
+	projects, err := parser.SelectProjects(projectsDir, project)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *parser.Session
+	var latestMod time.Time
+	for _, p := range projects {
+		for _, s := range p.Sessions {
+			info, err := os.Stat(s.FilePath)
+			if err != nil {
+				continue
+			}
+			if latest == nil || info.ModTime().After(latestMod) {
+				latest, latestMod = s, info.ModTime()
+			}
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no sessions found")
+	}
+	return latest, nil
+}
+
+// lastMessages keeps the last n lines that are user or assistant messages
+func lastMessages(lines [][]byte, n int) [][]byte {
+	if n <= 0 {
+		return nil
+	}
+	start := len(lines)
+	for count := 0; start > 0 && count < n; {
+		start--
+		if _, ok := parser.ParseLine(lines[start]); ok {
+			count++
+		}
+	}
+	return lines[start:]
+}
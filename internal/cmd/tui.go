@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/thevibeworks/ccx/internal/config"
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/tui"
+)
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui [session]",
+	Short: "Browse sessions in a full-screen terminal UI",
+	Long: `Browse projects and sessions in the terminal, the same two-panel layout
+as the web UI: projects and sessions on the left, the conversation on the right.
+
+Keys:
+  Tab        Switch pane            j/k     Move
+  Enter      Open / fold block      J/K     Previous / next message
+  /          Filter or search       n/N     Next / previous match
+  t          Toggle all thinking    o       Toggle all tool calls
+  w          Live tail on/off       ?       Help
+  q          Quit
+
+If SESSION is given, it opens straight into that conversation.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runTUI,
+}
+
+var tuiProject string
+
+func init() {
+	tuiCmd.Flags().StringVarP(&tuiProject, "project", "p", "", "project name")
+	rootCmd.AddCommand(tuiCmd)
+}
+
+func runTUI(cmd *cobra.Command, args []string) error {
+	projectsDir := config.ProjectsDir()
+
+	var session *parser.Session
+	if len(args) > 0 {
+		projectName, sessionID := parseSessionArg(args[0])
+		if tuiProject != "" {
+			projectName = tuiProject
+		}
+		var err error
+		session, err = parser.FindSession(projectsDir, projectName, sessionID)
+		if err != nil {
+			return fmt.Errorf("failed to find session: %w", err)
+		}
+	}
+
+	return tui.Run(projectsDir, session)
+}
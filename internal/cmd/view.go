@@ -2,9 +2,10 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
-	"strconv"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -12,8 +13,13 @@ import (
 	"github.com/thevibeworks/ccx/internal/config"
 	"github.com/thevibeworks/ccx/internal/parser"
 	"github.com/thevibeworks/ccx/internal/render"
+	"github.com/thevibeworks/ccx/internal/tui"
 )
 
+// stdin is shared by every prompt so that buffered input isn't lost
+// between them
+var stdin = bufio.NewReader(os.Stdin)
+
 var viewCmd = &cobra.Command{
 	Use:   "view [session]",
 	Short: "View a session in terminal",
@@ -25,7 +31,7 @@ SESSION can be:
   - Index: @1 (most recent), @2 (second most recent)
   - With project: myproject:e38536
 
-If SESSION is omitted, shows an interactive picker.`,
+If SESSION is omitted, pick one with the fuzzy finder.`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runView,
 }
@@ -51,7 +57,7 @@ func runView(cmd *cobra.Command, args []string) error {
 	var err error
 
 	if len(args) == 0 {
-		session, err = selectSession(projectsDir)
+		session, err = selectSession(projectsDir, viewProject, "View session")
 	} else {
 		sessionArg := args[0]
 		projectName, sessionID := parseSessionArg(sessionArg)
@@ -61,6 +67,9 @@ func runView(cmd *cobra.Command, args []string) error {
 		session, err = parser.FindSession(projectsDir, projectName, sessionID)
 	}
 
+	if errors.Is(err, tui.ErrCancelled) {
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to find session: %w", err)
 	}
@@ -74,10 +83,12 @@ func runView(cmd *cobra.Command, args []string) error {
 	}
 
 	opts := render.TerminalOptions{
-		ShowThinking: viewShowThinking,
-		ShowAgents:   viewShowAgents,
-		FlatMode:     viewFlat,
-		Theme:        config.Theme(),
+		ShowAgents: viewShowAgents,
+		FlatMode:   viewFlat,
+		Theme:      config.Theme(),
+	}
+	if viewShowThinking {
+		opts.Thinking = render.ThinkingShown
 	}
 
 	return render.Terminal(fullSession, opts)
@@ -91,48 +102,30 @@ func parseSessionArg(arg string) (project, session string) {
 	return "", arg
 }
 
-func selectSession(projectsDir string) (*parser.Session, error) {
-	projects, err := parser.DiscoverProjects(projectsDir)
+// selectSession asks for a session: a fuzzy finder over every session, or
+// just those of project, when on a terminal, otherwise a list answered on
+// stdin
+func selectSession(projectsDir, project, prompt string) (*parser.Session, error) {
+	projects, err := parser.SelectProjects(projectsDir, project)
 	if err != nil {
 		return nil, err
 	}
 
-	var allSessions []*parser.Session
+	var cands []tui.Candidate
 	for _, p := range projects {
 		for _, s := range p.Sessions {
-			s.ProjectName = p.Name
-			allSessions = append(allSessions, s)
+			cands = append(cands, tui.Candidate{Session: s, Project: p.Name})
 		}
 	}
-
-	if len(allSessions) == 0 {
+	if len(cands) == 0 {
 		return nil, fmt.Errorf("no sessions found")
 	}
+	sort.SliceStable(cands, func(i, j int) bool {
+		return cands[i].Session.EndTime.After(cands[j].Session.EndTime)
+	})
 
-	fmt.Println("Recent sessions:")
-	limit := 10
-	if len(allSessions) < limit {
-		limit = len(allSessions)
+	if tui.IsTerminal() {
+		return tui.Pick(cands, prompt)
 	}
-
-	for i, s := range allSessions[:limit] {
-		summary := s.Summary
-		if len(summary) > 50 {
-			summary = summary[:47] + "..."
-		}
-		fmt.Printf("  %d. [%s] %s\n", i+1, s.ProjectName, summary)
-	}
-
-	fmt.Printf("\nSelect session (1-%d): ", limit)
-	scanner := bufio.NewScanner(os.Stdin)
-	if !scanner.Scan() {
-		return nil, fmt.Errorf("no input")
-	}
-	input := strings.TrimSpace(scanner.Text())
-	choice, err := strconv.Atoi(input)
-	if err != nil || choice < 1 || choice > limit {
-		return nil, fmt.Errorf("invalid selection: %s", input)
-	}
-
-	return allSessions[choice-1], nil
+	return tui.PickFrom(cands, prompt, stdin, os.Stderr)
 }
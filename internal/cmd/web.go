@@ -6,6 +6,7 @@ import (
 	"runtime"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	"github.com/thevibeworks/ccx/internal/config"
 	"github.com/thevibeworks/ccx/internal/db"
@@ -27,7 +28,12 @@ Features:
   - Dark/light theme toggle (press 'd')
   - Keyboard navigation (j/k scroll, / search, z fold, r refresh)
 
-Opens browser automatically. Use --no-open to disable.`,
+Opens browser automatically. Use --no-open to disable.
+
+--redact masks secrets and personal data in everything the server sends,
+for sharing the UI or a screen: pages, search results, live updates and
+exports. Set redact.web in config.yaml to always do so. Without it,
+exports can still be masked one at a time from the export menu.`,
 	RunE: runWeb,
 }
 
@@ -35,18 +41,23 @@ var (
 	webPort   int
 	webHost   string
 	webNoOpen bool
+	webRedact bool
 )
 
 func init() {
 	webCmd.Flags().IntVarP(&webPort, "port", "p", 8080, "port to listen on")
 	webCmd.Flags().StringVar(&webHost, "host", "localhost", "host to bind to")
 	webCmd.Flags().BoolVar(&webNoOpen, "no-open", false, "don't open browser automatically")
+	webCmd.Flags().BoolVar(&webRedact, "redact", false, "mask secrets and personal data in everything served")
 
 	rootCmd.AddCommand(webCmd)
 }
 
 func runWeb(cmd *cobra.Command, args []string) error {
 	projectsDir := config.ProjectsDir()
+	if webRedact {
+		viper.Set("redact.web", true)
+	}
 	addr := fmt.Sprintf("%s:%d", webHost, webPort)
 	url := fmt.Sprintf("http://%s", addr)
 
@@ -0,0 +1,374 @@
+// Package compare lines up two conversations by user prompt: two sessions
+// that ran the same task, or two branches of one session. Each side is a
+// single path through its message tree.
+package compare
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/pricing"
+)
+
+// Side is one of the two compared conversations
+type Side struct {
+	Session *parser.Session
+	Leaf    string // UUID of the last message on the compared path
+	Turns   []*Turn
+	Models  []string // In order of first use
+	Activity
+	Cost pricing.Estimate
+}
+
+// Activity is what a side or a turn did
+type Activity struct {
+	Tools     map[string]int  // Calls by tool name, agent transcripts included
+	Files     map[string]bool // Paths read or changed; true if changed
+	Usage     parser.Usage
+	Start     time.Time
+	End       time.Time
+	Responses int
+}
+
+// Duration is the time from the first to the last message
+func (a Activity) Duration() time.Duration {
+	return a.End.Sub(a.Start)
+}
+
+// ToolCalls is the number of tool calls
+func (a Activity) ToolCalls() int {
+	n := 0
+	for _, c := range a.Tools {
+		n += c
+	}
+	return n
+}
+
+// Turn is a user prompt or slash command and everything up to the next
+type Turn struct {
+	Prompt   *parser.Message // nil for messages before the first prompt
+	Messages []*parser.Message
+	Activity
+}
+
+// Text is the prompt as typed, or the command and its arguments
+func (t *Turn) Text() string {
+	if t.Prompt == nil {
+		return ""
+	}
+	return t.Prompt.PromptText()
+}
+
+// key is the prompt text the alignment matches on
+func (t *Turn) key() string {
+	return strings.Join(strings.Fields(t.Text()), " ")
+}
+
+// Row is one line of the comparison: a prompt both sides share, or one
+// only a single side has
+type Row struct {
+	A, B   *Turn // nil on the side without this prompt
+	Shared bool  // The same message on both sides, as on two branches before they fork
+}
+
+// Differs reports whether the row is one-sided or its two turns called
+// different tools or touched different files
+func (r Row) Differs() bool {
+	if r.Shared {
+		return false
+	}
+	if r.A == nil || r.B == nil {
+		return true
+	}
+	return !sameCounts(r.A.Tools, r.B.Tools) || !sameKeys(r.A.Files, r.B.Files)
+}
+
+// Result is a comparison of two sides
+type Result struct {
+	A, B    *Side
+	Rows    []Row
+	Diverge int // Index of the first row that differs; -1 if none does
+}
+
+// Sessions compares two parsed sessions. leafA and leafB pick the path to
+// compare by the UUID, or a UUID prefix, of its last message; empty picks
+// the path ending at the most recent message.
+func Sessions(a *parser.Session, leafA string, b *parser.Session, leafB string, prices *pricing.Table) (*Result, error) {
+	sideA, err := newSide(a, leafA, prices)
+	if err != nil {
+		return nil, err
+	}
+	sideB, err := newSide(b, leafB, prices)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{A: sideA, B: sideB, Rows: align(sideA.Turns, sideB.Turns), Diverge: -1}
+	for i, row := range res.Rows {
+		if row.Differs() {
+			res.Diverge = i
+			break
+		}
+	}
+	return res, nil
+}
+
+func newSide(s *parser.Session, leaf string, prices *pricing.Table) (*Side, error) {
+	last, err := findLeaf(s, leaf)
+	if err != nil {
+		return nil, err
+	}
+	side := &Side{Session: s, Leaf: last, Activity: newActivity()}
+	if last == "" {
+		return side, nil
+	}
+
+	models := make(map[string]bool)
+	var turn *Turn
+	for _, msg := range parser.Path(s.RootMessages, last) {
+		if msg.Kind == parser.KindUserPrompt || msg.Kind == parser.KindCommand || turn == nil {
+			turn = &Turn{Activity: newActivity()}
+			if msg.Kind == parser.KindUserPrompt || msg.Kind == parser.KindCommand {
+				turn.Prompt = msg
+			}
+			side.Turns = append(side.Turns, turn)
+		}
+		turn.Messages = append(turn.Messages, msg)
+		turn.addMessage(msg)
+		side.addMessage(msg)
+		side.Cost.Add(prices.Message(msg))
+		if msg.Model != "" && !models[msg.Model] {
+			models[msg.Model] = true
+			side.Models = append(side.Models, msg.Model)
+		}
+		for _, block := range msg.Content {
+			if block.Sidechain != nil {
+				side.Cost.Add(prices.Session(block.Sidechain.Stats))
+			}
+		}
+	}
+	return side, nil
+}
+
+// findLeaf resolves a leaf UUID or prefix; empty picks the branch that
+// ends most recently
+func findLeaf(s *parser.Session, leaf string) (string, error) {
+	if leaf == "" {
+		latest := parser.LatestBranch(s.Branches)
+		if latest == nil {
+			return "", nil
+		}
+		return latest.LeafUUID, nil
+	}
+
+	var found *parser.Message
+	var walk func(msgs []*parser.Message) error
+	walk = func(msgs []*parser.Message) error {
+		for _, m := range msgs {
+			if !m.IsSidechain && strings.HasPrefix(m.UUID, leaf) {
+				if found != nil && found.UUID != m.UUID {
+					return fmt.Errorf("message %q is ambiguous in session %s", leaf, s.ID)
+				}
+				found = m
+			}
+			if err := walk(m.Children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(s.RootMessages); err != nil {
+		return "", err
+	}
+	if found == nil {
+		return "", fmt.Errorf("message %q not found in session %s", leaf, s.ID)
+	}
+	return found.UUID, nil
+}
+
+func newActivity() Activity {
+	return Activity{Tools: make(map[string]int), Files: make(map[string]bool)}
+}
+
+func (a *Activity) addMessage(msg *parser.Message) {
+	if a.Start.IsZero() || msg.Timestamp.Before(a.Start) {
+		a.Start = msg.Timestamp
+	}
+	if msg.Timestamp.After(a.End) {
+		a.End = msg.Timestamp
+	}
+	if msg.Usage != nil {
+		a.Usage.Add(*msg.Usage)
+		a.Responses++
+	}
+	for _, block := range msg.Content {
+		if block.Type != "tool_use" {
+			continue
+		}
+		a.Tools[block.ToolName]++
+		if path := toolFile(block.ToolInput); path != "" {
+			a.Files[path] = a.Files[path] || parser.IsEditTool(block.ToolName)
+		}
+		if sc := block.Sidechain; sc != nil {
+			a.addSidechain(sc)
+		}
+	}
+}
+
+// addSidechain counts an agent's tool calls, files and tokens toward the
+// turn that spawned it
+func (a *Activity) addSidechain(sc *parser.Sidechain) {
+	var walk func(msgs []*parser.Message)
+	walk = func(msgs []*parser.Message) {
+		for _, msg := range msgs {
+			for _, block := range msg.Content {
+				if block.Type != "tool_use" {
+					continue
+				}
+				a.Tools[block.ToolName]++
+				if path := toolFile(block.ToolInput); path != "" {
+					a.Files[path] = a.Files[path] || parser.IsEditTool(block.ToolName)
+				}
+			}
+			walk(msg.Children)
+		}
+	}
+	walk(sc.RootMessages)
+	a.Usage.Add(parser.Usage{
+		InputTokens:       sc.Stats.InputTokens,
+		OutputTokens:      sc.Stats.OutputTokens,
+		CacheReadTokens:   sc.Stats.CacheReadTokens,
+		CacheCreateTokens: sc.Stats.CacheCreateTokens,
+	})
+}
+
+func toolFile(input any) string {
+	m, ok := input.(map[string]any)
+	if !ok {
+		return ""
+	}
+	for _, key := range []string{"file_path", "notebook_path"} {
+		if p, ok := m[key].(string); ok && p != "" {
+			return p
+		}
+	}
+	return ""
+}
+
+// align pairs turns by prompt text along their longest common subsequence,
+// keeping one-sided turns in place between the pairs
+func align(a, b []*Turn) []Row {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if matchTurns(a[i], b[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var rows []Row
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case matchTurns(a[i], b[j]):
+			shared := a[i].Prompt != nil && b[j].Prompt != nil && a[i].Prompt.UUID == b[j].Prompt.UUID &&
+				a[i].Messages[len(a[i].Messages)-1].UUID == b[j].Messages[len(b[j].Messages)-1].UUID
+			rows = append(rows, Row{A: a[i], B: b[j], Shared: shared})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			rows = append(rows, Row{A: a[i]})
+			i++
+		default:
+			rows = append(rows, Row{B: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		rows = append(rows, Row{A: a[i]})
+	}
+	for ; j < m; j++ {
+		rows = append(rows, Row{B: b[j]})
+	}
+	return rows
+}
+
+func matchTurns(a, b *Turn) bool {
+	return a.key() == b.key()
+}
+
+// FileDiff splits the files two sides touched into those only one touched
+// and those both did, each sorted
+func FileDiff(a, b map[string]bool) (onlyA, onlyB, both []string) {
+	for f := range a {
+		if _, ok := b[f]; ok {
+			both = append(both, f)
+		} else {
+			onlyA = append(onlyA, f)
+		}
+	}
+	for f := range b {
+		if _, ok := a[f]; !ok {
+			onlyB = append(onlyB, f)
+		}
+	}
+	sort.Strings(onlyA)
+	sort.Strings(onlyB)
+	sort.Strings(both)
+	return onlyA, onlyB, both
+}
+
+// ToolNames lists the tools either side called, most calls first
+func ToolNames(a, b map[string]int) []string {
+	var names []string
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ci, cj := a[names[i]]+b[names[i]], a[names[j]]+b[names[j]]
+		if ci != cj {
+			return ci > cj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+func sameCounts(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func sameKeys(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
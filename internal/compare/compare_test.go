@@ -0,0 +1,84 @@
+package compare
+
+import (
+	"testing"
+
+	"github.com/thevibeworks/ccx/internal/testutil"
+)
+
+func TestSessionsAlignsByPrompt(t *testing.T) {
+	a := testutil.ParseSession(t, `{"type":"user","timestamp":"2026-01-01T10:00:00Z","uuid":"u1","message":{"content":"Fix the bug"}}
+{"type":"assistant","timestamp":"2026-01-01T10:00:05Z","uuid":"a1","parentUuid":"u1","message":{"model":"claude-opus-4-5","usage":{"input_tokens":100,"output_tokens":10},"content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/src/main.go"}}]}}
+{"type":"assistant","timestamp":"2026-01-01T10:00:10Z","uuid":"a2","parentUuid":"a1","message":{"model":"claude-opus-4-5","usage":{"input_tokens":100,"output_tokens":10},"content":[{"type":"tool_use","id":"t2","name":"Edit","input":{"file_path":"/src/main.go"}}]}}
+{"type":"user","timestamp":"2026-01-01T10:01:00Z","uuid":"u2","parentUuid":"a2","message":{"content":"Add a test"}}
+{"type":"assistant","timestamp":"2026-01-01T10:01:30Z","uuid":"a3","parentUuid":"u2","message":{"model":"claude-opus-4-5","usage":{"input_tokens":100,"output_tokens":10},"content":"Done"}}
+`)
+	b := testutil.ParseSession(t, `{"type":"user","timestamp":"2026-01-02T10:00:00Z","uuid":"v1","message":{"content":"Fix  the bug"}}
+{"type":"assistant","timestamp":"2026-01-02T10:00:05Z","uuid":"b1","parentUuid":"v1","message":{"model":"claude-sonnet-4-5","usage":{"input_tokens":50,"output_tokens":5},"content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/src/main.go"}}]}}
+{"type":"assistant","timestamp":"2026-01-02T10:00:10Z","uuid":"b2","parentUuid":"b1","message":{"model":"claude-sonnet-4-5","usage":{"input_tokens":50,"output_tokens":5},"content":[{"type":"tool_use","id":"t2","name":"Edit","input":{"file_path":"/src/main.go"}}]}}
+{"type":"user","timestamp":"2026-01-02T10:01:00Z","uuid":"v2","parentUuid":"b2","message":{"content":"Explain it"}}
+{"type":"assistant","timestamp":"2026-01-02T10:01:10Z","uuid":"b3","parentUuid":"v2","message":{"model":"claude-sonnet-4-5","content":[{"type":"tool_use","id":"t3","name":"Read","input":{"file_path":"/src/util.go"}}]}}
+`)
+
+	res, err := Sessions(a, "", b, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Rows) != 3 {
+		t.Fatalf("len(Rows) = %d, want 3", len(res.Rows))
+	}
+	if r := res.Rows[0]; r.A == nil || r.B == nil || r.Shared || r.Differs() {
+		t.Errorf("row 0 = %+v, want a matched row with the same tools", r)
+	}
+	if res.Diverge != 1 {
+		t.Errorf("Diverge = %d, want 1", res.Diverge)
+	}
+	if res.A.Usage.Total() != 330 || res.B.Usage.Total() != 110 {
+		t.Errorf("tokens = %d, %d; want 330, 110", res.A.Usage.Total(), res.B.Usage.Total())
+	}
+	if res.A.ToolCalls() != 2 || res.B.ToolCalls() != 3 {
+		t.Errorf("tool calls = %d, %d; want 2, 3", res.A.ToolCalls(), res.B.ToolCalls())
+	}
+	onlyA, onlyB, both := FileDiff(res.A.Files, res.B.Files)
+	if len(onlyA) != 0 || len(onlyB) != 1 || onlyB[0] != "/src/util.go" || len(both) != 1 {
+		t.Errorf("FileDiff = %v, %v, %v", onlyA, onlyB, both)
+	}
+	if !res.A.Files["/src/main.go"] || res.B.Files["/src/util.go"] {
+		t.Errorf("edited flags: A %v, B %v", res.A.Files, res.B.Files)
+	}
+	if len(res.B.Models) != 1 || res.B.Models[0] != "claude-sonnet-4-5" {
+		t.Errorf("B.Models = %v", res.B.Models)
+	}
+}
+
+func TestSessionsComparesBranches(t *testing.T) {
+	// The user rewound to u1's answer and asked something else
+	s := testutil.ParseSession(t, `{"type":"user","timestamp":"2026-01-01T10:00:00Z","uuid":"u1","message":{"content":"Plan it"}}
+{"type":"assistant","timestamp":"2026-01-01T10:00:05Z","uuid":"a1","parentUuid":"u1","message":{"content":"Plan"}}
+{"type":"user","timestamp":"2026-01-01T10:01:00Z","uuid":"u2","parentUuid":"a1","message":{"content":"Use Go"}}
+{"type":"assistant","timestamp":"2026-01-01T10:01:05Z","uuid":"a2","parentUuid":"u2","message":{"content":"Go it is"}}
+{"type":"user","timestamp":"2026-01-01T10:02:00Z","uuid":"u3","parentUuid":"a1","message":{"content":"Use Rust"}}
+{"type":"assistant","timestamp":"2026-01-01T10:02:05Z","uuid":"a3","parentUuid":"u3","message":{"content":"Rust it is"}}
+`)
+
+	res, err := Sessions(s, "a2", s, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.B.Leaf != "a3" {
+		t.Errorf("default leaf = %q, want the latest, a3", res.B.Leaf)
+	}
+	if len(res.Rows) != 3 || !res.Rows[0].Shared {
+		t.Fatalf("Rows = %+v, want a shared first turn and two one-sided ones", res.Rows)
+	}
+	if res.Diverge != 1 {
+		t.Errorf("Diverge = %d, want 1", res.Diverge)
+	}
+
+	if _, err := Sessions(s, "zz", s, "", nil); err == nil {
+		t.Error("expected error for unknown leaf")
+	}
+	if _, err := Sessions(s, "a", s, "", nil); err == nil {
+		t.Error("expected error for ambiguous leaf")
+	}
+}
@@ -1,10 +1,17 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"os/exec"
+	"os/user"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
+
+	"github.com/thevibeworks/ccx/internal/pricing"
+	"github.com/thevibeworks/ccx/internal/redact"
 )
 
 func DefaultClaudeHome() string {
@@ -49,6 +56,58 @@ func DefaultExportFormat() string {
 	return viper.GetString("export.default_format")
 }
 
+// Author is the name recorded on new annotations: the author setting,
+// falling back to the login name
+func Author() string {
+	if v := viper.GetString("author"); v != "" {
+		return v
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// PatchAuthor is the "Name <email>" that signs exported patch commits: the
+// export.patch_author setting, falling back to the git identity. "" when
+// neither is set.
+func PatchAuthor() string {
+	if v := viper.GetString("export.patch_author"); v != "" {
+		return v
+	}
+	name, _ := exec.Command("git", "config", "user.name").Output()
+	email, _ := exec.Command("git", "config", "user.email").Output()
+	if n, e := strings.TrimSpace(string(name)), strings.TrimSpace(string(email)); n != "" && e != "" {
+		return fmt.Sprintf("%s <%s>", n, e)
+	}
+	return ""
+}
+
+// Pricing is the table for cost estimates: the built-in rates with the
+// pricing entries of config.yaml applied over them
+func Pricing() (*pricing.Table, error) {
+	var rates []pricing.Rate
+	if err := viper.UnmarshalKey("pricing", &rates); err != nil {
+		return nil, fmt.Errorf("invalid pricing table: %w", err)
+	}
+	return pricing.New(rates)
+}
+
+// Redactor masks secrets with the built-in detectors and the redact
+// settings of config.yaml: extra patterns and detectors to turn off
+func Redactor() (*redact.Redactor, error) {
+	var patterns []redact.Pattern
+	if err := viper.UnmarshalKey("redact.patterns", &patterns); err != nil {
+		return nil, fmt.Errorf("invalid redact patterns: %w", err)
+	}
+	return redact.New(patterns, viper.GetStringSlice("redact.disable"))
+}
+
+// RedactWeb reports whether the web server masks everything it serves
+func RedactWeb() bool {
+	return viper.GetBool("redact.web")
+}
+
 func DataDir() string {
 	// XDG_DATA_HOME, or fallback to ~/.local/share
 	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
@@ -61,6 +120,25 @@ func DataDir() string {
 	return filepath.Join(home, ".local", "share", "ccx")
 }
 
+// TemplatesDir holds export templates that override the built-in formats:
+// html.tmpl, md.tmpl, org.tmpl
+func TemplatesDir() string {
+	// XDG_CONFIG_HOME, or fallback to ~/.config
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "ccx", "templates")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "ccx", "templates")
+}
+
+// SessionCacheFile is where parsed session metadata is cached between runs
+func SessionCacheFile() string {
+	return filepath.Join(DataDir(), "session-cache.json")
+}
+
 func expandPath(path string) string {
 	if len(path) > 0 && path[0] == '~' {
 		home, err := os.UserHomeDir()
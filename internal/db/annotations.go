@@ -0,0 +1,230 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Annotation is a note on a message, optionally highlighting a character
+// range inside one of its text blocks
+type Annotation struct {
+	ID          int64     `json:"id"`
+	Project     string    `json:"project"` // Project encoded name
+	SessionID   string    `json:"session_id"`
+	MessageUUID string    `json:"message_uuid"`
+	Block       int       `json:"block"` // Index among the message's text blocks, -1 for the whole message
+	Start       int       `json:"start"` // Highlight offsets into the block text; End 0 means no highlight
+	End         int       `json:"end"`
+	Quote       string    `json:"quote,omitempty"` // Highlighted text, used to re-anchor the highlight
+	Note        string    `json:"note"`
+	Author      string    `json:"author"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// HasHighlight reports whether the annotation marks a range of text
+func (a Annotation) HasHighlight() bool {
+	return a.Block >= 0 && a.End > a.Start
+}
+
+// AnnotationFilter narrows ListAnnotations; empty fields match everything
+type AnnotationFilter struct {
+	Project   string
+	SessionID string
+	Author    string
+	Limit     int
+}
+
+func migrateAnnotations() error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS annotations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		project TEXT NOT NULL,
+		session_id TEXT NOT NULL,
+		message_uuid TEXT NOT NULL,
+		block INTEGER NOT NULL DEFAULT -1,
+		start_offset INTEGER NOT NULL DEFAULT 0,
+		end_offset INTEGER NOT NULL DEFAULT 0,
+		quote TEXT NOT NULL DEFAULT '',
+		note TEXT NOT NULL DEFAULT '',
+		author TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_annotations_session ON annotations(session_id);
+	CREATE INDEX IF NOT EXISTS idx_annotations_message ON annotations(message_uuid);
+	`)
+	return err
+}
+
+// validateAnnotation checks the parts of an annotation a client can send
+func validateAnnotation(a *Annotation) error {
+	a.Note = strings.TrimSpace(a.Note)
+	if a.SessionID == "" || a.MessageUUID == "" {
+		return fmt.Errorf("annotation needs a session and message")
+	}
+	if a.Block < 0 {
+		a.Block, a.Start, a.End = -1, 0, 0
+	}
+	if a.Start < 0 || a.End < a.Start {
+		return fmt.Errorf("invalid highlight range %d-%d", a.Start, a.End)
+	}
+	if a.Note == "" && !a.HasHighlight() {
+		return fmt.Errorf("empty annotation")
+	}
+	return nil
+}
+
+// AddAnnotation stores a new annotation and fills in its ID and timestamps
+func AddAnnotation(a *Annotation) error {
+	if db == nil {
+		return ErrNotInitialized
+	}
+	if err := validateAnnotation(a); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	res, err := db.Exec(
+		`INSERT INTO annotations (project, session_id, message_uuid, block, start_offset, end_offset, quote, note, author, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.Project, a.SessionID, a.MessageUUID, a.Block, a.Start, a.End, a.Quote, a.Note, a.Author,
+		now.UnixMilli(), now.UnixMilli(),
+	)
+	if err != nil {
+		return err
+	}
+	if a.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+	a.CreatedAt = time.UnixMilli(now.UnixMilli())
+	a.UpdatedAt = a.CreatedAt
+	return nil
+}
+
+// UpdateAnnotationNote replaces the note text of an annotation
+func UpdateAnnotationNote(id int64, note string) (*Annotation, error) {
+	if db == nil {
+		return nil, ErrNotInitialized
+	}
+	a, err := GetAnnotation(id)
+	if err != nil || a == nil {
+		return a, err
+	}
+	a.Note = note
+	if err := validateAnnotation(a); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if _, err := db.Exec(
+		`UPDATE annotations SET note = ?, updated_at = ? WHERE id = ?`,
+		a.Note, now.UnixMilli(), id,
+	); err != nil {
+		return nil, err
+	}
+	a.UpdatedAt = time.UnixMilli(now.UnixMilli())
+	return a, nil
+}
+
+// DeleteAnnotation removes an annotation, reporting whether it existed
+func DeleteAnnotation(id int64) (bool, error) {
+	if db == nil {
+		return false, ErrNotInitialized
+	}
+	res, err := db.Exec(`DELETE FROM annotations WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+const annotationColumns = `id, project, session_id, message_uuid, block, start_offset, end_offset, quote, note, author, created_at, updated_at`
+
+// GetAnnotation returns one annotation, or nil if it does not exist
+func GetAnnotation(id int64) (*Annotation, error) {
+	if db == nil {
+		return nil, ErrNotInitialized
+	}
+	a, err := scanAnnotation(db.QueryRow(`SELECT `+annotationColumns+` FROM annotations WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return a, err
+}
+
+// GetSessionAnnotations returns a session's annotations, oldest first
+func GetSessionAnnotations(sessionID string) ([]Annotation, error) {
+	return ListAnnotations(AnnotationFilter{SessionID: sessionID})
+}
+
+// ListAnnotations returns annotations matching filter. A session filter
+// lists them oldest first, like reading the session; otherwise newest first.
+func ListAnnotations(f AnnotationFilter) ([]Annotation, error) {
+	if db == nil {
+		return nil, ErrNotInitialized
+	}
+
+	var where []string
+	var args []any
+	if f.Project != "" {
+		where = append(where, "project = ?")
+		args = append(args, f.Project)
+	}
+	if f.SessionID != "" {
+		where = append(where, "session_id = ?")
+		args = append(args, f.SessionID)
+	}
+	if f.Author != "" {
+		where = append(where, "author = ?")
+		args = append(args, f.Author)
+	}
+	if len(where) == 0 {
+		where = append(where, "1")
+	}
+
+	order := "created_at DESC, id DESC"
+	if f.SessionID != "" {
+		order = "created_at, id"
+	}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	args = append(args, limit)
+
+	rows, err := db.Query(
+		`SELECT `+annotationColumns+` FROM annotations WHERE `+strings.Join(where, " AND ")+` ORDER BY `+order+` LIMIT ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var annotations []Annotation
+	for rows.Next() {
+		a, err := scanAnnotation(rows)
+		if err != nil {
+			continue
+		}
+		annotations = append(annotations, *a)
+	}
+	return annotations, rows.Err()
+}
+
+func scanAnnotation(row interface{ Scan(...any) error }) (*Annotation, error) {
+	var a Annotation
+	var created, updated int64
+	if err := row.Scan(&a.ID, &a.Project, &a.SessionID, &a.MessageUUID, &a.Block, &a.Start, &a.End,
+		&a.Quote, &a.Note, &a.Author, &created, &updated); err != nil {
+		return nil, err
+	}
+	a.CreatedAt = time.UnixMilli(created)
+	a.UpdatedAt = time.UnixMilli(updated)
+	return &a, nil
+}
@@ -2,9 +2,12 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+	"unicode"
 
 	_ "modernc.org/sqlite"
 )
@@ -38,12 +41,24 @@ func Init(dataDir string) error {
 
 	dbPath := filepath.Join(dataDir, "ccx.db")
 	var err error
-	db, err = sql.Open("sqlite", dbPath)
+	// busy_timeout lets concurrent writers (index sync, stars) wait instead of failing
+	db, err = sql.Open("sqlite", dbPath+"?_pragma=busy_timeout(5000)")
 	if err != nil {
 		return err
 	}
 
-	return migrate()
+	if err := migrate(); err != nil {
+		return err
+	}
+	if err := migrateAnnotations(); err != nil {
+		return err
+	}
+	return migrateSearch()
+}
+
+// Available reports whether Init has opened the database
+func Available() bool {
+	return db != nil
 }
 
 func migrate() error {
@@ -82,7 +97,9 @@ func migrate() error {
 
 func Close() error {
 	if db != nil {
-		return db.Close()
+		err := db.Close()
+		db = nil
+		return err
 	}
 	return nil
 }
@@ -161,19 +178,15 @@ func GetAllStars() ([]Star, error) {
 	return stars, nil
 }
 
+// AddTag returns the ID of the tag called name, creating it if needed
 func AddTag(name string) (int64, error) {
-	result, err := db.Exec(`INSERT OR IGNORE INTO tags (name) VALUES (?)`, name)
-	if err != nil {
+	if _, err := db.Exec(`INSERT OR IGNORE INTO tags (name) VALUES (?)`, name); err != nil {
 		return 0, err
 	}
-
-	id, err := result.LastInsertId()
-	if err != nil || id == 0 {
-		var tagID int64
-		err = db.QueryRow(`SELECT id FROM tags WHERE name = ?`, name).Scan(&tagID)
-		return tagID, err
-	}
-	return id, nil
+	// LastInsertId is stale when the insert was ignored, so look the tag up
+	var id int64
+	err := db.QueryRow(`SELECT id FROM tags WHERE name = ?`, name).Scan(&id)
+	return id, err
 }
 
 func TagItem(itemType, itemID string, tagID int64) error {
@@ -232,3 +245,113 @@ func GetAllTags() ([]Tag, error) {
 	}
 	return tags, nil
 }
+
+// TagCount is a tag and the number of items carrying it
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// NormalizeTag lowercases a tag name and rejects characters that the
+// search syntax (tag:a,b) and the CLI reserve
+func NormalizeTag(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return "", fmt.Errorf("empty tag name")
+	}
+	if strings.ContainsAny(name, ",:\"") || strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+		return "", fmt.Errorf("invalid tag %q: no spaces, commas, colons or quotes", name)
+	}
+	return name, nil
+}
+
+// TagItemByName tags an item, creating the tag if it does not exist
+func TagItemByName(itemType, itemID, name string) error {
+	if db == nil {
+		return ErrNotInitialized
+	}
+	name, err := NormalizeTag(name)
+	if err != nil {
+		return err
+	}
+	tagID, err := AddTag(name)
+	if err != nil {
+		return err
+	}
+	return TagItem(itemType, itemID, tagID)
+}
+
+// UntagItemByName removes a tag from an item and drops the tag once
+// nothing carries it any more
+func UntagItemByName(itemType, itemID, name string) error {
+	if db == nil {
+		return ErrNotInitialized
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	var tagID int64
+	err := db.QueryRow(`SELECT id FROM tags WHERE name = ?`, name).Scan(&tagID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := UntagItem(itemType, itemID, tagID); err != nil {
+		return err
+	}
+	_, err = db.Exec(`DELETE FROM tags WHERE id = ? AND NOT EXISTS (SELECT 1 FROM item_tags WHERE tag_id = ?)`, tagID, tagID)
+	return err
+}
+
+// GetTagCounts returns every tag in use with its item count, most used first
+func GetTagCounts() ([]TagCount, error) {
+	if db == nil {
+		return nil, ErrNotInitialized
+	}
+	rows, err := db.Query(
+		`SELECT t.name, COUNT(*) FROM tags t
+		 JOIN item_tags it ON t.id = it.tag_id
+		 GROUP BY t.id ORDER BY COUNT(*) DESC, t.name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []TagCount
+	for rows.Next() {
+		var c TagCount
+		if err := rows.Scan(&c.Name, &c.Count); err != nil {
+			continue
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// GetTagsByType maps item ID to sorted tag names for every tagged item of itemType
+func GetTagsByType(itemType string) (map[string][]string, error) {
+	if db == nil {
+		return nil, ErrNotInitialized
+	}
+	rows, err := db.Query(
+		`SELECT it.item_id, t.name FROM item_tags it
+		 JOIN tags t ON t.id = it.tag_id
+		 WHERE it.item_type = ? ORDER BY it.item_id, t.name`,
+		itemType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make(map[string][]string)
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			continue
+		}
+		tags[id] = append(tags[id], name)
+	}
+	return tags, rows.Err()
+}
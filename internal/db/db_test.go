@@ -0,0 +1,29 @@
+package db
+
+import "testing"
+
+func TestTagItemByName_ExistingTag(t *testing.T) {
+	if err := Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer Close()
+
+	if err := TagItemByName("session", "s1", "incident"); err != nil {
+		t.Fatal(err)
+	}
+	if err := TagItemByName("session", "s2", "fresh"); err != nil {
+		t.Fatal(err)
+	}
+	// "incident" already exists; the item must get it, not the last tag created
+	if err := TagItemByName("session", "s3", "incident"); err != nil {
+		t.Fatal(err)
+	}
+
+	tags, err := GetItemTags("session", "s3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 || tags[0].Name != "incident" {
+		t.Errorf("s3 tags = %+v, want [incident]", tags)
+	}
+}
@@ -0,0 +1,413 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// searchSchemaVersion is bumped whenever the index layout changes;
+// a mismatch drops the search tables so they are rebuilt from scratch.
+const searchSchemaVersion = 3
+
+var ErrNotInitialized = errors.New("database not initialized")
+
+// IndexedFile records the state of a session file when it was last indexed
+type IndexedFile struct {
+	Path      string
+	Project   string // Project encoded name
+	SessionID string
+	Branch    string
+	Size      int64
+	ModTime   time.Time
+	Variant   string // "" for the transcript as written, else the redactor it was masked with
+}
+
+// SearchDoc is one indexed message
+type SearchDoc struct {
+	UUID       string
+	Kind       string
+	Model      string
+	Tools      []string // Names of tools the message called
+	IsError    bool     // One of the message's tool calls failed
+	Timestamp  time.Time
+	Text       string // Message text blocks
+	ToolInput  string // Tool inputs (JSON)
+	ToolResult string // Results of the message's tool calls
+}
+
+// SearchHit is a ranked match from the full-text index
+type SearchHit struct {
+	Project   string
+	SessionID string
+	UUID      string
+	Kind      string
+	Timestamp time.Time
+	Snippet   string
+	Rank      float64 // BM25, lower is better
+}
+
+func migrateSearch() error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_versions (
+		name TEXT PRIMARY KEY,
+		version INTEGER NOT NULL
+	)`); err != nil {
+		return err
+	}
+
+	var version int
+	err := db.QueryRow(`SELECT version FROM schema_versions WHERE name = 'search'`).Scan(&version)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if version != searchSchemaVersion {
+		drop := `
+		DROP TABLE IF EXISTS search_fts;
+		DROP TABLE IF EXISTS search_docs;
+		DROP TABLE IF EXISTS search_files;
+		`
+		if _, err := db.Exec(drop); err != nil {
+			return err
+		}
+	}
+
+	schema := `
+	CREATE TABLE IF NOT EXISTS search_files (
+		path TEXT NOT NULL,
+		variant TEXT NOT NULL DEFAULT '',
+		project TEXT NOT NULL,
+		session_id TEXT NOT NULL,
+		branch TEXT NOT NULL DEFAULT '',
+		size INTEGER NOT NULL,
+		mtime INTEGER NOT NULL,
+		PRIMARY KEY (path, variant)
+	);
+
+	CREATE TABLE IF NOT EXISTS search_docs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		path TEXT NOT NULL,
+		variant TEXT NOT NULL DEFAULT '',
+		uuid TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		model TEXT NOT NULL DEFAULT '',
+		tools TEXT NOT NULL DEFAULT '',
+		is_error INTEGER NOT NULL DEFAULT 0,
+		timestamp INTEGER NOT NULL
+	);
+
+	CREATE VIRTUAL TABLE IF NOT EXISTS search_fts USING fts5(
+		text, tool_input, tool_result,
+		tokenize = 'porter unicode61'
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_search_docs_path ON search_docs(path, variant);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(
+		`INSERT OR REPLACE INTO schema_versions (name, version) VALUES ('search', ?)`,
+		searchSchemaVersion,
+	)
+	return err
+}
+
+// GetIndexedFiles returns the indexed state of every session file in one
+// variant of the index, keyed by path
+func GetIndexedFiles(variant string) (map[string]IndexedFile, error) {
+	if db == nil {
+		return nil, ErrNotInitialized
+	}
+
+	rows, err := db.Query(`SELECT path, project, session_id, branch, size, mtime FROM search_files WHERE variant = ?`, variant)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := make(map[string]IndexedFile)
+	for rows.Next() {
+		var f IndexedFile
+		var mtime int64
+		if err := rows.Scan(&f.Path, &f.Project, &f.SessionID, &f.Branch, &f.Size, &mtime); err != nil {
+			continue
+		}
+		f.ModTime = time.Unix(0, mtime)
+		f.Variant = variant
+		files[f.Path] = f
+	}
+	return files, rows.Err()
+}
+
+// IndexFile replaces all indexed messages of a session file in f.Variant
+func IndexFile(f IndexedFile, docs []SearchDoc) error {
+	if db == nil {
+		return ErrNotInitialized
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := deleteFileDocs(tx, f.Path, f.Variant); err != nil {
+		return err
+	}
+
+	for _, d := range docs {
+		res, err := tx.Exec(
+			`INSERT INTO search_docs (path, variant, uuid, kind, model, tools, is_error, timestamp) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			f.Path, f.Variant, d.UUID, d.Kind, d.Model, toolList(d.Tools), d.IsError, d.Timestamp.Unix(),
+		)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO search_fts (rowid, text, tool_input, tool_result) VALUES (?, ?, ?, ?)`,
+			id, d.Text, d.ToolInput, d.ToolResult,
+		); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(
+		`INSERT OR REPLACE INTO search_files (path, variant, project, session_id, branch, size, mtime) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		f.Path, f.Variant, f.Project, f.SessionID, f.Branch, f.Size, f.ModTime.UnixNano(),
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveIndexedFile drops a session file from one variant of the index
+func RemoveIndexedFile(path, variant string) error {
+	if db == nil {
+		return ErrNotInitialized
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := deleteFileDocs(tx, path, variant); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM search_files WHERE path = ? AND variant = ?`, path, variant); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// PruneSearchVariants drops the masked variants of the index other than
+// keep, left behind when redaction settings change
+func PruneSearchVariants(keep string) error {
+	if db == nil {
+		return ErrNotInitialized
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, stmt := range []string{
+		`DELETE FROM search_fts WHERE rowid IN (SELECT id FROM search_docs WHERE variant NOT IN ('', ?))`,
+		`DELETE FROM search_docs WHERE variant NOT IN ('', ?)`,
+		`DELETE FROM search_files WHERE variant NOT IN ('', ?)`,
+	} {
+		if _, err := tx.Exec(stmt, keep); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ResetSearchIndex empties the index so the next sync rebuilds it
+func ResetSearchIndex() error {
+	if db == nil {
+		return ErrNotInitialized
+	}
+	_, err := db.Exec(`
+		DELETE FROM search_fts;
+		DELETE FROM search_docs;
+		DELETE FROM search_files;
+	`)
+	return err
+}
+
+func deleteFileDocs(tx *sql.Tx, path, variant string) error {
+	if _, err := tx.Exec(
+		`DELETE FROM search_fts WHERE rowid IN (SELECT id FROM search_docs WHERE path = ? AND variant = ?)`,
+		path, variant,
+	); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`DELETE FROM search_docs WHERE path = ? AND variant = ?`, path, variant)
+	return err
+}
+
+// toolList stores tool names space-delimited with a leading and trailing
+// space, so a single name can be matched with LIKE '% name %'
+func toolList(tools []string) string {
+	if len(tools) == 0 {
+		return ""
+	}
+	return " " + strings.Join(tools, " ") + " "
+}
+
+// Condition restricts search hits on one field.
+// Values are alternatives; Negate excludes matching messages instead.
+type Condition struct {
+	Field  string   // project, branch, model, tool, kind, tag or error
+	Values []string // Ignored for error
+	Negate bool
+}
+
+// MessageQuery describes a structured message search
+type MessageQuery struct {
+	Match      string // FTS5 expression messages must match; empty matches all
+	Exclude    string // FTS5 expression matching messages are dropped
+	Conditions []Condition
+	After      time.Time // Inclusive lower bound on message time
+	Before     time.Time // Exclusive upper bound on message time
+	Limit      int       // 0 returns every match
+	Variant    string    // Index variant to search, see IndexedFile
+}
+
+// conditionSQL returns the SQL predicate and arguments for one value of a field
+func conditionSQL(field, value string) (string, []any, error) {
+	switch field {
+	case "project":
+		return "f.project = ?", []any{value}, nil
+	case "branch":
+		return "f.branch = ? COLLATE NOCASE", []any{value}, nil
+	case "model":
+		return `d.model LIKE ? ESCAPE '\'`, []any{"%" + escapeLike(value) + "%"}, nil
+	case "tool":
+		return `d.tools LIKE ? ESCAPE '\'`, []any{"% " + escapeLike(value) + " %"}, nil
+	case "kind":
+		return "d.kind = ?", []any{value}, nil
+	case "error":
+		return "d.is_error = 1", nil, nil
+	case "tag":
+		return `(f.session_id IN (SELECT it.item_id FROM item_tags it JOIN tags t ON t.id = it.tag_id
+			WHERE it.item_type = 'session' AND t.name = ?)
+		OR f.project IN (SELECT it.item_id FROM item_tags it JOIN tags t ON t.id = it.tag_id
+			WHERE it.item_type = 'project' AND t.name = ?))`, []any{value, value}, nil
+	}
+	return "", nil, fmt.Errorf("unknown search field %q", field)
+}
+
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return r.Replace(s)
+}
+
+// SearchMessages runs a structured search. Hits are ordered by BM25 when
+// the query has a MATCH expression, newest first otherwise.
+func SearchMessages(q MessageQuery) ([]SearchHit, error) {
+	if db == nil {
+		return nil, ErrNotInitialized
+	}
+
+	where := []string{"d.variant = ?"}
+	args := []any{q.Variant}
+
+	if q.Match != "" {
+		where = append(where, "search_fts MATCH ?")
+		args = append(args, q.Match)
+	}
+	if q.Exclude != "" {
+		where = append(where, "d.id NOT IN (SELECT rowid FROM search_fts WHERE search_fts MATCH ?)")
+		args = append(args, q.Exclude)
+	}
+	for _, c := range q.Conditions {
+		values := c.Values
+		if c.Field == "error" {
+			values = []string{""}
+		}
+		var alts []string
+		for _, v := range values {
+			pred, pargs, err := conditionSQL(c.Field, v)
+			if err != nil {
+				return nil, err
+			}
+			alts = append(alts, pred)
+			args = append(args, pargs...)
+		}
+		expr := "0"
+		if len(alts) > 0 {
+			expr = "(" + strings.Join(alts, " OR ") + ")"
+		}
+		if c.Negate {
+			expr = "NOT " + expr
+		}
+		where = append(where, expr)
+	}
+	if !q.After.IsZero() {
+		where = append(where, "d.timestamp >= ?")
+		args = append(args, q.After.Unix())
+	}
+	if !q.Before.IsZero() {
+		where = append(where, "d.timestamp < ?")
+		args = append(args, q.Before.Unix())
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+
+	var query string
+	if q.Match != "" {
+		query = `
+		SELECT f.project, f.session_id, d.uuid, d.kind, d.timestamp,
+		       snippet(search_fts, -1, '', '', '...', 16), bm25(search_fts) AS score
+		FROM search_fts
+		JOIN search_docs d ON d.id = search_fts.rowid
+		JOIN search_files f ON f.path = d.path AND f.variant = d.variant
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY score
+		LIMIT ?`
+	} else {
+		query = `
+		SELECT f.project, f.session_id, d.uuid, d.kind, d.timestamp,
+		       substr(CASE WHEN search_fts.text != '' THEN search_fts.text ELSE search_fts.tool_input END, 1, 120), 0
+		FROM search_docs d
+		JOIN search_fts ON search_fts.rowid = d.id
+		JOIN search_files f ON f.path = d.path AND f.variant = d.variant
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY d.timestamp DESC, d.id DESC
+		LIMIT ?`
+	}
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []SearchHit
+	for rows.Next() {
+		var h SearchHit
+		var ts int64
+		if err := rows.Scan(&h.Project, &h.SessionID, &h.UUID, &h.Kind, &ts, &h.Snippet, &h.Rank); err != nil {
+			continue
+		}
+		h.Timestamp = time.Unix(ts, 0)
+		hits = append(hits, h)
+	}
+	return hits, rows.Err()
+}
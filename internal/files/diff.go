@@ -0,0 +1,272 @@
+package files
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	contextLines = 3
+
+	// maxEditDistance bounds the diff search. Texts further apart than this
+	// are shown as one replacement rather than a minimal diff.
+	maxEditDistance = 1000
+
+	noNewline = `\ No newline at end of file`
+)
+
+// Hunk is one block of a unified diff. Lines start with ' ', '-' or '+',
+// and carry no line terminator; a line the file ends without a newline
+// is followed by "\ No newline at end of file".
+type Hunk struct {
+	OldStart int      `json:"old_start"`
+	OldLines int      `json:"old_lines"`
+	NewStart int      `json:"new_start"`
+	NewLines int      `json:"new_lines"`
+	Lines    []string `json:"lines"`
+}
+
+// Header is the hunk's "@@ -a,b +c,d @@" line. Without known line numbers
+// (exact false) the starts are shown as "?".
+func (h Hunk) Header(exact bool) string {
+	if !exact {
+		return fmt.Sprintf("@@ -?,%d +?,%d @@", h.OldLines, h.NewLines)
+	}
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+}
+
+func hunkRange(start, lines int) string {
+	if lines == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// splitLines splits s into lines that keep their "\n", so a missing final
+// newline shows up as a change
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Diff returns the hunks turning before into after, with contextLines of
+// context
+func Diff(before, after string) []Hunk {
+	return hunks(diffLines(splitLines(before), splitLines(after)), contextLines)
+}
+
+// diffLines is Myers' O(ND) diff over lines, after trimming the common
+// prefix and suffix
+func diffLines(a, b []string) []diffOp {
+	var pre, suf []diffOp
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		pre = append(pre, diffOp{' ', a[0]})
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		suf = append(suf, diffOp{' ', a[len(a)-1]})
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	mid := myers(a, b)
+	ops := append(pre, mid...)
+	for i := len(suf) - 1; i >= 0; i-- {
+		ops = append(ops, suf[i])
+	}
+	return ops
+}
+
+func myers(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replaceAll(a, b)
+	}
+
+	off := n + m
+	v := make([]int, 2*off+2)
+	var trace [][]int // trace[d][k+d] is the furthest x on diagonal k after d edits
+	for d := 0; d <= min(n+m, maxEditDistance); d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+		}
+		trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			if v[off+k] >= n && v[off+k]-k >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+	return replaceAll(a, b)
+}
+
+func backtrack(a, b []string, trace [][]int) []diffOp {
+	var ops []diffOp
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+		k := x - y
+		var pk int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			pk = k + 1
+		} else {
+			pk = k - 1
+		}
+		px := at(pk)
+		py := px - pk
+		for x > px && y > py {
+			ops = append(ops, diffOp{' ', a[x-1]})
+			x--
+			y--
+		}
+		if x == px {
+			ops = append(ops, diffOp{'+', b[y-1]})
+			y--
+		} else {
+			ops = append(ops, diffOp{'-', a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, diffOp{' ', a[x-1]})
+		x--
+		y--
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+func replaceAll(a, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, l := range a {
+		ops = append(ops, diffOp{'-', l})
+	}
+	for _, l := range b {
+		ops = append(ops, diffOp{'+', l})
+	}
+	return ops
+}
+
+// hunks groups changes that are within 2*ctx lines of each other
+func hunks(ops []diffOp, ctx int) []Hunk {
+	// Line numbers before each op
+	oldPos := make([]int, len(ops)+1)
+	newPos := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldPos[i+1], newPos[i+1] = oldPos[i], newPos[i]
+		if op.kind != '+' {
+			oldPos[i+1]++
+		}
+		if op.kind != '-' {
+			newPos[i+1]++
+		}
+	}
+
+	var out []Hunk
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		start := max(0, i-ctx)
+		end := i
+		for {
+			for end < len(ops) && ops[end].kind != ' ' {
+				end++
+			}
+			j := end
+			for j < len(ops) && ops[j].kind == ' ' {
+				j++
+			}
+			if j < len(ops) && j-end <= 2*ctx {
+				end = j
+				continue
+			}
+			end = min(len(ops), end+ctx)
+			break
+		}
+
+		h := Hunk{
+			OldStart: oldPos[start] + 1,
+			OldLines: oldPos[end] - oldPos[start],
+			NewStart: newPos[start] + 1,
+			NewLines: newPos[end] - newPos[start],
+		}
+		if h.OldLines == 0 {
+			h.OldStart--
+		}
+		if h.NewLines == 0 {
+			h.NewStart--
+		}
+		for _, op := range ops[start:end] {
+			line := strings.TrimSuffix(op.line, "\n")
+			h.Lines = append(h.Lines, string(op.kind)+line)
+			if !strings.HasSuffix(op.line, "\n") {
+				h.Lines = append(h.Lines, noNewline)
+			}
+		}
+		out = append(out, h)
+		i = end
+	}
+	return out
+}
+
+// countChanges is the number of added and removed lines in hs
+func countChanges(hs []Hunk) (added, removed int) {
+	for _, h := range hs {
+		for _, l := range h.Lines {
+			switch {
+			case l == noNewline:
+			case strings.HasPrefix(l, "+"):
+				added++
+			case strings.HasPrefix(l, "-"):
+				removed++
+			}
+		}
+	}
+	return added, removed
+}
+
+// FormatHunks writes hunks as a unified diff of path; a created file's old
+// side is /dev/null, as in git
+func FormatHunks(b *strings.Builder, path string, hs []Hunk, exact, created bool) {
+	path = strings.TrimPrefix(path, "/")
+	if created {
+		b.WriteString("--- /dev/null\n")
+	} else {
+		fmt.Fprintf(b, "--- a/%s\n", path)
+	}
+	fmt.Fprintf(b, "+++ b/%s\n", path)
+	for _, h := range hs {
+		b.WriteString(h.Header(exact))
+		b.WriteByte('\n')
+		for _, l := range h.Lines {
+			b.WriteString(l)
+			b.WriteByte('\n')
+		}
+	}
+}
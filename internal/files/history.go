@@ -0,0 +1,367 @@
+// Package files rebuilds what a session did to each file by replaying its
+// Write, Edit, MultiEdit and NotebookEdit calls in order. Where a file's
+// prior content is known, from an earlier Write or the original file
+// Claude Code records with an edit, each step gets an exact unified diff;
+// otherwise the diff covers just the edited text.
+package files
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+// Step is one tool call that changed (or tried to change) a file
+type Step struct {
+	Tool        string    `json:"tool"`
+	ToolID      string    `json:"tool_id"`
+	MessageUUID string    `json:"message_uuid"`
+	Timestamp   time.Time `json:"timestamp"`
+	Agent       bool      `json:"agent,omitempty"`   // Made by a subagent
+	Failed      bool      `json:"failed,omitempty"`  // The tool reported an error; nothing changed
+	Created     bool      `json:"created,omitempty"` // The step created the file
+	Exact       bool      `json:"exact"`             // Hunks carry the file's real line numbers
+	Note        string    `json:"note,omitempty"`
+	Hunks       []Hunk    `json:"hunks,omitempty"`
+	Added       int       `json:"added"`
+	Removed     int       `json:"removed"`
+
+	// Full content around the step, when known
+	Before string `json:"-"`
+	After  string `json:"-"`
+	Known  bool   `json:"-"`
+}
+
+// Unified is the step as a unified diff of path
+func (s *Step) Unified(path string) string {
+	var b strings.Builder
+	FormatHunks(&b, path, s.Hunks, s.Exact, s.Created)
+	return b.String()
+}
+
+// File is the change log of one path
+type File struct {
+	Path    string  `json:"path"`
+	Created bool    `json:"created,omitempty"`
+	Steps   []*Step `json:"steps"`
+	Added   int     `json:"added"`
+	Removed int     `json:"removed"`
+}
+
+// Changed reports whether any step took effect
+func (f *File) Changed() bool {
+	for _, s := range f.Steps {
+		if !s.Failed {
+			return true
+		}
+	}
+	return false
+}
+
+// RelPath is path relative to the session's working directory when it lies
+// inside it, otherwise path unchanged
+func RelPath(cwd, path string) string {
+	if cwd == "" || !filepath.IsAbs(path) {
+		return path
+	}
+	rel, err := filepath.Rel(cwd, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return path
+	}
+	return rel
+}
+
+type toolResult struct {
+	block parser.ContentBlock
+	meta  map[string]any // toolUseResult
+}
+
+// History replays the file changes on the branch ending at leaf, or the
+// latest branch when leaf is empty, including those made by subagents. Files
+// are listed in the order they were first changed.
+func History(session *parser.Session, leaf string) []*File {
+	path := sessionPath(session, leaf)
+
+	results := make(map[string]toolResult)
+	var collect func(msgs []*parser.Message)
+	collect = func(msgs []*parser.Message) {
+		for _, msg := range msgs {
+			meta, _ := msg.ToolUseResult().(map[string]any)
+			for _, block := range msg.Content {
+				if block.Type == "tool_result" && block.ToolID != "" {
+					results[block.ToolID] = toolResult{block: block, meta: meta}
+				}
+				if block.Sidechain != nil {
+					collect(block.Sidechain.RootMessages)
+				}
+			}
+			collect(msg.Children)
+		}
+	}
+	collect(session.RootMessages)
+
+	r := &replay{results: results, files: make(map[string]*File), content: make(map[string]*string)}
+	for _, msg := range path {
+		r.message(msg, false)
+		for _, child := range msg.Children {
+			if child.IsSidechain {
+				r.tree([]*parser.Message{child})
+			}
+		}
+	}
+	return r.order
+}
+
+// sessionPath is the main-thread path to leaf
+func sessionPath(session *parser.Session, leaf string) []*parser.Message {
+	if leaf == "" {
+		latest := parser.LatestBranch(session.Branches)
+		if latest == nil {
+			return nil
+		}
+		leaf = latest.LeafUUID
+	}
+	return parser.Path(session.RootMessages, leaf)
+}
+
+type replay struct {
+	results map[string]toolResult
+	files   map[string]*File
+	order   []*File
+	content map[string]*string // Known content by path; nil when unknown
+}
+
+func (r *replay) tree(msgs []*parser.Message) {
+	for _, msg := range msgs {
+		r.message(msg, true)
+		r.tree(msg.Children)
+	}
+}
+
+func (r *replay) message(msg *parser.Message, agent bool) {
+	agent = agent || msg.IsSidechain
+	for _, block := range msg.Content {
+		if block.Type != "tool_use" {
+			continue
+		}
+		if parser.IsEditTool(block.ToolName) {
+			r.step(msg, block, agent)
+		}
+		if block.Sidechain != nil {
+			r.tree(block.Sidechain.RootMessages)
+		}
+	}
+}
+
+func (r *replay) step(msg *parser.Message, block parser.ContentBlock, agent bool) {
+	input, _ := block.ToolInput.(map[string]any)
+	path := str(input, "file_path")
+	if block.ToolName == "NotebookEdit" {
+		path = str(input, "notebook_path")
+	}
+	if path == "" {
+		return
+	}
+
+	s := &Step{
+		Tool:        block.ToolName,
+		ToolID:      block.ToolID,
+		MessageUUID: msg.UUID,
+		Timestamp:   msg.Timestamp,
+		Agent:       agent,
+	}
+	f := r.files[path]
+	if f == nil {
+		f = &File{Path: path}
+		r.files[path] = f
+		r.order = append(r.order, f)
+	}
+	f.Steps = append(f.Steps, s)
+
+	res, hasResult := r.results[block.ToolID]
+	if res.block.IsError {
+		s.Failed = true
+		s.Note = firstLine(res.block.ResultText())
+		return
+	}
+	if !hasResult {
+		s.Note = "no result recorded; assumed applied"
+	}
+
+	// The original file Claude Code saw beats our reconstruction, which
+	// misses changes made outside the session
+	before, known := "", false
+	if orig, ok := res.meta["originalFile"].(string); ok {
+		before, known = orig, true
+	} else if c := r.content[path]; c != nil {
+		before, known = *c, true
+	}
+	if block.ToolName == "Write" && r.content[path] == nil && writeCreated(res) {
+		before, known = "", true
+		s.Created = true
+	}
+	s.Before, s.Known = before, known
+
+	switch block.ToolName {
+	case "Write":
+		r.apply(s, path, str(input, "content"), true, res)
+	case "Edit":
+		r.edit(s, path, []map[string]any{input}, res)
+	case "MultiEdit":
+		var edits []map[string]any
+		list, _ := input["edits"].([]any)
+		for _, e := range list {
+			if m, ok := e.(map[string]any); ok {
+				edits = append(edits, m)
+			}
+		}
+		r.edit(s, path, edits, res)
+	case "NotebookEdit":
+		// Cells live inside the notebook's JSON; show the new source only
+		s.Exact, s.Known = false, false
+		mode := str(input, "edit_mode")
+		if mode == "" {
+			mode = "replace"
+		}
+		s.Note = strings.TrimSpace(fmt.Sprintf("cell %s %s", str(input, "cell_id"), mode))
+		if mode != "delete" {
+			s.Hunks = hunks(diffLines(nil, fragment(str(input, "new_source"))), contextLines)
+		}
+		r.content[path] = nil
+	}
+
+	s.Added, s.Removed = countChanges(s.Hunks)
+	if !s.Failed {
+		f.Added += s.Added
+		f.Removed += s.Removed
+		if s.Created && len(f.Steps) == 1 {
+			f.Created = true
+		}
+	}
+}
+
+// edit applies each old_string -> new_string replacement in turn
+func (r *replay) edit(s *Step, path string, edits []map[string]any, res toolResult) {
+	if !s.Known {
+		// Only the edited text is known: diff the fragments, or use the
+		// patch Claude Code recorded, which has real line numbers
+		if patch := structuredPatch(res.meta); patch != nil {
+			s.Hunks, s.Exact = patch, true
+		} else {
+			for _, e := range edits {
+				ops := diffLines(fragment(str(e, "old_string")), fragment(str(e, "new_string")))
+				s.Hunks = append(s.Hunks, hunks(ops, contextLines)...)
+			}
+		}
+		r.content[path] = nil
+		return
+	}
+
+	after := s.Before
+	for i, e := range edits {
+		old, repl := str(e, "old_string"), str(e, "new_string")
+		if !strings.Contains(after, old) || old == "" {
+			where := ""
+			if len(edits) > 1 {
+				where = fmt.Sprintf(" (edit %d)", i+1)
+			}
+			s.Note = "old_string not found in the reconstructed file" + where + "; later diffs of this file may be off"
+			s.Known = false
+			r.edit(s, path, edits, res)
+			return
+		}
+		if all, _ := e["replace_all"].(bool); all {
+			after = strings.ReplaceAll(after, old, repl)
+		} else {
+			after = strings.Replace(after, old, repl, 1)
+		}
+	}
+	r.apply(s, path, after, false, res)
+}
+
+// apply records the step's new content and exact diff
+func (r *replay) apply(s *Step, path, after string, write bool, res toolResult) {
+	s.After = after
+	r.content[path] = &after
+	if s.Known {
+		s.Hunks, s.Exact = Diff(s.Before, after), true
+		return
+	}
+	// A Write over unknown content: show what was written
+	if patch := structuredPatch(res.meta); patch != nil && write {
+		s.Hunks, s.Exact = patch, true
+		return
+	}
+	s.Hunks = Diff("", after)
+	s.Note = "previous content unknown; showing the written file"
+}
+
+// writeCreated reports whether a Write's result says it made a new file
+func writeCreated(res toolResult) bool {
+	if t, _ := res.meta["type"].(string); t != "" {
+		return t == "create"
+	}
+	return strings.HasPrefix(res.block.ResultText(), "File created successfully")
+}
+
+// structuredPatch reads the hunks Claude Code records with an edit result
+func structuredPatch(meta map[string]any) []Hunk {
+	list, ok := meta["structuredPatch"].([]any)
+	if !ok || len(list) == 0 {
+		return nil
+	}
+	var out []Hunk
+	for _, item := range list {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil
+		}
+		h := Hunk{
+			OldStart: num(m, "oldStart"),
+			OldLines: num(m, "oldLines"),
+			NewStart: num(m, "newStart"),
+			NewLines: num(m, "newLines"),
+		}
+		lines, _ := m["lines"].([]any)
+		for _, l := range lines {
+			if s, ok := l.(string); ok {
+				h.Lines = append(h.Lines, s)
+			}
+		}
+		out = append(out, h)
+	}
+	return out
+}
+
+// fragment splits a piece of a file into lines; it isn't the end of the
+// file, so a missing final newline doesn't count
+func fragment(s string) []string {
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	return splitLines(s)
+}
+
+func str(m map[string]any, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+func num(m map[string]any, key string) int {
+	n, _ := m[key].(float64)
+	return int(n)
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	if len(s) > 200 {
+		s = s[:197] + "..."
+	}
+	return s
+}
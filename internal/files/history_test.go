@@ -0,0 +1,141 @@
+package files
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/thevibeworks/ccx/internal/testutil"
+)
+
+// applyHunks is a minimal patch(1) for exact hunks, to check diffs round-trip
+func applyHunks(t *testing.T, before string, hs []Hunk) string {
+	t.Helper()
+	old := splitLines(before)
+	var out []string
+	pos := 0
+	for _, h := range hs {
+		start := h.OldStart - 1
+		if h.OldLines == 0 {
+			start = h.OldStart
+		}
+		out = append(out, old[pos:start]...)
+		pos = start
+		for i, l := range h.Lines {
+			if l == noNewline {
+				continue
+			}
+			nl := "\n"
+			if i+1 < len(h.Lines) && h.Lines[i+1] == noNewline {
+				nl = ""
+			}
+			switch l[0] {
+			case ' ':
+				if old[pos] != l[1:]+nl {
+					t.Fatalf("context mismatch at line %d: %q vs %q", pos+1, old[pos], l[1:]+nl)
+				}
+				out = append(out, old[pos])
+				pos++
+			case '-':
+				pos++
+			case '+':
+				out = append(out, l[1:]+nl)
+			}
+		}
+	}
+	out = append(out, old[pos:]...)
+	return strings.Join(out, "")
+}
+
+func TestDiff(t *testing.T) {
+	lines := func(n int, f func(i int) string) string {
+		var b strings.Builder
+		for i := 0; i < n; i++ {
+			b.WriteString(f(i) + "\n")
+		}
+		return b.String()
+	}
+	base := lines(30, func(i int) string { return "line " + string(rune('a'+i%26)) })
+	tests := []struct {
+		name          string
+		before, after string
+		hunks         int
+	}{
+		{"identical", base, base, 0},
+		{"create", "", "a\nb\n", 1},
+		{"delete all", "a\nb\n", "", 1},
+		{"one line", base, strings.Replace(base, "line c\n", "line C\n", 1), 1},
+		{"far apart", base, strings.Replace(strings.Replace(base, "line b\n", "B\n", 1), "line x\n", "X\n", 1), 2},
+		{"no final newline", "a\nb\n", "a\nb", 1},
+		{"insert and delete", "a\nb\nc\nd\n", "a\nx\nc\nd\ne\n", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hs := Diff(tt.before, tt.after)
+			if len(hs) != tt.hunks {
+				t.Fatalf("got %d hunks, want %d: %+v", len(hs), tt.hunks, hs)
+			}
+			if got := applyHunks(t, tt.before, hs); got != tt.after {
+				t.Errorf("applying hunks gave %q, want %q", got, tt.after)
+			}
+		})
+	}
+
+	hs := Diff("a\nb\n", "a\nc\n")
+	if got := hs[0].Header(true); got != "@@ -1,2 +1,2 @@" {
+		t.Errorf("header = %q", got)
+	}
+}
+
+var historySession = `{"type":"user","timestamp":"2026-01-01T10:00:00Z","uuid":"u1","message":{"content":"Add a greeting"}}
+{"type":"assistant","timestamp":"2026-01-01T10:00:01Z","uuid":"a1","parentUuid":"u1","message":{"content":[{"type":"tool_use","id":"w1","name":"Write","input":{"file_path":"/repo/hello.go","content":"package main\n\nfunc main() {\n}\n"}}]}}
+{"type":"user","timestamp":"2026-01-01T10:00:02Z","uuid":"r1","parentUuid":"a1","toolUseResult":{"type":"create","filePath":"/repo/hello.go"},"message":{"content":[{"type":"tool_result","tool_use_id":"w1","content":"File created successfully at: /repo/hello.go"}]}}
+{"type":"assistant","timestamp":"2026-01-01T10:00:03Z","uuid":"a2","parentUuid":"r1","message":{"content":[{"type":"tool_use","id":"e1","name":"Edit","input":{"file_path":"/repo/hello.go","old_string":"func main() {\n}","new_string":"func main() {\n\tprintln(\"hi\")\n}"}}]}}
+{"type":"user","timestamp":"2026-01-01T10:00:04Z","uuid":"r2","parentUuid":"a2","message":{"content":[{"type":"tool_result","tool_use_id":"e1","content":"The file /repo/hello.go has been updated."}]}}
+{"type":"assistant","timestamp":"2026-01-01T10:00:05Z","uuid":"a3","parentUuid":"r2","message":{"content":[{"type":"tool_use","id":"e2","name":"Edit","input":{"file_path":"/repo/hello.go","old_string":"nope","new_string":"x"}}]}}
+{"type":"user","timestamp":"2026-01-01T10:00:06Z","uuid":"r3","parentUuid":"a3","message":{"content":[{"type":"tool_result","tool_use_id":"e2","content":"String to replace not found in file.","is_error":true}]}}
+{"type":"assistant","timestamp":"2026-01-01T10:00:07Z","uuid":"a4","parentUuid":"r3","message":{"content":[{"type":"tool_use","id":"m1","name":"MultiEdit","input":{"file_path":"/repo/README.md","edits":[{"old_string":"Old title","new_string":"New title"},{"old_string":"foo","new_string":"bar","replace_all":true}]}},{"type":"tool_use","id":"e3","name":"Edit","input":{"file_path":"/repo/util.go","old_string":"a := 1","new_string":"a := 2"}}]}}
+{"type":"user","timestamp":"2026-01-01T10:00:08Z","uuid":"r4","parentUuid":"a4","toolUseResult":{"filePath":"/repo/README.md","originalFile":"# Old title\nfoo and foo\n"},"message":{"content":[{"type":"tool_result","tool_use_id":"m1","content":"Applied 2 edits"}]}}
+{"type":"user","timestamp":"2026-01-01T10:00:09Z","uuid":"r5","parentUuid":"r4","toolUseResult":{"filePath":"/repo/util.go","structuredPatch":[{"oldStart":10,"oldLines":1,"newStart":10,"newLines":1,"lines":["-a := 1","+a := 2"]}]},"message":{"content":[{"type":"tool_result","tool_use_id":"e3","content":"ok"}]}}
+`
+
+func TestHistory(t *testing.T) {
+	session := testutil.ParseSession(t, historySession)
+
+	files := History(session, "")
+	if len(files) != 3 {
+		t.Fatalf("got %d files, want 3", len(files))
+	}
+
+	hello := files[0]
+	if hello.Path != "/repo/hello.go" || !hello.Created || len(hello.Steps) != 3 {
+		t.Fatalf("hello.go = %+v", hello)
+	}
+	write, edit, failed := hello.Steps[0], hello.Steps[1], hello.Steps[2]
+	if !write.Created || !write.Exact || write.Added != 4 {
+		t.Errorf("write step = %+v", write)
+	}
+	if !strings.HasPrefix(write.Unified("repo/hello.go"), "--- /dev/null\n+++ b/repo/hello.go\n@@ -0,0 +1,4 @@\n") {
+		t.Errorf("write diff:\n%s", write.Unified("repo/hello.go"))
+	}
+	if !edit.Exact || edit.Added != 1 || edit.Removed != 0 || edit.After != "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n" {
+		t.Errorf("edit step = %+v, after %q", edit, edit.After)
+	}
+	if !failed.Failed || failed.Note != "String to replace not found in file." || len(failed.Hunks) != 0 {
+		t.Errorf("failed step = %+v", failed)
+	}
+	if hello.Added != 5 {
+		t.Errorf("hello.go added = %d, want 5", hello.Added)
+	}
+
+	// MultiEdit on a file first seen through the recorded original
+	readme := files[1].Steps[0]
+	if !readme.Exact || readme.After != "# New title\nbar and bar\n" {
+		t.Errorf("readme step = %+v, after %q", readme, readme.After)
+	}
+
+	// Unknown base: the recorded patch keeps real line numbers
+	util := files[2].Steps[0]
+	if !util.Exact || util.Hunks[0].OldStart != 10 || util.Added != 1 || util.Removed != 1 {
+		t.Errorf("util step = %+v", util)
+	}
+}
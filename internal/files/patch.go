@@ -0,0 +1,315 @@
+package files
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+// DefaultAuthor signs the commits of a patch series when no author is given
+const DefaultAuthor = "ccx <ccx@localhost>"
+
+// maxSubject is the longest commit subject, in runes
+const maxSubject = 72
+
+// Commit is the file changes made during one turn of a conversation: a
+// user prompt and everything up to the next
+type Commit struct {
+	Prompt  string    // The prompt or command that started the turn
+	Text    string    // What the assistant said during the turn
+	Date    time.Time // When the turn's first change was made
+	Diffs   []FileDiff
+	Skipped []string // Changes that couldn't be turned into a patch, and why
+}
+
+// FileDiff is one file's diff in a commit, with its path relative to the
+// session's working directory
+type FileDiff struct {
+	Path    string
+	Created bool
+	Hunks   []Hunk
+}
+
+// Subject is the first line of the commit message
+func (c *Commit) Subject() string {
+	s, _, _ := strings.Cut(strings.TrimSpace(c.Prompt), "\n")
+	if s == "" {
+		s, _, _ = strings.Cut(strings.TrimSpace(c.Text), "\n")
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "Apply session changes"
+	}
+	if utf8.RuneCountInString(s) > maxSubject {
+		s = string([]rune(s)[:maxSubject-3]) + "..."
+	}
+	return s
+}
+
+// Body is the rest of the commit message: the full prompt when it didn't
+// fit the subject, the assistant's text, and any skipped changes
+func (c *Commit) Body() string {
+	var parts []string
+	if p := strings.TrimSpace(c.Prompt); p != "" && p != c.Subject() {
+		parts = append(parts, p)
+	}
+	if t := strings.TrimSpace(c.Text); t != "" {
+		parts = append(parts, t)
+	}
+	if len(c.Skipped) > 0 {
+		parts = append(parts, "Not included:\n- "+strings.Join(c.Skipped, "\n- "))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// Commits groups the file changes on the branch ending at leaf (the latest
+// when empty) by turn, one commit per turn that changed a file. A file
+// whose content is known throughout a turn gets one diff from its state
+// before the turn to after it; otherwise each edit with known line numbers
+// gets its own. Edits without line numbers, failed edits and files outside
+// the session's working directory are left out.
+func Commits(session *parser.Session, leaf string) []*Commit {
+	type turn struct {
+		commit *Commit
+		steps  map[string][]*Step
+		order  []string
+	}
+	var turns []*turn
+	byTool := make(map[string]*turn)
+
+	var current *turn
+	for _, msg := range sessionPath(session, leaf) {
+		prompt := msg.Kind == parser.KindUserPrompt || msg.Kind == parser.KindCommand
+		if prompt || current == nil {
+			current = &turn{commit: &Commit{}, steps: make(map[string][]*Step)}
+			if prompt {
+				current.commit.Prompt = msg.PromptText()
+			}
+			turns = append(turns, current)
+		}
+		if msg.Type == "assistant" {
+			for _, block := range msg.Content {
+				if block.Type == "text" && strings.TrimSpace(block.Text) != "" {
+					if current.commit.Text != "" {
+						current.commit.Text += "\n\n"
+					}
+					current.commit.Text += strings.TrimSpace(block.Text)
+				}
+			}
+		}
+		t := current
+		collectToolIDs([]*parser.Message{msg}, func(id string) { byTool[id] = t })
+	}
+
+	for _, f := range History(session, leaf) {
+		for _, s := range f.Steps {
+			t := byTool[s.ToolID]
+			if t == nil || s.Failed {
+				continue
+			}
+			if _, ok := t.steps[f.Path]; !ok {
+				t.order = append(t.order, f.Path)
+			}
+			t.steps[f.Path] = append(t.steps[f.Path], s)
+		}
+	}
+
+	var out []*Commit
+	for _, t := range turns {
+		for _, path := range t.order {
+			t.commit.addFile(session.CWD, path, t.steps[path])
+		}
+		if len(t.commit.Diffs) == 0 {
+			continue
+		}
+		sort.SliceStable(t.commit.Diffs, func(i, j int) bool { return t.commit.Diffs[i].Path < t.commit.Diffs[j].Path })
+		out = append(out, t.commit)
+	}
+	return out
+}
+
+// collectToolIDs calls fn with the ID of every tool call in msgs, their
+// children and the agent transcripts they started
+func collectToolIDs(msgs []*parser.Message, fn func(string)) {
+	for _, msg := range msgs {
+		for _, block := range msg.Content {
+			if block.Type == "tool_use" && block.ToolID != "" {
+				fn(block.ToolID)
+			}
+			if block.Sidechain != nil {
+				collectToolIDs(block.Sidechain.RootMessages, fn)
+			}
+		}
+		for _, child := range msg.Children {
+			if child.IsSidechain {
+				collectToolIDs([]*parser.Message{child}, fn)
+			}
+		}
+	}
+}
+
+func (c *Commit) addFile(cwd, path string, steps []*Step) {
+	rel := RelPath(cwd, path)
+	if cwd != "" && filepath.IsAbs(rel) {
+		c.Skipped = append(c.Skipped, fmt.Sprintf("%s: outside %s", path, cwd))
+		return
+	}
+	if c.Date.IsZero() || steps[0].Timestamp.Before(c.Date) {
+		c.Date = steps[0].Timestamp
+	}
+
+	known := true
+	for _, s := range steps {
+		known = known && s.Known
+	}
+	if known {
+		first, last := steps[0], steps[len(steps)-1]
+		if first.Before != last.After || first.Created {
+			c.Diffs = append(c.Diffs, FileDiff{Path: rel, Created: first.Created, Hunks: Diff(first.Before, last.After)})
+		}
+		return
+	}
+
+	for _, s := range steps {
+		switch {
+		case s.Known:
+			c.Diffs = append(c.Diffs, FileDiff{Path: rel, Created: s.Created, Hunks: Diff(s.Before, s.After)})
+		case s.Exact:
+			c.Diffs = append(c.Diffs, FileDiff{Path: rel, Created: s.Created, Hunks: s.Hunks})
+		default:
+			c.Skipped = append(c.Skipped, fmt.Sprintf("%s: %s at %s without line numbers", rel, s.Tool, s.Timestamp.UTC().Format("15:04:05")))
+		}
+	}
+}
+
+// Patch writes the session's commits as a git format-patch mbox, which
+// git am can apply in the session's working directory. It is empty when
+// the session changed no files. Every commit is signed by author, a
+// "Name <email>" identity, or DefaultAuthor when it is empty.
+func Patch(session *parser.Session, leaf, author string) string {
+	if author == "" {
+		author = DefaultAuthor
+	}
+	commits := Commits(session, leaf)
+	var b strings.Builder
+	for i, c := range commits {
+		writeCommit(&b, session.ID, author, c, i+1, len(commits))
+	}
+	return b.String()
+}
+
+func writeCommit(b *strings.Builder, sessionID, author string, c *Commit, n, total int) {
+	id := sha1.Sum([]byte(fmt.Sprintf("%s/%d/%s", sessionID, n, c.Prompt)))
+	subject := "[PATCH] " + c.Subject()
+	if total > 1 {
+		subject = fmt.Sprintf("[PATCH %d/%d] %s", n, total, c.Subject())
+	}
+
+	fmt.Fprintf(b, "From %x Mon Sep 17 00:00:00 2001\n", id)
+	fmt.Fprintf(b, "From: %s\n", author)
+	fmt.Fprintf(b, "Date: %s\n", c.Date.Format(time.RFC1123Z))
+	fmt.Fprintf(b, "Subject: %s\n", mime.QEncoding.Encode("utf-8", subject))
+	b.WriteString("MIME-Version: 1.0\nContent-Type: text/plain; charset=UTF-8\nContent-Transfer-Encoding: 8bit\n\n")
+	if body := c.Body(); body != "" {
+		b.WriteString(escapeBody(body))
+		b.WriteString("\n\n")
+	}
+	b.WriteString("---\n")
+	writeDiffstat(b, c.Diffs)
+	b.WriteString("\n")
+
+	for _, d := range c.Diffs {
+		path := strings.TrimPrefix(d.Path, "/")
+		fmt.Fprintf(b, "diff --git a/%s b/%s\n", path, path)
+		if d.Created {
+			b.WriteString("new file mode 100644\n")
+			if len(d.Hunks) == 0 {
+				continue
+			}
+		}
+		FormatHunks(b, path, d.Hunks, true, d.Created)
+	}
+	b.WriteString("-- \nccx\n\n")
+}
+
+// escapeBody indents the lines git am would take for the start of the
+// patch: "---" rules, as in Markdown, and diff headers
+func escapeBody(body string) string {
+	lines := strings.Split(body, "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(l, "---") || strings.HasPrefix(l, "diff -") || strings.HasPrefix(l, "Index: ") || strings.HasPrefix(l, "From ") {
+			lines[i] = " " + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// writeDiffstat writes the "path | n ++-" summary git puts above a patch
+func writeDiffstat(b *strings.Builder, diffs []FileDiff) {
+	type stat struct {
+		path           string
+		added, removed int
+	}
+	var stats []*stat
+	byPath := make(map[string]*stat)
+	width, most, added, removed := 0, 0, 0, 0
+	for _, d := range diffs {
+		s := byPath[d.Path]
+		if s == nil {
+			s = &stat{path: d.Path}
+			byPath[d.Path] = s
+			stats = append(stats, s)
+			width = max(width, len(d.Path))
+		}
+		a, r := countChanges(d.Hunks)
+		s.added += a
+		s.removed += r
+		added += a
+		removed += r
+	}
+	for _, s := range stats {
+		most = max(most, s.added+s.removed)
+	}
+	numWidth := len(fmt.Sprint(most))
+
+	const graphWidth = 50
+	for _, s := range stats {
+		plus, minus := s.added, s.removed
+		if most > graphWidth {
+			plus = scaleStat(plus, most, graphWidth)
+			minus = scaleStat(minus, most, graphWidth)
+		}
+		fmt.Fprintf(b, " %-*s | %*d %s%s\n", width, s.path, numWidth, s.added+s.removed, strings.Repeat("+", plus), strings.Repeat("-", minus))
+	}
+
+	fmt.Fprintf(b, " %d %s changed", len(stats), plural(len(stats), "file", "files"))
+	if added > 0 {
+		fmt.Fprintf(b, ", %d %s(+)", added, plural(added, "insertion", "insertions"))
+	}
+	if removed > 0 {
+		fmt.Fprintf(b, ", %d %s(-)", removed, plural(removed, "deletion", "deletions"))
+	}
+	b.WriteString("\n")
+}
+
+// scaleStat shrinks n of total to fit width, keeping any change visible
+func scaleStat(n, total, width int) int {
+	if n == 0 {
+		return 0
+	}
+	return max(1, n*width/total)
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
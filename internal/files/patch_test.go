@@ -0,0 +1,100 @@
+package files
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/thevibeworks/ccx/internal/testutil"
+)
+
+var patchSession = `{"type":"user","timestamp":"2026-01-01T10:00:00Z","uuid":"u1","cwd":"/repo","message":{"content":"Add a greeting"}}
+{"type":"assistant","timestamp":"2026-01-01T10:00:01Z","uuid":"a1","parentUuid":"u1","message":{"content":[{"type":"text","text":"Writing it."},{"type":"tool_use","id":"w1","name":"Write","input":{"file_path":"/repo/hello.go","content":"package main\n\nfunc main() {\n}\n"}}]}}
+{"type":"user","timestamp":"2026-01-01T10:00:02Z","uuid":"r1","parentUuid":"a1","toolUseResult":{"type":"create","filePath":"/repo/hello.go"},"message":{"content":[{"type":"tool_result","tool_use_id":"w1","content":"File created successfully at: /repo/hello.go"}]}}
+{"type":"assistant","timestamp":"2026-01-01T10:00:03Z","uuid":"a2","parentUuid":"r1","message":{"content":[{"type":"tool_use","id":"e1","name":"Edit","input":{"file_path":"/repo/hello.go","old_string":"func main() {\n}","new_string":"func main() {\n\tprintln(\"hi\")\n}"}}]}}
+{"type":"user","timestamp":"2026-01-01T10:00:04Z","uuid":"r2","parentUuid":"a2","message":{"content":[{"type":"tool_result","tool_use_id":"e1","content":"The file /repo/hello.go has been updated."}]}}
+{"type":"user","timestamp":"2026-01-01T10:01:00Z","uuid":"u2","parentUuid":"r2","message":{"content":"Document it\n---\nKeep it short"}}
+{"type":"assistant","timestamp":"2026-01-01T10:01:01Z","uuid":"a3","parentUuid":"u2","message":{"content":[{"type":"text","text":"Adding a README."},{"type":"tool_use","id":"w2","name":"Write","input":{"file_path":"/repo/README.md","content":"# Hello\n"}},{"type":"tool_use","id":"e2","name":"Edit","input":{"file_path":"/etc/hosts","old_string":"a","new_string":"b"}},{"type":"tool_use","id":"e3","name":"Edit","input":{"file_path":"/repo/util.go","old_string":"a := 1","new_string":"a := 2"}}]}}
+{"type":"user","timestamp":"2026-01-01T10:01:02Z","uuid":"r3","parentUuid":"a3","toolUseResult":{"type":"create","filePath":"/repo/README.md"},"message":{"content":[{"type":"tool_result","tool_use_id":"w2","content":"File created successfully at: /repo/README.md"}]}}
+{"type":"user","timestamp":"2026-01-01T10:01:03Z","uuid":"r4","parentUuid":"r3","message":{"content":[{"type":"tool_result","tool_use_id":"e2","content":"ok"}]}}
+{"type":"user","timestamp":"2026-01-01T10:01:04Z","uuid":"r5","parentUuid":"r4","message":{"content":[{"type":"tool_result","tool_use_id":"e3","content":"ok"}]}}
+{"type":"assistant","timestamp":"2026-01-01T10:01:05Z","uuid":"a4","parentUuid":"r5","message":{"content":[{"type":"tool_use","id":"e4","name":"Edit","input":{"file_path":"/repo/hello.go","old_string":"\"hi\"","new_string":"\"hello\""}}]}}
+{"type":"user","timestamp":"2026-01-01T10:01:06Z","uuid":"r6","parentUuid":"a4","message":{"content":[{"type":"tool_result","tool_use_id":"e4","content":"ok"}]}}
+{"type":"assistant","timestamp":"2026-01-01T10:01:07Z","uuid":"a5","parentUuid":"r6","message":{"content":[{"type":"text","text":"Done."}]}}
+`
+
+func TestCommits(t *testing.T) {
+	session := testutil.ParseSession(t, patchSession)
+
+	commits := Commits(session, "")
+	if len(commits) != 2 {
+		t.Fatalf("got %d commits, want 2", len(commits))
+	}
+	first, second := commits[0], commits[1]
+	if first.Subject() != "Add a greeting" || first.Body() != "Writing it." {
+		t.Errorf("first commit message = %q / %q", first.Subject(), first.Body())
+	}
+	// The Write and Edit in one turn are one diff of a new file
+	if len(first.Diffs) != 1 || first.Diffs[0].Path != "hello.go" || !first.Diffs[0].Created || first.Diffs[0].Hunks[0].NewLines != 5 {
+		t.Errorf("first commit diffs = %+v", first.Diffs)
+	}
+
+	if second.Subject() != "Document it" || !strings.Contains(second.Body(), "Adding a README.\n\nDone.") {
+		t.Errorf("second commit message = %q / %q", second.Subject(), second.Body())
+	}
+	if len(second.Diffs) != 2 || second.Diffs[0].Path != "README.md" || second.Diffs[1].Path != "hello.go" {
+		t.Errorf("second commit diffs = %+v", second.Diffs)
+	}
+	if len(second.Skipped) != 2 || !strings.Contains(second.Skipped[0], "/etc/hosts: outside /repo") || !strings.Contains(second.Skipped[1], "util.go: Edit") {
+		t.Errorf("second commit skipped = %q", second.Skipped)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	session := testutil.ParseSession(t, patchSession)
+	patch := Patch(session, "", "")
+
+	for _, want := range []string{
+		"From: " + DefaultAuthor + "\n",
+		"Subject: [PATCH 1/2] Add a greeting\n",
+		"Date: Thu, 01 Jan 2026 10:01:01 +0000\n",
+		"\n ---\nKeep it short\n",
+		" README.md | 1 +\n hello.go  | 2 +-\n 2 files changed, 2 insertions(+), 1 deletion(-)\n",
+		"diff --git a/README.md b/README.md\nnew file mode 100644\n--- /dev/null\n+++ b/README.md\n@@ -0,0 +1 @@\n+# Hello\n",
+	} {
+		if !strings.Contains(patch, want) {
+			t.Errorf("patch missing %q:\n%s", want, patch)
+		}
+	}
+
+	if signed := Patch(session, "", "Jane Doe <jane@example.com>"); !strings.Contains(signed, "From: Jane Doe <jane@example.com>\n") {
+		t.Errorf("patch not signed by the given author:\n%s", signed)
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	git := func(stdin string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		cmd.Stdin = strings.NewReader(stdin)
+		cmd.Env = append(os.Environ(), "GIT_COMMITTER_NAME=ccx", "GIT_COMMITTER_EMAIL=ccx@example.com", "GIT_CONFIG_GLOBAL=/dev/null")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	git("", "init", "-q")
+	git(patch, "am", "-q")
+
+	got, err := os.ReadFile(filepath.Join(repo, "hello.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n"; string(got) != want {
+		t.Errorf("hello.go after git am = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,141 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/render"
+)
+
+const (
+	uriScheme            = "ccx://"
+	resourcePageSize     = 100
+	codeResourceNotFound = -32002
+)
+
+type resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType"`
+}
+
+type resourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	MimeType    string `json:"mimeType"`
+}
+
+var sessionTemplate = resourceTemplate{
+	URITemplate: uriScheme + "{project}/{session}",
+	Name:        "session",
+	Description: "A session transcript as Markdown; session may be an ID prefix",
+	MimeType:    "text/markdown",
+}
+
+func sessionURI(project, id string) string {
+	return uriScheme + url.PathEscape(project) + "/" + url.PathEscape(id)
+}
+
+// parseSessionURI splits ccx://{project}/{session}
+func parseSessionURI(uri string) (project, id string, ok bool) {
+	rest, found := strings.CutPrefix(uri, uriScheme)
+	if !found {
+		return "", "", false
+	}
+	project, id, found = strings.Cut(rest, "/")
+	if !found || project == "" || id == "" || strings.Contains(id, "/") {
+		return "", "", false
+	}
+	var err1, err2 error
+	project, err1 = url.PathUnescape(project)
+	id, err2 = url.PathUnescape(id)
+	return project, id, err1 == nil && err2 == nil
+}
+
+// listResources pages through every session, most recent first
+func (s *Server) listResources(params json.RawMessage) (any, error) {
+	var p struct {
+		Cursor string `json:"cursor"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	offset := 0
+	if p.Cursor != "" {
+		n, err := strconv.Atoi(p.Cursor)
+		if err != nil || n < 0 {
+			return nil, invalidParams("invalid cursor: %s", p.Cursor)
+		}
+		offset = n
+	}
+
+	projects, err := parser.DiscoverProjects(s.ProjectsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover projects: %w", err)
+	}
+	type entry struct {
+		project *parser.Project
+		session *parser.Session
+	}
+	var all []entry
+	for _, proj := range projects {
+		for _, sess := range proj.Sessions {
+			all = append(all, entry{proj, sess})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].session.EndTime.After(all[j].session.EndTime)
+	})
+
+	page := paginate(all, offset, resourcePageSize, resourcePageSize)
+	out := make([]resource, 0, len(page))
+	for _, e := range page {
+		name := e.session.Summary
+		if name == "" {
+			name = e.session.ID
+		}
+		out = append(out, resource{
+			URI:  sessionURI(e.project.EncodedName, e.session.ID),
+			Name: name,
+			Description: fmt.Sprintf("%s · %s · %d messages", e.project.Name,
+				e.session.EndTime.Format("2006-01-02 15:04"), e.session.Stats.MessageCount),
+			MimeType: "text/markdown",
+		})
+	}
+	result := map[string]any{"resources": out}
+	if next := offset + len(page); next < len(all) {
+		result["nextCursor"] = strconv.Itoa(next)
+	}
+	return result, nil
+}
+
+func (s *Server) readResource(params json.RawMessage) (any, error) {
+	var p struct {
+		URI string `json:"uri"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	project, id, ok := parseSessionURI(p.URI)
+	if !ok {
+		return nil, &rpcError{Code: codeResourceNotFound, Message: "not a session URI: " + p.URI}
+	}
+	session, err := s.findSession(project, id)
+	if err != nil {
+		return nil, &rpcError{Code: codeResourceNotFound, Message: err.Error()}
+	}
+	text, err := render.Export(session, render.ExportOptions{Format: "md", Pricing: s.Prices})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render session: %w", err)
+	}
+	return map[string]any{
+		"contents": []map[string]any{{"uri": p.URI, "mimeType": "text/markdown", "text": text}},
+	}, nil
+}
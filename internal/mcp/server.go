@@ -0,0 +1,195 @@
+// Package mcp serves sessions to other agents over the Model Context
+// Protocol: JSON-RPC 2.0, one message per line on stdin and stdout. Tools
+// list, search and read sessions; each session is also a resource at
+// ccx://{project}/{session}.
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/thevibeworks/ccx/internal/pricing"
+)
+
+// Protocol versions the server speaks, newest first
+var protocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// Server answers MCP requests from the sessions under ProjectsDir
+type Server struct {
+	ProjectsDir string
+	Prices      *pricing.Table // Rates for cost estimates; nil for none
+	Version     string
+
+	mu  sync.Mutex // Serializes writes to out
+	out io.Writer
+}
+
+// NewServer returns a server for the sessions under projectsDir
+func NewServer(projectsDir string, prices *pricing.Table, version string) *Server {
+	return &Server{ProjectsDir: projectsDir, Prices: prices, Version: version}
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"` // Absent for notifications
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+func invalidParams(format string, args ...any) *rpcError {
+	return &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+// Serve reads requests from in and writes responses to out until in is
+// exhausted
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	s.out = out
+	r := bufio.NewReader(in)
+	for {
+		line, err := r.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			s.handleLine(line)
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read request: %w", err)
+		}
+	}
+}
+
+func (s *Server) handleLine(line []byte) {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		s.write(response{ID: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: "parse error: " + err.Error()}})
+		return
+	}
+	// A null id is treated like a missing one: MCP forbids it, and a reply
+	// could not be matched to the request anyway
+	notification := isNull(req.ID)
+	if req.JSONRPC != "2.0" || req.Method == "" || !(isNull(req.Params) || req.Params[0] == '{') {
+		if !notification {
+			s.write(response{ID: req.ID, Error: &rpcError{Code: codeInvalidRequest, Message: "invalid request"}})
+		}
+		return
+	}
+
+	result, err := s.dispatch(req.Method, req.Params)
+	if notification {
+		return // Notifications get no reply
+	}
+	resp := response{ID: req.ID, Result: result}
+	if err != nil {
+		var rerr *rpcError
+		if !errors.As(err, &rerr) {
+			rerr = &rpcError{Code: codeInternalError, Message: err.Error()}
+		}
+		resp.Result, resp.Error = nil, rerr
+	}
+	s.write(resp)
+}
+
+func (s *Server) write(resp response) {
+	resp.JSONRPC = "2.0"
+	data, err := json.Marshal(resp)
+	if err != nil {
+		data, _ = json.Marshal(response{JSONRPC: "2.0", ID: resp.ID, Error: &rpcError{Code: codeInternalError, Message: err.Error()}})
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _ = s.out.Write(append(data, '\n'))
+}
+
+func (s *Server) dispatch(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		return s.initialize(params)
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return map[string]any{"tools": tools}, nil
+	case "tools/call":
+		return s.callTool(params)
+	case "resources/list":
+		return s.listResources(params)
+	case "resources/templates/list":
+		return map[string]any{"resourceTemplates": []resourceTemplate{sessionTemplate}}, nil
+	case "resources/read":
+		return s.readResource(params)
+	case "notifications/initialized", "notifications/cancelled":
+		return nil, nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + method}
+}
+
+func (s *Server) initialize(params json.RawMessage) (any, error) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	version := protocolVersions[0]
+	for _, v := range protocolVersions {
+		if v == p.ProtocolVersion {
+			version = v
+		}
+	}
+	return map[string]any{
+		"protocolVersion": version,
+		"capabilities": map[string]any{
+			"tools":     map[string]any{},
+			"resources": map[string]any{},
+		},
+		"serverInfo": map[string]any{"name": "ccx", "version": s.Version},
+		"instructions": "Past Claude Code sessions on this machine. Find them with list_projects, " +
+			"list_sessions or search_sessions, then read them with get_session and get_message.",
+	}, nil
+}
+
+// decodeParams unmarshals params into v, treating missing params as empty
+// isNull reports whether raw is absent or the JSON null literal
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(raw) == "null"
+}
+
+func decodeParams(params json.RawMessage, v any) error {
+	if isNull(params) {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return invalidParams("invalid params: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/thevibeworks/ccx/internal/db"
+)
+
+var testSession = `{"type":"summary","summary":"Fix the tests"}
+{"type":"user","timestamp":"2026-01-01T10:00:00Z","uuid":"u1","message":{"content":"Run the test suite"}}
+{"type":"assistant","timestamp":"2026-01-01T10:00:01Z","uuid":"a1","parentUuid":"u1","message":{"model":"claude-sonnet-4-5","usage":{"input_tokens":100,"output_tokens":10},"content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"go test ./..."}}]}}
+{"type":"user","timestamp":"2026-01-01T10:00:02Z","uuid":"r1","parentUuid":"a1","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"FAIL: TestParse\\n` + strings.Repeat("x", 2000) + `","is_error":true}]}}
+{"type":"assistant","timestamp":"2026-01-01T10:00:03Z","uuid":"a2","parentUuid":"r1","message":{"model":"claude-sonnet-4-5","usage":{"input_tokens":200,"output_tokens":20},"content":"TestParse fails on empty input"}}
+`
+
+func setupServer(t *testing.T) *Server {
+	t.Helper()
+	dir := t.TempDir()
+	if err := db.Init(filepath.Join(dir, "data")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	projectDir := filepath.Join(dir, "projects", "-test-project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "session-1.jsonl"), []byte(testSession), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return NewServer(filepath.Join(dir, "projects"), nil, "test")
+}
+
+type testResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// exchange sends each request on its own line and returns the replies
+func exchange(t *testing.T, s *Server, requests ...string) []testResponse {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.Serve(strings.NewReader(strings.Join(requests, "\n")), &out); err != nil {
+		t.Fatal(err)
+	}
+	var resps []testResponse
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var r testResponse
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		resps = append(resps, r)
+	}
+	return resps
+}
+
+// callText calls a tool and returns its text content
+func callText(t *testing.T, s *Server, name, args string) (string, bool) {
+	t.Helper()
+	resps := exchange(t, s, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"`+name+`","arguments":`+args+`}}`)
+	if len(resps) != 1 || resps[0].Error != nil {
+		t.Fatalf("%s: unexpected reply %+v", name, resps)
+	}
+	var result struct {
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+		IsError bool `json:"isError"`
+	}
+	if err := json.Unmarshal(resps[0].Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	return result.Content[0].Text, result.IsError
+}
+
+func TestServeProtocol(t *testing.T) {
+	s := setupServer(t)
+	resps := exchange(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":"two","method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"no/such/method"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"no_such_tool"}}`,
+		`not json`,
+		`{"jsonrpc":"2.0","id":null,"method":"ping"}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized","params":[1]}`,
+		`{"jsonrpc":"2.0","id":5,"method":"ping","params":"x"}`,
+	)
+	if len(resps) != 6 {
+		t.Fatalf("got %d replies, want 6 (none for the notifications or the null id)", len(resps))
+	}
+	if !strings.Contains(string(resps[0].Result), `"protocolVersion":"2024-11-05"`) {
+		t.Errorf("initialize = %s, want the client's version echoed", resps[0].Result)
+	}
+	if string(resps[1].ID) != `"two"` {
+		t.Errorf("string id = %s, want it echoed", resps[1].ID)
+	}
+	for _, name := range []string{"search_sessions", "get_session", "list_projects", "get_message", "session_stats"} {
+		if !strings.Contains(string(resps[1].Result), `"name":"`+name+`"`) {
+			t.Errorf("tools/list missing %s", name)
+		}
+	}
+	for i, code := range map[int]int{2: codeMethodNotFound, 3: codeInvalidParams, 4: codeParseError, 5: codeInvalidRequest} {
+		if resps[i].Error == nil || resps[i].Error.Code != code {
+			t.Errorf("reply %d error = %+v, want code %d", i, resps[i].Error, code)
+		}
+	}
+}
+
+func TestTools(t *testing.T) {
+	s := setupServer(t)
+
+	text, isErr := callText(t, s, "get_session", `{"session":"session"}`)
+	if isErr {
+		t.Fatalf("get_session: %s", text)
+	}
+	var session struct {
+		Summary  string        `json:"summary"`
+		Total    int           `json:"total"`
+		Messages []messageJSON `json:"messages"`
+	}
+	if err := json.Unmarshal([]byte(text), &session); err != nil {
+		t.Fatal(err)
+	}
+	if session.Summary != "Fix the tests" || session.Total != 4 {
+		t.Errorf("get_session = %q with %d messages, want %q with 4", session.Summary, session.Total, "Fix the tests")
+	}
+	if r := session.Messages[2].Results; len(r) != 1 || !r[0].Truncated || !r[0].IsError || len(r[0].Text) != maxResultChars {
+		t.Errorf("tool result = %+v, want it truncated to %d", r, maxResultChars)
+	}
+
+	text, _ = callText(t, s, "get_message", `{"session":"session-1","uuid":"r1"}`)
+	if !strings.Contains(text, strings.Repeat("x", 2000)) {
+		t.Error("get_message should return the whole tool result")
+	}
+
+	text, _ = callText(t, s, "search_sessions", `{"query":"TestParse kind:assistant"}`)
+	if !strings.Contains(text, `"message": "a2"`) || strings.Contains(text, `"message": "r1"`) {
+		t.Errorf("search_sessions = %s, want only a2", text)
+	}
+
+	text, _ = callText(t, s, "session_stats", `{"session":"session-1"}`)
+	if !strings.Contains(text, `"tool_errors": 1`) || !strings.Contains(text, `"input_tokens": 300`) {
+		t.Errorf("session_stats = %s", text)
+	}
+
+	if text, isErr = callText(t, s, "get_session", `{"session":"missing"}`); !isErr {
+		t.Errorf("get_session(missing) = %s, want a tool error", text)
+	}
+}
+
+func TestResources(t *testing.T) {
+	s := setupServer(t)
+	resps := exchange(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"resources/read","params":{"uri":"ccx://-test-project/session-1"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"resources/read","params":{"uri":"ccx://-test-project/missing"}}`,
+	)
+	if !strings.Contains(string(resps[0].Result), `"uri":"ccx://-test-project/session-1"`) {
+		t.Errorf("resources/list = %s", resps[0].Result)
+	}
+	if !strings.Contains(string(resps[1].Result), "TestParse fails on empty input") {
+		t.Errorf("resources/read = %s, want the transcript", resps[1].Result)
+	}
+	if resps[2].Error == nil || resps[2].Error.Code != codeResourceNotFound {
+		t.Errorf("reading a missing session = %+v, want code %d", resps[2].Error, codeResourceNotFound)
+	}
+
+	if _, _, ok := parseSessionURI("ccx://a/b/c"); ok {
+		t.Error("parseSessionURI accepted a nested path")
+	}
+}
@@ -0,0 +1,535 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thevibeworks/ccx/internal/db"
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/search"
+	"github.com/thevibeworks/ccx/internal/stats"
+)
+
+const (
+	defaultListLimit    = 20
+	defaultMessageLimit = 50
+	maxResultChars      = 1000 // Tool results in get_session; get_message has them whole
+)
+
+type tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+
+	call func(s *Server, args json.RawMessage) (any, error)
+}
+
+func schema(required []string, props map[string]any) map[string]any {
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func prop(typ, desc string) map[string]any {
+	return map[string]any{"type": typ, "description": desc}
+}
+
+var (
+	projectProp = prop("string", "Project name, encoded directory name or a unique part of either")
+	sessionProp = prop("string", "Session ID or ID prefix")
+)
+
+var tools = []tool{
+	{
+		Name:        "list_projects",
+		Description: "List the projects with Claude Code sessions, most recently active first.",
+		InputSchema: schema(nil, map[string]any{}),
+		call:        (*Server).listProjects,
+	},
+	{
+		Name:        "list_sessions",
+		Description: "List a project's sessions, most recent first, with their summaries and sizes.",
+		InputSchema: schema([]string{"project"}, map[string]any{
+			"project": projectProp,
+			"limit":   prop("integer", "Sessions to return (default 20)"),
+			"offset":  prop("integer", "Sessions to skip, for paging"),
+		}),
+		call: (*Server).listSessions,
+	},
+	{
+		Name: "search_sessions",
+		Description: "Full-text search over every session's messages, tool inputs and tool results, best match first. " +
+			`Supports "quoted phrases", prefix*, -negation and the qualifiers project:, branch:, model:, tool:, kind:, is:error, after: and before: (YYYY-MM-DD).`,
+		InputSchema: schema([]string{"query"}, map[string]any{
+			"query": prop("string", "Search query"),
+			"limit": prop("integer", "Matches to return (default 20)"),
+		}),
+		call: (*Server).searchSessions,
+	},
+	{
+		Name: "get_session",
+		Description: "Read a session's prompts and responses in order, with tool calls and shortened tool results. " +
+			"A forked session returns its latest branch unless leaf picks another. Page through long sessions with offset.",
+		InputSchema: schema([]string{"session"}, map[string]any{
+			"project": projectProp,
+			"session": sessionProp,
+			"leaf":    prop("string", "UUID of the last message of the branch to read"),
+			"offset":  prop("integer", "Messages to skip"),
+			"limit":   prop("integer", "Messages to return (default 50)"),
+		}),
+		call: (*Server).getSession,
+	},
+	{
+		Name:        "get_message",
+		Description: "Read one message in full, including complete tool inputs and results.",
+		InputSchema: schema([]string{"session", "uuid"}, map[string]any{
+			"project": projectProp,
+			"session": sessionProp,
+			"uuid":    prop("string", "Message UUID or UUID prefix"),
+		}),
+		call: (*Server).getMessage,
+	},
+	{
+		Name: "session_stats",
+		Description: "Token usage, estimated cost, tool calls and errors, and models. " +
+			"For one session when session is given, otherwise across all sessions matching project and the date range.",
+		InputSchema: schema(nil, map[string]any{
+			"project": projectProp,
+			"session": sessionProp,
+			"since":   prop("string", "Start date: YYYY-MM-DD, or 7d / 2w before today"),
+			"until":   prop("string", "End date, inclusive"),
+		}),
+		call: (*Server).sessionStats,
+	},
+}
+
+func (s *Server) callTool(params json.RawMessage) (any, error) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	for _, t := range tools {
+		if t.Name != p.Name {
+			continue
+		}
+		// Failures are results the calling model can read and act on
+		result, err := t.call(s, p.Arguments)
+		if err != nil {
+			return toolResult(err.Error(), true), nil
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return toolResult(string(data), false), nil
+	}
+	return nil, invalidParams("unknown tool: %s", p.Name)
+}
+
+func toolResult(text string, isError bool) map[string]any {
+	return map[string]any{
+		"content": []map[string]any{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
+
+// findSession resolves and parses a session
+func (s *Server) findSession(project, id string) (*parser.Session, error) {
+	if id == "" {
+		return nil, fmt.Errorf("session is required")
+	}
+	found, err := parser.FindSession(s.ProjectsDir, project, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find session: %w", err)
+	}
+	if found == nil {
+		return nil, fmt.Errorf("session not found: %s", id)
+	}
+	session, err := parser.ParseSession(found.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse session: %w", err)
+	}
+	session.ProjectName = found.ProjectName
+	return session, nil
+}
+
+type projectJSON struct {
+	Project    string    `json:"project"` // Encoded name
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`
+	Sessions   int       `json:"sessions"`
+	LastActive time.Time `json:"last_active"`
+}
+
+func (s *Server) listProjects(json.RawMessage) (any, error) {
+	projects, err := parser.DiscoverProjects(s.ProjectsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover projects: %w", err)
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].LastModified.After(projects[j].LastModified)
+	})
+	out := make([]projectJSON, 0, len(projects))
+	for _, p := range projects {
+		out = append(out, projectJSON{
+			Project:    p.EncodedName,
+			Name:       p.Name,
+			Path:       parser.GetProjectFullPath(p.EncodedName),
+			Sessions:   len(p.Sessions),
+			LastActive: p.LastModified,
+		})
+	}
+	return out, nil
+}
+
+type sessionJSON struct {
+	ID        string    `json:"id"`
+	URI       string    `json:"uri"`
+	Summary   string    `json:"summary,omitempty"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Messages  int       `json:"messages"`
+	ToolCalls int       `json:"tool_calls"`
+	GitBranch string    `json:"git_branch,omitempty"`
+}
+
+func (s *Server) listSessions(args json.RawMessage) (any, error) {
+	var p struct {
+		Project string `json:"project"`
+		Limit   int    `json:"limit"`
+		Offset  int    `json:"offset"`
+	}
+	if err := decodeParams(args, &p); err != nil {
+		return nil, err
+	}
+	if p.Project == "" {
+		return nil, fmt.Errorf("project is required")
+	}
+	project, err := parser.FindProject(s.ProjectsDir, p.Project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find project: %w", err)
+	}
+	if project == nil {
+		return nil, fmt.Errorf("project not found: %s", p.Project)
+	}
+
+	sessions := project.Sessions
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].EndTime.After(sessions[j].EndTime)
+	})
+	page := paginate(sessions, p.Offset, p.Limit, defaultListLimit)
+	out := make([]sessionJSON, 0, len(page))
+	for _, sess := range page {
+		out = append(out, sessionJSON{
+			ID:        sess.ID,
+			URI:       sessionURI(project.EncodedName, sess.ID),
+			Summary:   sess.Summary,
+			Start:     sess.StartTime,
+			End:       sess.EndTime,
+			Messages:  sess.Stats.MessageCount,
+			ToolCalls: sess.Stats.ToolCalls,
+			GitBranch: sess.GitBranch,
+		})
+	}
+	return map[string]any{"project": project.EncodedName, "total": len(sessions), "sessions": out}, nil
+}
+
+type searchHitJSON struct {
+	Project   string    `json:"project"`
+	Session   string    `json:"session"`
+	Summary   string    `json:"summary,omitempty"`
+	Message   string    `json:"message"` // UUID, for get_message
+	Kind      string    `json:"kind"`
+	Timestamp time.Time `json:"timestamp"`
+	Snippet   string    `json:"snippet"`
+}
+
+func (s *Server) searchSessions(args json.RawMessage) (any, error) {
+	var p struct {
+		Query string `json:"query"`
+		Limit int    `json:"limit"`
+	}
+	if err := decodeParams(args, &p); err != nil {
+		return nil, err
+	}
+	q, err := search.Parse(p.Query)
+	if err != nil {
+		return nil, err
+	}
+	if q.IsEmpty() {
+		return nil, fmt.Errorf("query is empty")
+	}
+	if !db.Available() {
+		return nil, fmt.Errorf("search index unavailable: the ccx database could not be opened")
+	}
+	if p.Limit <= 0 {
+		p.Limit = defaultListLimit
+	}
+
+	projects, err := parser.DiscoverProjects(s.ProjectsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover projects: %w", err)
+	}
+	if _, err := search.Sync(projects); err != nil {
+		return nil, fmt.Errorf("failed to update search index: %w", err)
+	}
+	hits, err := search.Messages(projects, q, p.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+
+	summaries := make(map[string]string)
+	for _, proj := range projects {
+		for _, sess := range proj.Sessions {
+			summaries[proj.EncodedName+"/"+sess.ID] = sess.Summary
+		}
+	}
+	out := make([]searchHitJSON, 0, len(hits))
+	for _, h := range hits {
+		out = append(out, searchHitJSON{
+			Project:   h.Project,
+			Session:   h.SessionID,
+			Summary:   summaries[h.Project+"/"+h.SessionID],
+			Message:   h.UUID,
+			Kind:      h.Kind,
+			Timestamp: h.Timestamp,
+			Snippet:   strings.Join(strings.Fields(h.Snippet), " "),
+		})
+	}
+	return out, nil
+}
+
+type messageJSON struct {
+	UUID      string             `json:"uuid"`
+	Kind      parser.MessageKind `json:"kind"`
+	Timestamp time.Time          `json:"timestamp"`
+	Model     string             `json:"model,omitempty"`
+	Text      string             `json:"text,omitempty"`
+	Command   string             `json:"command,omitempty"`
+	ToolCalls []toolCallJSON     `json:"tool_calls,omitempty"`
+	Results   []toolResultJSON   `json:"tool_results,omitempty"`
+}
+
+type toolCallJSON struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Input any    `json:"input,omitempty"`
+}
+
+type toolResultJSON struct {
+	ToolID    string `json:"tool_id"`
+	IsError   bool   `json:"is_error,omitempty"`
+	Text      string `json:"text"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+func newMessageJSON(msg *parser.Message, resultChars int) messageJSON {
+	m := messageJSON{UUID: msg.UUID, Kind: msg.Kind, Timestamp: msg.Timestamp, Model: msg.Model}
+	if msg.Kind == parser.KindCommand {
+		m.Command = strings.TrimSpace(msg.CommandName + " " + msg.CommandArgs)
+	}
+	var text []string
+	for _, block := range msg.Content {
+		switch block.Type {
+		case "text":
+			if block.Text != "" {
+				text = append(text, block.Text)
+			}
+		case "tool_use":
+			m.ToolCalls = append(m.ToolCalls, toolCallJSON{ID: block.ToolID, Name: block.ToolName, Input: block.ToolInput})
+		case "tool_result":
+			r := toolResultJSON{ToolID: block.ToolID, IsError: block.IsError, Text: block.ResultText()}
+			if resultChars > 0 && len(r.Text) > resultChars {
+				r.Text, r.Truncated = strings.ToValidUTF8(r.Text[:resultChars], ""), true
+			}
+			m.Results = append(m.Results, r)
+		}
+	}
+	m.Text = strings.Join(text, "\n\n")
+	return m
+}
+
+type branchJSON struct {
+	Leaf     string    `json:"leaf"`
+	Summary  string    `json:"summary,omitempty"`
+	Messages int       `json:"messages"`
+	End      time.Time `json:"end"`
+}
+
+func (s *Server) getSession(args json.RawMessage) (any, error) {
+	var p struct {
+		Project string `json:"project"`
+		Session string `json:"session"`
+		Leaf    string `json:"leaf"`
+		Offset  int    `json:"offset"`
+		Limit   int    `json:"limit"`
+	}
+	if err := decodeParams(args, &p); err != nil {
+		return nil, err
+	}
+	session, err := s.findSession(p.Project, p.Session)
+	if err != nil {
+		return nil, err
+	}
+
+	leaf := p.Leaf
+	if leaf == "" {
+		if latest := parser.LatestBranch(session.Branches); latest != nil {
+			leaf = latest.LeafUUID
+		}
+	}
+	path := parser.Path(session.RootMessages, leaf)
+	if leaf != "" && path == nil {
+		return nil, fmt.Errorf("message not found: %s", leaf)
+	}
+	var msgs []*parser.Message
+	for _, msg := range path {
+		if msg.Kind != parser.KindMeta {
+			msgs = append(msgs, msg)
+		}
+	}
+
+	page := paginate(msgs, p.Offset, p.Limit, defaultMessageLimit)
+	out := make([]messageJSON, 0, len(page))
+	for _, msg := range page {
+		out = append(out, newMessageJSON(msg, maxResultChars))
+	}
+	var branches []branchJSON
+	for _, b := range session.Branches {
+		branches = append(branches, branchJSON{Leaf: b.LeafUUID, Summary: b.Summary, Messages: b.Messages, End: b.EndTime})
+	}
+	return map[string]any{
+		"id":         session.ID,
+		"uri":        sessionURI(session.ProjectName, session.ID),
+		"summary":    session.Summary,
+		"cwd":        session.CWD,
+		"git_branch": session.GitBranch,
+		"start":      session.StartTime,
+		"end":        session.EndTime,
+		"branches":   branches,
+		"leaf":       leaf,
+		"total":      len(msgs),
+		"offset":     max(p.Offset, 0),
+		"messages":   out,
+	}, nil
+}
+
+func (s *Server) getMessage(args json.RawMessage) (any, error) {
+	var p struct {
+		Project string `json:"project"`
+		Session string `json:"session"`
+		UUID    string `json:"uuid"`
+	}
+	if err := decodeParams(args, &p); err != nil {
+		return nil, err
+	}
+	if p.UUID == "" {
+		return nil, fmt.Errorf("uuid is required")
+	}
+	session, err := s.findSession(p.Project, p.Session)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *parser.Message
+	var walk func(msgs []*parser.Message) error
+	walk = func(msgs []*parser.Message) error {
+		for _, msg := range msgs {
+			if strings.HasPrefix(msg.UUID, p.UUID) {
+				if found != nil && found.UUID != msg.UUID {
+					return fmt.Errorf("message %q is ambiguous", p.UUID)
+				}
+				found = msg
+			}
+			if err := walk(msg.Children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	roots := [][]*parser.Message{session.RootMessages}
+	for _, sc := range session.Sidechains {
+		roots = append(roots, sc.RootMessages)
+	}
+	for _, r := range roots {
+		if err := walk(r); err != nil {
+			return nil, err
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("message not found: %s", p.UUID)
+	}
+
+	m := newMessageJSON(found, 0)
+	return map[string]any{
+		"message":   m,
+		"parent":    found.ParentUUID,
+		"sidechain": found.IsSidechain,
+		"usage":     found.Usage,
+	}, nil
+}
+
+func (s *Server) sessionStats(args json.RawMessage) (any, error) {
+	var p struct {
+		Project string `json:"project"`
+		Session string `json:"session"`
+		Since   string `json:"since"`
+		Until   string `json:"until"`
+	}
+	if err := decodeParams(args, &p); err != nil {
+		return nil, err
+	}
+
+	f := stats.Filter{Project: p.Project}
+	var err error
+	if p.Since != "" {
+		if f.After, err = stats.ParseDate(p.Since, false); err != nil {
+			return nil, err
+		}
+	}
+	if p.Until != "" {
+		if f.Before, err = stats.ParseDate(p.Until, true); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.Session == "" {
+		report, err := stats.Collect(s.ProjectsDir, f, s.Prices)
+		if err != nil {
+			return nil, fmt.Errorf("failed to collect stats: %w", err)
+		}
+		return report, nil
+	}
+
+	session, err := s.findSession(p.Project, p.Session)
+	if err != nil {
+		return nil, err
+	}
+	project, _ := parser.FindProject(s.ProjectsDir, session.ProjectName)
+	if project == nil {
+		project = &parser.Project{Name: session.ProjectName, EncodedName: session.ProjectName}
+	}
+	agg := stats.NewAggregator(stats.Filter{After: f.After, Before: f.Before}, s.Prices)
+	agg.Add(project, session)
+	return agg.Report(), nil
+}
+
+// paginate returns up to limit items from offset; limit 0 takes def
+func paginate[T any](items []T, offset, limit, def int) []T {
+	if limit <= 0 {
+		limit = def
+	}
+	offset = max(offset, 0)
+	if offset >= len(items) {
+		return nil
+	}
+	return items[offset:min(offset+limit, len(items))]
+}
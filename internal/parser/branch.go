@@ -0,0 +1,147 @@
+package parser
+
+func hasMainChild(msg *Message) bool {
+	for _, c := range msg.Children {
+		if !c.IsSidechain {
+			return true
+		}
+	}
+	return false
+}
+
+// Flatten returns a message tree in depth-first order
+func Flatten(roots []*Message) []*Message {
+	var out []*Message
+	var walk func(msgs []*Message)
+	walk = func(msgs []*Message) {
+		for _, msg := range msgs {
+			out = append(out, msg)
+			walk(msg.Children)
+		}
+	}
+	walk(roots)
+	return out
+}
+
+// Path returns the main-thread messages from a root down to the message
+// with the given UUID, or nil if the tree has no such message
+func Path(roots []*Message, uuid string) []*Message {
+	var path []*Message
+	var walk func(msgs []*Message) bool
+	walk = func(msgs []*Message) bool {
+		for _, msg := range msgs {
+			if msg.IsSidechain {
+				continue
+			}
+			path = append(path, msg)
+			if msg.UUID == uuid || walk(msg.Children) {
+				return true
+			}
+			path = path[:len(path)-1]
+		}
+		return false
+	}
+	if !walk(roots) {
+		return nil
+	}
+	return path
+}
+
+// buildBranches lists every root-to-leaf path of the main thread. A
+// branch takes the summary written for its leaf or, failing that, for the
+// latest message on its path that has one.
+func buildBranches(roots []*Message, summaries map[string]string) []Branch {
+	leafCounts := make(map[*Message]int)
+	var count func(msg *Message) int
+	count = func(msg *Message) int {
+		n := 0
+		for _, c := range msg.Children {
+			if !c.IsSidechain {
+				n += count(c)
+			}
+		}
+		if n == 0 {
+			n = 1
+		}
+		leafCounts[msg] = n
+		return n
+	}
+	for _, r := range roots {
+		if !r.IsSidechain {
+			count(r)
+		}
+	}
+
+	var branches []Branch
+	var path []*Message
+	var walk func(msgs []*Message)
+	walk = func(msgs []*Message) {
+		for _, msg := range msgs {
+			if msg.IsSidechain {
+				continue
+			}
+			path = append(path, msg)
+			if !hasMainChild(msg) {
+				branches = append(branches, newBranch(path, leafCounts, summaries))
+			}
+			walk(msg.Children)
+			path = path[:len(path)-1]
+		}
+	}
+	walk(roots)
+	return branches
+}
+
+func newBranch(path []*Message, leafCounts map[*Message]int, summaries map[string]string) Branch {
+	leaf := path[len(path)-1]
+	b := Branch{LeafUUID: leaf.UUID, EndTime: leaf.Timestamp}
+	for _, msg := range path {
+		if b.ForkUUID == "" && leafCounts[msg] == 1 {
+			b.ForkUUID = msg.UUID
+		}
+		if msg.Kind == KindUserPrompt || msg.Kind == KindAssistant {
+			b.Messages++
+		}
+	}
+	for i := len(path) - 1; i >= 0 && b.Summary == ""; i-- {
+		b.Summary = summaries[path[i].UUID]
+	}
+	return b
+}
+
+// LatestBranch returns the branch that ends most recently, or nil if there
+// are none
+func LatestBranch(branches []Branch) *Branch {
+	var latest *Branch
+	for i := range branches {
+		if latest == nil || branches[i].EndTime.After(latest.EndTime) {
+			latest = &branches[i]
+		}
+	}
+	return latest
+}
+
+// BranchRoots returns the tree cut down to the one path ending at the
+// message with the given UUID, agent sidechains kept, or nil if the tree
+// has no such message. The messages are copies; the tree is unchanged.
+func BranchRoots(roots []*Message, leafUUID string) []*Message {
+	path := Path(roots, leafUUID)
+	if path == nil {
+		return nil
+	}
+	var next *Message
+	for i := len(path) - 1; i >= 0; i-- {
+		c := *path[i]
+		c.Children = nil
+		for _, child := range path[i].Children {
+			if child.IsSidechain {
+				c.Children = append(c.Children, child)
+			}
+		}
+		if next != nil {
+			c.Children = append(c.Children, next)
+		}
+		next = &c
+	}
+	return []*Message{next}
+}
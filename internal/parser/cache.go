@@ -0,0 +1,196 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// cacheVersion is bumped whenever quickState changes meaning;
+// a cache file with another version is ignored.
+const cacheVersion = 3
+
+// tailSize is how many bytes before the parsed offset are kept to detect
+// a file that was rewritten rather than appended to
+const tailSize = 64
+
+// cacheEntry is the cached quick-parse result of one file
+type cacheEntry struct {
+	Size    int64
+	ModTime time.Time
+	Offset  int64  // Bytes parsed so far (complete lines only)
+	Tail    []byte // Bytes just before Offset
+	State   quickState
+}
+
+type cacheFile struct {
+	Version int
+	Entries map[string]*cacheEntry
+}
+
+// metaCache holds quick-parse results keyed by path. Unchanged files are
+// served from memory; files that only grew are parsed from their last offset.
+type metaCache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	path    string // On-disk cache file; empty keeps the cache in memory only
+	loaded  bool
+	dirty   bool
+}
+
+var sessionCache = &metaCache{entries: make(map[string]*cacheEntry)}
+
+// SetCacheFile persists session metadata between runs in the file at path.
+// The file is read on first use and rewritten after discovery when it changed.
+func SetCacheFile(path string) {
+	sessionCache.mu.Lock()
+	defer sessionCache.mu.Unlock()
+	sessionCache.path = path
+	sessionCache.loaded = false
+}
+
+// ResetCache drops every cached entry, in memory and on disk
+func ResetCache() error {
+	c := sessionCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*cacheEntry)
+	c.loaded = true
+	c.dirty = false
+	if c.path == "" {
+		return nil
+	}
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// loadLocked reads the cache file once; callers hold c.mu
+func (c *metaCache) loadLocked() {
+	if c.loaded || c.path == "" {
+		return
+	}
+	c.loaded = true
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return
+	}
+	var f cacheFile
+	if err := json.Unmarshal(data, &f); err != nil || f.Version != cacheVersion {
+		return
+	}
+	for path, e := range f.Entries {
+		if _, ok := c.entries[path]; !ok {
+			c.entries[path] = e
+		}
+	}
+}
+
+// parse returns the quick-parse state of a file, reading only what changed
+// since the cached pass. The state is a copy callers may change.
+func (c *metaCache) parse(path string) quickState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return quickState{}
+	}
+
+	c.mu.Lock()
+	c.loadLocked()
+	prev := c.entries[path]
+	c.mu.Unlock()
+
+	if prev != nil && prev.Size == info.Size() && prev.ModTime.Equal(info.ModTime()) {
+		return prev.State.clone()
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return quickState{}
+	}
+	defer file.Close()
+
+	entry := &cacheEntry{Size: info.Size(), ModTime: info.ModTime()}
+	if prev != nil && appendedTo(file, prev, info.Size()) {
+		entry.State = prev.State.clone()
+		entry.Offset = prev.Offset
+	}
+
+	if _, err := file.Seek(entry.Offset, 0); err != nil {
+		return quickState{}
+	}
+	entry.Offset += entry.State.scan(file)
+	entry.Tail = readTail(file, entry.Offset)
+
+	c.mu.Lock()
+	c.entries[path] = entry
+	c.dirty = true
+	c.mu.Unlock()
+
+	return entry.State.clone()
+}
+
+// appendedTo reports whether file still starts with the bytes prev parsed,
+// i.e. it only grew since then
+func appendedTo(file *os.File, prev *cacheEntry, size int64) bool {
+	if size < prev.Offset {
+		return false
+	}
+	return bytes.Equal(readTail(file, prev.Offset), prev.Tail)
+}
+
+// readTail returns up to tailSize bytes ending at offset
+func readTail(file *os.File, offset int64) []byte {
+	start := offset - tailSize
+	if start < 0 {
+		start = 0
+	}
+	buf := make([]byte, offset-start)
+	n, _ := file.ReadAt(buf, start)
+	return buf[:n]
+}
+
+// flush writes the cache file if anything changed, dropping entries for
+// files that no longer exist
+func (c *metaCache) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty || c.path == "" {
+		return
+	}
+
+	for path := range c.entries {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			delete(c.entries, path)
+		}
+	}
+
+	data, err := json.Marshal(cacheFile{Version: cacheVersion, Entries: c.entries})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return
+	}
+
+	// Write to a temp file and rename so concurrent readers never see a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".session-cache-*")
+	if err != nil {
+		return
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	c.dirty = false
+}
@@ -0,0 +1,168 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const cacheLine1 = `{"type":"user","sessionId":"s1","timestamp":"2026-01-01T10:00:00Z","message":{"content":"First prompt"}}` + "\n"
+const cacheLine2 = `{"type":"assistant","timestamp":"2026-01-01T10:00:01Z","message":{"usage":{"input_tokens":3,"output_tokens":4},"content":[{"type":"tool_use","name":"Bash"}]}}` + "\n"
+const cacheLine3 = `{"type":"user","timestamp":"2026-01-01T10:05:00Z","message":{"content":"Second prompt"}}` + "\n"
+
+func setupCache(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	SetCacheFile(filepath.Join(dir, "data", "session-cache.json"))
+	if err := ResetCache(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		SetCacheFile("")
+		_ = ResetCache()
+	})
+	return dir
+}
+
+// touch moves the mtime forward so the cache sees a change even on coarse clocks
+func touch(t *testing.T, path string, step int) {
+	t.Helper()
+	ts := time.Now().Add(time.Duration(step) * time.Second)
+	if err := os.Chtimes(path, ts, ts); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQuickParseCache_Append(t *testing.T) {
+	dir := setupCache(t)
+	path := filepath.Join(dir, "s1.jsonl")
+	if err := os.WriteFile(path, []byte(cacheLine1+cacheLine2), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, _, _, stats, meta := quickParseSession(path)
+	if summary != "First prompt" || stats.MessageCount != 2 || meta.SessionID != "s1" {
+		t.Fatalf("first pass = %q %+v %+v", summary, stats, meta)
+	}
+	offset := sessionCache.entries[path].Offset
+	if offset != int64(len(cacheLine1+cacheLine2)) {
+		t.Errorf("Offset = %d, want %d", offset, len(cacheLine1+cacheLine2))
+	}
+
+	// Append: only the new line is parsed, totals carry over
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(cacheLine3)
+	f.Close()
+	touch(t, path, 1)
+
+	summary, _, end, stats, _ := quickParseSession(path)
+	if summary != "First prompt" {
+		t.Errorf("summary = %q, want first prompt kept", summary)
+	}
+	if stats.MessageCount != 3 || stats.UserPrompts != 2 || stats.ToolCalls != 1 || stats.InputTokens != 3 {
+		t.Errorf("stats after append = %+v", stats)
+	}
+	if end.Minute() != 5 {
+		t.Errorf("EndTime = %v, want 10:05", end)
+	}
+}
+
+func TestQuickParseCache_PartialLine(t *testing.T) {
+	dir := setupCache(t)
+	path := filepath.Join(dir, "s1.jsonl")
+	partial := cacheLine3[:20]
+	if err := os.WriteFile(path, []byte(cacheLine1+partial), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, _, stats, _ := quickParseSession(path)
+	if stats.MessageCount != 1 {
+		t.Fatalf("MessageCount = %d, want 1", stats.MessageCount)
+	}
+	if got := sessionCache.entries[path].Offset; got != int64(len(cacheLine1)) {
+		t.Errorf("Offset = %d, want partial line left unparsed", got)
+	}
+
+	// The writer finishes the line
+	if err := os.WriteFile(path, []byte(cacheLine1+cacheLine3), 0644); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, path, 1)
+
+	_, _, _, stats, _ = quickParseSession(path)
+	if stats.MessageCount != 2 {
+		t.Errorf("MessageCount = %d, want 2 once the line is complete", stats.MessageCount)
+	}
+}
+
+func TestQuickParseCache_Rewrite(t *testing.T) {
+	dir := setupCache(t)
+	path := filepath.Join(dir, "s1.jsonl")
+	if err := os.WriteFile(path, []byte(cacheLine1+cacheLine2), 0644); err != nil {
+		t.Fatal(err)
+	}
+	quickParseSession(path)
+
+	// Larger but with different content: must not be treated as an append
+	rewritten := `{"type":"user","sessionId":"s1","timestamp":"2026-01-01T10:00:00Z","message":{"content":"Other prompt"}}` + "\n"
+	if err := os.WriteFile(path, []byte(rewritten+cacheLine3+cacheLine2), 0644); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, path, 1)
+
+	summary, _, _, stats, _ := quickParseSession(path)
+	if summary != "Other prompt" {
+		t.Errorf("summary = %q, want full reparse after rewrite", summary)
+	}
+	if stats.MessageCount != 3 {
+		t.Errorf("MessageCount = %d, want 3", stats.MessageCount)
+	}
+
+	// Truncated
+	if err := os.WriteFile(path, []byte(cacheLine1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, path, 2)
+
+	summary, _, _, stats, _ = quickParseSession(path)
+	if summary != "First prompt" || stats.MessageCount != 1 {
+		t.Errorf("after truncate = %q %+v", summary, stats)
+	}
+}
+
+func TestQuickParseCache_Persists(t *testing.T) {
+	dir := setupCache(t)
+	path := filepath.Join(dir, "s1.jsonl")
+	if err := os.WriteFile(path, []byte(cacheLine1+cacheLine2), 0644); err != nil {
+		t.Fatal(err)
+	}
+	quickParseSession(path)
+	sessionCache.flush()
+
+	// A new process starts with an empty memory cache and loads the file
+	cacheFile := sessionCache.path
+	sessionCache.entries = make(map[string]*cacheEntry)
+	SetCacheFile(cacheFile)
+
+	sessionCache.mu.Lock()
+	sessionCache.loadLocked()
+	entry, ok := sessionCache.entries[path]
+	sessionCache.mu.Unlock()
+	if !ok || entry.State.Summary != "First prompt" {
+		t.Fatalf("entry after reload = %+v", entry)
+	}
+
+	// Deleted files are pruned on the next write
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	sessionCache.dirty = true
+	sessionCache.flush()
+	if _, ok := sessionCache.entries[path]; ok {
+		t.Error("entry for deleted file survived flush")
+	}
+}
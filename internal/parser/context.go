@@ -0,0 +1,71 @@
+package parser
+
+import "sort"
+
+// setContextTokens fills in Message.ContextTokens down each path of the
+// tree. A response resets the estimate to its own usage; other messages
+// carry their parent's. A compact summary starts a new context whose size
+// is unknown until the next response.
+func setContextTokens(msgs []*Message, parent int) {
+	for _, msg := range msgs {
+		switch {
+		case msg.Usage != nil:
+			msg.ContextTokens = msg.Usage.Total()
+		case msg.Kind == KindCompactSummary:
+			msg.ContextTokens = 0
+		default:
+			msg.ContextTokens = parent
+		}
+		setContextTokens(msg.Children, msg.ContextTokens)
+	}
+}
+
+// ContextPoint is the estimated context size after one API response
+type ContextPoint struct {
+	Message *Message
+	Tokens  int // Message.ContextTokens
+	Added   int // Growth since the previous response on the path; 0 when there is none
+}
+
+// ContextTimeline lists the context size after each main-thread API
+// response, in tree order. Responses written as several entries (one per
+// content block) with the same usage count once.
+func ContextTimeline(roots []*Message) []ContextPoint {
+	var points []ContextPoint
+	var walk func(msgs []*Message, prev int)
+	walk = func(msgs []*Message, prev int) {
+		for _, msg := range msgs {
+			next := prev
+			if msg.Kind == KindCompactSummary {
+				next = 0
+			}
+			if msg.Usage != nil && !msg.IsSidechain && msg.ContextTokens != prev {
+				p := ContextPoint{Message: msg, Tokens: msg.ContextTokens}
+				if prev > 0 {
+					p.Added = msg.ContextTokens - prev
+				}
+				points = append(points, p)
+				next = msg.ContextTokens
+			}
+			walk(msg.Children, next)
+		}
+	}
+	walk(roots, 0)
+	return points
+}
+
+// HeaviestTurns returns up to n points that grew the context the most,
+// largest first
+func HeaviestTurns(points []ContextPoint, n int) []ContextPoint {
+	var grew []ContextPoint
+	for _, p := range points {
+		if p.Added > 0 {
+			grew = append(grew, p)
+		}
+	}
+	sort.SliceStable(grew, func(i, j int) bool { return grew[i].Added > grew[j].Added })
+	if len(grew) > n {
+		grew = grew[:n]
+	}
+	return grew
+}
@@ -0,0 +1,89 @@
+package parser
+
+import "sync"
+
+var textFilter struct {
+	mu sync.RWMutex
+	fn func(string) string
+}
+
+// SetTextFilter rewrites the text of every session parsed or discovered
+// from now on with fn: summaries, messages, tool inputs and results. nil
+// turns it off.
+func SetTextFilter(fn func(string) string) {
+	textFilter.mu.Lock()
+	defer textFilter.mu.Unlock()
+	textFilter.fn = fn
+}
+
+func currentTextFilter() func(string) string {
+	textFilter.mu.RLock()
+	defer textFilter.mu.RUnlock()
+	return textFilter.fn
+}
+
+// ParseSessionUnfiltered parses like ParseSession but ignores the text
+// filter, for indexes that must hold what the transcript really says
+func ParseSessionUnfiltered(filePath string) (*Session, error) {
+	session, messages, err := parseTranscript(filePath)
+	if err != nil {
+		return nil, err
+	}
+	attachSidechains(session, messages)
+	return session, nil
+}
+
+// RewriteText applies fn to the session's summaries and to every message,
+// agent transcripts included
+func (s *Session) RewriteText(fn func(string) string) {
+	s.Summary = fn(s.Summary)
+	for i := range s.Branches {
+		s.Branches[i].Summary = fn(s.Branches[i].Summary)
+	}
+	var walk func([]*Message)
+	walk = func(msgs []*Message) {
+		for _, msg := range msgs {
+			msg.RewriteText(fn)
+			walk(msg.Children)
+		}
+	}
+	walk(s.RootMessages)
+	for _, sc := range s.Sidechains {
+		walk(sc.RootMessages)
+	}
+}
+
+// RewriteText applies fn to the message's text: prompts, responses,
+// thinking, command arguments, tool inputs and results, and the tool
+// metadata Claude Code recorded. Images are left alone.
+func (m *Message) RewriteText(fn func(string) string) {
+	m.CommandArgs = fn(m.CommandArgs)
+	for i := range m.Content {
+		b := &m.Content[i]
+		b.Text = fn(b.Text)
+		b.ToolInput = RewriteValue(b.ToolInput, fn)
+		b.ToolResult = RewriteValue(b.ToolResult, fn)
+	}
+	m.raw.ToolUseResult = RewriteValue(m.raw.ToolUseResult, fn)
+}
+
+// RewriteValue applies fn to every string in a decoded JSON value, in
+// place, except base64 image sources
+func RewriteValue(v any, fn func(string) string) any {
+	switch v := v.(type) {
+	case string:
+		return fn(v)
+	case []any:
+		for i := range v {
+			v[i] = RewriteValue(v[i], fn)
+		}
+	case map[string]any:
+		if v["type"] == "base64" {
+			return v
+		}
+		for k := range v {
+			v[k] = RewriteValue(v[k], fn)
+		}
+	}
+	return v
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
@@ -53,15 +54,35 @@ func DiscoverProjects(projectsDir string) ([]*Project, error) {
 		return projects[i].LastModified.After(projects[j].LastModified)
 	})
 
+	sessionCache.flush()
+
 	return projects, nil
 }
 
+// DiscoverSessions lists the sessions in one project directory, newest first.
+// Unlike DiscoverProjects it leaves the metadata cache unflushed, so it is
+// cheap enough to call on every change to a live project.
+func DiscoverSessions(projectPath string) ([]*Session, error) {
+	return discoverSessions(projectPath)
+}
+
 func discoverSessions(projectPath string) ([]*Session, error) {
 	entries, err := os.ReadDir(projectPath)
 	if err != nil {
 		return nil, err
 	}
 
+	// Agent transcripts roll up into their parent session's stats
+	agentStats := make(map[string][]SessionStats)
+	for _, entry := range entries {
+		if !isAgentFile(entry) {
+			continue
+		}
+		if stats, sessionID, ok := quickParseSidechain(filepath.Join(projectPath, entry.Name())); ok {
+			agentStats[sessionID] = append(agentStats[sessionID], stats)
+		}
+	}
+
 	var sessions []*Session
 	for _, entry := range entries {
 		if entry.IsDir() {
@@ -84,9 +105,27 @@ func discoverSessions(projectPath string) ([]*Session, error) {
 		if strings.ToLower(summary) == "warmup" {
 			continue
 		}
+		if fn := currentTextFilter(); fn != nil {
+			summary = fn(summary)
+		}
+
+		id := strings.TrimSuffix(name, ".jsonl")
+		for _, sc := range agentStats[id] {
+			addSidechainStats(&stats, sc)
+		}
+		if subEntries, err := os.ReadDir(filepath.Join(projectPath, id, "subagents")); err == nil {
+			for _, sub := range subEntries {
+				if !isAgentFile(sub) {
+					continue
+				}
+				if sc, _, ok := quickParseSidechain(filepath.Join(projectPath, id, "subagents", sub.Name())); ok {
+					addSidechainStats(&stats, sc)
+				}
+			}
+		}
 
 		session := &Session{
-			ID:          strings.TrimSuffix(name, ".jsonl"),
+			ID:          id,
 			FilePath:    sessionPath,
 			ProjectName: filepath.Base(projectPath),
 			Summary:     summary,
@@ -126,6 +165,23 @@ func FindProject(projectsDir, name string) (*Project, error) {
 	return nil, nil
 }
 
+// SelectProjects returns the project called name, or every project when
+// name is empty. Only the named project is kept; an unknown name is an
+// error.
+func SelectProjects(projectsDir, name string) ([]*Project, error) {
+	if name == "" {
+		return DiscoverProjects(projectsDir)
+	}
+	p, err := FindProject(projectsDir, name)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("project not found: %s", name)
+	}
+	return []*Project{p}, nil
+}
+
 func FindSession(projectsDir, projectName, sessionID string) (*Session, error) {
 	var project *Project
 	var err error
@@ -2,24 +2,40 @@ package parser
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
+	"io"
 	"os"
+	"slices"
 	"strings"
 	"time"
 )
 
 func ParseSession(filePath string) (*Session, error) {
-	file, err := os.Open(filePath)
+	session, err := ParseSessionUnfiltered(filePath)
 	if err != nil {
 		return nil, err
 	}
+	if fn := currentTextFilter(); fn != nil {
+		session.RewriteText(fn)
+	}
+	return session, nil
+}
+
+// parseTranscript parses one JSONL transcript into a session and its flat message list
+func parseTranscript(filePath string) (*Session, []*Message, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
 	defer file.Close()
 
 	var messages []*Message
 	var summaryFromFile string
-	var totalInputTokens, totalOutputTokens, totalCacheRead, totalCacheCreate int
+	var usageStats SessionStats // Token totals only
 	var sessionSlug, sessionVersion, sessionBranch, sessionCWD string
 	logicalParents := make(map[string]string) // compact_boundary UUID -> logical parent UUID
+	leafSummaries := make(map[string]string)  // leafUuid -> summary line
 	scanner := bufio.NewScanner(file)
 	buf := make([]byte, 0, 64*1024)
 	scanner.Buffer(buf, 10*1024*1024)
@@ -45,10 +61,8 @@ func ParseSession(filePath string) (*Session, error) {
 			usage = raw.Message.Usage
 		}
 		if usage != nil {
-			totalInputTokens += usage.InputTokens
-			totalOutputTokens += usage.OutputTokens
-			totalCacheRead += usage.CacheReadInputTokens
-			totalCacheCreate += usage.CacheCreationInputTokens
+			ts, _ := time.Parse(time.RFC3339Nano, raw.Timestamp)
+			usageStats.addUsage(raw.Message.Model, ts, usage.toUsage())
 		}
 
 		// Extract session metadata from first message that has it
@@ -72,9 +86,14 @@ func ParseSession(filePath string) (*Session, error) {
 			continue
 		}
 
-		if raw.Type == "summary" && summaryFromFile == "" {
+		if raw.Type == "summary" {
 			if s := strings.TrimSpace(raw.Summary); s != "" {
-				summaryFromFile = s
+				if summaryFromFile == "" {
+					summaryFromFile = s
+				}
+				if raw.LeafUUID != "" {
+					leafSummaries[raw.LeafUUID] = s
+				}
 			}
 			continue
 		}
@@ -90,7 +109,7 @@ func ParseSession(filePath string) (*Session, error) {
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	for _, msg := range messages {
@@ -98,13 +117,16 @@ func ParseSession(filePath string) (*Session, error) {
 	}
 
 	rootMessages := buildMessageTree(messages)
+	setContextTokens(rootMessages, 0)
+	branches := buildBranches(rootMessages, leafSummaries)
 	stats := computeStats(messages)
 
 	// Add token usage stats
-	stats.InputTokens = totalInputTokens
-	stats.OutputTokens = totalOutputTokens
-	stats.CacheReadTokens = totalCacheRead
-	stats.CacheCreateTokens = totalCacheCreate
+	stats.InputTokens = usageStats.InputTokens
+	stats.OutputTokens = usageStats.OutputTokens
+	stats.CacheReadTokens = usageStats.CacheReadTokens
+	stats.CacheCreateTokens = usageStats.CacheCreateTokens
+	stats.ModelUsage = usageStats.ModelUsage
 
 	var startTime, endTime time.Time
 	if len(messages) > 0 {
@@ -126,13 +148,14 @@ func ParseSession(filePath string) (*Session, error) {
 		EndTime:      endTime,
 		RootMessages: rootMessages,
 		Stats:        stats,
+		Branches:     branches,
 		Slug:         sessionSlug,
 		Version:      sessionVersion,
 		GitBranch:    sessionBranch,
 		CWD:          sessionCWD,
 	}
 
-	return session, nil
+	return session, messages, nil
 }
 
 func resolveLogicalParent(parentUUID string, logicalParents map[string]string) string {
@@ -149,6 +172,24 @@ func resolveLogicalParent(parentUUID string, logicalParents map[string]string) s
 	return cur
 }
 
+// ParseLine parses one transcript line on its own, as when following a
+// live session. ok is false for blank or malformed lines and for lines
+// that aren't user or assistant messages, such as summaries.
+func ParseLine(line []byte) (msg *Message, ok bool) {
+	var raw rawMessage
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return nil, false
+	}
+	if raw.Type != "user" && raw.Type != "assistant" {
+		return nil, false
+	}
+	msg = parseMessage(raw)
+	if fn := currentTextFilter(); fn != nil {
+		msg.RewriteText(fn)
+	}
+	return msg, true
+}
+
 func parseMessage(raw rawMessage) *Message {
 	ts, _ := time.Parse(time.RFC3339Nano, raw.Timestamp)
 
@@ -166,6 +207,15 @@ func parseMessage(raw rawMessage) *Message {
 		raw:         raw,
 	}
 
+	usage := raw.Usage
+	if usage == nil {
+		usage = raw.Message.Usage
+	}
+	if usage != nil {
+		u := usage.toUsage()
+		msg.Usage = &u
+	}
+
 	msg.Content = parseContent(raw.Message.Content)
 	msg.Kind = classifyMessage(msg, raw)
 
@@ -403,6 +453,7 @@ func extractSessionID(filePath string) string {
 
 // SessionMeta holds quick-parsed session metadata
 type SessionMeta struct {
+	SessionID string // Parent session for agent transcripts
 	Slug      string
 	Version   string
 	GitBranch string
@@ -410,133 +461,166 @@ type SessionMeta struct {
 }
 
 func quickParseSession(filePath string) (summary string, startTime, endTime time.Time, stats SessionStats, meta SessionMeta) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "(no summary)", time.Time{}, time.Time{}, SessionStats{}, SessionMeta{}
+	st := sessionCache.parse(filePath)
+
+	summary = st.Summary
+	if summary == "" {
+		summary = "(no summary)"
 	}
-	defer file.Close()
+	return summary, st.FirstTime, st.LastTime, st.Stats, st.Meta
+}
 
-	scanner := bufio.NewScanner(file)
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 10*1024*1024)
+// quickState is the resumable state of a quick parse: everything the
+// listing needs, accumulated line by line so appended lines can be
+// folded in without rereading the file.
+type quickState struct {
+	Summary      string
+	FoundSummary bool
+	FirstTime    time.Time
+	LastTime     time.Time
+	Stats        SessionStats
+	Meta         SessionMeta
+}
 
-	var firstTime, lastTime time.Time
-	foundSummary := false
+// clone copies the state so changing its stats leaves the original alone
+func (q quickState) clone() quickState {
+	q.Stats.ModelUsage = slices.Clone(q.Stats.ModelUsage)
+	return q
+}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
+// parseLine folds one JSONL line into the state
+func (q *quickState) parseLine(line []byte) {
+	if len(bytes.TrimSpace(line)) == 0 {
+		return
+	}
 
-		var raw struct {
-			Type             string     `json:"type"`
-			Timestamp        string     `json:"timestamp"`
-			IsCompactSummary bool       `json:"isCompactSummary"`
-			IsSidechain      bool       `json:"isSidechain"`
-			IsMeta           bool       `json:"isMeta"`
-			Summary          string     `json:"summary"`
-			Slug             string     `json:"slug"`
-			Version          string     `json:"version"`
-			GitBranch        string     `json:"gitBranch"`
-			CWD              string     `json:"cwd"`
-			Usage            *usageData `json:"usage"`
-			Message          struct {
-				Content any        `json:"content"`
-				Usage   *usageData `json:"usage"`
-			} `json:"message"`
-		}
+	var raw struct {
+		Type             string     `json:"type"`
+		Timestamp        string     `json:"timestamp"`
+		IsCompactSummary bool       `json:"isCompactSummary"`
+		IsSidechain      bool       `json:"isSidechain"`
+		IsMeta           bool       `json:"isMeta"`
+		SessionID        string     `json:"sessionId"`
+		Summary          string     `json:"summary"`
+		Slug             string     `json:"slug"`
+		Version          string     `json:"version"`
+		GitBranch        string     `json:"gitBranch"`
+		CWD              string     `json:"cwd"`
+		Usage            *usageData `json:"usage"`
+		Message          struct {
+			Content any        `json:"content"`
+			Model   string     `json:"model"`
+			Usage   *usageData `json:"usage"`
+		} `json:"message"`
+	}
 
-		if err := json.Unmarshal([]byte(line), &raw); err != nil {
-			continue
-		}
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return
+	}
 
-		// Accumulate token usage (check both top-level and message.usage)
-		usage := raw.Usage
-		if usage == nil && raw.Message.Usage != nil {
-			usage = raw.Message.Usage
-		}
-		if usage != nil {
-			stats.InputTokens += usage.InputTokens
-			stats.OutputTokens += usage.OutputTokens
-			stats.CacheReadTokens += usage.CacheReadInputTokens
-			stats.CacheCreateTokens += usage.CacheCreationInputTokens
-		}
+	stats := &q.Stats
+	meta := &q.Meta
+	ts, tsErr := time.Parse(time.RFC3339Nano, raw.Timestamp)
 
-		// Extract metadata from first message that has it
-		if meta.Slug == "" && raw.Slug != "" {
-			meta.Slug = raw.Slug
-		}
-		if meta.Version == "" && raw.Version != "" {
-			meta.Version = raw.Version
-		}
-		if meta.GitBranch == "" && raw.GitBranch != "" {
-			meta.GitBranch = raw.GitBranch
-		}
-		if meta.CWD == "" && raw.CWD != "" {
-			meta.CWD = raw.CWD
+	// Accumulate token usage (check both top-level and message.usage)
+	usage := raw.Usage
+	if usage == nil && raw.Message.Usage != nil {
+		usage = raw.Message.Usage
+	}
+	if usage != nil {
+		stats.addUsage(raw.Message.Model, ts, usage.toUsage())
+	}
+
+	// Extract metadata from first message that has it
+	if meta.SessionID == "" && raw.SessionID != "" {
+		meta.SessionID = raw.SessionID
+	}
+	if meta.Slug == "" && raw.Slug != "" {
+		meta.Slug = raw.Slug
+	}
+	if meta.Version == "" && raw.Version != "" {
+		meta.Version = raw.Version
+	}
+	if meta.GitBranch == "" && raw.GitBranch != "" {
+		meta.GitBranch = raw.GitBranch
+	}
+	if meta.CWD == "" && raw.CWD != "" {
+		meta.CWD = raw.CWD
+	}
+
+	if tsErr == nil {
+		if q.FirstTime.IsZero() {
+			q.FirstTime = ts
 		}
+		q.LastTime = ts
+	}
 
-		if ts, err := time.Parse(time.RFC3339Nano, raw.Timestamp); err == nil {
-			if firstTime.IsZero() {
-				firstTime = ts
-			}
-			lastTime = ts
-		}
-
-		// Count only meaningful conversation turns (matching computeStats logic)
-		// User: only actual prompts (not compact summaries, meta, or tool results)
-		if raw.Type == "user" && !raw.IsCompactSummary && !raw.IsMeta {
-			// Check if it's a tool result
-			isToolResult := false
-			if arr, ok := raw.Message.Content.([]any); ok && len(arr) > 0 {
-				if m, ok := arr[0].(map[string]any); ok {
-					if t, ok := m["type"].(string); ok && t == "tool_result" {
-						isToolResult = true
-					}
+	// Count only meaningful conversation turns (matching computeStats logic)
+	// User: only actual prompts (not compact summaries, meta, or tool results)
+	if raw.Type == "user" && !raw.IsCompactSummary && !raw.IsMeta {
+		// Check if it's a tool result
+		isToolResult := false
+		if arr, ok := raw.Message.Content.([]any); ok && len(arr) > 0 {
+			if m, ok := arr[0].(map[string]any); ok {
+				if t, ok := m["type"].(string); ok && t == "tool_result" {
+					isToolResult = true
 				}
 			}
-			if !isToolResult {
-				stats.MessageCount++
-				stats.UserPrompts++
-			}
-		} else if raw.Type == "assistant" {
-			stats.MessageCount++
 		}
-		if raw.IsCompactSummary {
-			stats.Continuations++
-		}
-		if raw.IsSidechain {
-			stats.AgentSidechains++
-		}
-		if raw.Type == "user" || raw.Type == "assistant" {
-			stats.ToolCalls += countToolCalls(raw.Message.Content)
+		if !isToolResult {
+			stats.MessageCount++
+			stats.UserPrompts++
 		}
+	} else if raw.Type == "assistant" {
+		stats.MessageCount++
+	}
+	if raw.IsCompactSummary {
+		stats.Continuations++
+	}
+	if raw.IsSidechain {
+		stats.AgentSidechains++
+	}
+	if raw.Type == "user" || raw.Type == "assistant" {
+		stats.ToolCalls += countToolCalls(raw.Message.Content)
+	}
 
-		if raw.Type == "summary" && raw.Summary != "" {
-			summary = raw.Summary
-			foundSummary = true
-			continue
-		}
+	if raw.Type == "summary" && raw.Summary != "" {
+		q.Summary = raw.Summary
+		q.FoundSummary = true
+		return
+	}
 
-		if !foundSummary && raw.Type == "user" && !raw.IsCompactSummary {
-			text := extractTextFromContent(raw.Message.Content)
-			if text != "" && !strings.HasPrefix(text, "<") {
-				// First line only (no truncation)
-				if idx := strings.Index(text, "\n"); idx > 0 {
-					text = text[:idx]
-				}
-				summary = text
-				foundSummary = true
+	if !q.FoundSummary && raw.Type == "user" && !raw.IsCompactSummary {
+		text := extractTextFromContent(raw.Message.Content)
+		if text != "" && !strings.HasPrefix(text, "<") {
+			// First line only (no truncation)
+			if idx := strings.Index(text, "\n"); idx > 0 {
+				text = text[:idx]
 			}
+			q.Summary = text
+			q.FoundSummary = true
 		}
 	}
+}
 
-	if summary == "" {
-		summary = "(no summary)"
+// scan folds every complete line of r into the state and returns the number
+// of bytes consumed. A trailing line without a newline is only consumed when
+// it is valid JSON, so a line still being written is picked up next time.
+func (q *quickState) scan(r io.Reader) int64 {
+	br := bufio.NewReaderSize(r, 64*1024)
+	var consumed int64
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if line[len(line)-1] == '\n' || json.Valid(line) {
+				q.parseLine(line)
+				consumed += int64(len(line))
+			}
+		}
+		if err != nil {
+			return consumed
+		}
 	}
-
-	return summary, firstTime, lastTime, stats, meta
 }
 
 func countToolCalls(content any) int {
@@ -3,6 +3,8 @@ package parser
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -479,6 +481,159 @@ func TestParseSession_CompactionBoundaryRewritesParentUUID(t *testing.T) {
 	}
 }
 
+func TestParseSession_ModelUsage(t *testing.T) {
+	dir := t.TempDir()
+	sessionPath := filepath.Join(dir, "test.jsonl")
+
+	content := `{"type":"user","timestamp":"2025-12-24T23:59:00.000Z","uuid":"u1","message":{"role":"user","content":"Hi"}}
+{"type":"assistant","timestamp":"2025-12-24T23:59:30.000Z","uuid":"a1","parentUuid":"u1","message":{"role":"assistant","model":"claude-sonnet-4-5","usage":{"input_tokens":10,"output_tokens":5},"content":"Hello"}}
+{"type":"assistant","timestamp":"2025-12-25T00:00:10.000Z","uuid":"a2","parentUuid":"a1","message":{"role":"assistant","model":"claude-sonnet-4-5","usage":{"input_tokens":20,"cache_read_input_tokens":100},"content":"More"}}
+{"type":"assistant","timestamp":"2025-12-25T00:00:20.000Z","uuid":"a3","parentUuid":"a2","message":{"role":"assistant","model":"claude-sonnet-4-5","usage":{"input_tokens":1,"output_tokens":1},"content":"Done"}}
+`
+	if err := os.WriteFile(sessionPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ModelUsage{
+		{Model: "claude-sonnet-4-5", Date: "2025-12-24", Usage: Usage{InputTokens: 10, OutputTokens: 5}},
+		{Model: "claude-sonnet-4-5", Date: "2025-12-25", Usage: Usage{InputTokens: 21, OutputTokens: 1, CacheReadTokens: 100}},
+	}
+
+	session, err := ParseSession(sessionPath)
+	if err != nil {
+		t.Fatalf("ParseSession() error: %v", err)
+	}
+	if !reflect.DeepEqual(session.Stats.ModelUsage, want) {
+		t.Errorf("ModelUsage = %+v, want %+v", session.Stats.ModelUsage, want)
+	}
+
+	// The listing's quick parse agrees with the full parse
+	_, _, _, stats, _ := quickParseSession(sessionPath)
+	if !reflect.DeepEqual(stats.ModelUsage, want) {
+		t.Errorf("quick ModelUsage = %+v, want %+v", stats.ModelUsage, want)
+	}
+}
+
+func TestParseSession_ContextTimeline(t *testing.T) {
+	dir := t.TempDir()
+	sessionPath := filepath.Join(dir, "test.jsonl")
+
+	content := `{"type":"user","timestamp":"2025-12-24T10:00:00.000Z","uuid":"u1","message":{"role":"user","content":"Hi"}}
+{"type":"assistant","timestamp":"2025-12-24T10:00:01.000Z","uuid":"a1","parentUuid":"u1","message":{"role":"assistant","usage":{"input_tokens":10,"cache_creation_input_tokens":1000,"output_tokens":90},"content":"Hello"}}
+{"type":"user","timestamp":"2025-12-24T10:00:02.000Z","uuid":"u2","parentUuid":"a1","message":{"role":"user","content":"Read it"}}
+{"type":"assistant","timestamp":"2025-12-24T10:00:03.000Z","uuid":"a2","parentUuid":"u2","message":{"role":"assistant","usage":{"input_tokens":10,"cache_read_input_tokens":1100,"output_tokens":40},"content":[{"type":"text","text":"Reading"}]}}
+{"type":"assistant","timestamp":"2025-12-24T10:00:03.100Z","uuid":"a2b","parentUuid":"a2","message":{"role":"assistant","usage":{"input_tokens":10,"cache_read_input_tokens":1100,"output_tokens":40},"content":[{"type":"tool_use","id":"t1","name":"Read","input":{}}]}}
+{"type":"user","timestamp":"2025-12-24T10:00:04.000Z","uuid":"r1","parentUuid":"a2b","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"big file"}]}}
+{"type":"assistant","timestamp":"2025-12-24T10:00:05.000Z","uuid":"a3","parentUuid":"r1","message":{"role":"assistant","usage":{"input_tokens":10,"cache_read_input_tokens":1150,"cache_creation_input_tokens":50000,"output_tokens":40},"content":"Done"}}
+{"type":"user","timestamp":"2025-12-24T10:00:06.000Z","uuid":"c1","parentUuid":"a3","isCompactSummary":true,"message":{"role":"user","content":"Summary"}}
+{"type":"assistant","timestamp":"2025-12-24T10:00:07.000Z","uuid":"a4","parentUuid":"c1","message":{"role":"assistant","usage":{"input_tokens":10,"cache_creation_input_tokens":3000,"output_tokens":10},"content":"Continuing"}}
+`
+	if err := os.WriteFile(sessionPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := ParseSession(sessionPath)
+	if err != nil {
+		t.Fatalf("ParseSession() error: %v", err)
+	}
+
+	points := ContextTimeline(session.RootMessages)
+	type point struct {
+		UUID          string
+		Tokens, Added int
+	}
+	var got []point
+	for _, p := range points {
+		got = append(got, point{p.Message.UUID, p.Tokens, p.Added})
+	}
+	want := []point{
+		{"a1", 1100, 0},
+		{"a2", 1150, 50},
+		{"a3", 51200, 50050},
+		{"a4", 3020, 0}, // New context after the compaction
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ContextTimeline = %+v, want %+v", got, want)
+	}
+
+	heaviest := HeaviestTurns(points, 1)
+	if len(heaviest) != 1 || heaviest[0].Message.UUID != "a3" {
+		t.Errorf("HeaviestTurns = %+v, want a3", heaviest)
+	}
+
+	// The tool result carries the context of the response before it
+	tree := flattenTree(session.RootMessages)
+	if r1 := tree["r1"]; r1 == nil || r1.ContextTokens != 1150 {
+		t.Errorf("r1.ContextTokens = %v, want 1150", r1)
+	}
+	if c1 := tree["c1"]; c1 == nil || c1.ContextTokens != 0 {
+		t.Errorf("compact summary ContextTokens = %v, want 0", c1)
+	}
+}
+
+func TestParseSession_Branches(t *testing.T) {
+	dir := t.TempDir()
+	sessionPath := filepath.Join(dir, "test.jsonl")
+
+	// u3 rewinds to a1 and asks again; the summary lines name their leaves
+	content := `{"type":"summary","summary":"Picked Go","leafUuid":"a2"}
+{"type":"summary","summary":"From another session","leafUuid":"elsewhere"}
+{"type":"user","timestamp":"2025-12-24T10:00:00.000Z","uuid":"u1","message":{"role":"user","content":"Plan it"}}
+{"type":"assistant","timestamp":"2025-12-24T10:00:01.000Z","uuid":"a1","parentUuid":"u1","message":{"role":"assistant","content":"Plan"}}
+{"type":"user","timestamp":"2025-12-24T10:01:00.000Z","uuid":"u2","parentUuid":"a1","message":{"role":"user","content":"Use Go"}}
+{"type":"assistant","timestamp":"2025-12-24T10:01:01.000Z","uuid":"a2","parentUuid":"u2","message":{"role":"assistant","content":"Go"}}
+{"type":"user","timestamp":"2025-12-24T10:02:00.000Z","uuid":"u3","parentUuid":"a1","message":{"role":"user","content":"Use Rust"}}
+{"type":"assistant","timestamp":"2025-12-24T10:02:01.000Z","uuid":"a3","parentUuid":"u3","message":{"role":"assistant","content":"Rust"}}
+{"type":"assistant","timestamp":"2025-12-24T10:02:02.000Z","uuid":"s1","parentUuid":"a3","isSidechain":true,"message":{"role":"assistant","content":"Agent"}}
+`
+	if err := os.WriteFile(sessionPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := ParseSession(sessionPath)
+	if err != nil {
+		t.Fatalf("ParseSession() error: %v", err)
+	}
+
+	want := []Branch{
+		{LeafUUID: "a2", ForkUUID: "u2", Summary: "Picked Go", Messages: 4, EndTime: time.Date(2025, 12, 24, 10, 1, 1, 0, time.UTC)},
+		{LeafUUID: "a3", ForkUUID: "u3", Messages: 4, EndTime: time.Date(2025, 12, 24, 10, 2, 1, 0, time.UTC)},
+	}
+	if !reflect.DeepEqual(session.Branches, want) {
+		t.Errorf("Branches = %+v, want %+v", session.Branches, want)
+	}
+
+	roots := BranchRoots(session.RootMessages, "a3")
+	var got []string
+	for msgs := roots; len(msgs) > 0; {
+		msg := msgs[len(msgs)-1]
+		got = append(got, msg.UUID)
+		msgs = msg.Children
+	}
+	if strings.Join(got, " ") != "u1 a1 u3 a3 s1" {
+		t.Errorf("BranchRoots path = %v, want u1 a1 u3 a3 s1", got)
+	}
+	if len(session.RootMessages[0].Children[0].Children) != 2 {
+		t.Error("BranchRoots modified the session tree")
+	}
+	if BranchRoots(session.RootMessages, "missing") != nil {
+		t.Error("BranchRoots(missing) should be nil")
+	}
+}
+
+func flattenTree(msgs []*Message) map[string]*Message {
+	out := make(map[string]*Message)
+	var walk func([]*Message)
+	walk = func(msgs []*Message) {
+		for _, m := range msgs {
+			out[m.UUID] = m
+			walk(m.Children)
+		}
+	}
+	walk(msgs)
+	return out
+}
+
 // Benchmark tests
 func BenchmarkComputeStats(b *testing.B) {
 	messages := make([]*Message, 1000)
@@ -517,3 +672,73 @@ func BenchmarkBuildMessageTree(b *testing.B) {
 		buildMessageTree(messages)
 	}
 }
+
+func TestParseLine(t *testing.T) {
+	msg, ok := ParseLine([]byte(`{"type":"assistant","timestamp":"2026-01-01T10:00:01Z","uuid":"a1","parentUuid":"u1","message":{"model":"claude-sonnet-4-5","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"ls"}}]}}`))
+	if !ok {
+		t.Fatal("assistant line should parse")
+	}
+	if msg.UUID != "a1" || msg.Kind != KindAssistant || len(msg.Content) != 1 || msg.Content[0].ToolName != "Bash" {
+		t.Errorf("got %+v", msg)
+	}
+
+	for _, line := range []string{
+		`{"type":"summary","summary":"Fix the tests","leafUuid":"a1"}`,
+		`{"type":"system","subtype":"compact_boundary","uuid":"c1"}`,
+		`{"type":"user",`,
+		``,
+	} {
+		if _, ok := ParseLine([]byte(line)); ok {
+			t.Errorf("ParseLine(%q) ok, want skipped", line)
+		}
+	}
+}
+
+func TestSetTextFilter(t *testing.T) {
+	dir := t.TempDir()
+	sessionPath := filepath.Join(dir, "filtered.jsonl")
+	content := `{"type":"user","timestamp":"2026-01-01T10:00:00Z","uuid":"u1","message":{"content":"Use key SECRET1"}}
+{"type":"assistant","timestamp":"2026-01-01T10:00:01Z","uuid":"a1","parentUuid":"u1","message":{"content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"login SECRET1"}},{"type":"image","source":{"type":"base64","data":"SECRET1"}}]}}
+{"type":"user","timestamp":"2026-01-01T10:00:02Z","uuid":"r1","parentUuid":"a1","toolUseResult":{"stdout":"SECRET1 ok"},"message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":[{"type":"text","text":"SECRET1 ok"}]}]}}
+`
+	if err := os.WriteFile(sessionPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	SetTextFilter(func(s string) string { return strings.ReplaceAll(s, "SECRET1", "***") })
+	t.Cleanup(func() { SetTextFilter(nil) })
+
+	session, err := ParseSession(sessionPath)
+	if err != nil {
+		t.Fatalf("ParseSession() error: %v", err)
+	}
+	if session.Summary != "Use key ***" {
+		t.Errorf("Summary = %q", session.Summary)
+	}
+	msgs := flattenTree(session.RootMessages)
+	if cmd := msgs["a1"].Content[0].ToolInput.(map[string]any)["command"]; cmd != "login ***" {
+		t.Errorf("tool input = %q", cmd)
+	}
+	if data := msgs["a1"].Content[1].ImageData; data != "SECRET1" {
+		t.Errorf("image data rewritten to %q", data)
+	}
+	if text := msgs["r1"].Content[0].ToolResult.([]any)[0].(map[string]any)["text"]; text != "*** ok" {
+		t.Errorf("tool result = %q", text)
+	}
+	if out := msgs["r1"].ToolUseResult().(map[string]any)["stdout"]; out != "*** ok" {
+		t.Errorf("tool use result = %q", out)
+	}
+
+	sessions, err := DiscoverSessions(dir)
+	if err != nil || len(sessions) != 1 || sessions[0].Summary != "Use key ***" {
+		t.Errorf("DiscoverSessions() = %v, %v; want the filtered summary", sessions, err)
+	}
+	if msg, _ := ParseLine([]byte(strings.SplitN(content, "\n", 2)[0])); msg.Content[0].Text != "Use key ***" {
+		t.Errorf("ParseLine text = %q", msg.Content[0].Text)
+	}
+
+	raw, err := ParseSessionUnfiltered(sessionPath)
+	if err != nil || raw.Summary != "Use key SECRET1" {
+		t.Errorf("ParseSessionUnfiltered() summary = %q, %v", raw.Summary, err)
+	}
+}
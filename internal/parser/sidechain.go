@@ -0,0 +1,185 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// isTaskTool reports whether a tool spawns a subagent
+func isTaskTool(name string) bool {
+	return name == "Task" || name == "Agent"
+}
+
+// sidechainFiles returns the agent transcripts belonging to a session:
+// agent-*.jsonl files next to it whose sessionId matches, and any
+// agent-*.jsonl under <session>/subagents/ (newer layout). The sessionId
+// comes from the metadata cache, so unchanged agent files aren't reread.
+func sidechainFiles(sessionPath, sessionID string) []string {
+	var files []string
+
+	dir := filepath.Dir(sessionPath)
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			if !isAgentFile(entry) {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if sessionCache.parse(path).Meta.SessionID == sessionID {
+				files = append(files, path)
+			}
+		}
+	}
+
+	subDir := filepath.Join(strings.TrimSuffix(sessionPath, ".jsonl"), "subagents")
+	if entries, err := os.ReadDir(subDir); err == nil {
+		for _, entry := range entries {
+			if isAgentFile(entry) {
+				files = append(files, filepath.Join(subDir, entry.Name()))
+			}
+		}
+	}
+
+	return files
+}
+
+func isAgentFile(entry os.DirEntry) bool {
+	name := entry.Name()
+	return !entry.IsDir() && strings.HasPrefix(name, "agent-") && strings.HasSuffix(name, ".jsonl")
+}
+
+// attachSidechains parses a session's agent transcripts and links each one to
+// the Task tool_use that spawned it. Links come from the agentId in the Task's
+// toolUseResult, falling back to matching the agent's first prompt against the
+// Task prompt. Token and tool counts of every sidechain roll up into the session.
+func attachSidechains(s *Session, messages []*Message) {
+	files := sidechainFiles(s.FilePath, s.ID)
+	if len(files) == 0 {
+		return
+	}
+
+	tasks := make(map[string]*ContentBlock) // tool ID -> Task tool_use
+	promptTools := make(map[string]string)  // Task prompt -> tool ID
+	agentTools := make(map[string]string)   // agent ID -> tool ID
+	for _, msg := range messages {
+		for i := range msg.Content {
+			block := &msg.Content[i]
+			switch block.Type {
+			case "tool_use":
+				if !isTaskTool(block.ToolName) {
+					continue
+				}
+				tasks[block.ToolID] = block
+				if m, ok := block.ToolInput.(map[string]any); ok {
+					if prompt, ok := m["prompt"].(string); ok {
+						promptTools[strings.TrimSpace(prompt)] = block.ToolID
+					}
+				}
+			case "tool_result":
+				if id := resultAgentID(msg.raw.ToolUseResult); id != "" {
+					agentTools[id] = block.ToolID
+				}
+			}
+		}
+	}
+
+	for _, path := range files {
+		sc, prompt, err := parseSidechain(path)
+		if err != nil || sc == nil {
+			continue
+		}
+		if strings.EqualFold(prompt, "warmup") {
+			continue
+		}
+
+		toolID, ok := agentTools[sc.AgentID]
+		if !ok {
+			toolID = promptTools[prompt]
+		}
+		if block, ok := tasks[toolID]; ok && block.Sidechain == nil {
+			block.Sidechain = sc
+			sc.ToolID = toolID
+		}
+
+		s.Sidechains = append(s.Sidechains, sc)
+		addSidechainStats(&s.Stats, sc.Stats)
+	}
+
+	sort.Slice(s.Sidechains, func(i, j int) bool {
+		return s.Sidechains[i].StartTime.Before(s.Sidechains[j].StartTime)
+	})
+}
+
+// parseSidechain parses an agent transcript and returns it with its first prompt
+func parseSidechain(path string) (*Sidechain, string, error) {
+	t, messages, err := parseTranscript(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(messages) == 0 {
+		return nil, "", nil
+	}
+
+	sc := &Sidechain{
+		AgentID:      strings.TrimPrefix(t.ID, "agent-"),
+		FilePath:     path,
+		StartTime:    t.StartTime,
+		EndTime:      t.EndTime,
+		RootMessages: t.RootMessages,
+		Stats:        t.Stats,
+	}
+
+	// The agentId recorded in the transcript wins over the file name
+	var prompt string
+	for _, msg := range messages {
+		if msg.AgentID != "" {
+			sc.AgentID = msg.AgentID
+			break
+		}
+	}
+	for _, msg := range messages {
+		if msg.Kind == KindUserPrompt {
+			prompt = extractTextFromContent(msg.raw.Message.Content)
+			break
+		}
+	}
+	return sc, prompt, nil
+}
+
+// quickParseSidechain returns the stats and parent session ID of an agent
+// transcript for listings. Warmup agents are reported as not ok.
+func quickParseSidechain(path string) (SessionStats, string, bool) {
+	summary, _, _, stats, meta := quickParseSession(path)
+	if strings.EqualFold(summary, "warmup") {
+		return stats, meta.SessionID, false
+	}
+	return stats, meta.SessionID, true
+}
+
+// resultAgentID extracts the agentId from a Task toolUseResult payload
+func resultAgentID(result any) string {
+	m, ok := result.(map[string]any)
+	if !ok {
+		return ""
+	}
+	id, _ := m["agentId"].(string)
+	return id
+}
+
+// addSidechainStats rolls a sidechain's token and tool usage into its session.
+// Turn counts stay with the main conversation.
+func addSidechainStats(stats *SessionStats, sc SessionStats) {
+	// ModelUsage entries are updated in place; don't write through a shared slice
+	stats.ModelUsage = slices.Clone(stats.ModelUsage)
+	stats.ToolCalls += sc.ToolCalls
+	stats.AgentSidechains += sc.AgentSidechains
+	stats.InputTokens += sc.InputTokens
+	stats.OutputTokens += sc.OutputTokens
+	stats.CacheReadTokens += sc.CacheReadTokens
+	stats.CacheCreateTokens += sc.CacheCreateTokens
+	for _, mu := range sc.ModelUsage {
+		stats.addModelUsage(mu)
+	}
+}
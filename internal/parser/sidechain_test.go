@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const parentSession = `{"type":"user","sessionId":"s1","timestamp":"2026-01-01T10:00:00Z","uuid":"u1","message":{"content":"Investigate the crash"}}
+{"type":"assistant","sessionId":"s1","timestamp":"2026-01-01T10:00:01Z","uuid":"a1","parentUuid":"u1","message":{"usage":{"input_tokens":100,"output_tokens":20},"content":[{"type":"tool_use","id":"t1","name":"Task","input":{"description":"find bug","prompt":"Find the bug"}},{"type":"tool_use","id":"t2","name":"Task","input":{"description":"list","prompt":"List files"}}]}}
+{"type":"user","sessionId":"s1","timestamp":"2026-01-01T10:00:05Z","uuid":"r1","parentUuid":"a1","toolUseResult":{"status":"completed","agentId":"abc"},"message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"found it"}]}}
+{"type":"user","sessionId":"s1","timestamp":"2026-01-01T10:00:06Z","uuid":"r2","parentUuid":"r1","message":{"content":[{"type":"tool_result","tool_use_id":"t2","content":"done"}]}}
+`
+
+const agentABC = `{"type":"user","sessionId":"s1","agentId":"abc","isSidechain":true,"timestamp":"2026-01-01T10:00:02Z","uuid":"x1","message":{"content":"Find the bug"}}
+{"type":"assistant","sessionId":"s1","agentId":"abc","isSidechain":true,"timestamp":"2026-01-01T10:00:03Z","uuid":"x2","parentUuid":"x1","message":{"usage":{"input_tokens":10,"output_tokens":5},"content":[{"type":"tool_use","id":"g1","name":"Grep","input":{"pattern":"panic"}}]}}
+`
+
+// Linked by prompt: the parent's tool_result has no toolUseResult
+const agentDEF = `{"type":"user","sessionId":"s1","agentId":"def","isSidechain":true,"timestamp":"2026-01-01T10:00:04Z","uuid":"y1","message":{"content":"List files"}}
+{"type":"assistant","sessionId":"s1","agentId":"def","isSidechain":true,"timestamp":"2026-01-01T10:00:05Z","uuid":"y2","parentUuid":"y1","message":{"usage":{"input_tokens":1,"output_tokens":1},"content":"a.go b.go"}}
+`
+
+const agentWarmup = `{"type":"user","sessionId":"s1","agentId":"warm","isSidechain":true,"timestamp":"2026-01-01T09:59:00Z","uuid":"w1","message":{"content":"Warmup"}}
+{"type":"assistant","sessionId":"s1","agentId":"warm","isSidechain":true,"timestamp":"2026-01-01T09:59:01Z","uuid":"w2","parentUuid":"w1","message":{"usage":{"input_tokens":500,"output_tokens":1},"content":"ready"}}
+`
+
+const agentOther = `{"type":"user","sessionId":"s2","agentId":"zzz","isSidechain":true,"timestamp":"2026-01-01T10:00:02Z","uuid":"z1","message":{"content":"Unrelated"}}
+`
+
+func writeSidechainFixture(t *testing.T) string {
+	t.Helper()
+	projectDir := filepath.Join(t.TempDir(), "-test-project")
+	subDir := filepath.Join(projectDir, "s1", "subagents")
+	if err := os.MkdirAll(subDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		filepath.Join(projectDir, "s1.jsonl"):         parentSession,
+		filepath.Join(projectDir, "agent-abc.jsonl"):  agentABC,
+		filepath.Join(projectDir, "agent-warm.jsonl"): agentWarmup,
+		filepath.Join(projectDir, "agent-zzz.jsonl"):  agentOther,
+		filepath.Join(subDir, "agent-def.jsonl"):      agentDEF,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return projectDir
+}
+
+func TestParseSession_AttachesSidechains(t *testing.T) {
+	projectDir := writeSidechainFixture(t)
+
+	s, err := ParseSession(filepath.Join(projectDir, "s1.jsonl"))
+	if err != nil {
+		t.Fatalf("ParseSession() error: %v", err)
+	}
+
+	if len(s.Sidechains) != 2 {
+		t.Fatalf("len(Sidechains) = %d, want 2 (warmup and other sessions excluded)", len(s.Sidechains))
+	}
+
+	tasks := make(map[string]ContentBlock)
+	for _, block := range s.RootMessages[0].Children[0].Content {
+		tasks[block.ToolID] = block
+	}
+	if sc := tasks["t1"].Sidechain; sc == nil || sc.AgentID != "abc" || sc.ToolID != "t1" {
+		t.Errorf("t1 sidechain = %+v, want agent abc linked by toolUseResult", sc)
+	}
+	if sc := tasks["t2"].Sidechain; sc == nil || sc.AgentID != "def" {
+		t.Errorf("t2 sidechain = %+v, want agent def linked by prompt", sc)
+	}
+	if sc := tasks["t1"].Sidechain; sc != nil && (len(sc.RootMessages) != 1 || len(sc.RootMessages[0].Children) != 1) {
+		t.Errorf("agent transcript not built as a tree: %+v", sc.RootMessages)
+	}
+
+	// Token and tool counts roll up; turn counts stay with the main thread
+	if s.Stats.ToolCalls != 3 {
+		t.Errorf("ToolCalls = %d, want 3", s.Stats.ToolCalls)
+	}
+	if s.Stats.InputTokens != 111 || s.Stats.OutputTokens != 26 {
+		t.Errorf("tokens = %d/%d, want 111/26", s.Stats.InputTokens, s.Stats.OutputTokens)
+	}
+	if s.Stats.MessageCount != 2 {
+		t.Errorf("MessageCount = %d, want 2", s.Stats.MessageCount)
+	}
+	if s.Stats.AgentSidechains != 4 {
+		t.Errorf("AgentSidechains = %d, want 4", s.Stats.AgentSidechains)
+	}
+}
+
+func TestDiscoverSessions_RollsUpSidechainStats(t *testing.T) {
+	projectDir := writeSidechainFixture(t)
+
+	sessions, err := discoverSessions(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != "s1" {
+		t.Fatalf("sessions = %+v, want only s1 (agent files are not sessions)", sessions)
+	}
+
+	stats := sessions[0].Stats
+	if stats.ToolCalls != 3 || stats.InputTokens != 111 || stats.OutputTokens != 26 {
+		t.Errorf("listing stats = %+v, want rolled-up tools 3, tokens 111/26", stats)
+	}
+}
+
+func TestSidechainFiles_UsesMetadataCache(t *testing.T) {
+	setupCache(t)
+	projectDir := writeSidechainFixture(t)
+	sessionPath := filepath.Join(projectDir, "s1.jsonl")
+
+	if files := sidechainFiles(sessionPath, "s1"); len(files) != 3 {
+		t.Fatalf("sidechainFiles = %v, want abc, warm and def", files)
+	}
+
+	// An unchanged file is served from the cache without being reread
+	other := filepath.Join(projectDir, "agent-zzz.jsonl")
+	info, err := os.Stat(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(other, []byte(strings.Replace(agentOther, `"s2"`, `"s1"`, 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(other, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if files := sidechainFiles(sessionPath, "s1"); len(files) != 3 {
+		t.Errorf("sidechainFiles = %v, want the cached sessionIds", files)
+	}
+}
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -35,7 +37,8 @@ type Session struct {
 	EndTime      time.Time
 	RootMessages []*Message
 	Stats        SessionStats
-	Branches     []Branch // For resume view tree structure
+	Branches     []Branch     // Paths through the message tree, in tree order
+	Sidechains   []*Sidechain // Subagent transcripts from agent-*.jsonl
 
 	// Metadata from session messages
 	Slug      string // Human-readable name like "melodic-cooking-piglet"
@@ -44,11 +47,25 @@ type Session struct {
 	CWD       string // Working directory
 }
 
-// Branch represents a conversation branch (for resume tree view)
+// Sidechain is a subagent transcript stored in its own agent-*.jsonl file
+type Sidechain struct {
+	AgentID      string
+	ToolID       string // tool_use ID of the Task call that spawned it; empty if unlinked
+	FilePath     string
+	StartTime    time.Time
+	EndTime      time.Time
+	RootMessages []*Message
+	Stats        SessionStats
+}
+
+// Branch is one root-to-leaf path through a session's message tree.
+// Rewinding or resuming from an earlier message forks a new branch.
 type Branch struct {
 	LeafUUID string
-	Summary  string
-	Messages int
+	ForkUUID string // First message on no other branch's path
+	Summary  string // From the summary line written for this leaf, if any
+	Messages int    // User prompts and responses on the path
+	EndTime  time.Time
 }
 
 type SessionStats struct {
@@ -64,10 +81,28 @@ type SessionStats struct {
 	CacheReadTokens   int
 	CacheCreateTokens int
 
+	// Token usage per model and UTC day, for cost estimates
+	ModelUsage []ModelUsage
+
 	// Duration
 	DurationSeconds float64
 }
 
+// Usage is the token usage reported with one API response
+type Usage struct {
+	InputTokens       int `json:"input_tokens"`
+	OutputTokens      int `json:"output_tokens"`
+	CacheReadTokens   int `json:"cache_read_tokens"`
+	CacheCreateTokens int `json:"cache_create_tokens"`
+}
+
+// ModelUsage is one model's token usage on one UTC day
+type ModelUsage struct {
+	Model string
+	Date  string // YYYY-MM-DD
+	Usage
+}
+
 type Message struct {
 	UUID       string
 	ParentUUID string
@@ -86,10 +121,51 @@ type Message struct {
 	AgentID     string
 	Model       string // Model ID (e.g., claude-sonnet-4-5-20250929)
 	Subtype     string // For system messages: compact_boundary, local_command
+	Usage       *Usage // Token usage of the API response; nil for user messages
+
+	// Estimated context size after this message, from the latest response
+	// on its path; 0 before the first response and after a compaction
+	ContextTokens int
 
 	raw rawMessage
 }
 
+// ToolUseResult is the structured outcome Claude Code records alongside a
+// tool_result, such as an edit's original file and patch; nil if absent
+func (m *Message) ToolUseResult() any {
+	return m.raw.ToolUseResult
+}
+
+// CWD is the working directory Claude Code recorded with the message
+func (m *Message) CWD() string {
+	return m.raw.CWD
+}
+
+// PromptText is what a prompt asked: a slash command with its arguments,
+// or the message's text blocks
+func (m *Message) PromptText() string {
+	if m.Kind == KindCommand {
+		return strings.TrimSpace(m.CommandName + " " + m.CommandArgs)
+	}
+	var parts []string
+	for _, block := range m.Content {
+		if block.Type == "text" && block.Text != "" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n"))
+}
+
+// IsEditTool reports whether a tool writes the file named by its
+// file_path (or notebook_path) input rather than reading it
+func IsEditTool(name string) bool {
+	switch name {
+	case "Write", "Edit", "MultiEdit", "NotebookEdit":
+		return true
+	}
+	return false
+}
+
 type ContentBlock struct {
 	Type       string // text | tool_use | tool_result | thinking | image
 	Text       string
@@ -100,6 +176,30 @@ type ContentBlock struct {
 	IsError    bool
 	ImageData  string
 	MediaType  string
+	Sidechain  *Sidechain `json:"-"` // Agent transcript spawned by this Task tool_use; also in Session.Sidechains
+}
+
+// ResultText extracts readable text from a tool_result payload,
+// which is either a string or a list of content blocks.
+func (b ContentBlock) ResultText() string {
+	switch v := b.ToolResult.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []any:
+		var parts []string
+		for _, item := range v {
+			if m, ok := item.(map[string]any); ok {
+				if t, ok := m["text"].(string); ok {
+					parts = append(parts, t)
+				}
+			}
+		}
+		return strings.Join(parts, "\n")
+	default:
+		return fmt.Sprintf("%v", v)
+	}
 }
 
 type rawMessage struct {
@@ -114,6 +214,7 @@ type rawMessage struct {
 	IsSidechain       bool           `json:"isSidechain"`
 	IsMeta            bool           `json:"isMeta"`
 	AgentID           string         `json:"agentId"`
+	ToolUseResult     any            `json:"toolUseResult"` // Tool metadata; Task results carry agentId
 	Message           messagePayload `json:"message"`
 	Content           string         `json:"content"`  // For system messages
 	Summary           string         `json:"summary"`  // For summary type
@@ -134,6 +235,49 @@ type usageData struct {
 	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
 }
 
+func (u *usageData) toUsage() Usage {
+	return Usage{
+		InputTokens:       u.InputTokens,
+		OutputTokens:      u.OutputTokens,
+		CacheReadTokens:   u.CacheReadInputTokens,
+		CacheCreateTokens: u.CacheCreationInputTokens,
+	}
+}
+
+// Add folds o into u
+func (u *Usage) Add(o Usage) {
+	u.InputTokens += o.InputTokens
+	u.OutputTokens += o.OutputTokens
+	u.CacheReadTokens += o.CacheReadTokens
+	u.CacheCreateTokens += o.CacheCreateTokens
+}
+
+// Total is the sum of all four counts. For one API response it is also the
+// context window size of the request plus the output it added.
+func (u Usage) Total() int {
+	return u.InputTokens + u.OutputTokens + u.CacheReadTokens + u.CacheCreateTokens
+}
+
+// addUsage folds one API response into the token totals and the
+// per-model breakdown
+func (s *SessionStats) addUsage(model string, t time.Time, u Usage) {
+	s.InputTokens += u.InputTokens
+	s.OutputTokens += u.OutputTokens
+	s.CacheReadTokens += u.CacheReadTokens
+	s.CacheCreateTokens += u.CacheCreateTokens
+	s.addModelUsage(ModelUsage{Model: model, Date: t.UTC().Format("2006-01-02"), Usage: u})
+}
+
+func (s *SessionStats) addModelUsage(mu ModelUsage) {
+	for i := range s.ModelUsage {
+		if s.ModelUsage[i].Model == mu.Model && s.ModelUsage[i].Date == mu.Date {
+			s.ModelUsage[i].Add(mu.Usage)
+			return
+		}
+	}
+	s.ModelUsage = append(s.ModelUsage, mu)
+}
+
 type messagePayload struct {
 	Role    string     `json:"role"`
 	Content any        `json:"content"` // string or []contentBlock
@@ -0,0 +1,216 @@
+// Package pricing estimates API cost from token usage and a table of
+// per-model rates. Claude Code doesn't record cost in its transcripts, so
+// every figure here is an estimate: list prices, no discounts, no
+// long-context or batch rates.
+package pricing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+// Rate is the price of a model from a date on, in USD per million tokens
+type Rate struct {
+	Model      string  `mapstructure:"model" json:"model"`           // Model ID or ID prefix, e.g. claude-sonnet-4-5
+	Since      string  `mapstructure:"since" json:"since,omitempty"` // YYYY-MM-DD the rate takes effect (UTC); empty for always
+	Input      float64 `mapstructure:"input" json:"input"`
+	Output     float64 `mapstructure:"output" json:"output"`
+	CacheRead  float64 `mapstructure:"cache_read" json:"cache_read"`
+	CacheWrite float64 `mapstructure:"cache_write" json:"cache_write"`
+
+	since time.Time
+}
+
+// Cost is the USD price of u at this rate
+func (r Rate) Cost(u parser.Usage) float64 {
+	return (float64(u.InputTokens)*r.Input +
+		float64(u.OutputTokens)*r.Output +
+		float64(u.CacheReadTokens)*r.CacheRead +
+		float64(u.CacheCreateTokens)*r.CacheWrite) / 1e6
+}
+
+// defaultRates are Anthropic list prices; cache writes at the 5-minute rate
+var defaultRates = []Rate{
+	{Model: "claude-opus-4-5", Input: 5, Output: 25, CacheRead: 0.50, CacheWrite: 6.25},
+	{Model: "claude-opus-4-1", Input: 15, Output: 75, CacheRead: 1.50, CacheWrite: 18.75},
+	{Model: "claude-opus-4", Input: 15, Output: 75, CacheRead: 1.50, CacheWrite: 18.75},
+	{Model: "claude-sonnet-4-5", Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+	{Model: "claude-sonnet-4", Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+	{Model: "claude-haiku-4-5", Input: 1, Output: 5, CacheRead: 0.10, CacheWrite: 1.25},
+	{Model: "claude-3-7-sonnet", Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+	{Model: "claude-3-5-sonnet", Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+	{Model: "claude-3-5-haiku", Input: 0.80, Output: 4, CacheRead: 0.08, CacheWrite: 1},
+	{Model: "claude-3-opus", Input: 15, Output: 75, CacheRead: 1.50, CacheWrite: 18.75},
+	{Model: "claude-3-haiku", Input: 0.25, Output: 1.25, CacheRead: 0.03, CacheWrite: 0.30},
+}
+
+// Table looks up rates by model and date. A nil Table prices nothing.
+type Table struct {
+	rates map[string][]Rate // Model -> rates, oldest first
+}
+
+// Default is the table of built-in rates
+func Default() *Table {
+	t, _ := New(nil)
+	return t
+}
+
+// New returns the built-in rates with overrides applied. An override
+// replaces the built-in rate for the same model and date, or adds to them.
+func New(overrides []Rate) (*Table, error) {
+	t := &Table{rates: make(map[string][]Rate)}
+	for _, r := range defaultRates {
+		t.set(r)
+	}
+	for _, r := range overrides {
+		r.Model = strings.TrimSpace(r.Model)
+		if r.Model == "" {
+			return nil, fmt.Errorf("pricing entry without a model")
+		}
+		if r.Since != "" {
+			since, err := time.Parse("2006-01-02", r.Since)
+			if err != nil {
+				return nil, fmt.Errorf("pricing for %s: invalid since %q (want YYYY-MM-DD)", r.Model, r.Since)
+			}
+			r.since = since
+		}
+		t.set(r)
+	}
+	return t, nil
+}
+
+func (t *Table) set(r Rate) {
+	rates := t.rates[r.Model]
+	for i := range rates {
+		if rates[i].Since == r.Since {
+			rates[i] = r
+			return
+		}
+	}
+	rates = append(rates, r)
+	sort.Slice(rates, func(i, j int) bool { return rates[i].since.Before(rates[j].since) })
+	t.rates[r.Model] = rates
+}
+
+// Lookup finds the rate for a model ID at a time. The entry whose model
+// is the longest prefix of the ID wins; of its rates, the latest in effect
+// at that time. The earliest rate also covers anything before it.
+func (t *Table) Lookup(model string, at time.Time) (Rate, bool) {
+	if t == nil || model == "" {
+		return Rate{}, false
+	}
+	var best string
+	for m := range t.rates {
+		if (model == m || strings.HasPrefix(model, m+"-")) && len(m) > len(best) {
+			best = m
+		}
+	}
+	rates := t.rates[best]
+	if len(rates) == 0 {
+		return Rate{}, false
+	}
+	rate := rates[0]
+	for _, r := range rates[1:] {
+		if !at.Before(r.since) {
+			rate = r
+		}
+	}
+	return rate, true
+}
+
+// Estimate is an estimated cost. Tokens of models without a rate are
+// counted, not priced.
+type Estimate struct {
+	USD      float64 `json:"usd"`
+	Unpriced int     `json:"unpriced_tokens,omitempty"`
+}
+
+// Add folds o into the estimate
+func (e *Estimate) Add(o Estimate) {
+	e.USD += o.USD
+	e.Unpriced += o.Unpriced
+}
+
+// IsZero reports whether there was no usage to estimate
+func (e Estimate) IsZero() bool {
+	return e.USD == 0 && e.Unpriced == 0
+}
+
+// String formats the estimate as "~$1.23", with a trailing "+" when some
+// tokens could not be priced, or "?" when none could
+func (e Estimate) String() string {
+	if e.USD == 0 && e.Unpriced > 0 {
+		return "?"
+	}
+	s := "~" + FormatUSD(e.USD)
+	if e.Unpriced > 0 {
+		s += "+"
+	}
+	return s
+}
+
+// FormatUSD formats a dollar amount to the cent
+func FormatUSD(usd float64) string {
+	if usd > 0 && usd < 0.01 {
+		return "<$0.01"
+	}
+	return fmt.Sprintf("$%.2f", usd)
+}
+
+// Usage estimates the cost of one model's usage at a time
+func (t *Table) Usage(model string, at time.Time, u parser.Usage) Estimate {
+	if r, ok := t.Lookup(model, at); ok {
+		return Estimate{USD: r.Cost(u)}
+	}
+	return Estimate{Unpriced: u.InputTokens + u.OutputTokens + u.CacheReadTokens + u.CacheCreateTokens}
+}
+
+// Message estimates the cost of one API response; zero for messages
+// without usage
+func (t *Table) Message(msg *parser.Message) Estimate {
+	if msg.Usage == nil {
+		return Estimate{}
+	}
+	return t.Usage(msg.Model, msg.Timestamp, *msg.Usage)
+}
+
+// Session estimates the cost of a session's usage, agent transcripts
+// included, from its per-model breakdown
+func (t *Table) Session(stats parser.SessionStats) Estimate {
+	var e Estimate
+	for _, mu := range stats.ModelUsage {
+		at, _ := time.Parse("2006-01-02", mu.Date)
+		e.Add(t.Usage(mu.Model, at, mu.Usage))
+	}
+	return e
+}
+
+// ByDay estimates a session's cost per UTC day, in date order
+func (t *Table) ByDay(stats parser.SessionStats) []DayEstimate {
+	byDate := make(map[string]*Estimate)
+	for _, mu := range stats.ModelUsage {
+		at, _ := time.Parse("2006-01-02", mu.Date)
+		e := byDate[mu.Date]
+		if e == nil {
+			e = &Estimate{}
+			byDate[mu.Date] = e
+		}
+		e.Add(t.Usage(mu.Model, at, mu.Usage))
+	}
+	out := make([]DayEstimate, 0, len(byDate))
+	for date, e := range byDate {
+		out = append(out, DayEstimate{Date: date, Estimate: *e})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Date < out[j].Date })
+	return out
+}
+
+// DayEstimate is the estimated cost of one UTC day
+type DayEstimate struct {
+	Date string `json:"date"`
+	Estimate
+}
@@ -0,0 +1,128 @@
+package pricing
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+func TestLookup(t *testing.T) {
+	table, err := New([]Rate{
+		{Model: "claude-sonnet-4-5", Since: "2026-06-01", Input: 2, Output: 10},
+		{Model: "claude-opus-4-1", Input: 20, Output: 100}, // replaces the built-in rate
+		{Model: "acme-coder", Input: 1, Output: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Date(2026, 5, 31, 23, 0, 0, 0, time.UTC)
+	after := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		model string
+		at    time.Time
+		input float64
+		ok    bool
+	}{
+		{"built-in before override", "claude-sonnet-4-5-20250929", before, 3, true},
+		{"override from its date", "claude-sonnet-4-5-20250929", after, 2, true},
+		{"longest prefix", "claude-opus-4-5-20251101", before, 5, true},
+		{"replaced built-in", "claude-opus-4-1-20250805", before, 20, true},
+		{"shorter prefix", "claude-opus-4-20250514", before, 15, true},
+		{"custom model", "acme-coder", before, 1, true},
+		{"prefix must end at a dash", "claude-sonnet-45", before, 0, false},
+		{"unknown", "<synthetic>", before, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, ok := table.Lookup(tt.model, tt.at)
+			if ok != tt.ok || r.Input != tt.input {
+				t.Errorf("Lookup(%q) = %v, %v; want input %v, %v", tt.model, r.Input, ok, tt.input, tt.ok)
+			}
+		})
+	}
+}
+
+func TestNewRejectsInvalidEntries(t *testing.T) {
+	if _, err := New([]Rate{{Model: "claude-sonnet-4-5", Since: "June"}}); err == nil {
+		t.Error("expected error for invalid since")
+	}
+	if _, err := New([]Rate{{Input: 1}}); err == nil {
+		t.Error("expected error for missing model")
+	}
+}
+
+func TestSessionEstimate(t *testing.T) {
+	table := Default()
+	stats := parser.SessionStats{ModelUsage: []parser.ModelUsage{
+		{Model: "claude-sonnet-4-5-20250929", Date: "2026-03-01", Usage: parser.Usage{InputTokens: 1_000_000, CacheReadTokens: 1_000_000}},
+		{Model: "claude-haiku-4-5-20251001", Date: "2026-03-02", Usage: parser.Usage{OutputTokens: 100_000, CacheCreateTokens: 200_000}},
+		{Model: "mystery-model", Date: "2026-03-02", Usage: parser.Usage{InputTokens: 50}},
+	}}
+
+	e := table.Session(stats)
+	if math.Abs(e.USD-(3.30+0.75)) > 1e-9 || e.Unpriced != 50 {
+		t.Errorf("Session = %+v, want $4.05 with 50 unpriced", e)
+	}
+	if e.String() != "~$4.05+" {
+		t.Errorf("String = %q", e.String())
+	}
+
+	days := table.ByDay(stats)
+	if len(days) != 2 || days[0].Date != "2026-03-01" || math.Abs(days[1].USD-0.75) > 1e-9 {
+		t.Errorf("ByDay = %+v", days)
+	}
+
+	var nilTable *Table
+	if got := nilTable.Session(stats); got.USD != 0 || got.String() != "?" {
+		t.Errorf("nil table = %+v", got)
+	}
+	if got := (Estimate{USD: 0.004}).String(); got != "~<$0.01" {
+		t.Errorf("small estimate = %q", got)
+	}
+}
+
+func TestSessionEstimate_StableAcrossDiscoveries(t *testing.T) {
+	projectDir := filepath.Join(t.TempDir(), "-test-project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"s1.jsonl": `{"type":"user","sessionId":"s1","timestamp":"2026-03-01T10:00:00Z","uuid":"u1","message":{"content":"Investigate"}}
+{"type":"assistant","sessionId":"s1","timestamp":"2026-03-01T10:00:01Z","uuid":"a1","parentUuid":"u1","message":{"model":"claude-sonnet-4-5","usage":{"input_tokens":100,"output_tokens":10},"content":[{"type":"tool_use","id":"t1","name":"Task","input":{"prompt":"Find the bug"}}]}}
+{"type":"user","sessionId":"s1","timestamp":"2026-03-01T10:00:05Z","uuid":"r1","parentUuid":"a1","toolUseResult":{"status":"completed","agentId":"abc"},"message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"found it"}]}}
+`,
+		"agent-abc.jsonl": `{"type":"user","sessionId":"s1","agentId":"abc","isSidechain":true,"timestamp":"2026-03-01T10:00:02Z","uuid":"x1","message":{"content":"Find the bug"}}
+{"type":"assistant","sessionId":"s1","agentId":"abc","isSidechain":true,"timestamp":"2026-03-01T10:00:03Z","uuid":"x2","parentUuid":"x1","message":{"model":"claude-sonnet-4-5","usage":{"input_tokens":100,"output_tokens":10},"content":"found it"}}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(projectDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Cached metadata is reused on the second pass; the agent's usage must
+	// not be added to it again
+	table := Default()
+	var costs []Estimate
+	for range 3 {
+		projects, err := parser.DiscoverProjects(filepath.Dir(projectDir))
+		if err != nil || len(projects) != 1 || len(projects[0].Sessions) != 1 {
+			t.Fatalf("DiscoverProjects = %+v, %v", projects, err)
+		}
+		stats := projects[0].Sessions[0].Stats
+		if len(stats.ModelUsage) != 1 || stats.ModelUsage[0].InputTokens != 200 {
+			t.Fatalf("ModelUsage = %+v, want 200 input tokens", stats.ModelUsage)
+		}
+		costs = append(costs, table.Session(stats))
+	}
+	if costs[0] != costs[1] || costs[1] != costs[2] {
+		t.Errorf("costs = %+v, want the same on every discovery", costs)
+	}
+}
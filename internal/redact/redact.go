@@ -0,0 +1,313 @@
+// Package redact masks secrets and personal data in session text before
+// it leaves the machine: API keys, tokens, private keys, email addresses
+// and strings random enough to be credentials, plus any patterns set in
+// config.yaml. Masking is best effort; read what you publish.
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+// Pattern is a custom detector from config.yaml. When the expression has
+// a capture group only the first group is masked, so context such as a
+// key name can stay readable.
+type Pattern struct {
+	Name    string `mapstructure:"name"`
+	Pattern string `mapstructure:"pattern"`
+}
+
+// rule is one compiled detector; keep reports whether a match is masked
+type rule struct {
+	name string
+	re   *regexp.Regexp
+	keep func(string) bool
+}
+
+// builtins are the detectors every Redactor starts with, most specific
+// first so a value is reported under its own name
+var builtins = []rule{
+	{name: "private-key", re: regexp.MustCompile(`-----BEGIN[A-Z ]*PRIVATE KEY-----(?:[\s\S]*?-----END[A-Z ]*PRIVATE KEY-----|[\s\S]*)`)},
+	{name: "aws-access-key", re: regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`)},
+	{name: "aws-secret-key", re: regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key\W{0,4}([A-Za-z0-9/+=]{40})\b`)},
+	{name: "github-token", re: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{name: "api-key", re: regexp.MustCompile(`\bsk-(?:ant-)?[A-Za-z0-9_-]{20,}`)},
+	{name: "jwt", re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{name: "secret-value", re: regexp.MustCompile(`(?i)\b[A-Z0-9_]*(?:password|passwd|secret|api_?key|access_?token|auth_?token)["']?\s*[=:]\s*["']?([^\s"'$<>(){}\[\],;` + "`" + `]{8,})(?:[\s"',;` + "`" + `]|$)`), keep: literal},
+	{name: "email", re: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+	{name: "high-entropy", re: regexp.MustCompile(`[A-Za-z0-9+/=_-]{20,}`), keep: highEntropy},
+}
+
+// Names lists the built-in detectors
+func Names() []string {
+	names := make([]string, len(builtins))
+	for i, r := range builtins {
+		names[i] = r.name
+	}
+	return names
+}
+
+// Redactor replaces what its detectors match with [REDACTED:<name>] and
+// counts the values it masked. It is safe for concurrent use.
+type Redactor struct {
+	rules []rule
+
+	mu     sync.Mutex
+	counts Report
+}
+
+// New returns a Redactor with the custom patterns followed by the
+// built-in detectors, less the ones named in disable
+func New(custom []Pattern, disable []string) (*Redactor, error) {
+	known := make(map[string]bool)
+	for _, b := range builtins {
+		known[b.name] = true
+	}
+	off := make(map[string]bool)
+	for _, name := range disable {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown redaction detector %q (known: %s)", name, strings.Join(Names(), ", "))
+		}
+		off[name] = true
+	}
+
+	r := &Redactor{counts: make(Report)}
+	for _, p := range custom {
+		p.Name = strings.TrimSpace(p.Name)
+		if p.Name == "" {
+			return nil, fmt.Errorf("redaction pattern without a name")
+		}
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redaction pattern %s: %w", p.Name, err)
+		}
+		if re.MatchString("") {
+			return nil, fmt.Errorf("redaction pattern %s matches empty text", p.Name)
+		}
+		r.rules = append(r.rules, rule{name: p.Name, re: re})
+	}
+	for _, b := range builtins {
+		if !off[b.name] {
+			r.rules = append(r.rules, b)
+		}
+	}
+	return r, nil
+}
+
+// String masks s
+func (r *Redactor) String(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	found := make(Report)
+	for _, rule := range r.rules {
+		s = rule.apply(s, found)
+	}
+	if len(found) > 0 {
+		r.mu.Lock()
+		for name, n := range found {
+			r.counts[name] += n
+		}
+		r.mu.Unlock()
+	}
+	return s
+}
+
+// Session masks a parsed session in place: summaries, messages, tool
+// calls and agent transcripts
+func (r *Redactor) Session(s *parser.Session) {
+	if r != nil {
+		s.RewriteText(r.String)
+	}
+}
+
+// JSON masks the strings of a JSON document, such as a transcript line,
+// keeping its structure and leaving images alone. Data that isn't JSON is
+// masked as text.
+func (r *Redactor) JSON(data []byte) []byte {
+	if r == nil {
+		return data
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return []byte(r.String(string(data)))
+	}
+	out, err := json.Marshal(parser.RewriteValue(v, r.String))
+	if err != nil {
+		return data
+	}
+	return out
+}
+
+// Fingerprint identifies the Redactor's detectors: two Redactors with the
+// same fingerprint mask text the same way
+func (r *Redactor) Fingerprint() string {
+	h := sha256.New()
+	for _, rule := range r.rules {
+		fmt.Fprintf(h, "%s\x00%s\x00", rule.name, rule.re)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Report is what the Redactor masked so far
+func (r *Redactor) Report() Report {
+	out := make(Report)
+	if r == nil {
+		return out
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, n := range r.counts {
+		out[name] = n
+	}
+	return out
+}
+
+func (rule rule) apply(s string, found Report) string {
+	group := rule.re.NumSubexp() > 0
+	var b strings.Builder
+	last := 0
+	for _, m := range rule.re.FindAllStringSubmatchIndex(s, -1) {
+		start, end := m[0], m[1]
+		if group {
+			if m[2] < 0 {
+				continue
+			}
+			start, end = m[2], m[3]
+		}
+		if inMarker(s, start) {
+			continue
+		}
+		if rule.keep != nil && !rule.keep(s[start:end]) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(Marker(rule.name))
+		last = end
+		found[rule.name]++
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// inMarker reports whether s[start:] begins inside a marker an earlier
+// rule wrote
+func inMarker(s string, start int) bool {
+	open := strings.LastIndex(s[:min(len(s), start+len(markerPrefix))], markerPrefix)
+	if open < 0 {
+		return false
+	}
+	end := strings.IndexByte(s[open:], ']')
+	return end >= 0 && open+end >= start
+}
+
+// Marker is what a value masked by the named detector is replaced with
+func Marker(name string) string {
+	return markerPrefix + name + "]"
+}
+
+const markerPrefix = "[REDACTED:"
+
+var markerRe = regexp.MustCompile(`\[REDACTED:([A-Za-z0-9_.-]+)\]`)
+
+// Tally adds the markers in s to report, for text masked earlier
+func Tally(s string, report Report) {
+	for _, m := range markerRe.FindAllStringSubmatch(s, -1) {
+		report[m[1]]++
+	}
+}
+
+var fieldRe = regexp.MustCompile(`^[A-Za-z_]+(?:\.[A-Za-z_]+)+$`)
+
+// literal reports whether an assigned value is a literal rather than code
+// such as cfg.Password
+func literal(s string) bool {
+	return !fieldRe.MatchString(s)
+}
+
+var (
+	wordRe  = regexp.MustCompile(`[A-Z]?[a-z]{3,}|[A-Z]{3,}`)
+	digitRe = regexp.MustCompile(`[0-9]`)
+	lowerRe = regexp.MustCompile(`[a-z]`)
+	upperRe = regexp.MustCompile(`[A-Z]`)
+)
+
+// highEntropy reports whether s looks like a random token rather than an
+// identifier or a path: digits and both cases, close to the entropy of
+// random characters, and mostly not made of words
+func highEntropy(s string) bool {
+	if !digitRe.MatchString(s) || !lowerRe.MatchString(s) || !upperRe.MatchString(s) {
+		return false
+	}
+	if entropy(s) < min(4.3, 0.85*math.Log2(float64(len(s)))) {
+		return false
+	}
+	words := 0
+	for _, w := range wordRe.FindAllString(s, -1) {
+		words += len(w)
+	}
+	return words*2 < len(s)
+}
+
+// entropy is the Shannon entropy of s in bits per byte
+func entropy(s string) float64 {
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	n := float64(len(s))
+	h := 0.0
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / n
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
+
+// Report counts masked values by detector
+type Report map[string]int
+
+// Total is the number of values masked
+func (r Report) Total() int {
+	n := 0
+	for _, c := range r {
+		n += c
+	}
+	return n
+}
+
+// String lists the counts, most first, e.g. "3 email, 1 github-token"
+func (r Report) String() string {
+	if r.Total() == 0 {
+		return "nothing masked"
+	}
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if r[names[i]] != r[names[j]] {
+			return r[names[i]] > r[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%d %s", r[name], name)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,144 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+// Fake credentials are assembled at run time so secret scanners leave
+// this file alone
+var (
+	awsKey      = "AKIA" + "Z7QX3M2KP4RT6WVB"
+	awsSecret   = "wJalrXUtnFEMI/K7MDENG/bPxRfiCY" + "EXAMPLEKEY"
+	githubToken = "ghp_" + "a1B2c3D4e5F6g7H8i9J0k1L2m3N4o5P6q7R8"
+	jwt         = "eyJhbGciOiJIUzI1NiJ9" + ".eyJzdWIiOiIxMjM0NTY3ODkwIn0" + ".dozjgNryP4J3jVmNHl0w5N_XgL0n3I9PlFUP0THsR8U"
+	privateKey  = "-----BEGIN RSA " + "PRIVATE KEY-----\nMIIEpAIBAAKCAQEA7\n-----END RSA " + "PRIVATE KEY-----"
+	randomToken = "Xk9" + "pQ2vL7mZ4rT8wN1bY6cF3hJ5"
+)
+
+func TestBuiltins(t *testing.T) {
+	r, err := New(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"aws access key", "key " + awsKey + " here", "key [REDACTED:aws-access-key] here"},
+		{"aws secret key", "aws_secret_access_key = " + awsSecret, "aws_secret_access_key = [REDACTED:aws-secret-key]"},
+		{"github token", "export GH=" + githubToken, "export GH=[REDACTED:github-token]"},
+		{"jwt", "Bearer " + jwt, "Bearer [REDACTED:jwt]"},
+		{"private key", "key:\n" + privateKey + "\nnext", "key:\n[REDACTED:private-key]\nnext"},
+		{"cut private key", "-----BEGIN " + "PRIVATE KEY-----\nMIIEvQIBADANBg", "[REDACTED:private-key]"},
+		{"email", "mail jane.doe@example.com now", "mail [REDACTED:email] now"},
+		{"secret assignment", "DB_PASSWORD=hunter2hunter2", "DB_PASSWORD=[REDACTED:secret-value]"},
+		{"quoted secret", `"api_key": "k3y-v4lue-1234"`, `"api_key": "[REDACTED:secret-value]"`},
+		{"high entropy", "token " + randomToken, "token [REDACTED:high-entropy]"},
+		{"code is not a secret", "password := cfg.Password", "password := cfg.Password"},
+		{"identifiers are not secrets", "sqlite3VdbeMemExpandBlob and TestHandleAPIExport_Patch", "sqlite3VdbeMemExpandBlob and TestHandleAPIExport_Patch"},
+		{"paths and ids are not secrets", "/home/user/.claude/projects/-home-user-src/e3853612-4b1c-4f0a-9d2e-7c6a5b4d3e2f.jsonl", "/home/user/.claude/projects/-home-user-src/e3853612-4b1c-4f0a-9d2e-7c6a5b4d3e2f.jsonl"},
+		{"model ids are not secrets", "claude-sonnet-4-5-20250929", "claude-sonnet-4-5-20250929"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.String(tt.in); got != tt.want {
+				t.Errorf("String(%q) = %q; want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomAndDisabled(t *testing.T) {
+	r, err := New([]Pattern{
+		{Name: "ticket", Pattern: `ACME-\d+`},
+		{Name: "host", Pattern: `host=(\S+)`},
+	}, []string{"email"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := r.String("ACME-1234 on host=db.internal by jane@example.com")
+	want := "[REDACTED:ticket] on host=[REDACTED:host] by jane@example.com"
+	if got != want {
+		t.Errorf("String = %q; want %q", got, want)
+	}
+
+	if _, err := New(nil, []string{"emails"}); err == nil {
+		t.Error("expected error for unknown detector")
+	}
+	if _, err := New([]Pattern{{Name: "bad", Pattern: "("}}, nil); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+	if _, err := New([]Pattern{{Name: "empty", Pattern: "x*"}}, nil); err == nil {
+		t.Error("expected error for pattern matching empty text")
+	}
+	if _, err := New([]Pattern{{Pattern: "x"}}, nil); err == nil {
+		t.Error("expected error for pattern without a name")
+	}
+}
+
+func TestReport(t *testing.T) {
+	r, _ := New(nil, nil)
+	r.String("a@example.com b@example.com " + githubToken)
+	r.String("c@example.com")
+
+	report := r.Report()
+	if report["email"] != 3 || report["github-token"] != 1 || report.Total() != 4 {
+		t.Errorf("Report = %v", report)
+	}
+	if got, want := report.String(), "3 email, 1 github-token"; got != want {
+		t.Errorf("Report.String = %q; want %q", got, want)
+	}
+	if got := (Report{}).String(); got != "nothing masked" {
+		t.Errorf("empty Report.String = %q", got)
+	}
+
+	tally := make(Report)
+	Tally(r.String("x@example.com and "+awsKey), tally)
+	if tally["email"] != 1 || tally["aws-access-key"] != 1 {
+		t.Errorf("Tally = %v", tally)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	r, _ := New(nil, nil)
+	line := `{"type":"user","message":{"content":[{"type":"text","text":"mail bob@example.com"},` +
+		`{"type":"image","source":{"type":"base64","data":"` + randomToken + `"}}]}}`
+	got := string(r.JSON([]byte(line)))
+	if strings.Contains(got, "bob@example.com") || !strings.Contains(got, "[REDACTED:email]") {
+		t.Errorf("email not masked: %s", got)
+	}
+	if !strings.Contains(got, randomToken) {
+		t.Errorf("image data was rewritten: %s", got)
+	}
+}
+
+func TestSession(t *testing.T) {
+	msg := &parser.Message{
+		Content: []parser.ContentBlock{
+			{Type: "tool_use", ToolName: "Bash", ToolInput: map[string]any{"command": "curl -H 'Authorization: token " + githubToken + "'"}},
+			{Type: "tool_result", ToolResult: []any{map[string]any{"type": "text", "text": "owner: ops@example.com"}}},
+		},
+	}
+	s := &parser.Session{Summary: "Rotate " + awsKey, RootMessages: []*parser.Message{msg}}
+
+	r, _ := New(nil, nil)
+	r.Session(s)
+
+	if s.Summary != "Rotate [REDACTED:aws-access-key]" {
+		t.Errorf("Summary = %q", s.Summary)
+	}
+	if cmd := msg.Content[0].ToolInput.(map[string]any)["command"].(string); strings.Contains(cmd, githubToken) {
+		t.Errorf("tool input not masked: %q", cmd)
+	}
+	text := msg.Content[1].ToolResult.([]any)[0].(map[string]any)["text"].(string)
+	if text != "owner: [REDACTED:email]" {
+		t.Errorf("tool result = %q", text)
+	}
+	if got := r.Report().Total(); got != 3 {
+		t.Errorf("Report total = %d; want 3", got)
+	}
+}
@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/thevibeworks/ccx/internal/db"
+	"github.com/thevibeworks/ccx/internal/files"
 	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/pricing"
+	"github.com/thevibeworks/ccx/internal/redact"
 )
 
 type ExportOptions struct {
@@ -12,10 +16,41 @@ type ExportOptions struct {
 	Theme           string
 	IncludeThinking bool
 	IncludeAgents   bool
-	TemplatePath    string
+	TemplatePath    string           // Go template to render with instead of the built-in format
+	TemplatesDir    string           // Holds <format>.tmpl overrides and named templates; "" for none
+	Annotations     []db.Annotation  // Rendered as footnotes
+	Pricing         *pricing.Table   // Rates for the cost estimate in the header; nil for none
+	Redactor        *redact.Redactor // Masks secrets in the session and annotations first; nil for none
+	PatchAuthor     string           // "Name <email>" signing patch commits; "" for files.DefaultAuthor
+
+	notes *Footnotes
 }
 
+// Export renders session in opts.Format. With a Redactor, the session is
+// masked in place before rendering. A template from opts.TemplatePath or
+// opts.TemplatesDir replaces the built-in renderer, and then any format
+// name goes.
 func Export(session *parser.Session, opts ExportOptions) (string, error) {
+	if opts.Redactor != nil {
+		opts.Redactor.Session(session)
+		annotations := make([]db.Annotation, len(opts.Annotations))
+		for i, a := range opts.Annotations {
+			a.Quote = opts.Redactor.String(a.Quote)
+			a.Note = opts.Redactor.String(a.Note)
+			annotations[i] = a
+		}
+		opts.Annotations = annotations
+	}
+	opts.notes = NewFootnotes(opts.Annotations)
+	if strings.EqualFold(opts.Format, "patch") {
+		if opts.TemplatePath != "" {
+			return "", fmt.Errorf("templates can't render patches")
+		}
+	} else if path, err := templateFor(opts); err != nil {
+		return "", err
+	} else if path != "" {
+		return exportTemplate(session, path, opts)
+	}
 	switch strings.ToLower(opts.Format) {
 	case "html":
 		return exportHTML(session, opts)
@@ -23,7 +58,26 @@ func Export(session *parser.Session, opts ExportOptions) (string, error) {
 		return exportMarkdown(session, opts)
 	case "org":
 		return exportOrg(session, opts)
+	case "patch":
+		patch := files.Patch(session, "", opts.PatchAuthor)
+		if patch == "" {
+			return "", fmt.Errorf("session changed no files")
+		}
+		return patch, nil
 	default:
 		return "", fmt.Errorf("unsupported format: %s", opts.Format)
 	}
 }
+
+// costEstimate is the header's cost line, or "" when nothing was priced
+func costEstimate(session *parser.Session, opts ExportOptions) string {
+	cost := opts.Pricing.Session(session.Stats)
+	if cost.USD == 0 {
+		return ""
+	}
+	s := cost.String() + " (estimate"
+	if cost.Unpriced > 0 {
+		s += fmt.Sprintf(", %d tokens without a rate not included", cost.Unpriced)
+	}
+	return s + ")"
+}
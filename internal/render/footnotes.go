@@ -0,0 +1,171 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/thevibeworks/ccx/internal/db"
+)
+
+// Footnotes numbers annotations in the order exporters reference them.
+// Highlights are referenced right after the quoted text; other notes
+// after the message they belong to.
+type Footnotes struct {
+	pending map[string][]db.Annotation // Message UUID -> notes not yet referenced
+	placed  []db.Annotation            // Footnote n is placed[n-1]
+}
+
+// NewFootnotes returns nil when there is nothing to annotate, which all
+// Footnotes methods accept
+func NewFootnotes(annotations []db.Annotation) *Footnotes {
+	if len(annotations) == 0 {
+		return nil
+	}
+	f := &Footnotes{pending: make(map[string][]db.Annotation)}
+	for _, a := range annotations {
+		f.pending[a.MessageUUID] = append(f.pending[a.MessageUUID], a)
+	}
+	return f
+}
+
+// Highlight references the notes highlighting text in a message's text
+// block. escape converts a quote into the form text is in; mark returns
+// what replaces the first occurrence of the escaped quote.
+func (f *Footnotes) Highlight(uuid string, block int, text string, escape func(string) string, mark func(quote string, n int) string) string {
+	if f == nil {
+		return text
+	}
+	var rest []db.Annotation
+	for _, a := range f.pending[uuid] {
+		quote := a.Quote
+		if escape != nil {
+			quote = escape(quote)
+		}
+		if a.Block != block || !a.HasHighlight() || quote == "" || !strings.Contains(text, quote) {
+			rest = append(rest, a)
+			continue
+		}
+		f.placed = append(f.placed, a)
+		text = strings.Replace(text, quote, mark(quote, len(f.placed)), 1)
+	}
+	f.pending[uuid] = rest
+	return text
+}
+
+// Remaining references every note of a message that Highlight did not
+// place and returns their footnote numbers
+func (f *Footnotes) Remaining(uuid string) []int {
+	if f == nil {
+		return nil
+	}
+	var refs []int
+	for _, a := range f.pending[uuid] {
+		f.placed = append(f.placed, a)
+		refs = append(refs, len(f.placed))
+	}
+	delete(f.pending, uuid)
+	return refs
+}
+
+// Empty reports whether no footnote has been referenced
+func (f *Footnotes) Empty() bool {
+	return f == nil || len(f.placed) == 0
+}
+
+func noteByline(a db.Annotation) string {
+	by := a.CreatedAt.Format("2006-01-02 15:04")
+	if a.Author != "" {
+		by = a.Author + ", " + by
+	}
+	return by
+}
+
+// MarkdownRefs formats footnote references as [^n]
+func MarkdownRefs(refs []int) string {
+	var b strings.Builder
+	for _, n := range refs {
+		fmt.Fprintf(&b, "[^%d]", n)
+	}
+	return b.String()
+}
+
+// WriteMarkdown appends the footnote definitions
+func (f *Footnotes) WriteMarkdown(b *strings.Builder) {
+	if f.Empty() {
+		return
+	}
+	b.WriteString("## Notes\n\n")
+	for i, a := range f.placed {
+		fmt.Fprintf(b, "[^%d]: ", i+1)
+		if a.Quote != "" {
+			fmt.Fprintf(b, "> %s\n    ", strings.ReplaceAll(a.Quote, "\n", " "))
+		}
+		if a.Note != "" {
+			b.WriteString(strings.ReplaceAll(a.Note, "\n", "\n    "))
+			b.WriteString("\n    ")
+		}
+		fmt.Fprintf(b, "*%s*\n\n", noteByline(a))
+	}
+}
+
+// OrgRefs formats footnote references as [fn:n]
+func OrgRefs(refs []int) string {
+	var b strings.Builder
+	for _, n := range refs {
+		fmt.Fprintf(&b, "[fn:%d]", n)
+	}
+	return b.String()
+}
+
+// WriteOrg appends a Footnotes section with the definitions
+func (f *Footnotes) WriteOrg(b *strings.Builder) {
+	if f.Empty() {
+		return
+	}
+	b.WriteString("* Footnotes\n\n")
+	for i, a := range f.placed {
+		fmt.Fprintf(b, "[fn:%d] ", i+1)
+		if a.Quote != "" {
+			fmt.Fprintf(b, "=%s= ", strings.ReplaceAll(a.Quote, "\n", " "))
+		}
+		if a.Note != "" {
+			b.WriteString(a.Note)
+			b.WriteString(" ")
+		}
+		fmt.Fprintf(b, "/%s/\n\n", noteByline(a))
+	}
+}
+
+// HTMLMark wraps a highlighted quote and links it to its footnote
+func HTMLMark(quote string, n int) string {
+	return fmt.Sprintf(`<mark class="annotation-mark">%s</mark>%s`, quote, HTMLRefs([]int{n}))
+}
+
+// HTMLRefs formats footnote references as superscript links
+func HTMLRefs(refs []int) string {
+	var b strings.Builder
+	for _, n := range refs {
+		fmt.Fprintf(&b, `<sup class="footnote-ref"><a href="#fn-%d" id="fnref-%d">%d</a></sup>`, n, n, n)
+	}
+	return b.String()
+}
+
+// WriteHTML appends the footnote list
+func (f *Footnotes) WriteHTML(b *strings.Builder) {
+	if f.Empty() {
+		return
+	}
+	b.WriteString("<section class=\"footnotes\">\n<h2>Notes</h2>\n<ol>\n")
+	for i, a := range f.placed {
+		fmt.Fprintf(b, "<li id=\"fn-%d\">", i+1)
+		if a.Quote != "" {
+			fmt.Fprintf(b, "<blockquote>%s</blockquote>", html.EscapeString(a.Quote))
+		}
+		if a.Note != "" {
+			fmt.Fprintf(b, "<p>%s</p>", html.EscapeString(a.Note))
+		}
+		fmt.Fprintf(b, "<p class=\"footnote-by\">%s <a href=\"#fnref-%d\">↩</a></p></li>\n", html.EscapeString(noteByline(a)), i+1)
+	}
+	b.WriteString("</ol>\n</section>\n")
+}
@@ -0,0 +1,147 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FormatTokens abbreviates a token count: 950, 12.3k, 1.2M
+func FormatTokens(n int) string {
+	if n >= 1000000 {
+		return fmt.Sprintf("%.1fM", float64(n)/1000000)
+	}
+	if n >= 1000 {
+		k := float64(n) / 1000
+		if k >= 999.95 {
+			return fmt.Sprintf("%.1fM", float64(n)/1000000)
+		}
+		return fmt.Sprintf("%.1fk", k)
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+// FormatDuration formats seconds as 45s, 3m 20s or 1h 5m; "-" for none
+func FormatDuration(seconds float64) string {
+	if seconds <= 0 {
+		return "-"
+	}
+	d := time.Duration(seconds * float64(time.Second))
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	if d < time.Hour {
+		m := int(d.Minutes())
+		s := int(d.Seconds()) % 60
+		if s > 0 {
+			return fmt.Sprintf("%dm %ds", m, s)
+		}
+		return fmt.Sprintf("%dm", m)
+	}
+	h := int(d.Hours())
+	m := int(d.Minutes()) % 60
+	return fmt.Sprintf("%dh %dm", h, m)
+}
+
+// ToolPreview is a one-line summary of a tool call's input: the file,
+// pattern, command or query it acts on
+func ToolPreview(toolName string, input any) string {
+	m, ok := input.(map[string]any)
+	if !ok {
+		return ""
+	}
+
+	switch toolName {
+	case "Read":
+		if fp, ok := m["file_path"].(string); ok {
+			return fp
+		}
+	case "Write":
+		if fp, ok := m["file_path"].(string); ok {
+			return fp
+		}
+	case "Edit":
+		if fp, ok := m["file_path"].(string); ok {
+			return fp
+		}
+	case "Grep":
+		if p, ok := m["pattern"].(string); ok {
+			return "/" + p + "/"
+		}
+	case "Glob":
+		if p, ok := m["pattern"].(string); ok {
+			return p
+		}
+	case "Bash":
+		if cmd, ok := m["command"].(string); ok {
+			if len(cmd) > 50 {
+				return "$ " + cmd[:50] + "..."
+			}
+			return "$ " + cmd
+		}
+	case "Task":
+		var parts []string
+		if agent, ok := m["subagent_type"].(string); ok && agent != "" {
+			parts = append(parts, "["+agent+"]")
+		}
+		if desc, ok := m["description"].(string); ok {
+			parts = append(parts, desc)
+		}
+		if len(parts) > 0 {
+			return strings.Join(parts, " ")
+		}
+	case "Skill":
+		if skill, ok := m["skill"].(string); ok {
+			return "/" + skill
+		}
+	case "WebSearch":
+		if q, ok := m["query"].(string); ok {
+			if len(q) > 50 {
+				return q[:50] + "..."
+			}
+			return q
+		}
+	case "WebFetch":
+		if url, ok := m["url"].(string); ok {
+			return url
+		}
+	case "AskUserQuestion":
+		if questions, ok := m["questions"].([]any); ok && len(questions) > 0 {
+			if q, ok := questions[0].(map[string]any); ok {
+				if header, ok := q["header"].(string); ok {
+					return header
+				}
+			}
+		}
+	case "LSP":
+		if op, ok := m["operation"].(string); ok {
+			if fp, ok := m["filePath"].(string); ok {
+				// Just show filename, not full path
+				parts := strings.Split(fp, "/")
+				return op + " " + parts[len(parts)-1]
+			}
+			return op
+		}
+	case "TaskOutput":
+		if id, ok := m["task_id"].(string); ok {
+			return id
+		}
+	case "KillShell":
+		if id, ok := m["shell_id"].(string); ok {
+			return id
+		}
+	}
+
+	// Fallback: show first key=value (sorted for determinism)
+	if len(m) > 0 {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		k := keys[0]
+		return fmt.Sprintf("%s=%v", k, m[k])
+	}
+	return ""
+}
@@ -36,6 +36,9 @@ func exportHTML(session *parser.Session, opts ExportOptions) (string, error) {
 	b.WriteString(fmt.Sprintf("<p class=\"meta\">Started: %s | Messages: %d | Tools: %d</p>\n",
 		session.StartTime.Format("2006-01-02 15:04"),
 		session.Stats.MessageCount, session.Stats.ToolCalls))
+	if cost := costEstimate(session, opts); cost != "" {
+		b.WriteString(fmt.Sprintf("<p class=\"meta\">Cost: %s</p>\n", html.EscapeString(cost)))
+	}
 	b.WriteString("</header>\n")
 
 	b.WriteString("<div class=\"messages\">\n")
@@ -43,6 +46,7 @@ func exportHTML(session *parser.Session, opts ExportOptions) (string, error) {
 		renderHTMLMessage(&b, msg, 0, opts)
 	}
 	b.WriteString("</div>\n")
+	opts.notes.WriteHTML(&b)
 
 	b.WriteString("</div>\n")
 
@@ -82,9 +86,18 @@ func renderHTMLMessage(b *strings.Builder, msg *parser.Message, depth int, opts
 		msg.Type, strings.ToUpper(msg.Type), msg.Timestamp.Format("15:04:05")))
 	b.WriteString("<div class=\"message-content\">\n")
 
+	textIndex := 0
 	for _, block := range msg.Content {
+		if block.Type == "text" && block.Text != "" {
+			renderHTMLText(b, msg.UUID, textIndex, block.Text, opts)
+			textIndex++
+			continue
+		}
 		renderHTMLBlock(b, block, opts)
 	}
+	if refs := opts.notes.Remaining(msg.UUID); len(refs) > 0 {
+		b.WriteString(fmt.Sprintf("<p class=\"footnote-refs\">Notes: %s</p>\n", HTMLRefs(refs)))
+	}
 
 	b.WriteString("</div>\n</div>\n")
 
@@ -97,13 +110,7 @@ func renderHTMLBlock(b *strings.Builder, block parser.ContentBlock, opts ExportO
 	switch block.Type {
 	case "text":
 		if block.Text != "" {
-			paragraphs := strings.Split(block.Text, "\n\n")
-			for _, p := range paragraphs {
-				p = strings.TrimSpace(p)
-				if p != "" {
-					b.WriteString(fmt.Sprintf("<p>%s</p>\n", html.EscapeString(p)))
-				}
-			}
+			renderHTMLText(b, "", 0, block.Text, opts)
 		}
 
 	case "thinking":
@@ -122,6 +129,14 @@ func renderHTMLBlock(b *strings.Builder, block parser.ContentBlock, opts ExportO
 			b.WriteString(html.EscapeString(formatToolInput(block.ToolInput)))
 			b.WriteString("</pre>\n")
 		}
+		if block.Sidechain != nil && opts.IncludeAgents {
+			b.WriteString(fmt.Sprintf("<div class=\"sidechain-transcript\">\n<div class=\"tool-header\">Agent %s</div>\n",
+				html.EscapeString(block.Sidechain.AgentID)))
+			for _, msg := range block.Sidechain.RootMessages {
+				renderHTMLMessage(b, msg, 1, opts)
+			}
+			b.WriteString("</div>\n")
+		}
 		b.WriteString("</div>\n")
 
 	case "tool_result":
@@ -145,6 +160,22 @@ func renderHTMLBlock(b *strings.Builder, block parser.ContentBlock, opts ExportO
 	}
 }
 
+// renderHTMLText writes a text block as paragraphs, marking highlighted
+// quotes of the message's annotations
+func renderHTMLText(b *strings.Builder, uuid string, index int, text string, opts ExportOptions) {
+	for _, p := range strings.Split(text, "\n\n") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		escaped := html.EscapeString(p)
+		if uuid != "" {
+			escaped = opts.notes.Highlight(uuid, index, escaped, html.EscapeString, HTMLMark)
+		}
+		b.WriteString(fmt.Sprintf("<p>%s</p>\n", escaped))
+	}
+}
+
 func formatToolInput(input any) string {
 	switch v := input.(type) {
 	case map[string]any:
@@ -221,6 +252,7 @@ header h1 { font-size: 1.5rem; margin-bottom: 8px; }
 .message.assistant { background: %s; border-color: #4caf50; }
 .message.compacted { background: %s; border-color: #ff9800; }
 .message.sidechain { margin-left: 20px; opacity: 0.9; }
+.sidechain-transcript { margin-top: 8px; border-left: 2px solid #86c; padding-left: 8px; }
 .message-header {
     font-weight: 600;
     font-size: 0.85rem;
@@ -268,7 +300,16 @@ header h1 { font-size: 1.5rem; margin-bottom: 8px; }
 .inline-image { max-width: 100%%; border-radius: 8px; margin: 12px 0; }
 details[open] summary { margin-bottom: 8px; }
 pre { font-family: 'SF Mono', Monaco, Consolas, monospace; }
-`, bg, text, accent, dim, cardBg, border, userBg, accent, assistBg, cardBg, accent, dim, toolBg, dim)
+.annotation-mark { background: rgba(255,213,79,0.45); color: inherit; border-radius: 2px; }
+.footnote-ref a { color: %s; text-decoration: none; font-size: 0.75rem; padding-left: 1px; }
+.footnote-refs { color: %s; font-size: 0.85rem; }
+.footnotes { margin-top: 32px; padding-top: 16px; border-top: 2px solid %s; }
+.footnotes h2 { font-size: 1.1rem; margin-bottom: 12px; }
+.footnotes li { margin: 0 0 12px 20px; }
+.footnotes blockquote { border-left: 3px solid #ffd54f; padding-left: 8px; color: %s; }
+.footnote-by { color: %s; font-size: 0.8rem; }
+`, bg, text, accent, dim, cardBg, border, userBg, accent, assistBg, cardBg, accent, dim, toolBg, dim,
+		accent, dim, accent, dim, dim)
 }
 
 func htmlJS() string {
@@ -21,11 +21,15 @@ func exportMarkdown(session *parser.Session, opts ExportOptions) (string, error)
 	b.WriteString(fmt.Sprintf("**Started:** %s\n", session.StartTime.Format("2006-01-02 15:04")))
 	b.WriteString(fmt.Sprintf("**Messages:** %d | **Tools:** %d\n\n",
 		session.Stats.MessageCount, session.Stats.ToolCalls))
+	if cost := costEstimate(session, opts); cost != "" {
+		b.WriteString(fmt.Sprintf("**Cost:** %s\n\n", cost))
+	}
 	b.WriteString("---\n\n")
 
 	for _, msg := range session.RootMessages {
 		renderMarkdownMessage(&b, msg, opts)
 	}
+	opts.notes.WriteMarkdown(&b)
 
 	return b.String(), nil
 }
@@ -55,9 +59,19 @@ func renderMarkdownMessage(b *strings.Builder, msg *parser.Message, opts ExportO
 		b.WriteString(fmt.Sprintf("## Assistant (%s)\n\n", ts))
 	}
 
+	textIndex := 0
 	for _, block := range msg.Content {
+		if block.Type == "text" && block.Text != "" {
+			block.Text = opts.notes.Highlight(msg.UUID, textIndex, block.Text, nil, func(quote string, n int) string {
+				return quote + MarkdownRefs([]int{n})
+			})
+			textIndex++
+		}
 		renderMarkdownBlock(b, block, opts)
 	}
+	if refs := opts.notes.Remaining(msg.UUID); len(refs) > 0 {
+		b.WriteString("Notes: " + MarkdownRefs(refs) + "\n\n")
+	}
 
 	b.WriteString("---\n\n")
 
@@ -93,6 +107,12 @@ func renderMarkdownBlock(b *strings.Builder, block parser.ContentBlock, opts Exp
 				b.WriteString(fmt.Sprintf("`%s`\n\n", input))
 			}
 		}
+		if block.Sidechain != nil && opts.IncludeAgents {
+			b.WriteString(fmt.Sprintf("#### Agent %s\n\n", block.Sidechain.AgentID))
+			for _, msg := range block.Sidechain.RootMessages {
+				renderMarkdownMessage(b, msg, opts)
+			}
+		}
 
 	case "tool_result":
 		label := "### Result"
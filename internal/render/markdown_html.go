@@ -0,0 +1,171 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// MarkdownHTML converts the Markdown Claude writes to HTML: fenced code
+// blocks, tables, inline code and bold; other lines become paragraphs
+func MarkdownHTML(text string) string {
+	var b strings.Builder
+	lines := strings.Split(text, "\n")
+	inCodeBlock := false
+	codeBlockLang := ""
+	var codeLines []string
+	inTable := false
+	var tableRows []string
+
+	for i, line := range lines {
+		if strings.HasPrefix(line, "```") {
+			if inCodeBlock {
+				b.WriteString(fmt.Sprintf(`<pre class="code-block"><code class="lang-%s">%s</code></pre>`,
+					html.EscapeString(codeBlockLang), html.EscapeString(strings.Join(codeLines, "\n"))))
+				codeLines = nil
+				inCodeBlock = false
+			} else {
+				inCodeBlock = true
+				codeBlockLang = strings.TrimPrefix(line, "```")
+				if codeBlockLang == "" {
+					codeBlockLang = "text"
+				}
+			}
+			continue
+		}
+
+		if inCodeBlock {
+			codeLines = append(codeLines, line)
+			continue
+		}
+
+		// Table detection: line starts with | and contains |
+		isTableLine := strings.HasPrefix(strings.TrimSpace(line), "|") && strings.Contains(line, "|")
+		isSeparatorLine := isTableLine && strings.Contains(line, "---")
+
+		if isTableLine {
+			if !inTable {
+				inTable = true
+				tableRows = nil
+			}
+			if !isSeparatorLine {
+				tableRows = append(tableRows, line)
+			}
+			// Check if next line is not a table line
+			if i+1 >= len(lines) || !strings.HasPrefix(strings.TrimSpace(lines[i+1]), "|") {
+				// End of table, render it
+				b.WriteString(renderTable(tableRows))
+				inTable = false
+				tableRows = nil
+			}
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			b.WriteString(`<br>`)
+			continue
+		}
+
+		// Process inline formatting
+		escaped := html.EscapeString(line)
+		escaped = processInlineCode(escaped)
+		escaped = processBold(escaped)
+
+		b.WriteString(`<p>` + escaped + `</p>`)
+	}
+
+	if inCodeBlock {
+		b.WriteString(fmt.Sprintf(`<pre class="code-block"><code class="lang-%s">%s</code></pre>`,
+			html.EscapeString(codeBlockLang), html.EscapeString(strings.Join(codeLines, "\n"))))
+	}
+
+	return b.String()
+}
+
+// renderTable converts markdown table rows to HTML table
+func renderTable(rows []string) string {
+	if len(rows) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(`<table class="md-table">`)
+
+	for i, row := range rows {
+		cells := parseTableRow(row)
+		if i == 0 {
+			b.WriteString(`<thead><tr>`)
+			for _, cell := range cells {
+				escaped := html.EscapeString(strings.TrimSpace(cell))
+				escaped = processInlineCode(escaped)
+				escaped = processBold(escaped)
+				b.WriteString(`<th>` + escaped + `</th>`)
+			}
+			b.WriteString(`</tr></thead><tbody>`)
+		} else {
+			b.WriteString(`<tr>`)
+			for _, cell := range cells {
+				escaped := html.EscapeString(strings.TrimSpace(cell))
+				escaped = processInlineCode(escaped)
+				escaped = processBold(escaped)
+				b.WriteString(`<td>` + escaped + `</td>`)
+			}
+			b.WriteString(`</tr>`)
+		}
+	}
+	b.WriteString(`</tbody></table>`)
+	return b.String()
+}
+
+// parseTableRow splits a markdown table row into cells
+func parseTableRow(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.Trim(row, "|")
+	return strings.Split(row, "|")
+}
+
+// processInlineCode converts `code` to <code>code</code>
+func processInlineCode(s string) string {
+	var result strings.Builder
+	inCode := false
+	for i := 0; i < len(s); i++ {
+		if s[i] == '`' {
+			if inCode {
+				result.WriteString("</code>")
+			} else {
+				result.WriteString("<code>")
+			}
+			inCode = !inCode
+		} else {
+			result.WriteByte(s[i])
+		}
+	}
+	// Close unclosed code tag
+	if inCode {
+		result.WriteString("</code>")
+	}
+	return result.String()
+}
+
+// processBold converts **text** to <strong>text</strong>
+func processBold(s string) string {
+	var result strings.Builder
+	inBold := false
+	for i := 0; i < len(s); i++ {
+		if i+1 < len(s) && s[i] == '*' && s[i+1] == '*' {
+			if inBold {
+				result.WriteString("</strong>")
+			} else {
+				result.WriteString("<strong>")
+			}
+			inBold = !inBold
+			i++ // skip second *
+		} else {
+			result.WriteByte(s[i])
+		}
+	}
+	// Close unclosed bold tag
+	if inBold {
+		result.WriteString("</strong>")
+	}
+	return result.String()
+}
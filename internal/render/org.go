@@ -28,6 +28,9 @@ func exportOrg(session *parser.Session, opts ExportOptions) (string, error) {
 	if session.Stats.Continuations > 0 {
 		b.WriteString(fmt.Sprintf("- Continuations: %d\n", session.Stats.Continuations))
 	}
+	if cost := costEstimate(session, opts); cost != "" {
+		b.WriteString(fmt.Sprintf("- Cost: %s\n", cost))
+	}
 	b.WriteString("\n")
 
 	b.WriteString("* Conversation\n\n")
@@ -35,6 +38,7 @@ func exportOrg(session *parser.Session, opts ExportOptions) (string, error) {
 	for _, msg := range session.RootMessages {
 		renderOrgMessage(&b, msg, 2, opts)
 	}
+	opts.notes.WriteOrg(&b)
 
 	return b.String(), nil
 }
@@ -65,9 +69,19 @@ func renderOrgMessage(b *strings.Builder, msg *parser.Message, level int, opts E
 		b.WriteString(fmt.Sprintf("%s ASSISTANT %s\n", stars, ts))
 	}
 
+	textIndex := 0
 	for _, block := range msg.Content {
+		if block.Type == "text" && block.Text != "" {
+			block.Text = opts.notes.Highlight(msg.UUID, textIndex, block.Text, nil, func(quote string, n int) string {
+				return quote + OrgRefs([]int{n})
+			})
+			textIndex++
+		}
 		renderOrgBlock(b, block, level, opts)
 	}
+	if refs := opts.notes.Remaining(msg.UUID); len(refs) > 0 {
+		b.WriteString("Notes: " + OrgRefs(refs) + "\n")
+	}
 
 	b.WriteString("\n")
 
@@ -103,6 +117,11 @@ func renderOrgBlock(b *strings.Builder, block parser.ContentBlock, level int, op
 			b.WriteString(input)
 			b.WriteString("\n#+END_SRC\n\n")
 		}
+		if block.Sidechain != nil && opts.IncludeAgents {
+			for _, msg := range block.Sidechain.RootMessages {
+				renderOrgMessage(b, msg, level+2, opts)
+			}
+		}
 
 	case "tool_result":
 		label := "RESULT"
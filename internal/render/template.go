@@ -0,0 +1,169 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/thevibeworks/ccx/internal/db"
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+// TemplateData is what export templates are executed with. html templates
+// use html/template and escape what they print; every other format uses
+// text/template.
+type TemplateData struct {
+	Session         *parser.Session
+	Project         string            // Display name of the session's project
+	Messages        []*parser.Message // Depth first; with IncludeAgents, agent transcripts follow the message that spawned them
+	Annotations     []db.Annotation
+	Cost            string // Cost estimate, "" when nothing was priced
+	Format          string
+	Theme           string
+	IncludeThinking bool
+	IncludeAgents   bool
+	Generated       time.Time
+}
+
+// templateFuncs are the helpers export templates can call besides the
+// text/template builtins. markdown returns HTML, which html templates
+// print unescaped.
+func templateFuncs(data *TemplateData, asHTML bool) map[string]any {
+	return map[string]any{
+		"markdown": func(text string) any {
+			if asHTML {
+				return htmltemplate.HTML(MarkdownHTML(text))
+			}
+			return MarkdownHTML(text)
+		},
+		"text":        messageText,
+		"toolPreview": ToolPreview,
+		"toolInput":   formatToolInput,
+		"toolResult":  formatToolResult,
+		"tokens":      FormatTokens,
+		"duration":    FormatDuration,
+		"time":        func(t time.Time) string { return t.Format("15:04:05") },
+		"date":        func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+		"formatTime":  func(layout string, t time.Time) string { return t.Format(layout) },
+		"shortID":     func(id string) string { return truncateID(id, 8) },
+		"truncate": func(n int, s string) string {
+			if r := []rune(s); len(r) > n {
+				return string(r[:n]) + "..."
+			}
+			return s
+		},
+		"json": func(v any) (string, error) {
+			out, err := json.MarshalIndent(v, "", "  ")
+			return string(out), err
+		},
+		"notes": func(uuid string) []db.Annotation {
+			var notes []db.Annotation
+			for _, a := range data.Annotations {
+				if a.MessageUUID == uuid {
+					notes = append(notes, a)
+				}
+			}
+			return notes
+		},
+	}
+}
+
+// messageText joins the text blocks of a message
+func messageText(msg *parser.Message) string {
+	var parts []string
+	for _, block := range msg.Content {
+		if block.Type == "text" && block.Text != "" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// templateFor finds the template that renders opts.Format: opts.TemplatePath,
+// also looked up by name in opts.TemplatesDir, or else the <format>.tmpl
+// override in opts.TemplatesDir. "" means the built-in renderer.
+func templateFor(opts ExportOptions) (string, error) {
+	if path := opts.TemplatePath; path != "" {
+		if _, err := os.Stat(path); err == nil || opts.TemplatesDir == "" || filepath.IsAbs(path) {
+			return path, nil
+		}
+		for _, name := range []string{path, path + ".tmpl"} {
+			candidate := filepath.Join(opts.TemplatesDir, name)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate, nil
+			}
+		}
+		return "", fmt.Errorf("template not found: %s", path)
+	}
+	if opts.TemplatesDir == "" {
+		return "", nil
+	}
+	format := strings.ToLower(opts.Format)
+	if format == "markdown" {
+		format = "md"
+	}
+	override := filepath.Join(opts.TemplatesDir, format+".tmpl")
+	if _, err := os.Stat(override); err != nil {
+		return "", nil
+	}
+	return override, nil
+}
+
+// exportTemplate renders session with the template at path
+func exportTemplate(session *parser.Session, path string, opts ExportOptions) (string, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	data := &TemplateData{
+		Session:         session,
+		Project:         parser.GetProjectDisplayName(filepath.Base(filepath.Dir(session.FilePath))),
+		Annotations:     opts.Annotations,
+		Cost:            costEstimate(session, opts),
+		Format:          opts.Format,
+		Theme:           opts.Theme,
+		IncludeThinking: opts.IncludeThinking,
+		IncludeAgents:   opts.IncludeAgents,
+		Generated:       time.Now(),
+	}
+	var walk func(msgs []*parser.Message)
+	walk = func(msgs []*parser.Message) {
+		for _, msg := range msgs {
+			if msg.IsSidechain && !opts.IncludeAgents {
+				continue
+			}
+			data.Messages = append(data.Messages, msg)
+			for _, block := range msg.Content {
+				if block.Sidechain != nil {
+					walk(block.Sidechain.RootMessages)
+				}
+			}
+			walk(msg.Children)
+		}
+	}
+	walk(session.RootMessages)
+
+	var b bytes.Buffer
+	name := filepath.Base(path)
+	if strings.EqualFold(opts.Format, "html") {
+		t, err := htmltemplate.New(name).Funcs(templateFuncs(data, true)).Parse(string(src))
+		if err != nil {
+			return "", err
+		}
+		err = t.Execute(&b, data)
+		return b.String(), err
+	}
+	t, err := texttemplate.New(name).Funcs(templateFuncs(data, false)).Parse(string(src))
+	if err != nil {
+		return "", err
+	}
+	err = t.Execute(&b, data)
+	return b.String(), err
+}
@@ -0,0 +1,124 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/testutil"
+)
+
+var templateSession = `{"type":"user","timestamp":"2026-01-01T10:00:00Z","uuid":"u1","sessionId":"s1","message":{"content":"Read the **config**"}}
+{"type":"assistant","timestamp":"2026-01-01T10:00:05Z","uuid":"a1","parentUuid":"u1","message":{"model":"claude-sonnet-4-5","content":[{"type":"text","text":"Reading <it>."},{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/repo/config.yaml"}}],"usage":{"input_tokens":1200,"output_tokens":300}}}
+`
+
+func writeTemplate(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExport_Template(t *testing.T) {
+	session := testutil.ParseSession(t, templateSession)
+	path := writeTemplate(t, t.TempDir(), "tools.tmpl",
+		`{{range .Messages}}{{range .Content}}{{if eq .Type "tool_use"}}{{.ToolName}},{{toolPreview .ToolName .ToolInput}}
+{{end}}{{end}}{{end}}{{len .Messages}} messages, {{tokens 1200}}, {{shortID "0123456789"}}`)
+
+	out, err := Export(session, ExportOptions{Format: "csv", TemplatePath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Read,/repo/config.yaml\n2 messages, 1.2k, 01234567"; out != want {
+		t.Errorf("Export = %q; want %q", out, want)
+	}
+}
+
+func TestExport_HTMLTemplate(t *testing.T) {
+	session := testutil.ParseSession(t, templateSession)
+	path := writeTemplate(t, t.TempDir(), "report.html",
+		`{{range .Messages}}<div>{{text .}}</div>{{markdown (text .)}}{{end}}`)
+
+	out, err := Export(session, ExportOptions{Format: "html", TemplatePath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Printed text is escaped, markdown is already HTML
+	for _, want := range []string{"<div>Reading &lt;it&gt;.</div>", "<strong>config</strong>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Export = %q; missing %q", out, want)
+		}
+	}
+}
+
+func TestExport_TemplatesDir(t *testing.T) {
+	session := testutil.ParseSession(t, templateSession)
+	dir := t.TempDir()
+	writeTemplate(t, dir, "md.tmpl", `house style: {{.Session.ID}}`)
+	writeTemplate(t, dir, "brief.tmpl", `brief: {{len .Messages}}`)
+
+	// <format>.tmpl replaces the built-in format, markdown included
+	for _, format := range []string{"md", "markdown"} {
+		out, err := Export(session, ExportOptions{Format: format, TemplatesDir: dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != "house style: s1" {
+			t.Errorf("Export(%s) = %q; want the override", format, out)
+		}
+	}
+	if out, err := Export(session, ExportOptions{Format: "org", TemplatesDir: dir}); err != nil || !strings.HasPrefix(out, "#+TITLE") {
+		t.Errorf("Export(org) = %q, %v; want the built-in format", out, err)
+	}
+
+	// Templates in the directory can be named without .tmpl
+	if out, err := Export(session, ExportOptions{Format: "txt", TemplatePath: "brief", TemplatesDir: dir}); err != nil || out != "brief: 2" {
+		t.Errorf("Export(brief) = %q, %v", out, err)
+	}
+	if _, err := Export(session, ExportOptions{Format: "txt", TemplatePath: "missing", TemplatesDir: dir}); err == nil {
+		t.Error("expected error for a missing template")
+	}
+	if _, err := Export(session, ExportOptions{Format: "patch", TemplatePath: "brief", TemplatesDir: dir}); err == nil {
+		t.Error("expected error for a patch template")
+	}
+}
+
+func TestExport_TemplateAgents(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"s1.jsonl": `{"type":"user","sessionId":"s1","timestamp":"2026-01-01T10:00:00Z","uuid":"u1","message":{"content":"Investigate"}}
+{"type":"assistant","sessionId":"s1","timestamp":"2026-01-01T10:00:01Z","uuid":"a1","parentUuid":"u1","message":{"content":[{"type":"tool_use","id":"t1","name":"Task","input":{"prompt":"Find the bug"}}]}}
+{"type":"user","sessionId":"s1","timestamp":"2026-01-01T10:00:05Z","uuid":"r1","parentUuid":"a1","toolUseResult":{"status":"completed","agentId":"abc"},"message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"found it"}]}}
+`,
+		"agent-abc.jsonl": `{"type":"user","sessionId":"s1","agentId":"abc","isSidechain":true,"timestamp":"2026-01-01T10:00:02Z","uuid":"x1","message":{"content":"Find the bug"}}
+`,
+	}
+	for name, content := range files {
+		writeTemplate(t, dir, name, content)
+	}
+	session, err := parser.ParseSession(filepath.Join(dir, "s1.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writeTemplate(t, t.TempDir(), "uuids.tmpl", `{{range .Messages}}{{.UUID}} {{end}}`)
+
+	for _, tt := range []struct {
+		agents bool
+		want   string
+	}{
+		{false, "u1 a1 r1 "},
+		{true, "u1 a1 x1 r1 "},
+	} {
+		out, err := Export(session, ExportOptions{Format: "txt", TemplatePath: path, IncludeAgents: tt.agents})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != tt.want {
+			t.Errorf("IncludeAgents %v: Export = %q; want %q", tt.agents, out, tt.want)
+		}
+	}
+}
@@ -7,11 +7,21 @@ import (
 	"github.com/thevibeworks/ccx/internal/parser"
 )
 
+// Thinking is how the terminal shows thinking blocks
+type Thinking int
+
+const (
+	ThinkingCollapsed Thinking = iota // A one-line marker in place of the text
+	ThinkingShown                     // The full text
+	ThinkingHidden                    // Nothing at all
+)
+
 type TerminalOptions struct {
-	ShowThinking bool
-	ShowAgents   bool
-	FlatMode     bool
-	Theme        string
+	Thinking   Thinking
+	ShowAgents bool
+	FlatMode   bool
+	Theme      string
+	ToolsOnly  bool // Only tool calls and their results
 }
 
 const (
@@ -41,6 +51,13 @@ func Terminal(session *parser.Session, opts TerminalOptions) error {
 	return nil
 }
 
+// TerminalMessage prints a single message without its children, for
+// following a session as lines are appended
+func TerminalMessage(msg *parser.Message, opts TerminalOptions) {
+	opts.FlatMode = true
+	printMessage(msg, 0, opts)
+}
+
 func printMessage(msg *parser.Message, depth int, opts TerminalOptions) {
 	indent := strings.Repeat("  ", depth)
 
@@ -55,15 +72,17 @@ func printMessage(msg *parser.Message, depth int, opts TerminalOptions) {
 
 	ts := msg.Timestamp.Format("15:04:05")
 
-	switch msg.Type {
-	case "user":
-		fmt.Printf("\n%s%s%s[USER] %s%s\n", indent, colorBold, colorUser, ts, colorReset)
-	case "assistant":
-		fmt.Printf("\n%s%s%s[ASSISTANT] %s%s\n", indent, colorBold, colorAssist, ts, colorReset)
-	}
+	if blocks := visibleBlocks(msg.Content, opts); len(blocks) > 0 || !opts.ToolsOnly {
+		switch msg.Type {
+		case "user":
+			fmt.Printf("\n%s%s%s[USER] %s%s\n", indent, colorBold, colorUser, ts, colorReset)
+		case "assistant":
+			fmt.Printf("\n%s%s%s[ASSISTANT] %s%s\n", indent, colorBold, colorAssist, ts, colorReset)
+		}
 
-	for _, block := range msg.Content {
-		printContentBlock(block, indent, opts)
+		for _, block := range blocks {
+			printContentBlock(block, indent, opts)
+		}
 	}
 
 	if !opts.FlatMode {
@@ -73,6 +92,23 @@ func printMessage(msg *parser.Message, depth int, opts TerminalOptions) {
 	}
 }
 
+// visibleBlocks drops the blocks hidden by ThinkingHidden and ToolsOnly
+func visibleBlocks(blocks []parser.ContentBlock, opts TerminalOptions) []parser.ContentBlock {
+	if opts.Thinking != ThinkingHidden && !opts.ToolsOnly {
+		return blocks
+	}
+	var out []parser.ContentBlock
+	for _, block := range blocks {
+		switch {
+		case opts.ToolsOnly && block.Type != "tool_use" && block.Type != "tool_result":
+		case opts.Thinking == ThinkingHidden && block.Type == "thinking":
+		default:
+			out = append(out, block)
+		}
+	}
+	return out
+}
+
 func printContentBlock(block parser.ContentBlock, indent string, opts TerminalOptions) {
 	switch block.Type {
 	case "text":
@@ -84,7 +120,7 @@ func printContentBlock(block parser.ContentBlock, indent string, opts TerminalOp
 		}
 
 	case "thinking":
-		if opts.ShowThinking && block.Text != "" {
+		if opts.Thinking == ThinkingShown && block.Text != "" {
 			fmt.Printf("\n%s%s[THINKING]%s\n", indent, colorThink, colorReset)
 			text := wrapText(block.Text, 80-len(indent))
 			for _, line := range strings.Split(text, "\n") {
@@ -99,6 +135,11 @@ func printContentBlock(block parser.ContentBlock, indent string, opts TerminalOp
 		if block.ToolInput != nil {
 			printToolInput(block.ToolInput, indent+"  ")
 		}
+		if block.Sidechain != nil && opts.ShowAgents {
+			for _, msg := range block.Sidechain.RootMessages {
+				printMessage(msg, len(indent)/2+1, opts)
+			}
+		}
 
 	case "tool_result":
 		label := "[RESULT]"
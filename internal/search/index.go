@@ -0,0 +1,183 @@
+// Package search maintains the full-text message index and answers queries against it.
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/thevibeworks/ccx/internal/db"
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/redact"
+)
+
+var syncMu sync.Mutex
+
+// redactor masks sessions before they are indexed; nil indexes them as
+// written. Masked sessions go to their own variant of the index, so
+// queries can't match text that was masked and snippets never show it.
+var redactor *redact.Redactor
+
+// SetRedactor makes Sync and Messages use the index masked with r, or the
+// index of the transcripts as written when r is nil
+func SetRedactor(r *redact.Redactor) {
+	syncMu.Lock()
+	defer syncMu.Unlock()
+	redactor = r
+}
+
+// variant names the index the current redactor reads and writes
+func variant() string {
+	if redactor == nil {
+		return ""
+	}
+	return redactor.Fingerprint()
+}
+
+// SyncStats reports what a Sync pass did
+type SyncStats struct {
+	Indexed int
+	Removed int
+	Skipped int
+}
+
+// Sync brings the index up to date with the session files on disk.
+// Files are reindexed only when their size or mtime changed since the last pass.
+func Sync(projects []*parser.Project) (SyncStats, error) {
+	var stats SyncStats
+	if !db.Available() {
+		return stats, db.ErrNotInitialized
+	}
+
+	syncMu.Lock()
+	defer syncMu.Unlock()
+
+	v := variant()
+	if v != "" {
+		if err := db.PruneSearchVariants(v); err != nil {
+			return stats, err
+		}
+	}
+	indexed, err := db.GetIndexedFiles(v)
+	if err != nil {
+		return stats, err
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range projects {
+		for _, s := range p.Sessions {
+			seen[s.FilePath] = true
+
+			info, err := os.Stat(s.FilePath)
+			if err != nil {
+				continue
+			}
+			if prev, ok := indexed[s.FilePath]; ok &&
+				prev.Size == info.Size() && prev.ModTime.Equal(info.ModTime()) &&
+				prev.Project == p.EncodedName {
+				stats.Skipped++
+				continue
+			}
+
+			full, err := parser.ParseSessionUnfiltered(s.FilePath)
+			if err != nil {
+				continue
+			}
+			redactor.Session(full)
+			file := db.IndexedFile{
+				Path:      s.FilePath,
+				Project:   p.EncodedName,
+				SessionID: s.ID,
+				Branch:    full.GitBranch,
+				Size:      info.Size(),
+				ModTime:   info.ModTime(),
+				Variant:   v,
+			}
+			if err := db.IndexFile(file, buildDocs(full)); err != nil {
+				return stats, fmt.Errorf("index %s: %w", s.FilePath, err)
+			}
+			stats.Indexed++
+		}
+	}
+
+	for path := range indexed {
+		if seen[path] {
+			continue
+		}
+		if err := db.RemoveIndexedFile(path, v); err != nil {
+			return stats, err
+		}
+		stats.Removed++
+	}
+
+	return stats, nil
+}
+
+// buildDocs turns a session and its agent transcripts into one document per message.
+// Tool results are folded into the assistant message that issued the call,
+// since that is where the viewer renders them.
+func buildDocs(s *parser.Session) []db.SearchDoc {
+	msgs := parser.Flatten(s.RootMessages)
+	for _, sc := range s.Sidechains {
+		msgs = append(msgs, parser.Flatten(sc.RootMessages)...)
+	}
+
+	results := make(map[string]parser.ContentBlock)
+	for _, msg := range msgs {
+		for _, block := range msg.Content {
+			if block.Type == "tool_result" && block.ToolID != "" {
+				results[block.ToolID] = block
+			}
+		}
+	}
+
+	var docs []db.SearchDoc
+	for _, msg := range msgs {
+		if msg.Kind == parser.KindToolResult {
+			continue
+		}
+
+		var text, inputs, outputs, tools []string
+		isError := false
+		for _, block := range msg.Content {
+			switch block.Type {
+			case "text":
+				if block.Text != "" {
+					text = append(text, block.Text)
+				}
+			case "tool_use":
+				tools = append(tools, block.ToolName)
+				if data, err := json.Marshal(block.ToolInput); err == nil {
+					inputs = append(inputs, block.ToolName+" "+string(data))
+				}
+				if result, ok := results[block.ToolID]; ok {
+					isError = isError || result.IsError
+					if out := result.ResultText(); out != "" {
+						outputs = append(outputs, out)
+					}
+				}
+			}
+		}
+		if msg.IsCommand {
+			text = append(text, strings.TrimSpace(msg.CommandName+" "+msg.CommandArgs))
+		}
+		if len(text) == 0 && len(inputs) == 0 && len(outputs) == 0 {
+			continue
+		}
+
+		docs = append(docs, db.SearchDoc{
+			UUID:       msg.UUID,
+			Kind:       string(msg.Kind),
+			Model:      msg.Model,
+			Tools:      tools,
+			IsError:    isError,
+			Timestamp:  msg.Timestamp,
+			Text:       strings.Join(text, "\n"),
+			ToolInput:  strings.Join(inputs, "\n"),
+			ToolResult: strings.Join(outputs, "\n"),
+		})
+	}
+	return docs
+}
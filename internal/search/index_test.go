@@ -0,0 +1,337 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/thevibeworks/ccx/internal/db"
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/redact"
+)
+
+func setupIndex(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := db.Init(filepath.Join(dir, "data")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	projectDir := filepath.Join(dir, "projects", "-test-project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "projects")
+}
+
+func writeSession(t *testing.T, projectsDir, id, content string) {
+	t.Helper()
+	path := filepath.Join(projectsDir, "-test-project", id+".jsonl")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const sessionWithTools = `{"type":"user","timestamp":"2026-01-01T10:00:00Z","uuid":"u1","gitBranch":"main","message":{"content":"Run the test suite"}}
+{"type":"assistant","timestamp":"2026-01-01T10:00:01Z","uuid":"a1","parentUuid":"u1","message":{"model":"claude-opus-4-1","content":[{"type":"text","text":"Running tests now"},{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"go test ./..."}}]}}
+{"type":"user","timestamp":"2026-01-01T10:00:02Z","uuid":"r1","parentUuid":"a1","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"open /etc/shadow: permission denied","is_error":true}]}}
+{"type":"assistant","timestamp":"2026-01-01T10:00:03Z","uuid":"a2","parentUuid":"r1","message":{"model":"claude-sonnet-4-5","content":"The tests hit a permission error"}}
+`
+
+// find parses query and runs it against the index
+func find(t *testing.T, projects []*parser.Project, query string) []db.SearchHit {
+	t.Helper()
+	q, err := Parse(query)
+	if err != nil {
+		t.Fatalf("Parse(%q) error: %v", query, err)
+	}
+	hits, err := Messages(projects, q, 0)
+	if err != nil {
+		t.Fatalf("Messages(%q) error: %v", query, err)
+	}
+	return hits
+}
+
+func TestSyncAndSearch(t *testing.T) {
+	projectsDir := setupIndex(t)
+	writeSession(t, projectsDir, "sess-1", sessionWithTools)
+
+	projects, err := parser.DiscoverProjects(projectsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := Sync(projects)
+	if err != nil {
+		t.Fatalf("Sync() error: %v", err)
+	}
+	if stats.Indexed != 1 {
+		t.Errorf("Indexed = %d, want 1", stats.Indexed)
+	}
+
+	// Tool result is attributed to the assistant message that made the call
+	hits := find(t, projects, "shadow")
+	if len(hits) != 1 || hits[0].UUID != "a1" {
+		t.Fatalf("hits = %+v, want one hit on a1", hits)
+	}
+	if hits[0].SessionID != "sess-1" || hits[0].Project != "-test-project" {
+		t.Errorf("hit location = %s/%s", hits[0].Project, hits[0].SessionID)
+	}
+
+	// Every matching message is returned, not just the first per session
+	hits = find(t, projects, "permission")
+	if len(hits) != 2 {
+		t.Errorf("len(hits) = %d, want 2", len(hits))
+	}
+
+	// Tool input
+	hits = find(t, projects, "go test")
+	if len(hits) == 0 {
+		t.Error("expected hit on tool input")
+	}
+}
+
+func TestSyncIsIncremental(t *testing.T) {
+	projectsDir := setupIndex(t)
+	writeSession(t, projectsDir, "sess-1", sessionWithTools)
+	writeSession(t, projectsDir, "sess-2", `{"type":"user","timestamp":"2026-01-02T10:00:00Z","uuid":"x1","message":{"content":"Refactor the parser"}}
+`)
+
+	projects, _ := parser.DiscoverProjects(projectsDir)
+	if _, err := Sync(projects); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := Sync(projects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Indexed != 0 || stats.Skipped != 2 {
+		t.Errorf("second sync = %+v, want nothing reindexed", stats)
+	}
+
+	// Removing a session file drops it from the index
+	if err := os.Remove(filepath.Join(projectsDir, "-test-project", "sess-2.jsonl")); err != nil {
+		t.Fatal(err)
+	}
+	projects, _ = parser.DiscoverProjects(projectsDir)
+	stats, err = Sync(projects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Removed != 1 {
+		t.Errorf("Removed = %d, want 1", stats.Removed)
+	}
+	hits := find(t, projects, "refactor")
+	if len(hits) != 0 {
+		t.Errorf("removed session still searchable: %+v", hits)
+	}
+}
+
+func TestSyncRedacted(t *testing.T) {
+	projectsDir := setupIndex(t)
+	writeSession(t, projectsDir, "sess-1", `{"type":"user","timestamp":"2026-01-01T10:00:00Z","uuid":"u1","message":{"content":"Log in with DB_PASSWORD=hunter2secret and the staging host"}}
+`)
+	projects, _ := parser.DiscoverProjects(projectsDir)
+	if _, err := Sync(projects); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := redact.New(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetRedactor(r)
+	t.Cleanup(func() { SetRedactor(nil) })
+
+	stats, err := Sync(projects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Indexed != 1 {
+		t.Errorf("Indexed = %d, want the masked variant built", stats.Indexed)
+	}
+	// A masked value can't be confirmed by searching for it
+	if hits := find(t, projects, "hunter2secret"); len(hits) != 0 {
+		t.Errorf("masked value is searchable: %+v", hits)
+	}
+	hits := find(t, projects, "staging")
+	if len(hits) != 1 || strings.Contains(hits[0].Snippet, "hunter2") || !strings.Contains(hits[0].Snippet, "[REDACTED:secret-value]") {
+		t.Errorf("hits = %+v, want one masked snippet", hits)
+	}
+
+	// The transcript as written is still indexed for the CLI
+	SetRedactor(nil)
+	if hits := find(t, projects, "hunter2secret"); len(hits) != 1 {
+		t.Errorf("unmasked hits = %+v, want one", hits)
+	}
+}
+
+func TestSyncWithoutDatabase(t *testing.T) {
+	if _, err := Sync(nil); err != db.ErrNotInitialized {
+		t.Errorf("Sync() error = %v, want ErrNotInitialized", err)
+	}
+}
+
+func TestQualifiers(t *testing.T) {
+	projectsDir := setupIndex(t)
+	writeSession(t, projectsDir, "sess-1", sessionWithTools)
+
+	projects, _ := parser.DiscoverProjects(projectsDir)
+	if _, err := Sync(projects); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"tool:Bash", []string{"a1"}},
+		{"tool:bash is:error", []string{"a1"}},
+		{"-is:error permission", []string{"a2"}},
+		{"model:opus", []string{"a1"}},
+		{"model:sonnet,opus permission", []string{"a1", "a2"}},
+		{"kind:user", []string{"u1"}},
+		{"kind:assistant -tool:Bash", []string{"a2"}},
+		{`"test suite"`, []string{"u1"}},
+		{`"suite test"`, nil},
+		{"permiss*", []string{"a1", "a2"}},
+		{"permission -shadow", []string{"a2"}},
+		{"branch:main tool:Bash", []string{"a1"}},
+		{"branch:dev tool:Bash", nil},
+		{"project:test kind:user", []string{"u1"}},
+		{"project:nope kind:user", nil},
+		{"-project:nope kind:user", []string{"u1"}},
+		{"after:2026-01-02 kind:user", nil},
+		{"before:2026-01-02 kind:user", []string{"u1"}},
+	}
+
+	for _, tt := range tests {
+		hits := find(t, projects, tt.query)
+		got := make(map[string]bool)
+		for _, h := range hits {
+			got[h.UUID] = true
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%q: hits = %v, want %v", tt.query, got, tt.want)
+			continue
+		}
+		for _, uuid := range tt.want {
+			if !got[uuid] {
+				t.Errorf("%q: missing %s in %v", tt.query, uuid, got)
+			}
+		}
+	}
+}
+
+func TestTagQualifier(t *testing.T) {
+	projectsDir := setupIndex(t)
+	writeSession(t, projectsDir, "sess-1", sessionWithTools)
+	writeSession(t, projectsDir, "sess-2", `{"type":"user","timestamp":"2026-01-03T10:00:00Z","uuid":"v1","message":{"content":"Untagged work"}}`+"\n")
+
+	projects, _ := parser.DiscoverProjects(projectsDir)
+	if _, err := Sync(projects); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.TagItemByName("session", "sess-1", "Incident"); err != nil {
+		t.Fatal(err)
+	}
+
+	if hits := find(t, projects, "tag:incident kind:user"); len(hits) != 1 || hits[0].UUID != "u1" {
+		t.Errorf("tag:incident kind:user = %+v, want u1", hits)
+	}
+	if hits := find(t, projects, "-tag:incident kind:user"); len(hits) != 1 || hits[0].UUID != "v1" {
+		t.Errorf("-tag:incident kind:user = %+v, want v1", hits)
+	}
+
+	q, _ := Parse("tag:INCIDENT")
+	p := projects[0]
+	for _, s := range p.Sessions {
+		if got, want := q.MatchSession(p, s), s.ID == "sess-1"; got != want {
+			t.Errorf("MatchSession(%s) = %v, want %v", s.ID, got, want)
+		}
+	}
+
+	// Project tags apply to every session in the project
+	if err := db.TagItemByName("project", "-test-project", "team"); err != nil {
+		t.Fatal(err)
+	}
+	if hits := find(t, projects, "tag:team kind:user"); len(hits) != 2 {
+		t.Errorf("tag:team kind:user = %d hits, want 2", len(hits))
+	}
+}
+
+func TestParse(t *testing.T) {
+	q, err := Parse(`fix -flaky "race condition" -"unit test" tool:Bash,Edit -is:error project:"my app" http://x`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantTerms := []Term{
+		{Text: "fix"},
+		{Text: "flaky", Negate: true},
+		{Text: "race condition", Phrase: true},
+		{Text: "unit test", Phrase: true, Negate: true},
+		{Text: "http://x"},
+	}
+	if len(q.Terms) != len(wantTerms) {
+		t.Fatalf("Terms = %+v", q.Terms)
+	}
+	for i, want := range wantTerms {
+		if q.Terms[i] != want {
+			t.Errorf("Terms[%d] = %+v, want %+v", i, q.Terms[i], want)
+		}
+	}
+
+	if len(q.Filters) != 3 {
+		t.Fatalf("Filters = %+v", q.Filters)
+	}
+	if f := q.Filters[0]; f.Key != "tool" || len(f.Values) != 2 || f.Values[1] != "Edit" {
+		t.Errorf("tool filter = %+v", f)
+	}
+	if f := q.Filters[1]; f.Key != "is" || !f.Negate {
+		t.Errorf("is filter = %+v", f)
+	}
+	if f := q.Filters[2]; f.Key != "project" || f.Values[0] != "my app" {
+		t.Errorf("project filter = %+v", f)
+	}
+	if q.Text() != "fix race condition http://x" {
+		t.Errorf("Text() = %q", q.Text())
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{
+		"kind:robot",
+		"is:open",
+		"after:yesterday",
+		"tool:",
+	} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", input)
+		}
+	}
+}
+
+func TestTermExpr(t *testing.T) {
+	tests := []struct {
+		term     Term
+		expected string
+	}{
+		{Term{Text: "hello"}, `"hello"`},
+		{Term{Text: `say"hi`}, `"say""hi"`},
+		{Term{Text: "NOT"}, `"NOT"`},
+		{Term{Text: "auth*"}, `"auth"*`},
+		{Term{Text: "auth*", Phrase: true}, `"auth*"`},
+		{Term{Text: "*"}, ""},
+	}
+
+	for _, tt := range tests {
+		result := termExpr(tt.term)
+		if result != tt.expected {
+			t.Errorf("termExpr(%+v) = %q, want %q", tt.term, result, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package search
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/thevibeworks/ccx/internal/db"
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+// Query is a parsed search string.
+//
+// Grammar (all parts are ANDed):
+//
+//	word           message contains word (trailing * for prefix match)
+//	"a phrase"     message contains the exact phrase
+//	key:value      qualifier, see below; key:a,b matches either value
+//	-term          negates a word, phrase or qualifier
+//
+// Qualifiers:
+//
+//	project:NAME   project name contains NAME
+//	branch:NAME    session git branch is NAME
+//	tag:NAME       session or its project is tagged NAME
+//	model:ID       message model contains ID
+//	tool:NAME      message calls tool NAME
+//	kind:KIND      message kind (user, assistant, command, meta, compact)
+//	is:error       one of the message's tool calls failed
+//	after:DATE     at or after DATE (YYYY-MM-DD or RFC3339)
+//	before:DATE    before DATE
+type Query struct {
+	Terms   []Term
+	Filters []Filter
+
+	tags *tagSets // Loaded on first tag: match
+}
+
+// tagSets holds the tags of every tagged session and project
+type tagSets struct {
+	sessions map[string][]string
+	projects map[string][]string
+}
+
+// Term is a free-text word or quoted phrase
+type Term struct {
+	Text   string
+	Phrase bool
+	Negate bool
+}
+
+// Filter is a key:value qualifier; Values are alternatives
+type Filter struct {
+	Key    string
+	Values []string
+	Negate bool
+	Time   time.Time // Parsed value for after/before
+}
+
+var qualifiers = map[string]bool{
+	"project": true,
+	"branch":  true,
+	"tag":     true,
+	"model":   true,
+	"tool":    true,
+	"kind":    true,
+	"is":      true,
+	"after":   true,
+	"before":  true,
+}
+
+// kindAliases maps kind: values onto parser.MessageKind
+var kindAliases = map[string]parser.MessageKind{
+	"user":            parser.KindUserPrompt,
+	"prompt":          parser.KindUserPrompt,
+	"user_prompt":     parser.KindUserPrompt,
+	"assistant":       parser.KindAssistant,
+	"command":         parser.KindCommand,
+	"meta":            parser.KindMeta,
+	"compact":         parser.KindCompactSummary,
+	"compact_summary": parser.KindCompactSummary,
+}
+
+// Parse parses a search string into terms and qualifiers
+func Parse(input string) (*Query, error) {
+	q := &Query{}
+	rs := []rune(input)
+	i := 0
+
+	for i < len(rs) {
+		if unicode.IsSpace(rs[i]) {
+			i++
+			continue
+		}
+
+		negate := false
+		if rs[i] == '-' && i+1 < len(rs) && !unicode.IsSpace(rs[i+1]) {
+			negate = true
+			i++
+		}
+
+		if rs[i] == '"' {
+			text, next := readQuoted(rs, i)
+			i = next
+			if text = strings.TrimSpace(text); text != "" {
+				q.Terms = append(q.Terms, Term{Text: text, Phrase: true, Negate: negate})
+			}
+			continue
+		}
+
+		start := i
+		for i < len(rs) && !unicode.IsSpace(rs[i]) && rs[i] != ':' {
+			i++
+		}
+		key := strings.ToLower(string(rs[start:i]))
+
+		if i < len(rs) && rs[i] == ':' && qualifiers[key] {
+			i++
+			var value string
+			if i < len(rs) && rs[i] == '"' {
+				value, i = readQuoted(rs, i)
+			} else {
+				vstart := i
+				for i < len(rs) && !unicode.IsSpace(rs[i]) {
+					i++
+				}
+				value = string(rs[vstart:i])
+			}
+			f, err := newFilter(key, value, negate)
+			if err != nil {
+				return nil, err
+			}
+			q.Filters = append(q.Filters, f)
+			continue
+		}
+
+		// Plain word (including unknown key:value pairs such as URLs)
+		for i < len(rs) && !unicode.IsSpace(rs[i]) {
+			i++
+		}
+		q.Terms = append(q.Terms, Term{Text: string(rs[start:i]), Negate: negate})
+	}
+
+	return q, nil
+}
+
+// readQuoted reads a "quoted" string starting at rs[i] == '"'.
+// An unterminated quote runs to the end of input.
+func readQuoted(rs []rune, i int) (string, int) {
+	i++
+	start := i
+	for i < len(rs) && rs[i] != '"' {
+		i++
+	}
+	text := string(rs[start:i])
+	if i < len(rs) {
+		i++
+	}
+	return text, i
+}
+
+func newFilter(key, value string, negate bool) (Filter, error) {
+	f := Filter{Key: key, Negate: negate}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			f.Values = append(f.Values, v)
+		}
+	}
+	if len(f.Values) == 0 {
+		return f, fmt.Errorf("%s: needs a value", key)
+	}
+
+	switch key {
+	case "kind":
+		for i, v := range f.Values {
+			kind, ok := kindAliases[strings.ToLower(v)]
+			if !ok {
+				return f, fmt.Errorf("unknown kind %q (want user, assistant, command, meta or compact)", v)
+			}
+			f.Values[i] = string(kind)
+		}
+	case "tag":
+		for i, v := range f.Values {
+			f.Values[i] = strings.ToLower(v)
+		}
+	case "is":
+		for _, v := range f.Values {
+			if strings.ToLower(v) != "error" {
+				return f, fmt.Errorf("unknown is:%s (only is:error is supported)", v)
+			}
+		}
+	case "after", "before":
+		if len(f.Values) != 1 {
+			return f, fmt.Errorf("%s: takes a single date", key)
+		}
+		t, err := parseDate(f.Values[0])
+		if err != nil {
+			return f, fmt.Errorf("%s: invalid date %q (want YYYY-MM-DD)", key, f.Values[0])
+		}
+		f.Time = t
+	}
+	return f, nil
+}
+
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// Text returns the positive free-text terms joined by spaces, lowercased.
+// Used for name and summary matching, which predate the index.
+func (q *Query) Text() string {
+	var parts []string
+	for _, t := range q.Terms {
+		if !t.Negate {
+			parts = append(parts, strings.ToLower(t.Text))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// IsEmpty reports whether the query has no terms and no qualifiers
+func (q *Query) IsEmpty() bool {
+	return len(q.Terms) == 0 && len(q.Filters) == 0
+}
+
+// HasFilters reports whether the query has any qualifiers
+func (q *Query) HasFilters() bool {
+	return len(q.Filters) > 0
+}
+
+// HasMessageFilters reports whether any qualifier only applies to messages
+func (q *Query) HasMessageFilters() bool {
+	for _, f := range q.Filters {
+		switch f.Key {
+		case "model", "tool", "kind", "is":
+			return true
+		}
+	}
+	return false
+}
+
+// SearchesMessages reports whether the query selects individual messages:
+// it has free text or a message-level qualifier. Session-level qualifiers
+// alone (project:, branch:, dates) list sessions rather than every message.
+func (q *Query) SearchesMessages() bool {
+	return len(q.Terms) > 0 || q.HasMessageFilters()
+}
+
+// MatchProject reports whether a project satisfies the project: qualifiers
+func (q *Query) MatchProject(p *parser.Project) bool {
+	for _, f := range q.Filters {
+		if f.Key != "project" {
+			continue
+		}
+		if anyValue(f.Values, func(v string) bool { return projectMatches(p, v) }) == f.Negate {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchSession reports whether a session satisfies the session-level qualifiers
+// (project, branch, tag, after, before) and its summary matches the free text.
+// Queries with message-level qualifiers never match whole sessions.
+func (q *Query) MatchSession(p *parser.Project, s *parser.Session) bool {
+	if q.HasMessageFilters() || !q.MatchProject(p) {
+		return false
+	}
+	for _, f := range q.Filters {
+		switch f.Key {
+		case "branch":
+			ok := anyValue(f.Values, func(v string) bool { return strings.EqualFold(s.GitBranch, v) })
+			if ok == f.Negate {
+				return false
+			}
+		case "tag":
+			tags := q.sessionTags(p, s)
+			ok := anyValue(f.Values, func(v string) bool { return slices.Contains(tags, v) })
+			if ok == f.Negate {
+				return false
+			}
+		case "after":
+			if s.EndTime.Before(f.Time) != f.Negate {
+				return false
+			}
+		case "before":
+			if !s.StartTime.Before(f.Time) != f.Negate {
+				return false
+			}
+		}
+	}
+
+	summary := strings.ToLower(s.Summary)
+	for _, t := range q.Terms {
+		if strings.Contains(summary, strings.ToLower(strings.TrimSuffix(t.Text, "*"))) == t.Negate {
+			return false
+		}
+	}
+	return true
+}
+
+// sessionTags returns the tags of a session and of its project
+func (q *Query) sessionTags(p *parser.Project, s *parser.Session) []string {
+	if q.tags == nil {
+		q.tags = &tagSets{}
+		if db.Available() {
+			q.tags.sessions, _ = db.GetTagsByType("session")
+			q.tags.projects, _ = db.GetTagsByType("project")
+		}
+	}
+	return append(slices.Clip(q.tags.sessions[s.ID]), q.tags.projects[p.EncodedName]...)
+}
+
+func projectMatches(p *parser.Project, value string) bool {
+	value = strings.ToLower(value)
+	return strings.Contains(strings.ToLower(p.Name), value) ||
+		strings.ToLower(p.EncodedName) == value
+}
+
+func anyValue(values []string, match func(string) bool) bool {
+	for _, v := range values {
+		if match(v) {
+			return true
+		}
+	}
+	return false
+}
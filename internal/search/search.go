@@ -0,0 +1,86 @@
+package search
+
+import (
+	"strings"
+
+	"github.com/thevibeworks/ccx/internal/db"
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+// Messages returns every indexed message matching q, best BM25 match first
+// (newest first when q has no free text). Project qualifiers are resolved
+// against projects. A limit of 0 returns every match.
+func Messages(projects []*parser.Project, q *Query, limit int) ([]db.SearchHit, error) {
+	if q.IsEmpty() {
+		return nil, nil
+	}
+
+	syncMu.Lock()
+	mq := db.MessageQuery{Limit: limit, Variant: variant()}
+	syncMu.Unlock()
+
+	var include, exclude []string
+	for _, t := range q.Terms {
+		expr := termExpr(t)
+		if expr == "" {
+			continue
+		}
+		if t.Negate {
+			exclude = append(exclude, expr)
+		} else {
+			include = append(include, expr)
+		}
+	}
+	mq.Match = strings.Join(include, " ")
+	mq.Exclude = strings.Join(exclude, " OR ")
+
+	for _, f := range q.Filters {
+		switch f.Key {
+		case "project":
+			var names []string
+			for _, p := range projects {
+				if anyValue(f.Values, func(v string) bool { return projectMatches(p, v) }) {
+					names = append(names, p.EncodedName)
+				}
+			}
+			mq.Conditions = append(mq.Conditions, db.Condition{Field: "project", Values: names, Negate: f.Negate})
+		case "is":
+			mq.Conditions = append(mq.Conditions, db.Condition{Field: "error", Negate: f.Negate})
+		case "after":
+			if f.Negate {
+				mq.Before = f.Time
+			} else {
+				mq.After = f.Time
+			}
+		case "before":
+			if f.Negate {
+				mq.After = f.Time
+			} else {
+				mq.Before = f.Time
+			}
+		default:
+			mq.Conditions = append(mq.Conditions, db.Condition{Field: f.Key, Values: f.Values, Negate: f.Negate})
+		}
+	}
+
+	return db.SearchMessages(mq)
+}
+
+// termExpr quotes a term so FTS5 operators in user input are taken literally.
+// A trailing * on a word becomes a prefix query.
+func termExpr(t Term) string {
+	text := t.Text
+	prefix := false
+	if !t.Phrase && strings.HasSuffix(text, "*") {
+		text = strings.TrimRight(text, "*")
+		prefix = true
+	}
+	if text == "" {
+		return ""
+	}
+	expr := `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+	if prefix {
+		expr += "*"
+	}
+	return expr
+}
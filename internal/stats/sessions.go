@@ -0,0 +1,78 @@
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/pricing"
+)
+
+// SessionSummary is one session's share of a Report
+type SessionSummary struct {
+	Project     string           `json:"project"` // Encoded name
+	ProjectName string           `json:"project_name"`
+	ID          string           `json:"id"`
+	Summary     string           `json:"summary"`
+	StartTime   time.Time        `json:"start_time"` // First message in range
+	Messages    int              `json:"messages"`
+	ToolCalls   int              `json:"tool_calls"`
+	Tokens      parser.Usage     `json:"tokens"`
+	Cost        pricing.Estimate `json:"cost"`
+}
+
+// Slot is one cell of the Activity heatmap
+type Slot struct {
+	Weekday time.Weekday
+	Hour    int
+}
+
+// Match selects the sessions behind one part of a Report. Empty fields
+// match every session.
+type Match struct {
+	Day   string // Counted under this date in ByDay
+	Model string // Got at least one response from this model
+	Tool  string // Called this tool at least once
+	Slot  *Slot  // Had a message in this weekday and hour
+}
+
+type sessionRecord struct {
+	summary SessionSummary
+	day     string
+	models  map[string]bool
+	tools   map[string]bool
+	slots   [7][24]int
+}
+
+func (rec *sessionRecord) matches(m Match) bool {
+	if m.Day != "" && rec.day != m.Day {
+		return false
+	}
+	if m.Model != "" && !rec.models[m.Model] {
+		return false
+	}
+	if m.Tool != "" && !rec.tools[m.Tool] {
+		return false
+	}
+	if m.Slot != nil {
+		if m.Slot.Weekday < 0 || m.Slot.Weekday > time.Saturday || m.Slot.Hour < 0 || m.Slot.Hour > 23 {
+			return false
+		}
+		if rec.slots[m.Slot.Weekday][m.Slot.Hour] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Sessions lists the aggregated sessions selected by m, newest first
+func (a *Aggregator) Sessions(m Match) []SessionSummary {
+	out := []SessionSummary{}
+	for _, rec := range a.sessions {
+		if rec.matches(m) {
+			out = append(out, rec.summary)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartTime.After(out[j].StartTime) })
+	return out
+}
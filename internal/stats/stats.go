@@ -0,0 +1,450 @@
+// Package stats aggregates token, model and tool usage across sessions.
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/pricing"
+)
+
+// Filter selects the sessions and messages a Report covers
+type Filter struct {
+	Project string    // Project name contains, or encoded name equals
+	Branch  string    // Session git branch, case-insensitive
+	After   time.Time // Inclusive lower bound on message time
+	Before  time.Time // Exclusive upper bound on message time
+}
+
+// ModelStats is the usage of one model
+type ModelStats struct {
+	Model    string           `json:"model"`
+	Messages int              `json:"messages"` // API responses
+	Tokens   parser.Usage     `json:"tokens"`
+	Cost     pricing.Estimate `json:"cost"`
+}
+
+// ToolStats counts the calls of one tool and how many failed
+type ToolStats struct {
+	Name      string  `json:"name"`
+	Calls     int     `json:"calls"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"` // Errors / Calls
+}
+
+// ProjectStats is the activity in one project
+type ProjectStats struct {
+	Project  string           `json:"project"` // Encoded name
+	Name     string           `json:"name"`
+	Sessions int              `json:"sessions"`
+	Messages int              `json:"messages"`
+	Tokens   parser.Usage     `json:"tokens"`
+	Cost     pricing.Estimate `json:"cost"`
+}
+
+// DayStats is the activity on one local calendar day
+type DayStats struct {
+	Date     string           `json:"date"` // YYYY-MM-DD
+	Sessions int              `json:"sessions"`
+	Messages int              `json:"messages"`
+	Tokens   parser.Usage     `json:"tokens"`
+	Cost     pricing.Estimate `json:"cost"`
+}
+
+// Report is the aggregate over every session matching a Filter.
+// Message counts follow SessionStats: user prompts and assistant responses.
+type Report struct {
+	Sessions           int              `json:"sessions"`
+	Projects           int              `json:"projects"`
+	Messages           int              `json:"messages"`
+	UserPrompts        int              `json:"user_prompts"`
+	ToolCalls          int              `json:"tool_calls"`
+	ToolErrors         int              `json:"tool_errors"`
+	Tokens             parser.Usage     `json:"tokens"`
+	Cost               pricing.Estimate `json:"cost"` // Estimated from the pricing table
+	AvgDurationSeconds float64          `json:"avg_duration_seconds"`
+	Compactions        int              `json:"compactions"`
+	CompactedSessions  int              `json:"compacted_sessions"` // Sessions with at least one compaction
+	ByModel            []ModelStats     `json:"by_model"`
+	ByTool             []ToolStats      `json:"by_tool"`
+	ByProject          []ProjectStats   `json:"by_project"`
+	ByDay              []DayStats       `json:"by_day"`
+	Activity           [7][24]int       `json:"activity"` // Messages by local weekday (0 = Sunday) and hour
+}
+
+// CompactionsPerSession is the average number of compactions per session
+func (r *Report) CompactionsPerSession() float64 {
+	if r.Sessions == 0 {
+		return 0
+	}
+	return float64(r.Compactions) / float64(r.Sessions)
+}
+
+// MatchProject reports whether a project satisfies the project filter
+func (f Filter) MatchProject(p *parser.Project) bool {
+	if f.Project == "" {
+		return true
+	}
+	value := strings.ToLower(f.Project)
+	return strings.Contains(strings.ToLower(p.Name), value) || strings.ToLower(p.EncodedName) == value
+}
+
+// MatchSession reports whether a listed session may have messages in the
+// filter's range and is on its branch, before anything is parsed
+func (f Filter) MatchSession(s *parser.Session) bool {
+	if f.Branch != "" && !strings.EqualFold(s.GitBranch, f.Branch) {
+		return false
+	}
+	if !f.After.IsZero() && s.EndTime.Before(f.After) {
+		return false
+	}
+	if !f.Before.IsZero() && !s.StartTime.Before(f.Before) {
+		return false
+	}
+	return true
+}
+
+// Scope describes the filter's date range, e.g. "since 2026-09-01"
+func (f Filter) Scope() string {
+	// Before is exclusive; show the last day it includes
+	switch {
+	case !f.After.IsZero() && !f.Before.IsZero():
+		return f.After.Format("2006-01-02") + " to " + f.Before.Add(-time.Second).Format("2006-01-02")
+	case !f.After.IsZero():
+		return "since " + f.After.Format("2006-01-02")
+	case !f.Before.IsZero():
+		return "until " + f.Before.Add(-time.Second).Format("2006-01-02")
+	}
+	return "all time"
+}
+
+// ParseDate parses YYYY-MM-DD, RFC3339 or a relative Nd/Nw (days or weeks
+// before today). An end date without a time covers the whole day.
+func ParseDate(s string, end bool) (time.Time, error) {
+	if n := len(s); n > 1 && (s[n-1] == 'd' || s[n-1] == 'w') {
+		if count, err := strconv.Atoi(s[:n-1]); err == nil && count >= 0 {
+			days := count
+			if s[n-1] == 'w' {
+				days *= 7
+			}
+			now := time.Now()
+			today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+			return today.AddDate(0, 0, -days), nil
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (want YYYY-MM-DD or 7d)", s)
+}
+
+func (f Filter) inRange(t time.Time) bool {
+	if !f.After.IsZero() && t.Before(f.After) {
+		return false
+	}
+	if !f.Before.IsZero() && !t.Before(f.Before) {
+		return false
+	}
+	return true
+}
+
+// Collect parses and aggregates every session under projectsDir that
+// matches f, estimating cost with prices (nil for none)
+func Collect(projectsDir string, f Filter, prices *pricing.Table) (*Report, error) {
+	a, err := Aggregate(projectsDir, f, prices)
+	if err != nil {
+		return nil, err
+	}
+	return a.Report(), nil
+}
+
+// Aggregate is Collect, returning the aggregator so the sessions behind
+// the report can be listed with Sessions
+func Aggregate(projectsDir string, f Filter, prices *pricing.Table) (*Aggregator, error) {
+	projects, err := parser.DiscoverProjects(projectsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	a := NewAggregator(f, prices)
+	for _, p := range projects {
+		if !f.MatchProject(p) {
+			continue
+		}
+		for _, s := range p.Sessions {
+			if !f.MatchSession(s) {
+				continue
+			}
+			full, err := parser.ParseSession(s.FilePath)
+			if err != nil {
+				continue
+			}
+			a.Add(p, full)
+		}
+	}
+	return a, nil
+}
+
+// Aggregator folds parsed sessions into a Report
+type Aggregator struct {
+	filter   Filter
+	prices   *pricing.Table
+	report   Report
+	duration float64
+	models   map[string]*ModelStats
+	tools    map[string]*ToolStats
+	projects map[string]*ProjectStats
+	days     map[string]*DayStats
+	sessions []*sessionRecord
+}
+
+// NewAggregator returns an empty aggregator counting only messages in f's
+// range and estimating cost with prices (nil for none)
+func NewAggregator(f Filter, prices *pricing.Table) *Aggregator {
+	return &Aggregator{
+		filter:   f,
+		prices:   prices,
+		models:   make(map[string]*ModelStats),
+		tools:    make(map[string]*ToolStats),
+		projects: make(map[string]*ProjectStats),
+		days:     make(map[string]*DayStats),
+	}
+}
+
+// Add folds in a fully parsed session of project p, including its agent
+// transcripts. Sessions without messages in range are skipped.
+func (a *Aggregator) Add(p *parser.Project, s *parser.Session) {
+	main := parser.Flatten(s.RootMessages)
+	var agents []*parser.Message
+	for _, sc := range s.Sidechains {
+		agents = append(agents, parser.Flatten(sc.RootMessages)...)
+	}
+
+	toolNames := make(map[string]string) // tool_use ID -> tool name
+	for _, msg := range append(main, agents...) {
+		for _, block := range msg.Content {
+			if block.Type == "tool_use" && block.ToolID != "" {
+				toolNames[block.ToolID] = block.ToolName
+			}
+		}
+	}
+
+	st := sessionTotals{models: make(map[string]bool), tools: make(map[string]bool)}
+	for _, msg := range main {
+		a.addMessage(msg, toolNames, true, &st)
+	}
+	// Agent transcripts add usage; turn counts stay with the main conversation
+	for _, msg := range agents {
+		a.addMessage(msg, toolNames, false, &st)
+	}
+	if st.first.IsZero() {
+		return
+	}
+
+	r := &a.report
+	r.Sessions++
+	r.Messages += st.messages
+	r.UserPrompts += st.prompts
+	r.Tokens.Add(st.tokens)
+	r.Cost.Add(st.cost)
+	r.Compactions += st.compactions
+	if st.compactions > 0 {
+		r.CompactedSessions++
+	}
+	a.duration += s.Stats.DurationSeconds
+
+	ps := a.projects[p.EncodedName]
+	if ps == nil {
+		ps = &ProjectStats{Project: p.EncodedName, Name: p.Name}
+		a.projects[p.EncodedName] = ps
+	}
+	ps.Sessions++
+	ps.Messages += st.messages
+	ps.Tokens.Add(st.tokens)
+	ps.Cost.Add(st.cost)
+
+	date := st.first.Local().Format("2006-01-02")
+	ds := a.days[date]
+	if ds == nil {
+		ds = &DayStats{Date: date}
+		a.days[date] = ds
+	}
+	ds.Sessions++
+	ds.Messages += st.messages
+	ds.Tokens.Add(st.tokens)
+	ds.Cost.Add(st.cost)
+
+	for day := range st.slots {
+		for hour, n := range st.slots[day] {
+			r.Activity[day][hour] += n
+		}
+	}
+	a.sessions = append(a.sessions, &sessionRecord{
+		summary: SessionSummary{
+			Project:     p.EncodedName,
+			ProjectName: p.Name,
+			ID:          s.ID,
+			Summary:     s.Summary,
+			StartTime:   st.first,
+			Messages:    st.messages,
+			ToolCalls:   st.toolCalls,
+			Tokens:      st.tokens,
+			Cost:        st.cost,
+		},
+		day:    date,
+		models: st.models,
+		tools:  st.tools,
+		slots:  st.slots,
+	})
+}
+
+// sessionTotals accumulates one session's in-range messages
+type sessionTotals struct {
+	first       time.Time
+	messages    int
+	prompts     int
+	compactions int
+	toolCalls   int
+	tokens      parser.Usage
+	cost        pricing.Estimate
+	models      map[string]bool
+	tools       map[string]bool
+	slots       [7][24]int
+}
+
+func (a *Aggregator) addMessage(msg *parser.Message, toolNames map[string]string, countTurns bool, st *sessionTotals) {
+	if !a.filter.inRange(msg.Timestamp) {
+		return
+	}
+	if st.first.IsZero() || msg.Timestamp.Before(st.first) {
+		st.first = msg.Timestamp
+	}
+
+	if countTurns {
+		switch msg.Kind {
+		case parser.KindUserPrompt:
+			st.messages++
+			st.prompts++
+			st.slot(msg.Timestamp)
+		case parser.KindAssistant:
+			st.messages++
+			st.slot(msg.Timestamp)
+		}
+		if msg.IsCompacted {
+			st.compactions++
+		}
+	}
+
+	if msg.Usage != nil {
+		model := msg.Model
+		if model == "" {
+			model = "unknown"
+		}
+		ms := a.models[model]
+		if ms == nil {
+			ms = &ModelStats{Model: model}
+			a.models[model] = ms
+		}
+		cost := a.prices.Message(msg)
+		ms.Messages++
+		ms.Tokens.Add(*msg.Usage)
+		ms.Cost.Add(cost)
+		st.tokens.Add(*msg.Usage)
+		st.cost.Add(cost)
+		st.models[model] = true
+	}
+
+	for _, block := range msg.Content {
+		switch block.Type {
+		case "tool_use":
+			a.tool(block.ToolName).Calls++
+			a.report.ToolCalls++
+			st.toolCalls++
+			st.tools[block.ToolName] = true
+		case "tool_result":
+			if name, ok := toolNames[block.ToolID]; ok && block.IsError {
+				a.tool(name).Errors++
+				a.report.ToolErrors++
+			}
+		}
+	}
+}
+
+func (st *sessionTotals) slot(t time.Time) {
+	local := t.Local()
+	st.slots[local.Weekday()][local.Hour()]++
+}
+
+func (a *Aggregator) tool(name string) *ToolStats {
+	ts := a.tools[name]
+	if ts == nil {
+		ts = &ToolStats{Name: name}
+		a.tools[name] = ts
+	}
+	return ts
+}
+
+// Report returns the aggregate so far, with breakdowns sorted by size
+// and days in date order
+func (a *Aggregator) Report() *Report {
+	r := a.report
+	r.Projects = len(a.projects)
+	if r.Sessions > 0 {
+		r.AvgDurationSeconds = a.duration / float64(r.Sessions)
+	}
+
+	r.ByModel = make([]ModelStats, 0, len(a.models))
+	for _, ms := range a.models {
+		r.ByModel = append(r.ByModel, *ms)
+	}
+	sort.Slice(r.ByModel, func(i, j int) bool {
+		if ti, tj := r.ByModel[i].Tokens.Total(), r.ByModel[j].Tokens.Total(); ti != tj {
+			return ti > tj
+		}
+		return r.ByModel[i].Model < r.ByModel[j].Model
+	})
+
+	r.ByTool = make([]ToolStats, 0, len(a.tools))
+	for _, ts := range a.tools {
+		t := *ts
+		if t.Calls > 0 {
+			t.ErrorRate = float64(t.Errors) / float64(t.Calls)
+		}
+		r.ByTool = append(r.ByTool, t)
+	}
+	sort.Slice(r.ByTool, func(i, j int) bool {
+		if r.ByTool[i].Calls != r.ByTool[j].Calls {
+			return r.ByTool[i].Calls > r.ByTool[j].Calls
+		}
+		return r.ByTool[i].Name < r.ByTool[j].Name
+	})
+
+	r.ByProject = make([]ProjectStats, 0, len(a.projects))
+	for _, ps := range a.projects {
+		r.ByProject = append(r.ByProject, *ps)
+	}
+	sort.Slice(r.ByProject, func(i, j int) bool {
+		if r.ByProject[i].Sessions != r.ByProject[j].Sessions {
+			return r.ByProject[i].Sessions > r.ByProject[j].Sessions
+		}
+		return r.ByProject[i].Name < r.ByProject[j].Name
+	})
+
+	r.ByDay = make([]DayStats, 0, len(a.days))
+	for _, ds := range a.days {
+		r.ByDay = append(r.ByDay, *ds)
+	}
+	sort.Slice(r.ByDay, func(i, j int) bool { return r.ByDay[i].Date < r.ByDay[j].Date })
+
+	return &r
+}
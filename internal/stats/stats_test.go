@@ -0,0 +1,155 @@
+package stats
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/pricing"
+)
+
+func writeSession(t *testing.T, dir, project, id string, lines ...string) {
+	t.Helper()
+	projectDir := filepath.Join(dir, project)
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Join(lines, "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, id+".jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setupProjects(t *testing.T) string {
+	dir := t.TempDir()
+	writeSession(t, dir, "-work-api", "s1",
+		`{"type":"user","timestamp":"2026-03-01T10:00:00Z","uuid":"u1","gitBranch":"main","message":{"content":"Run the tests"}}`,
+		`{"type":"assistant","timestamp":"2026-03-01T10:00:05Z","uuid":"a1","parentUuid":"u1","message":{"model":"claude-sonnet-4-5","usage":{"input_tokens":100,"output_tokens":20,"cache_read_input_tokens":1000},"content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"go test"}}]}}`,
+		`{"type":"user","timestamp":"2026-03-01T10:00:10Z","uuid":"r1","parentUuid":"a1","message":{"content":[{"type":"tool_result","tool_use_id":"t1","is_error":true,"content":"FAIL"}]}}`,
+		`{"type":"assistant","timestamp":"2026-03-01T10:00:20Z","uuid":"a2","parentUuid":"r1","message":{"model":"claude-sonnet-4-5","usage":{"input_tokens":50,"output_tokens":10},"content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"go test"}}]}}`,
+		`{"type":"user","timestamp":"2026-03-01T10:00:30Z","uuid":"c1","parentUuid":"a2","isCompactSummary":true,"message":{"content":"Summary of earlier work"}}`,
+	)
+	writeSession(t, dir, "-work-web", "s2",
+		`{"type":"user","timestamp":"2026-03-05T09:00:00Z","uuid":"u1","gitBranch":"feature","message":{"content":"Fix the CSS"}}`,
+		`{"type":"assistant","timestamp":"2026-03-05T09:01:00Z","uuid":"a1","parentUuid":"u1","message":{"model":"claude-opus-4-1","usage":{"input_tokens":10,"output_tokens":500,"cache_creation_input_tokens":200},"content":[{"type":"tool_use","id":"t1","name":"Edit","input":{}}]}}`,
+	)
+	return dir
+}
+
+func TestCollect(t *testing.T) {
+	dir := setupProjects(t)
+
+	r, err := Collect(dir, Filter{}, pricing.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Sessions != 2 || r.Projects != 2 || r.UserPrompts != 2 || r.Messages != 5 {
+		t.Errorf("totals = %d sessions, %d projects, %d prompts, %d messages", r.Sessions, r.Projects, r.UserPrompts, r.Messages)
+	}
+	want := parser.Usage{InputTokens: 160, OutputTokens: 530, CacheReadTokens: 1000, CacheCreateTokens: 200}
+	if r.Tokens != want {
+		t.Errorf("Tokens = %+v, want %+v", r.Tokens, want)
+	}
+	if r.Compactions != 1 || r.CompactedSessions != 1 || r.CompactionsPerSession() != 0.5 {
+		t.Errorf("compactions = %d in %d sessions", r.Compactions, r.CompactedSessions)
+	}
+	// sonnet-4-5: 150 in, 30 out, 1000 cache read; opus-4-1: 10 in, 500 out, 200 cache write
+	if math.Abs(r.Cost.USD-0.0426) > 1e-9 || math.Abs(r.ByModel[1].Cost.USD-0.0414) > 1e-9 {
+		t.Errorf("Cost = %+v, by model %+v", r.Cost, r.ByModel)
+	}
+	if r.AvgDurationSeconds != 45 {
+		t.Errorf("AvgDurationSeconds = %v, want 45", r.AvgDurationSeconds)
+	}
+
+	if len(r.ByModel) != 2 || r.ByModel[0].Model != "claude-sonnet-4-5" || r.ByModel[0].Messages != 2 || r.ByModel[0].Tokens.InputTokens != 150 {
+		t.Errorf("ByModel = %+v", r.ByModel)
+	}
+	if len(r.ByTool) != 2 || r.ByTool[0] != (ToolStats{Name: "Bash", Calls: 2, Errors: 1, ErrorRate: 0.5}) {
+		t.Errorf("ByTool = %+v", r.ByTool)
+	}
+	if len(r.ByDay) != 2 || r.ByDay[0].Sessions != 1 || r.ByDay[0].Date > r.ByDay[1].Date {
+		t.Errorf("ByDay = %+v", r.ByDay)
+	}
+}
+
+func TestCollectFilters(t *testing.T) {
+	dir := setupProjects(t)
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		sessions int
+	}{
+		{"project name", Filter{Project: "web"}, 1},
+		{"encoded name", Filter{Project: "-work-api"}, 1},
+		{"branch", Filter{Branch: "FEATURE"}, 1},
+		{"after", Filter{After: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)}, 1},
+		{"before", Filter{Before: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)}, 1},
+		{"empty range", Filter{After: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Collect(dir, tt.filter, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Sessions != tt.sessions {
+				t.Errorf("Sessions = %d, want %d", r.Sessions, tt.sessions)
+			}
+		})
+	}
+
+	// Messages outside the range are not counted, even in a matching session
+	r, _ := Collect(dir, Filter{Before: time.Date(2026, 3, 1, 10, 0, 15, 0, time.UTC)}, nil)
+	if r.Messages != 2 || r.ToolCalls != 1 || r.Tokens.InputTokens != 100 {
+		t.Errorf("partial range = %d messages, %d tool calls, %+v", r.Messages, r.ToolCalls, r.Tokens)
+	}
+}
+
+func TestAggregatorSessions(t *testing.T) {
+	dir := setupProjects(t)
+
+	a, err := Aggregate(dir, Filter{}, pricing.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// s1 has three main-conversation messages in one hour, s2 two
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC).Local()
+	if n := a.Report().Activity[start.Weekday()][start.Hour()]; n != 3 {
+		t.Errorf("Activity at s1 = %d, want 3", n)
+	}
+
+	tests := []struct {
+		name  string
+		match Match
+		want  []string
+	}{
+		{"all, newest first", Match{}, []string{"s2", "s1"}},
+		{"day", Match{Day: start.Format("2006-01-02")}, []string{"s1"}},
+		{"model", Match{Model: "claude-opus-4-1"}, []string{"s2"}},
+		{"tool", Match{Tool: "Bash"}, []string{"s1"}},
+		{"slot", Match{Slot: &Slot{Weekday: start.Weekday(), Hour: start.Hour()}}, []string{"s1"}},
+		{"no match", Match{Tool: "Read"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, s := range a.Sessions(tt.match) {
+				got = append(got, s.ID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Sessions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	s1 := a.Sessions(Match{Tool: "Bash"})[0]
+	if s1.Project != "-work-api" || s1.ToolCalls != 2 || s1.Messages != 3 || s1.Tokens.InputTokens != 150 {
+		t.Errorf("s1 summary = %+v", s1)
+	}
+}
@@ -0,0 +1,38 @@
+// Package testutil holds helpers shared by the tests of other packages.
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+// ParseSession writes content, the JSONL of a transcript, to a temporary
+// s1.jsonl and parses it
+func ParseSession(t testing.TB, content string) *parser.Session {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "s1.jsonl")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	session, err := parser.ParseSession(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return session
+}
+
+// AppendFile appends data to the file at path, as a session being written
+func AppendFile(t testing.TB, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,521 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+type pane int
+
+const (
+	paneProjects pane = iota
+	paneSessions
+	paneConversation
+)
+
+// App is the browser's state, kept apart from the terminal so it can be
+// driven by keys and rendered to lines in tests
+type App struct {
+	projectsDir string
+	projects    []*parser.Project
+	sessions    []*parser.Session // Of the project under the cursor
+	projList    list
+	sessList    list
+
+	session *parser.Session // Fully parsed, shown in the conversation pane
+	conv    *conversation
+
+	focus     pane
+	inputting bool
+	input     string
+	status    string
+	help      bool
+	tail      bool
+	tailSize  int64
+	quit      bool
+}
+
+// NewApp lists the projects under projectsDir, most recently active first
+func NewApp(projectsDir string) (*App, error) {
+	projects, err := parser.DiscoverProjects(projectsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover projects: %w", err)
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].LastModified.After(projects[j].LastModified)
+	})
+
+	a := &App{projectsDir: projectsDir, projects: projects}
+	items := make([]listItem, len(projects))
+	for i, p := range projects {
+		items[i] = listItem{
+			title:  p.Name,
+			detail: fmt.Sprintf("%d", len(p.Sessions)),
+			search: p.Name + " " + p.EncodedName,
+		}
+	}
+	a.projList.setItems(items)
+	a.loadSessions()
+	return a, nil
+}
+
+// loadSessions fills the session list from the project under the cursor
+func (a *App) loadSessions() {
+	a.sessions = nil
+	if i := a.projList.selected(); i >= 0 {
+		a.sessions = append(a.sessions, a.projects[i].Sessions...)
+	}
+	sort.Slice(a.sessions, func(i, j int) bool {
+		return a.sessions[i].EndTime.After(a.sessions[j].EndTime)
+	})
+	items := make([]listItem, len(a.sessions))
+	for i, s := range a.sessions {
+		title := s.Summary
+		if title == "" {
+			title = s.ID
+		}
+		items[i] = listItem{
+			title:  title,
+			detail: shortAge(s.EndTime),
+			search: strings.Join([]string{s.Summary, s.ID, s.Slug, s.GitBranch}, " "),
+		}
+	}
+	a.sessList.cursor, a.sessList.offset = 0, 0
+	a.sessList.filter = ""
+	a.sessList.setItems(items)
+}
+
+// OpenSession selects s in the lists and shows it
+func (a *App) OpenSession(s *parser.Session) error {
+	for i, p := range a.projects {
+		if p.EncodedName != s.ProjectName && p.Name != s.ProjectName {
+			continue
+		}
+		for n, idx := range a.projList.visible {
+			if idx == i {
+				a.projList.cursor = n
+			}
+		}
+		a.loadSessions()
+		for n, sess := range a.sessions {
+			if sess.ID == s.ID {
+				a.sessList.cursor = n
+			}
+		}
+	}
+	return a.open(s)
+}
+
+func (a *App) open(s *parser.Session) error {
+	full, err := parser.ParseSession(s.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to parse session: %w", err)
+	}
+	a.session = full
+	a.conv = newConversation(full)
+	a.tailSize = fileSize(s.FilePath)
+	a.focus = paneConversation
+	a.help = false
+	return nil
+}
+
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return -1
+	}
+	return info.Size()
+}
+
+// Tick re-reads the open session while live tail is on, following it to
+// the end when it grows
+func (a *App) Tick() {
+	if !a.tail || a.session == nil {
+		return
+	}
+	size := fileSize(a.session.FilePath)
+	if size == a.tailSize {
+		return
+	}
+	full, err := parser.ParseSession(a.session.FilePath)
+	if err != nil {
+		a.status = err.Error()
+		return
+	}
+	a.tailSize = size
+	a.session = full
+	a.conv.load(full)
+	a.conv.relayout()
+	a.conv.cursor = max(len(a.conv.lines)-1, 0)
+}
+
+// Quit reports whether the user asked to leave
+func (a *App) Quit() bool {
+	return a.quit
+}
+
+// HandleKey applies one key press
+func (a *App) HandleKey(k Key) {
+	a.status = ""
+	if a.inputting {
+		a.handleInput(k)
+		return
+	}
+
+	switch {
+	case k.is('q') || k.ctrl('c'):
+		a.quit = true
+		return
+	case k.is('?'):
+		a.help = !a.help
+		return
+	case k.Code == KeyTab:
+		a.cycleFocus(1)
+		return
+	case k.Code == KeyBackTab:
+		a.cycleFocus(-1)
+		return
+	case k.is('/'):
+		a.inputting, a.input = true, ""
+		return
+	case k.is('w'):
+		if a.session == nil {
+			a.status = "Open a session to tail it"
+			return
+		}
+		a.tail = !a.tail
+		if a.tail {
+			a.tailSize = -1 // Reload now
+			a.Tick()
+		}
+		return
+	}
+
+	switch a.focus {
+	case paneProjects, paneSessions:
+		a.handleListKey(k)
+	case paneConversation:
+		a.handleConversationKey(k)
+	}
+}
+
+func (a *App) cycleFocus(dir int) {
+	n := 3
+	if a.conv == nil {
+		n = 2
+	}
+	a.focus = pane((int(a.focus) + dir + n) % n)
+}
+
+func (a *App) activeList() *list {
+	if a.focus == paneProjects {
+		return &a.projList
+	}
+	return &a.sessList
+}
+
+func (a *App) handleListKey(k Key) {
+	l := a.activeList()
+	before := a.projList.selected()
+	switch {
+	case k.is('j') || k.Code == KeyDown:
+		l.move(1)
+	case k.is('k') || k.Code == KeyUp:
+		l.move(-1)
+	case k.is('g') || k.Code == KeyHome:
+		l.cursor = 0
+	case k.is('G') || k.Code == KeyEnd:
+		l.cursor = max(len(l.visible)-1, 0)
+	case k.ctrl('d') || k.Code == KeyPgDn:
+		l.move(10)
+	case k.ctrl('u') || k.Code == KeyPgUp:
+		l.move(-10)
+	case k.Code == KeyEsc:
+		l.setFilter("")
+	case k.is('h') || k.Code == KeyLeft:
+		if a.focus == paneSessions {
+			a.focus = paneProjects
+		}
+	case k.Code == KeyEnter || k.is('l') || k.Code == KeyRight:
+		if a.focus == paneProjects {
+			a.focus = paneSessions
+			return
+		}
+		if i := a.sessList.selected(); i >= 0 {
+			if err := a.open(a.sessions[i]); err != nil {
+				a.status = err.Error()
+			}
+		}
+	}
+	if a.focus == paneProjects && a.projList.selected() != before {
+		a.loadSessions()
+	}
+}
+
+func (a *App) handleConversationKey(k Key) {
+	c := a.conv
+	if c == nil {
+		return
+	}
+	page := 20
+	switch {
+	case k.is('j') || k.Code == KeyDown:
+		c.move(1)
+	case k.is('k') || k.Code == KeyUp:
+		c.move(-1)
+	case k.is('J') || k.is(']'):
+		c.jumpHeader(1)
+	case k.is('K') || k.is('['):
+		c.jumpHeader(-1)
+	case k.ctrl('d') || k.Code == KeyPgDn || k.is(' '):
+		c.move(page)
+	case k.ctrl('u') || k.Code == KeyPgUp:
+		c.move(-page)
+	case k.is('g') || k.Code == KeyHome:
+		c.cursor = 0
+	case k.is('G') || k.Code == KeyEnd:
+		c.cursor = max(len(c.lines)-1, 0)
+	case k.Code == KeyEnter || k.is('z'):
+		c.toggleFold()
+	case k.is('t'):
+		c.setAll(secThinking, !c.showThinking)
+	case k.is('o'):
+		c.setAll(secTool, !c.showTools)
+	case k.is('n'):
+		if !c.nextMatch(1) && c.query != "" {
+			a.status = "No matches for " + c.query
+		}
+	case k.is('N'):
+		c.nextMatch(-1)
+	case k.Code == KeyEsc:
+		if c.query != "" {
+			c.find("")
+			return
+		}
+		a.focus = paneSessions
+	case k.is('h') || k.Code == KeyLeft:
+		a.focus = paneSessions
+	}
+}
+
+func (a *App) handleInput(k Key) {
+	done := false
+	switch {
+	case k.Code == KeyEsc || k.ctrl('c'):
+		a.input = ""
+		done = true
+	case k.Code == KeyEnter:
+		done = true
+	case k.Code == KeyBackspace:
+		if r := []rune(a.input); len(r) > 0 {
+			a.input = string(r[:len(r)-1])
+		}
+	case k.Code == KeyRune:
+		a.input += string(k.Rune)
+	}
+
+	if a.focus == paneConversation {
+		if done {
+			a.inputting = false
+			if a.conv != nil {
+				a.conv.find(a.input)
+				if a.input != "" && !a.conv.nextMatch(1) {
+					a.status = "No matches for " + a.input
+				}
+			}
+		}
+		return
+	}
+	// Lists filter as you type
+	a.activeList().setFilter(a.input)
+	if a.focus == paneProjects {
+		a.loadSessions()
+	}
+	if done {
+		a.inputting = false
+	}
+}
+
+// View renders the whole screen
+func (a *App) View(width, height int) []string {
+	if width < 20 || height < 5 {
+		return []string{clip("ccx: terminal too small", width)}
+	}
+	bodyH := height - 1
+
+	var body []string
+	if width < 72 {
+		// Narrow: only the focused pane
+		p := a.focus
+		if a.help {
+			p = paneConversation
+		}
+		body = a.paneView(p, width, bodyH)
+	} else {
+		leftW := max(24, min(48, width/3))
+		rightW := width - leftW - 1
+		projH := max(3, (bodyH*2)/5)
+		left := append(a.paneView(paneProjects, leftW, projH), a.paneView(paneSessions, leftW, bodyH-projH)...)
+		right := a.paneView(paneConversation, rightW, bodyH)
+		for i := 0; i < bodyH; i++ {
+			body = append(body, left[i]+styleDim+"│"+styleReset+right[i])
+		}
+	}
+	return append(body, a.statusLine(width))
+}
+
+func (a *App) paneView(p pane, width, height int) []string {
+	focused := a.focus == p
+	titleStyle := styleDim
+	if focused {
+		titleStyle = styleBold + styleUser
+	}
+	title := func(s string) string {
+		return titleStyle + pad(" "+s, width) + styleReset
+	}
+
+	switch p {
+	case paneProjects:
+		t := fmt.Sprintf("Projects (%d)", len(a.projList.visible))
+		if a.projList.filter != "" {
+			t += "  /" + a.projList.filter
+		}
+		return append([]string{title(t)}, a.projList.view(width, height-1, focused)...)
+	case paneSessions:
+		t := fmt.Sprintf("Sessions (%d)", len(a.sessList.visible))
+		if a.sessList.filter != "" {
+			t += "  /" + a.sessList.filter
+		}
+		return append([]string{title(t)}, a.sessList.view(width, height-1, focused)...)
+	}
+
+	if a.help {
+		return append([]string{title("Keys")}, helpView(width, height-1)...)
+	}
+	if a.conv == nil {
+		lines := []string{title("Conversation")}
+		for len(lines) < height {
+			lines = append(lines, strings.Repeat(" ", width))
+		}
+		lines[min(2, height-1)] = styleDim + pad("  Pick a session and press Enter", width) + styleReset
+		return lines
+	}
+	t := a.session.Summary
+	if t == "" {
+		t = a.session.ID
+	}
+	t += fmt.Sprintf("  · %d msgs", a.session.Stats.MessageCount)
+	return append([]string{title(t)}, a.conv.view(width, height-1, focused)...)
+}
+
+var helpKeys = [][2]string{
+	{"Tab / S-Tab", "Next / previous pane"},
+	{"j k  ↓ ↑", "Move"},
+	{"g G", "Top / bottom"},
+	{"C-d C-u", "Page down / up"},
+	{"Enter l", "Open project or session"},
+	{"h Esc", "Back"},
+	{"/", "Filter list or search conversation"},
+	{"n N", "Next / previous match"},
+	{"J K  ] [", "Next / previous message"},
+	{"Enter z", "Fold or unfold block"},
+	{"t", "Show or hide all thinking"},
+	{"o", "Show or hide all tool calls"},
+	{"w", "Live tail on/off"},
+	{"?", "This help"},
+	{"q", "Quit"},
+}
+
+func helpView(width, height int) []string {
+	var out []string
+	for _, k := range helpKeys {
+		out = append(out, pad(fmt.Sprintf("  %-14s %s", k[0], k[1]), width))
+	}
+	for len(out) < height {
+		out = append(out, strings.Repeat(" ", width))
+	}
+	return out[:height]
+}
+
+func (a *App) statusLine(width int) string {
+	if a.inputting {
+		return pad("/"+a.input+"█", width)
+	}
+	right := "? help  q quit"
+	if a.tail {
+		right = styleError + "● LIVE" + styleReset + styleReverse + "  " + right
+	}
+	left := a.status
+	if left == "" && a.conv != nil && len(a.conv.matches) > 0 && a.focus == paneConversation {
+		left = fmt.Sprintf("match %d/%d  %q", a.conv.match+1, len(a.conv.matches), a.conv.query)
+	}
+	rightW := textWidth("? help  q quit")
+	if a.tail {
+		rightW += textWidth("● LIVE  ")
+	}
+	return styleReverse + pad(" "+left, width-rightW-1) + right + " " + styleReset
+}
+
+// shortAge is a compact relative time like 5m, 3h or 2d
+func shortAge(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+	return t.Format("Jan 2")
+}
+
+// Run opens the terminal and runs the browser until the user quits,
+// showing initial first when it is set
+func Run(projectsDir string, initial *parser.Session) error {
+	app, err := NewApp(projectsDir)
+	if err != nil {
+		return err
+	}
+	if initial != nil {
+		if err := app.OpenSession(initial); err != nil {
+			return err
+		}
+	}
+
+	t, err := Open()
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	// The tick also picks up window resizes
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	draw := func() {
+		w, h := t.Size()
+		t.Draw(app.View(w, h))
+	}
+	draw()
+	for !app.Quit() {
+		select {
+		case k, ok := <-t.Keys():
+			if !ok {
+				return nil
+			}
+			app.HandleKey(k)
+		case <-ticker.C:
+			app.Tick()
+		}
+		draw()
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testSession = `{"type":"summary","summary":"Fix the tests","leafUuid":"a2"}
+{"type":"user","timestamp":"2026-01-01T10:00:00Z","uuid":"u1","message":{"content":"Run the test suite"}}
+{"type":"assistant","timestamp":"2026-01-01T10:00:01Z","uuid":"a1","parentUuid":"u1","message":{"model":"claude-sonnet-4-5","content":[{"type":"thinking","thinking":"Probably a parser bug"},{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"go test ./..."}}]}}
+{"type":"user","timestamp":"2026-01-01T10:00:02Z","uuid":"r1","parentUuid":"a1","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"FAIL: TestParse","is_error":true}]}}
+{"type":"assistant","timestamp":"2026-01-01T10:00:03Z","uuid":"a2","parentUuid":"r1","message":{"model":"claude-sonnet-4-5","content":"TestParse fails on empty input"}}
+`
+
+func setupApp(t *testing.T) (*App, string) {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "projects")
+	projectDir := filepath.Join(dir, "-test-project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(projectDir, "session-1.jsonl")
+	if err := os.WriteFile(path, []byte(testSession), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewApp(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, path
+}
+
+func press(a *App, keys string) {
+	for _, k := range decodeKeys([]byte(keys)) {
+		a.HandleKey(k)
+	}
+}
+
+func screen(a *App) string {
+	return strings.Join(a.View(120, 30), "\n")
+}
+
+func TestDecodeKeys(t *testing.T) {
+	keys := decodeKeys([]byte("j\x1b[A\x1b[6~\r\x04é\x1b[Z\x1b"))
+	want := []Key{
+		{Code: KeyRune, Rune: 'j'},
+		{Code: KeyUp},
+		{Code: KeyPgDn},
+		{Code: KeyEnter},
+		{Code: KeyCtrl, Rune: 'd'},
+		{Code: KeyRune, Rune: 'é'},
+		{Code: KeyBackTab},
+		{Code: KeyEsc},
+	}
+	if len(keys) != len(want) {
+		t.Fatalf("got %d keys %v, want %d", len(keys), keys, len(want))
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("key %d = %+v, want %+v", i, keys[i], want[i])
+		}
+	}
+}
+
+func TestWrapAndClip(t *testing.T) {
+	lines := wrap("the quick brown fox jumps", 10)
+	for _, l := range lines {
+		if textWidth(l) > 10 {
+			t.Errorf("line %q wider than 10", l)
+		}
+	}
+	if got := strings.Join(lines, "|"); got != "the quick|brown fox|jumps" {
+		t.Errorf("wrap = %q", got)
+	}
+	if got := clip("日本語テキスト", 7); textWidth(got) > 7 || !strings.HasSuffix(got, "…") {
+		t.Errorf("clip = %q (width %d)", got, textWidth(got))
+	}
+	if got := sanitize("a\x1b[31mred\x1b[0m\tb"); got != "ared    b" {
+		t.Errorf("sanitize = %q", got)
+	}
+}
+
+func TestApp_BrowseFoldSearch(t *testing.T) {
+	a, _ := setupApp(t)
+
+	out := screen(a)
+	if !strings.Contains(out, "test-project") || !strings.Contains(out, "Fix the tests") {
+		t.Fatalf("lists missing project or session:\n%s", out)
+	}
+
+	// Projects -> sessions -> open
+	press(a, "\r\r")
+	if a.focus != paneConversation || a.conv == nil {
+		t.Fatalf("focus = %v, conversation open = %v", a.focus, a.conv != nil)
+	}
+	out = screen(a)
+	if !strings.Contains(out, "Run the test suite") || !strings.Contains(out, "$ go test ./...") {
+		t.Errorf("conversation missing prompt or tool call:\n%s", out)
+	}
+	if strings.Contains(out, "Probably a parser bug") || strings.Contains(out, "FAIL: TestParse") {
+		t.Error("thinking and tool blocks should start folded")
+	}
+
+	press(a, "t")
+	if !strings.Contains(screen(a), "Probably a parser bug") {
+		t.Error("t should unfold thinking")
+	}
+
+	// Searching unfolds the first matching block, the tool result
+	press(a, "/fail\r")
+	if len(a.conv.matches) != 2 {
+		t.Fatalf("matches = %v, want tool result and reply", a.conv.matches)
+	}
+	// The match itself is highlighted, so look past it
+	if !strings.Contains(screen(a), ": TestParse") {
+		t.Error("search should unfold the tool block holding the match")
+	}
+
+	// Fold it again from the cursor
+	press(a, "z")
+	if strings.Contains(screen(a), ": TestParse") {
+		t.Error("z should fold the block under the cursor")
+	}
+
+	press(a, "\x1b\x1b")
+	if a.focus != paneSessions || a.conv.query != "" {
+		t.Errorf("Esc should clear search then go back; focus = %v, query = %q", a.focus, a.conv.query)
+	}
+
+	press(a, "/nomatch")
+	if len(a.sessList.visible) != 0 {
+		t.Errorf("filter should hide sessions, visible = %v", a.sessList.visible)
+	}
+	press(a, "\x1b")
+	if len(a.sessList.visible) != 1 || a.inputting {
+		t.Errorf("Esc should clear the filter, visible = %v", a.sessList.visible)
+	}
+
+	press(a, "q")
+	if !a.Quit() {
+		t.Error("q should quit")
+	}
+}
+
+func TestApp_LiveTail(t *testing.T) {
+	a, path := setupApp(t)
+	press(a, "\r\rw")
+	if !a.tail {
+		t.Fatal("w should turn live tail on")
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.WriteString(`{"type":"user","timestamp":"2026-01-01T10:00:04Z","uuid":"u2","parentUuid":"a2","message":{"content":"Now fix it"}}` + "\n")
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a.Tick()
+	if !strings.Contains(screen(a), "Now fix it") {
+		t.Error("tail should pick up the appended message")
+	}
+	if a.conv.cursor != len(a.conv.lines)-1 {
+		t.Errorf("cursor = %d, want last line %d", a.conv.cursor, len(a.conv.lines)-1)
+	}
+}
@@ -0,0 +1,447 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+const maxResultLines = 200 // Tool result lines shown before the rest is elided
+
+type sectionKind int
+
+const (
+	secHeader sectionKind = iota
+	secText
+	secThinking
+	secTool
+	secCompact
+	secMeta
+)
+
+// section is a piece of the conversation: a message header, a text block,
+// or a foldable thinking, tool or compaction block
+type section struct {
+	id     string // Message UUID and block index, stable across reloads
+	kind   sectionKind
+	title  string
+	body   string
+	indent int
+	style  string
+}
+
+func (s *section) foldable() bool {
+	return s.kind >= secThinking
+}
+
+type convLine struct {
+	text    string
+	style   string
+	section int
+}
+
+// conversation is the scrollable, foldable transcript of one session
+type conversation struct {
+	sections     []section
+	folds        map[string]bool // Explicitly folded (true) or unfolded (false)
+	showThinking bool
+	showTools    bool
+
+	lines  []convLine
+	width  int
+	cursor int
+	offset int
+
+	query   string
+	matches []int // Sections containing query
+	match   int
+}
+
+func newConversation(session *parser.Session) *conversation {
+	c := &conversation{folds: make(map[string]bool)}
+	c.load(session)
+	return c
+}
+
+// load replaces the transcript, keeping fold state and search
+func (c *conversation) load(session *parser.Session) {
+	c.sections = buildSections(session)
+	c.width = 0
+	if c.query != "" {
+		c.find(c.query)
+	}
+}
+
+func (c *conversation) folded(i int) bool {
+	s := &c.sections[i]
+	if !s.foldable() {
+		return false
+	}
+	if f, ok := c.folds[s.id]; ok {
+		return f
+	}
+	switch s.kind {
+	case secThinking:
+		return !c.showThinking
+	case secTool:
+		return !c.showTools
+	}
+	return true
+}
+
+func buildSections(session *parser.Session) []section {
+	results := make(map[string]parser.ContentBlock)
+	var collect func(msgs []*parser.Message)
+	collect = func(msgs []*parser.Message) {
+		for _, msg := range msgs {
+			for _, block := range msg.Content {
+				if block.Type == "tool_result" && block.ToolID != "" {
+					results[block.ToolID] = block
+				}
+			}
+			collect(msg.Children)
+		}
+	}
+	collect(session.RootMessages)
+
+	var sections []section
+	var walk func(msgs []*parser.Message, indent int)
+	walk = func(msgs []*parser.Message, indent int) {
+		for _, msg := range msgs {
+			msgIndent := indent
+			if msg.IsSidechain {
+				msgIndent = indent + 1
+			}
+			if msg.Kind != parser.KindToolResult {
+				sections = append(sections, messageSections(msg, msgIndent, results)...)
+			}
+			walk(msg.Children, indent)
+		}
+	}
+	walk(session.RootMessages, 0)
+	return sections
+}
+
+func messageSections(msg *parser.Message, indent int, results map[string]parser.ContentBlock) []section {
+	id := msg.UUID
+	ts := msg.Timestamp.Format("15:04:05")
+
+	switch msg.Kind {
+	case parser.KindCompactSummary:
+		return []section{{id: id, kind: secCompact, title: "◇ Context compacted", body: blockText(msg), indent: indent, style: styleDim}}
+	case parser.KindMeta:
+		return []section{{id: id, kind: secMeta, title: "▽ System instructions", body: blockText(msg), indent: indent, style: styleDim}}
+	case parser.KindCommand:
+		title := strings.TrimSpace(msg.CommandName + " " + msg.CommandArgs)
+		if title == "" {
+			title = "/command"
+		}
+		return []section{{id: id, kind: secHeader, title: "⌘ " + title + "  " + ts, indent: indent, style: styleBold + styleUser}}
+	}
+
+	header := section{id: id, kind: secHeader, indent: indent}
+	switch {
+	case msg.IsSidechain:
+		header.title, header.style = "◆ AGENT  "+ts, styleBold+styleAgent
+	case msg.Kind == parser.KindUserPrompt:
+		header.title, header.style = "▶ USER  "+ts, styleBold+styleUser
+	default:
+		header.title, header.style = "● ASSISTANT  "+ts, styleBold+styleReply
+		if msg.Model != "" {
+			header.title += "  " + msg.Model
+		}
+	}
+	sections := []section{header}
+
+	for i, block := range msg.Content {
+		bid := fmt.Sprintf("%s/%d", id, i)
+		switch block.Type {
+		case "text":
+			if strings.TrimSpace(block.Text) != "" {
+				sections = append(sections, section{id: bid, kind: secText, body: block.Text, indent: indent + 1})
+			}
+		case "thinking":
+			if strings.TrimSpace(block.Text) != "" {
+				sections = append(sections, section{id: bid, kind: secThinking, title: "∴ Thinking", body: block.Text, indent: indent + 1, style: styleDim})
+			}
+		case "tool_use":
+			sections = append(sections, toolSection(bid, block, results[block.ToolID], indent+1))
+		case "image":
+			sections = append(sections, section{id: bid, kind: secText, body: "[image]", indent: indent + 1, style: styleDim})
+		}
+	}
+	return sections
+}
+
+func toolSection(id string, block, result parser.ContentBlock, indent int) section {
+	s := section{id: id, kind: secTool, indent: indent, style: styleTool}
+	s.title = "◎ " + block.ToolName
+	if preview := toolPreview(block.ToolName, block.ToolInput); preview != "" {
+		s.title += "  " + preview
+	}
+	if block.Sidechain != nil {
+		s.title += fmt.Sprintf("  (%d agent messages)", block.Sidechain.Stats.MessageCount)
+	}
+
+	var body strings.Builder
+	if input, err := json.MarshalIndent(block.ToolInput, "", "  "); err == nil && block.ToolInput != nil {
+		body.Write(input)
+	}
+	if result.ToolID != "" {
+		if result.IsError {
+			s.style = styleError
+			s.title += "  ✗"
+		}
+		text := strings.TrimRight(result.ResultText(), "\n")
+		lines := strings.Split(text, "\n")
+		if len(lines) > maxResultLines {
+			lines = append(lines[:maxResultLines], fmt.Sprintf("… %d more lines", len(lines)-maxResultLines))
+		}
+		body.WriteString("\n── result ──\n")
+		body.WriteString(strings.Join(lines, "\n"))
+	}
+	s.body = body.String()
+	return s
+}
+
+// toolPreview is the one argument that says what a tool call did
+func toolPreview(name string, input any) string {
+	m, ok := input.(map[string]any)
+	if !ok {
+		return ""
+	}
+	keys := []string{"file_path", "notebook_path", "pattern", "url", "query", "description", "skill", "prompt"}
+	if name == "Bash" {
+		keys = []string{"command"}
+	}
+	for _, k := range keys {
+		if v, ok := m[k].(string); ok && v != "" {
+			v = strings.Join(strings.Fields(v), " ")
+			if name == "Bash" {
+				v = "$ " + v
+			}
+			return v
+		}
+	}
+	return ""
+}
+
+func blockText(msg *parser.Message) string {
+	var parts []string
+	for _, block := range msg.Content {
+		if block.Type == "text" && block.Text != "" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// layout wraps the sections to width, keeping the cursor on its section
+func (c *conversation) layout(width int) {
+	if width == c.width && c.lines != nil {
+		return
+	}
+	cur := -1
+	if c.cursor < len(c.lines) {
+		cur = c.lines[c.cursor].section
+	}
+	c.width = width
+	c.lines = c.lines[:0]
+	for i := range c.sections {
+		s := &c.sections[i]
+		pre := strings.Repeat("  ", s.indent)
+		if s.kind == secHeader && len(c.lines) > 0 {
+			c.lines = append(c.lines, convLine{section: i})
+		}
+		if s.title != "" {
+			title := s.title
+			if s.foldable() {
+				if c.folded(i) {
+					title = "▸ " + title
+				} else {
+					title = "▾ " + title
+				}
+			}
+			c.lines = append(c.lines, convLine{text: pre + sanitize(title), style: s.style, section: i})
+		}
+		if s.title != "" && c.folded(i) {
+			continue
+		}
+		bodyPre := pre
+		if s.title != "" {
+			bodyPre += "  "
+		}
+		style := ""
+		if s.kind == secThinking || s.kind == secCompact || s.kind == secMeta {
+			style = styleDim
+		}
+		for _, line := range wrap(sanitize(s.body), width-textWidth(bodyPre)) {
+			if s.body == "" {
+				break
+			}
+			c.lines = append(c.lines, convLine{text: bodyPre + line, style: style, section: i})
+		}
+	}
+	if cur >= 0 {
+		c.gotoSection(cur)
+	}
+	c.cursor = min(c.cursor, max(len(c.lines)-1, 0))
+}
+
+func (c *conversation) gotoSection(i int) {
+	for n, line := range c.lines {
+		if line.section == i && line.text != "" {
+			c.cursor = n
+			return
+		}
+	}
+}
+
+func (c *conversation) move(delta int) {
+	c.cursor = max(0, min(len(c.lines)-1, c.cursor+delta))
+}
+
+// jumpHeader moves to the next or previous message header
+func (c *conversation) jumpHeader(dir int) {
+	for n := c.cursor + dir; n >= 0 && n < len(c.lines); n += dir {
+		l := c.lines[n]
+		if l.text != "" && c.sections[l.section].kind == secHeader {
+			c.cursor = n
+			return
+		}
+	}
+}
+
+// toggleFold folds or unfolds the block under the cursor
+func (c *conversation) toggleFold() {
+	if c.cursor >= len(c.lines) {
+		return
+	}
+	i := c.lines[c.cursor].section
+	if !c.sections[i].foldable() {
+		return
+	}
+	c.folds[c.sections[i].id] = !c.folded(i)
+	c.relayout()
+	c.gotoSection(i)
+}
+
+// setAll shows or hides every thinking or tool block
+func (c *conversation) setAll(kind sectionKind, show bool) {
+	for _, s := range c.sections {
+		if s.kind == kind {
+			delete(c.folds, s.id)
+		}
+	}
+	if kind == secThinking {
+		c.showThinking = show
+	} else {
+		c.showTools = show
+	}
+	c.relayout()
+}
+
+// relayout rewraps at the last width after folds change
+func (c *conversation) relayout() {
+	w := c.width
+	if w == 0 {
+		w = 80
+	}
+	c.width = 0
+	c.layout(w)
+}
+
+// find records the sections containing query and jumps to the first one
+// at or after the cursor
+func (c *conversation) find(query string) {
+	c.query = query
+	c.matches = c.matches[:0]
+	c.match = -1
+	if query == "" {
+		return
+	}
+	q := strings.ToLower(query)
+	for i, s := range c.sections {
+		if strings.Contains(strings.ToLower(s.title), q) || strings.Contains(strings.ToLower(s.body), q) {
+			c.matches = append(c.matches, i)
+		}
+	}
+}
+
+// nextMatch unfolds and moves to the next or previous matching section
+func (c *conversation) nextMatch(dir int) bool {
+	if len(c.matches) == 0 {
+		return false
+	}
+	cur := -1
+	if c.cursor < len(c.lines) {
+		cur = c.lines[c.cursor].section
+	}
+	next := -1
+	if dir > 0 {
+		for n, i := range c.matches {
+			if i > cur {
+				next = n
+				break
+			}
+		}
+		if next < 0 {
+			next = 0
+		}
+	} else {
+		for n := len(c.matches) - 1; n >= 0; n-- {
+			if c.matches[n] < cur {
+				next = n
+				break
+			}
+		}
+		if next < 0 {
+			next = len(c.matches) - 1
+		}
+	}
+	c.match = next
+	i := c.matches[next]
+	if c.sections[i].foldable() && c.folded(i) {
+		c.folds[c.sections[i].id] = false
+		c.relayout()
+	}
+	c.gotoSection(i)
+	// Land on the first matching line within the section
+	q := strings.ToLower(c.query)
+	for n := c.cursor; n < len(c.lines) && c.lines[n].section == i; n++ {
+		if strings.Contains(strings.ToLower(c.lines[n].text), q) {
+			c.cursor = n
+			break
+		}
+	}
+	return true
+}
+
+// view renders height lines, scrolled to keep the cursor visible
+func (c *conversation) view(width, height int, focused bool) []string {
+	c.layout(width)
+	if c.cursor < c.offset {
+		c.offset = c.cursor
+	}
+	if c.cursor >= c.offset+height {
+		c.offset = c.cursor - height + 1
+	}
+	c.offset = max(0, min(c.offset, len(c.lines)-height))
+
+	out := make([]string, 0, height)
+	for n := c.offset; n < len(c.lines) && len(out) < height; n++ {
+		l := c.lines[n]
+		text := pad(l.text, width)
+		style := l.style
+		if focused && n == c.cursor {
+			style += styleReverse
+		}
+		out = append(out, style+highlight(text, c.query, style)+styleReset)
+	}
+	for len(out) < height {
+		out = append(out, strings.Repeat(" ", width))
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+package tui
+
+import (
+	"strings"
+	"unicode"
+)
+
+// fuzzyMatch scores text against every space-separated term of query,
+// each of which must appear in order, case-insensitively, though not
+// necessarily contiguously. ok is false when some term doesn't match.
+func fuzzyMatch(query, text string) (score int, ok bool) {
+	t := []rune(strings.ToLower(text))
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		s, ok := fuzzyTerm([]rune(term), t)
+		if !ok {
+			return 0, false
+		}
+		score += s
+	}
+	return score, true
+}
+
+// fuzzyTerm tries each place the term's first rune occurs and keeps the
+// best greedy match. Runs of consecutive runes and matches at the start
+// of a word score higher; gaps cost a little.
+func fuzzyTerm(q, t []rune) (int, bool) {
+	best, found := 0, false
+	for start := range t {
+		if t[start] != q[0] {
+			continue
+		}
+		score, qi, last := 0, 0, -1
+		for i := start; i < len(t) && qi < len(q); i++ {
+			if t[i] != q[qi] {
+				continue
+			}
+			score++
+			if i == 0 || !isWordRune(t[i-1]) {
+				score += 8
+			}
+			if last >= 0 {
+				if i == last+1 {
+					score += 5
+				} else {
+					score -= min(i-last-1, 3)
+				}
+			}
+			last = i
+			qi++
+		}
+		if qi == len(q) && (!found || score > best) {
+			best, found = score, true
+		}
+	}
+	return best, found
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,91 @@
+package tui
+
+import (
+	"sort"
+	"strings"
+)
+
+type listItem struct {
+	title  string
+	detail string // Right-aligned, dimmed
+	search string // Text the filter matches against
+}
+
+// list is a scrollable, filterable selection
+type list struct {
+	items   []listItem
+	visible []int // Indices into items that pass the filter
+	filter  string
+	cursor  int // Index into visible
+	offset  int
+}
+
+func (l *list) setItems(items []listItem) {
+	l.items = items
+	l.applyFilter()
+}
+
+func (l *list) setFilter(f string) {
+	l.filter = f
+	l.applyFilter()
+}
+
+// applyFilter keeps the items fuzzy-matching the filter, best match first
+// and in their original order among equals
+func (l *list) applyFilter() {
+	l.visible = l.visible[:0]
+	scores := make(map[int]int)
+	for i, it := range l.items {
+		if score, ok := fuzzyMatch(l.filter, it.search); ok {
+			l.visible = append(l.visible, i)
+			scores[i] = score
+		}
+	}
+	if l.filter != "" {
+		sort.SliceStable(l.visible, func(a, b int) bool {
+			return scores[l.visible[a]] > scores[l.visible[b]]
+		})
+	}
+	l.cursor = max(0, min(l.cursor, len(l.visible)-1))
+}
+
+// selected is the item index under the cursor, or -1
+func (l *list) selected() int {
+	if l.cursor < len(l.visible) {
+		return l.visible[l.cursor]
+	}
+	return -1
+}
+
+func (l *list) move(delta int) {
+	l.cursor = max(0, min(len(l.visible)-1, l.cursor+delta))
+}
+
+func (l *list) view(width, height int, focused bool) []string {
+	if l.cursor < l.offset {
+		l.offset = l.cursor
+	}
+	if l.cursor >= l.offset+height {
+		l.offset = l.cursor - height + 1
+	}
+	l.offset = max(0, min(l.offset, len(l.visible)-height))
+
+	out := make([]string, 0, height)
+	for n := l.offset; n < len(l.visible) && len(out) < height; n++ {
+		it := l.items[l.visible[n]]
+		detail := clip(it.detail, width/3)
+		titleW := width - textWidth(detail) - 1
+		line := pad(" "+it.title, titleW) + " " + styleDim + detail + styleReset
+		switch {
+		case n == l.cursor && focused:
+			line = styleReverse + pad(" "+it.title, titleW) + " " + detail + styleReset
+		case n == l.cursor:
+			line = styleBold + pad(" "+it.title, titleW) + styleReset + " " + styleDim + detail + styleReset
+		}
+		out = append(out, line)
+	}
+	for len(out) < height {
+		out = append(out, strings.Repeat(" ", width))
+	}
+	return out
+}
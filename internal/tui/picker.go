@@ -0,0 +1,284 @@
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+// ErrCancelled is returned when the user leaves a picker without choosing
+var ErrCancelled = errors.New("cancelled")
+
+const (
+	previewPrompts = 5  // User prompts shown in the picker's preview
+	fallbackRows   = 20 // Sessions listed when reading a choice from stdin
+)
+
+// Candidate is a session offered by a picker
+type Candidate struct {
+	Session *parser.Session
+	Project string // Display name
+}
+
+func (c Candidate) title() string {
+	switch {
+	case c.Session.Summary != "":
+		return c.Session.Summary
+	case c.Session.Slug != "":
+		return c.Session.Slug
+	}
+	return c.Session.ID
+}
+
+func (c Candidate) search() string {
+	s := c.Session
+	return strings.Join([]string{s.Summary, s.Slug, c.Project, s.GitBranch, s.ID}, " ")
+}
+
+// IsTerminal reports whether stdin and stdout are both a terminal, so the
+// fuzzy picker can run
+func IsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// picker is a fuzzy finder over sessions with a preview of the one under
+// the cursor
+type picker struct {
+	cands   []Candidate
+	list    list
+	prompt  string
+	query   string
+	prompts map[string][]string // First user prompts by session file
+	done    bool
+	chosen  int // Index into cands, -1 if cancelled
+}
+
+func newPicker(cands []Candidate, prompt string) *picker {
+	p := &picker{cands: cands, prompt: prompt, prompts: make(map[string][]string), chosen: -1}
+	items := make([]listItem, len(cands))
+	for i, c := range cands {
+		items[i] = listItem{
+			title:  c.title(),
+			detail: c.Project + "  " + shortAge(c.Session.EndTime),
+			search: c.search(),
+		}
+	}
+	p.list.setItems(items)
+	return p
+}
+
+func (p *picker) handleKey(k Key) {
+	switch {
+	case k.Code == KeyEsc || k.ctrl('c') || k.ctrl('g'):
+		p.done = true
+	case k.Code == KeyEnter:
+		if i := p.list.selected(); i >= 0 {
+			p.chosen, p.done = i, true
+		}
+	case k.Code == KeyUp || k.ctrl('p') || k.ctrl('k'):
+		p.list.move(-1)
+	case k.Code == KeyDown || k.ctrl('n') || k.Code == KeyTab:
+		p.list.move(1)
+	case k.Code == KeyPgUp:
+		p.list.move(-10)
+	case k.Code == KeyPgDn:
+		p.list.move(10)
+	case k.Code == KeyBackspace:
+		if r := []rune(p.query); len(r) > 0 {
+			p.setQuery(string(r[:len(r)-1]))
+		}
+	case k.ctrl('u'):
+		p.setQuery("")
+	case k.ctrl('w'):
+		q := strings.TrimRight(p.query, " ")
+		p.setQuery(q[:strings.LastIndex(q, " ")+1])
+	case k.Code == KeyRune:
+		p.setQuery(p.query + string(k.Rune))
+	}
+}
+
+func (p *picker) setQuery(q string) {
+	p.query = q
+	p.list.cursor = 0
+	p.list.setFilter(q)
+}
+
+func (p *picker) view(width, height int) []string {
+	if width < 20 || height < 4 {
+		return []string{clip("ccx: terminal too small", width)}
+	}
+	count := fmt.Sprintf("%d/%d ", len(p.list.visible), len(p.cands))
+	lines := []string{
+		styleBold + pad(" "+p.prompt+" > "+p.query+"█", width-textWidth(count)) + styleReset + styleDim + count + styleReset,
+	}
+
+	bodyH := height - 2
+	if width < 80 {
+		lines = append(lines, p.list.view(width, bodyH, true)...)
+	} else {
+		listW := width * 3 / 5
+		previewW := width - listW - 1
+		left := p.list.view(listW, bodyH, true)
+		right := p.preview(previewW, bodyH)
+		for i := 0; i < bodyH; i++ {
+			lines = append(lines, left[i]+styleDim+"│"+styleReset+right[i])
+		}
+	}
+	return append(lines, styleDim+pad(" ↑↓ move  Enter select  Esc cancel  C-u clear", width)+styleReset)
+}
+
+// preview describes the session under the cursor and its first prompts
+func (p *picker) preview(width, height int) []string {
+	var out []string
+	add := func(text, style string) {
+		for _, l := range wrap(sanitize(text), width-2) {
+			out = append(out, style+pad(" "+l, width)+styleReset)
+		}
+	}
+
+	if i := p.list.selected(); i >= 0 {
+		c := p.cands[i]
+		s := c.Session
+		add(c.title(), styleBold)
+		out = append(out, strings.Repeat(" ", width))
+		field := func(name, value string) {
+			if value != "" {
+				add(fmt.Sprintf("%-9s %s", name, value), "")
+			}
+		}
+		field("Project", c.Project)
+		field("Branch", s.GitBranch)
+		field("Slug", s.Slug)
+		field("ID", s.ID)
+		if !s.StartTime.IsZero() {
+			field("Started", s.StartTime.Local().Format("2006-01-02 15:04"))
+			field("Duration", s.EndTime.Sub(s.StartTime).Round(time.Second).String())
+		}
+		field("Messages", strconv.Itoa(s.Stats.MessageCount))
+
+		if prompts := p.firstPrompts(s); len(prompts) > 0 {
+			out = append(out, strings.Repeat(" ", width))
+			for _, prompt := range prompts {
+				add("▶ "+strings.Join(strings.Fields(prompt), " "), styleUser)
+			}
+		}
+	}
+	for len(out) < height {
+		out = append(out, strings.Repeat(" ", width))
+	}
+	return out[:height]
+}
+
+// firstPrompts parses a session the first time it's previewed
+func (p *picker) firstPrompts(s *parser.Session) []string {
+	if prompts, ok := p.prompts[s.FilePath]; ok {
+		return prompts
+	}
+	var prompts []string
+	if full, err := parser.ParseSession(s.FilePath); err == nil {
+		var walk func(msgs []*parser.Message)
+		walk = func(msgs []*parser.Message) {
+			for _, msg := range msgs {
+				if len(prompts) == previewPrompts {
+					return
+				}
+				if msg.Kind == parser.KindUserPrompt && !msg.IsSidechain {
+					if text := blockText(msg); text != "" {
+						prompts = append(prompts, clip(text, 300))
+					}
+				}
+				walk(msg.Children)
+			}
+		}
+		walk(full.RootMessages)
+	}
+	p.prompts[s.FilePath] = prompts
+	return prompts
+}
+
+// Pick runs the fuzzy finder full-screen over cands and returns the chosen
+// session, or ErrCancelled
+func Pick(cands []Candidate, prompt string) (*parser.Session, error) {
+	if len(cands) == 0 {
+		return nil, fmt.Errorf("no sessions found")
+	}
+	t, err := Open()
+	if err != nil {
+		return nil, err
+	}
+	defer t.Close()
+
+	p := newPicker(cands, prompt)
+	// The tick picks up window resizes
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for !p.done {
+		w, h := t.Size()
+		t.Draw(p.view(w, h))
+		select {
+		case k, ok := <-t.Keys():
+			if !ok {
+				return nil, ErrCancelled
+			}
+			p.handleKey(k)
+		case <-ticker.C:
+		}
+	}
+	if p.chosen < 0 {
+		return nil, ErrCancelled
+	}
+	return cands[p.chosen].Session, nil
+}
+
+// PickFrom lists the most recent of cands on out and reads a choice from
+// in: a number from the list, or a search over every candidate that takes
+// the best match. An empty line cancels.
+func PickFrom(cands []Candidate, prompt string, in *bufio.Reader, out io.Writer) (*parser.Session, error) {
+	if len(cands) == 0 {
+		return nil, fmt.Errorf("no sessions found")
+	}
+	shown := min(len(cands), fallbackRows)
+	fmt.Fprintf(out, "%s:\n", prompt)
+	for i, c := range cands[:shown] {
+		fmt.Fprintf(out, "  %2d. [%s] %s  %s\n", i+1, c.Project, clip(c.title(), 60), shortAge(c.Session.EndTime))
+	}
+	if len(cands) > shown {
+		fmt.Fprintf(out, "  … %d more; type to search them all\n", len(cands)-shown)
+	}
+	fmt.Fprintf(out, "\nSelect session (1-%d or search): ", shown)
+
+	line, err := in.ReadString('\n')
+	if err != nil && line == "" {
+		return nil, fmt.Errorf("no input")
+	}
+	input := strings.TrimSpace(line)
+	if input == "" {
+		return nil, ErrCancelled
+	}
+	if n, err := strconv.Atoi(input); err == nil {
+		if n < 1 || n > shown {
+			return nil, fmt.Errorf("invalid selection: %s", input)
+		}
+		return cands[n-1].Session, nil
+	}
+
+	best, bestScore := -1, 0
+	for i, c := range cands {
+		if score, ok := fuzzyMatch(input, c.search()); ok && (best < 0 || score > bestScore) {
+			best, bestScore = i, score
+		}
+	}
+	if best < 0 {
+		return nil, fmt.Errorf("no session matches %q", input)
+	}
+	return cands[best].Session, nil
+}
@@ -0,0 +1,115 @@
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+func testCandidates() []Candidate {
+	now := time.Now()
+	return []Candidate{
+		{Project: "ccx", Session: &parser.Session{ID: "e38536a2", Summary: "Add fuzzy session picker", GitBranch: "main", EndTime: now}},
+		{Project: "webapp", Session: &parser.Session{ID: "f1a2b3c4", Summary: "Fix login redirect", Slug: "melodic-cooking-piglet", GitBranch: "auth-fix", EndTime: now.Add(-time.Hour)}},
+		{Project: "ccx", Session: &parser.Session{ID: "0b9c8d7e", Slug: "quiet-river-otter", EndTime: now.Add(-48 * time.Hour)}},
+	}
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	if _, ok := fuzzyMatch("fzp", "Add fuzzy session picker"); !ok {
+		t.Error("subsequence should match")
+	}
+	if _, ok := fuzzyMatch("pf", "Add fuzzy session picker"); ok {
+		t.Error("out-of-order runes should not match")
+	}
+	if _, ok := fuzzyMatch("login webapp", "Fix login redirect webapp"); !ok {
+		t.Error("every term should match anywhere")
+	}
+	if _, ok := fuzzyMatch("login ccx", "Fix login redirect webapp"); ok {
+		t.Error("a missing term should fail the match")
+	}
+	contiguous, _ := fuzzyMatch("fix", "Fix login")
+	scattered, _ := fuzzyMatch("fix", "Find the index")
+	if contiguous <= scattered {
+		t.Errorf("contiguous word-start match scored %d, scattered %d", contiguous, scattered)
+	}
+}
+
+func TestPicker(t *testing.T) {
+	p := newPicker(testCandidates(), "View session")
+	if len(p.list.visible) != 3 {
+		t.Fatalf("visible = %v, want all 3", p.list.visible)
+	}
+
+	for _, r := range "piglet" {
+		p.handleKey(Key{Code: KeyRune, Rune: r})
+	}
+	if len(p.list.visible) != 1 || p.list.selected() != 1 {
+		t.Fatalf("slug search: visible = %v", p.list.visible)
+	}
+	out := strings.Join(p.view(120, 20), "\n")
+	for _, want := range []string{"Fix login redirect", "auth-fix", "webapp", "1/3"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("view missing %q:\n%s", want, out)
+		}
+	}
+
+	p.handleKey(Key{Code: KeyCtrl, Rune: 'u'})
+	for _, r := range "ccx" {
+		p.handleKey(Key{Code: KeyRune, Rune: r})
+	}
+	// Loose matches elsewhere rank below the project name
+	if v := p.list.visible; len(v) < 2 || v[0] != 0 || v[1] != 2 {
+		t.Fatalf("project search: visible = %v, want [0 2 ...]", v)
+	}
+	p.handleKey(Key{Code: KeyDown})
+	p.handleKey(Key{Code: KeyEnter})
+	if !p.done || p.chosen != 2 {
+		t.Errorf("done = %v, chosen = %d, want 2", p.done, p.chosen)
+	}
+
+	p = newPicker(testCandidates(), "View session")
+	p.handleKey(Key{Code: KeyEsc})
+	if !p.done || p.chosen != -1 {
+		t.Errorf("Esc: done = %v, chosen = %d", p.done, p.chosen)
+	}
+}
+
+func TestPickFrom(t *testing.T) {
+	cands := testCandidates()
+	tests := []struct {
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"2\n", "f1a2b3c4", nil},
+		{"otter\n", "0b9c8d7e", nil},
+		{"login auth\n", "f1a2b3c4", nil},
+		{"\n", "", ErrCancelled},
+	}
+	for _, tt := range tests {
+		s, err := PickFrom(cands, "View session", bufio.NewReader(strings.NewReader(tt.input)), io.Discard)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%q: err = %v, want %v", tt.input, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.input, err)
+			continue
+		}
+		if s.ID != tt.want {
+			t.Errorf("%q: picked %s, want %s", tt.input, s.ID, tt.want)
+		}
+	}
+
+	if _, err := PickFrom(cands, "View session", bufio.NewReader(strings.NewReader("9\n")), io.Discard); err == nil {
+		t.Error("out-of-range number should fail")
+	}
+}
@@ -0,0 +1,235 @@
+// Package tui is ccx's full-screen terminal interface: a project and
+// session browser with a conversation pane, drawn with plain ANSI escapes
+// on the alternate screen.
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// KeyCode identifies a key that has no printable rune
+type KeyCode int
+
+const (
+	KeyRune KeyCode = iota // A printable character in Key.Rune
+	KeyCtrl                // Ctrl plus the letter in Key.Rune
+	KeyEnter
+	KeyEsc
+	KeyBackspace
+	KeyTab
+	KeyBackTab
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyHome
+	KeyEnd
+	KeyPgUp
+	KeyPgDn
+)
+
+// Key is one key press
+type Key struct {
+	Code KeyCode
+	Rune rune
+}
+
+func (k Key) is(r rune) bool {
+	return k.Code == KeyRune && k.Rune == r
+}
+
+func (k Key) ctrl(r rune) bool {
+	return k.Code == KeyCtrl && k.Rune == r
+}
+
+// Terminal is the controlling terminal in raw mode on the alternate screen
+type Terminal struct {
+	in    *os.File
+	out   *bufio.Writer
+	fd    int
+	state *term.State
+	keys  chan Key
+}
+
+// Open switches the terminal to raw mode and the alternate screen
+func Open() (*Terminal, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return nil, fmt.Errorf("not a terminal")
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enter raw mode: %w", err)
+	}
+	t := &Terminal{in: os.Stdin, out: bufio.NewWriterSize(os.Stdout, 64*1024), fd: fd, state: state, keys: make(chan Key, 64)}
+	t.out.WriteString("\x1b[?1049h\x1b[?25l")
+	_ = t.out.Flush()
+	go t.readKeys()
+	return t, nil
+}
+
+// Close restores the screen and the terminal mode
+func (t *Terminal) Close() {
+	t.out.WriteString("\x1b[0m\x1b[?25h\x1b[?1049l")
+	_ = t.out.Flush()
+	_ = term.Restore(t.fd, t.state)
+}
+
+// Keys delivers key presses until stdin closes
+func (t *Terminal) Keys() <-chan Key {
+	return t.keys
+}
+
+// Size is the terminal's width and height in cells
+func (t *Terminal) Size() (int, int) {
+	w, h, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || w <= 0 || h <= 0 {
+		return 80, 24
+	}
+	return w, h
+}
+
+// Draw replaces the screen with lines, which must already fit its width
+func (t *Terminal) Draw(lines []string) {
+	t.out.WriteString("\x1b[H")
+	for i, line := range lines {
+		if i > 0 {
+			t.out.WriteString("\r\n")
+		}
+		t.out.WriteString(line)
+		t.out.WriteString("\x1b[0m\x1b[K")
+	}
+	t.out.WriteString("\x1b[J")
+	_ = t.out.Flush()
+}
+
+func (t *Terminal) readKeys() {
+	defer close(t.keys)
+	buf := make([]byte, 256)
+	for {
+		n, err := t.in.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, k := range decodeKeys(buf[:n]) {
+			t.keys <- k
+		}
+	}
+}
+
+// Escape sequences for special keys, xterm and VT flavors
+var escapeKeys = map[string]KeyCode{
+	"[A": KeyUp, "[B": KeyDown, "[C": KeyRight, "[D": KeyLeft,
+	"OA": KeyUp, "OB": KeyDown, "OC": KeyRight, "OD": KeyLeft,
+	"[H": KeyHome, "[F": KeyEnd, "OH": KeyHome, "OF": KeyEnd,
+	"[1~": KeyHome, "[4~": KeyEnd, "[7~": KeyHome, "[8~": KeyEnd,
+	"[5~": KeyPgUp, "[6~": KeyPgDn, "[Z": KeyBackTab,
+}
+
+// decodeKeys splits one read from the terminal into key presses
+func decodeKeys(b []byte) []Key {
+	var keys []Key
+	s := string(b)
+	for len(s) > 0 {
+		c := s[0]
+		switch {
+		case c == 0x1b:
+			if len(s) == 1 {
+				keys = append(keys, Key{Code: KeyEsc})
+				s = ""
+				continue
+			}
+			seq, rest := splitEscape(s[1:])
+			if code, ok := escapeKeys[seq]; ok {
+				keys = append(keys, Key{Code: code})
+			} else if seq == "" {
+				keys = append(keys, Key{Code: KeyEsc})
+			}
+			s = rest
+			continue
+		case c == '\r' || c == '\n':
+			keys = append(keys, Key{Code: KeyEnter})
+		case c == '\t':
+			keys = append(keys, Key{Code: KeyTab})
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, Key{Code: KeyBackspace})
+		case c < 0x20:
+			keys = append(keys, Key{Code: KeyCtrl, Rune: rune('a' + c - 1)})
+		default:
+			r, size := utf8.DecodeRuneInString(s)
+			keys = append(keys, Key{Code: KeyRune, Rune: r})
+			s = s[size:]
+			continue
+		}
+		s = s[1:]
+	}
+	return keys
+}
+
+// splitEscape takes the body of a CSI or SS3 sequence following ESC. A
+// lone ESC followed by other input yields an empty sequence.
+func splitEscape(s string) (seq, rest string) {
+	switch s[0] {
+	case 'O':
+		if len(s) >= 2 {
+			return s[:2], s[2:]
+		}
+	case '[':
+		for i := 1; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return s[:i+1], s[i+1:]
+			}
+		}
+		return s, ""
+	}
+	return "", s
+}
+
+// Styles for drawn text
+const (
+	styleBold    = "\x1b[1m"
+	styleDim     = "\x1b[2m"
+	styleReverse = "\x1b[7m"
+	styleUser    = "\x1b[38;5;173m"
+	styleReply   = "\x1b[38;5;108m"
+	styleTool    = "\x1b[38;5;179m"
+	styleError   = "\x1b[38;5;167m"
+	styleAgent   = "\x1b[38;5;140m"
+	styleMatch   = "\x1b[38;5;0;48;5;179m"
+	styleReset   = "\x1b[0m"
+)
+
+// sanitize drops control characters and escape sequences from transcript
+// text so it can't move the cursor or restyle the screen
+func sanitize(s string) string {
+	if !strings.ContainsFunc(s, func(r rune) bool { return r < 0x20 || r == 0x7f }) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == 0x1b:
+			if i+1 < len(s) && s[i+1] == '[' {
+				i += 2
+				for i < len(s) && (s[i] < 0x40 || s[i] > 0x7e) {
+					i++
+				}
+			}
+		case c == '\t':
+			b.WriteString("    ")
+		case c == '\n':
+			b.WriteByte('\n')
+		case c < 0x20 || c == 0x7f:
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,131 @@
+package tui
+
+import (
+	"strings"
+	"unicode"
+)
+
+// runeWidth is the number of cells r takes: 2 for wide East Asian
+// characters and emoji, 0 for combining marks, otherwise 1
+func runeWidth(r rune) int {
+	switch {
+	case unicode.Is(unicode.Mn, r):
+		return 0
+	case r >= 0x1100 && r <= 0x115f,
+		r >= 0x2e80 && r <= 0xa4cf && r != 0x303f,
+		r >= 0xac00 && r <= 0xd7a3,
+		r >= 0xf900 && r <= 0xfaff,
+		r >= 0xfe30 && r <= 0xfe4f,
+		r >= 0xff00 && r <= 0xff60,
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1faff,
+		r >= 0x20000 && r <= 0x3fffd:
+		return 2
+	}
+	return 1
+}
+
+// textWidth is the number of cells s takes
+func textWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeWidth(r)
+	}
+	return n
+}
+
+// clip cuts s to at most w cells, marking the cut with an ellipsis
+func clip(s string, w int) string {
+	if w <= 0 {
+		return ""
+	}
+	if textWidth(s) <= w {
+		return s
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		rw := runeWidth(r)
+		if n+rw > w-1 {
+			break
+		}
+		b.WriteRune(r)
+		n += rw
+	}
+	b.WriteString("…")
+	return b.String()
+}
+
+// pad clips s to w cells and fills the rest with spaces
+func pad(s string, w int) string {
+	s = clip(s, w)
+	if n := textWidth(s); n < w {
+		s += strings.Repeat(" ", w-n)
+	}
+	return s
+}
+
+// wrap breaks text into lines of at most w cells, at spaces where it can
+func wrap(text string, w int) []string {
+	if w < 1 {
+		w = 1
+	}
+	var lines []string
+	for _, para := range strings.Split(text, "\n") {
+		if textWidth(para) <= w {
+			lines = append(lines, para)
+			continue
+		}
+		var line strings.Builder
+		n := 0
+		lastSpace := -1 // Byte offset in line after the last space
+		for _, r := range para {
+			rw := runeWidth(r)
+			if n+rw > w {
+				cur := line.String()
+				if lastSpace > 0 {
+					lines = append(lines, strings.TrimRight(cur[:lastSpace], " "))
+					cur = cur[lastSpace:]
+				} else {
+					lines = append(lines, cur)
+					cur = ""
+				}
+				line.Reset()
+				line.WriteString(cur)
+				n = textWidth(cur)
+				lastSpace = -1
+			}
+			line.WriteRune(r)
+			n += rw
+			if r == ' ' {
+				lastSpace = line.Len()
+			}
+		}
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+// highlight marks case-insensitive occurrences of query in a plain line,
+// then restores base
+func highlight(line, query, base string) string {
+	if query == "" {
+		return line
+	}
+	lower := strings.ToLower(line)
+	q := strings.ToLower(query)
+	if len(lower) != len(line) || !strings.Contains(lower, q) {
+		return line
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(lower, q)
+		if i < 0 {
+			b.WriteString(line)
+			return b.String()
+		}
+		b.WriteString(line[:i])
+		b.WriteString(styleMatch + line[i:i+len(q)] + styleReset + base)
+		line, lower = line[i+len(q):], lower[i+len(q):]
+	}
+}
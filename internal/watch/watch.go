@@ -0,0 +1,374 @@
+// Package watch follows files that are appended to, such as a session
+// transcript being written, and delivers each complete line as it lands.
+package watch
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// maxChunkSize caps a single read so a large burst never becomes one huge
+// allocation; the tail keeps reading until it has caught up.
+const maxChunkSize = 1 << 20
+
+// pollInterval is used for files fsnotify cannot watch
+const pollInterval = 500 * time.Millisecond
+
+// Event is a complete line appended to a file, or a reset when the file
+// was truncated or replaced and what was read before is stale
+type Event struct {
+	Type string // "line" or "reset"
+	Data string // The line, or {"reason":"truncated"|"replaced"}
+}
+
+// tail follows one file and fans new lines out to every subscriber
+type tail struct {
+	path string
+
+	mu      sync.Mutex
+	info    os.FileInfo
+	offset  int64
+	partial []byte
+	subs    map[chan Event]struct{}
+	stop    chan struct{} // Closed to end the follower or polling fallback, if running
+}
+
+// Follow delivers the lines of path past offset from, or past its current
+// end when from is negative, to a single reader with its own watcher. The
+// returned channel is closed if the reader falls buffer events behind, or
+// once the returned func is called.
+func Follow(path string, from int64, buffer int) (<-chan Event, func(), error) {
+	path = filepath.Clean(path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	offset := info.Size()
+	if from >= 0 && from < offset {
+		offset = from
+	}
+	ch := make(chan Event, buffer)
+	t := &tail{
+		path:   path,
+		info:   info,
+		offset: offset,
+		subs:   map[chan Event]struct{}{ch: {}},
+		stop:   make(chan struct{}),
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("watch: fsnotify unavailable, polling instead: %v", err)
+		go t.pollLoop()
+	} else if err := w.Add(filepath.Dir(path)); err != nil {
+		// Watch the directory rather than the file so replacement by rename is seen
+		log.Printf("watch: %s: %v, polling instead", filepath.Dir(path), err)
+		w.Close()
+		go t.pollLoop()
+	} else {
+		go t.follow(w)
+	}
+
+	// Catch up on what was written before from without waiting for a change
+	t.poll()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			close(t.stop)
+			t.mu.Lock()
+			if _, ok := t.subs[ch]; ok {
+				delete(t.subs, ch)
+				close(ch)
+			}
+			t.mu.Unlock()
+		})
+	}
+	return ch, cancel, nil
+}
+
+// follow polls t on each fsnotify event for its file until t.stop is closed
+func (t *tail) follow(w *fsnotify.Watcher) {
+	defer w.Close()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case event, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) == t.path {
+				t.poll()
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			// Events may have been dropped; catch up
+			log.Printf("watch: %v", err)
+			t.poll()
+		}
+	}
+}
+
+// Hub shares one tail per file across its subscribers and drives them
+// from a single fsnotify watcher on the containing directories
+type Hub struct {
+	buffer int
+
+	mu      sync.Mutex
+	watcher *fsnotify.Watcher
+	files   map[string]*tail
+	dirs    map[string]int // Watched directory -> number of tails in it
+}
+
+// NewHub returns a Hub whose subscribers may fall buffer events behind
+// before they are dropped
+func NewHub(buffer int) *Hub {
+	return &Hub{
+		buffer: buffer,
+		files:  make(map[string]*tail),
+		dirs:   make(map[string]int),
+	}
+}
+
+// Subscribe delivers the lines of path past offset from, or past its
+// current end when from is negative. A file that already has subscribers
+// is delivered from where they are. The returned channel is closed if the
+// subscriber falls too far behind.
+func (h *Hub) Subscribe(path string, from int64) (<-chan Event, func(), error) {
+	path = filepath.Clean(path)
+	ch := make(chan Event, h.buffer)
+
+	h.mu.Lock()
+	t, ok := h.files[path]
+	if !ok {
+		info, err := os.Stat(path)
+		if err != nil {
+			h.mu.Unlock()
+			return nil, nil, err
+		}
+		offset := info.Size()
+		if from >= 0 && from < offset {
+			offset = from
+		}
+		t = &tail{
+			path:   path,
+			info:   info,
+			offset: offset,
+			subs:   make(map[chan Event]struct{}),
+		}
+		h.files[path] = t
+		if !h.watchDirLocked(filepath.Dir(path)) {
+			t.stop = make(chan struct{})
+			go t.pollLoop()
+		}
+	}
+
+	t.mu.Lock()
+	t.subs[ch] = struct{}{}
+	behind := t.offset < t.info.Size()
+	t.mu.Unlock()
+	h.mu.Unlock()
+
+	// Catch up on what was written before from without waiting for a change
+	if behind {
+		t.poll()
+	}
+	return ch, func() { h.unsubscribe(t, ch) }, nil
+}
+
+func (h *Hub) unsubscribe(t *tail, ch chan Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t.mu.Lock()
+	if _, ok := t.subs[ch]; ok {
+		delete(t.subs, ch)
+		close(ch)
+	}
+	empty := len(t.subs) == 0
+	t.mu.Unlock()
+
+	if !empty || h.files[t.path] != t {
+		return
+	}
+	delete(h.files, t.path)
+	if t.stop != nil {
+		close(t.stop)
+		return
+	}
+	h.unwatchDirLocked(filepath.Dir(t.path))
+}
+
+// watchDirLocked adds dir to the fsnotify watcher, starting it on first use.
+// It returns false when fsnotify is unavailable and the caller should poll.
+func (h *Hub) watchDirLocked(dir string) bool {
+	if h.watcher == nil {
+		w, err := fsnotify.NewWatcher()
+		if err != nil {
+			log.Printf("watch: fsnotify unavailable, polling instead: %v", err)
+			return false
+		}
+		h.watcher = w
+		go h.run(w)
+	}
+	if h.dirs[dir] == 0 {
+		// Watch the directory rather than the file so replacement by rename is seen
+		if err := h.watcher.Add(dir); err != nil {
+			log.Printf("watch: %s: %v, polling instead", dir, err)
+			return false
+		}
+	}
+	h.dirs[dir]++
+	return true
+}
+
+func (h *Hub) unwatchDirLocked(dir string) {
+	h.dirs[dir]--
+	if h.dirs[dir] > 0 {
+		return
+	}
+	delete(h.dirs, dir)
+	_ = h.watcher.Remove(dir)
+}
+
+// run dispatches fsnotify events to the tail following the changed file
+func (h *Hub) run(w *fsnotify.Watcher) {
+	for {
+		select {
+		case event, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			h.mu.Lock()
+			t := h.files[filepath.Clean(event.Name)]
+			h.mu.Unlock()
+			if t != nil {
+				t.poll()
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			// Events may have been dropped; catch every tail up
+			log.Printf("watch: %v", err)
+			h.mu.Lock()
+			tails := make([]*tail, 0, len(h.files))
+			for _, t := range h.files {
+				tails = append(tails, t)
+			}
+			h.mu.Unlock()
+			for _, t := range tails {
+				t.poll()
+			}
+		}
+	}
+}
+
+// pollLoop is the fallback for files fsnotify cannot watch
+func (t *tail) pollLoop() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+			t.poll()
+		}
+	}
+}
+
+// poll reads everything appended since the last call and broadcasts each
+// complete line. A file that shrank or was replaced is read from the start
+// after a reset event.
+func (t *tail) poll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	info, err := os.Stat(t.path)
+	if err != nil {
+		// Removed; wait for it to be recreated
+		return
+	}
+	switch {
+	case !os.SameFile(info, t.info):
+		t.reset("replaced")
+	case info.Size() < t.offset:
+		t.reset("truncated")
+	}
+	t.info = info
+	if info.Size() == t.offset {
+		return
+	}
+
+	file, err := os.Open(t.path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	if _, err := file.Seek(t.offset, io.SeekStart); err != nil {
+		return
+	}
+
+	buf := make([]byte, min(info.Size()-t.offset, maxChunkSize))
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			t.offset += int64(n)
+			t.consume(buf[:n])
+		}
+		if err != nil || n == 0 {
+			return
+		}
+	}
+}
+
+// consume splits data into lines, holding back a trailing partial line
+func (t *tail) consume(data []byte) {
+	if len(t.partial) > 0 {
+		data = append(t.partial, data...)
+		t.partial = nil
+	}
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		if line := bytes.TrimSpace(data[:i]); len(line) > 0 {
+			t.broadcast(Event{Type: "line", Data: string(line)})
+		}
+		data = data[i+1:]
+	}
+	if len(data) > 0 {
+		t.partial = append([]byte(nil), data...)
+	}
+}
+
+func (t *tail) reset(reason string) {
+	t.offset = 0
+	t.partial = nil
+	t.broadcast(Event{Type: "reset", Data: `{"reason":"` + reason + `"}`})
+}
+
+// broadcast never blocks: a subscriber whose buffer is full is dropped,
+// and should start over rather than skip lines
+func (t *tail) broadcast(ev Event) {
+	for ch := range t.subs {
+		select {
+		case ch <- ev:
+		default:
+			delete(t.subs, ch)
+			close(ch)
+		}
+	}
+}
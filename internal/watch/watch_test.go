@@ -0,0 +1,200 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thevibeworks/ccx/internal/testutil"
+)
+
+func nextEvent(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatal("events channel closed")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for watch event")
+	}
+	return Event{}
+}
+
+func TestHub_FansOutAppendedLines(t *testing.T) {
+	h := NewHub(1024)
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	if err := os.WriteFile(path, []byte(`{"n":0}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, cancelA, err := h.Subscribe(path, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancelA()
+	b, cancelB, err := h.Subscribe(path, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancelB()
+
+	h.mu.Lock()
+	tails := len(h.files)
+	h.mu.Unlock()
+	if tails != 1 {
+		t.Errorf("tails = %d, want one shared tail", tails)
+	}
+
+	// A partial line is held back until its newline arrives
+	testutil.AppendFile(t, path, `{"n":1}`+"\n"+`{"n":`)
+	testutil.AppendFile(t, path, "2}\n")
+
+	for _, events := range []<-chan Event{a, b} {
+		for _, want := range []string{`{"n":1}`, `{"n":2}`} {
+			if ev := nextEvent(t, events); ev.Type != "line" || ev.Data != want {
+				t.Errorf("event = %+v, want line %s", ev, want)
+			}
+		}
+	}
+}
+
+func TestHub_ReadsPastChunkSize(t *testing.T) {
+	h := NewHub(1024)
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	events, cancel, err := h.Subscribe(path, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	big := `{"text":"` + strings.Repeat("x", maxChunkSize+10) + `"}`
+	testutil.AppendFile(t, path, big+"\n"+`{"n":"after"}`+"\n")
+
+	if ev := nextEvent(t, events); ev.Data != big {
+		t.Errorf("first line length = %d, want %d", len(ev.Data), len(big))
+	}
+	if ev := nextEvent(t, events); ev.Data != `{"n":"after"}` {
+		t.Errorf("second line = %q", ev.Data)
+	}
+}
+
+func TestHub_TruncateAndReplace(t *testing.T) {
+	h := NewHub(1024)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "s.jsonl")
+	if err := os.WriteFile(path, []byte(`{"n":0}`+"\n"+`{"n":1}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	events, cancel, err := h.Subscribe(path, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	if err := os.WriteFile(path, []byte(`{"n":"t"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if ev := nextEvent(t, events); ev.Type != "reset" || !strings.Contains(ev.Data, "truncated") {
+		t.Fatalf("event = %+v, want truncated reset", ev)
+	}
+	if ev := nextEvent(t, events); ev.Data != `{"n":"t"}` {
+		t.Errorf("after truncate = %+v, want new content from the start", ev)
+	}
+
+	tmp := filepath.Join(dir, "s.jsonl.tmp")
+	if err := os.WriteFile(tmp, []byte(`{"n":"r"}`+"\n"+`{"n":"r2"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	if ev := nextEvent(t, events); ev.Type != "reset" || !strings.Contains(ev.Data, "replaced") {
+		t.Fatalf("event = %+v, want replaced reset", ev)
+	}
+	if ev := nextEvent(t, events); ev.Data != `{"n":"r"}` {
+		t.Errorf("after replace = %+v", ev)
+	}
+}
+
+func TestHub_ReleasesTailOnUnsubscribe(t *testing.T) {
+	h := NewHub(1024)
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, cancel, err := h.Subscribe(path, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	h.mu.Lock()
+	_, ok := h.files[path]
+	h.mu.Unlock()
+	if ok {
+		t.Error("tail still registered after last subscriber left")
+	}
+}
+
+func TestHub_SubscribeFromOffset(t *testing.T) {
+	h := NewHub(1024)
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	first := `{"n":0}` + "\n"
+	if err := os.WriteFile(path, []byte(first+`{"n":1}`+"\n"+`{"n":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// What was written past the offset arrives without waiting for a change
+	events, cancel, err := h.Subscribe(path, int64(len(first)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+	if ev := nextEvent(t, events); ev.Data != `{"n":1}` {
+		t.Errorf("first event = %+v, want the line past the offset", ev)
+	}
+
+	testutil.AppendFile(t, path, "2}\n")
+	if ev := nextEvent(t, events); ev.Data != `{"n":2}` {
+		t.Errorf("second event = %+v, want the completed line", ev)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	first := `{"n":0}` + "\n"
+	if err := os.WriteFile(path, []byte(first+`{"n":1}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	events, cancel, err := Follow(path, int64(len(first)), 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev := nextEvent(t, events); ev.Data != `{"n":1}` {
+		t.Errorf("first event = %+v, want the line past the offset", ev)
+	}
+
+	testutil.AppendFile(t, path, `{"n":2}`+"\n")
+	if ev := nextEvent(t, events); ev.Data != `{"n":2}` {
+		t.Errorf("second event = %+v, want the appended line", ev)
+	}
+
+	cancel()
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("events channel still open after cancel")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("events channel not closed after cancel")
+	}
+}
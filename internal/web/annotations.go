@@ -0,0 +1,160 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/thevibeworks/ccx/internal/config"
+	"github.com/thevibeworks/ccx/internal/db"
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+// handleAPIAnnotations lists a session's annotations (GET ?session=) or
+// adds one (POST)
+func handleAPIAnnotations(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		filter := db.AnnotationFilter{Project: q.Get("project"), SessionID: q.Get("session")}
+		annotations, err := db.ListAnnotations(filter)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if annotations == nil {
+			annotations = []db.Annotation{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"annotations": redactAnnotations(redactor, annotations)})
+
+	case http.MethodPost:
+		var a db.Annotation
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		session, err := parser.FindSession(projectsDir, a.Project, a.SessionID)
+		if err != nil || session == nil {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+		full, err := parser.ParseSession(session.FilePath)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !hasMessage(full, a.MessageUUID) {
+			http.Error(w, fmt.Sprintf("no message %q in session", a.MessageUUID), http.StatusBadRequest)
+			return
+		}
+		a.SessionID = session.ID
+		a.Project = session.ProjectName
+		if a.Author == "" {
+			a.Author = config.Author()
+		}
+		if err := db.AddAnnotation(&a); err != nil {
+			if err == db.ErrNotInitialized {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			} else {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(a)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// hasMessage reports whether uuid is a message of the session or one of
+// its agent transcripts
+func hasMessage(session *parser.Session, uuid string) bool {
+	trees := [][]*parser.Message{session.RootMessages}
+	for _, sc := range session.Sidechains {
+		trees = append(trees, sc.RootMessages)
+	}
+	for _, roots := range trees {
+		for _, msg := range parser.Flatten(roots) {
+			if msg.UUID == uuid {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handleAPIAnnotation edits (PUT/PATCH with {"note"}) or deletes one annotation
+func handleAPIAnnotation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/annotations/"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a, err := db.GetAnnotation(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if a == nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(redactAnnotations(redactor, []db.Annotation{*a})[0])
+
+	case http.MethodPut, http.MethodPatch:
+		var req struct {
+			Note string `json:"note"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		a, err := db.UpdateAnnotationNote(id, req.Note)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if a == nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(redactAnnotations(redactor, []db.Annotation{*a})[0])
+
+	case http.MethodDelete:
+		ok, err := db.DeleteAnnotation(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"deleted": id})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// loadAnnotations returns a session's annotations, masked when redaction
+// is forced, or nil when the database is unavailable
+func loadAnnotations(sessionID string) []db.Annotation {
+	if !db.Available() {
+		return nil
+	}
+	annotations, _ := db.GetSessionAnnotations(sessionID)
+	return redactAnnotations(redactor, annotations)
+}
@@ -0,0 +1,279 @@
+package web
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/render"
+	"github.com/thevibeworks/ccx/internal/stats"
+)
+
+// Charts are plain SVG drawn on the server: no chart library, no script.
+// Every mark is a link to the sessions behind it and carries a <title>
+// tooltip. Colors come from CSS classes so both themes apply.
+
+const chartWidth = 720
+
+// chartItem is one labelled value of a bar chart or donut
+type chartItem struct {
+	Label  string
+	Value  int
+	Errors int // Drawn as a red overlay on the bar, if any
+	Title  string
+	Href   string
+}
+
+// svgBarChart draws horizontal bars, one row per item, longest first
+func svgBarChart(items []chartItem) string {
+	if len(items) == 0 {
+		return `<p class="chart-empty">No data</p>`
+	}
+	const labelW, rowH, barH, valueW = 150, 24, 16, 70
+	maxVal := 1
+	for _, it := range items {
+		maxVal = max(maxVal, it.Value)
+	}
+	plotW := float64(chartWidth - labelW - valueW)
+	height := len(items) * rowH
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf(`<svg class="chart" viewBox="0 0 %d %d" role="img">`, chartWidth, height))
+	for i, it := range items {
+		y := i * rowH
+		w := plotW * float64(it.Value) / float64(maxVal)
+		if it.Href != "" {
+			b.WriteString(fmt.Sprintf(`<a href="%s">`, html.EscapeString(it.Href)))
+		}
+		b.WriteString(`<g class="chart-row">`)
+		b.WriteString(fmt.Sprintf(`<title>%s</title>`, html.EscapeString(it.Title)))
+		b.WriteString(fmt.Sprintf(`<rect class="chart-hit" x="0" y="%d" width="%d" height="%d"/>`, y, chartWidth, rowH))
+		b.WriteString(fmt.Sprintf(`<text class="chart-label" x="%d" y="%d" text-anchor="end">%s</text>`,
+			labelW-8, y+rowH/2+4, html.EscapeString(truncate(it.Label, 22))))
+		b.WriteString(fmt.Sprintf(`<rect class="chart-bar" x="%d" y="%d" width="%.1f" height="%d" rx="2"/>`,
+			labelW, y+(rowH-barH)/2, math.Max(w, 1), barH))
+		if it.Errors > 0 {
+			ew := plotW * float64(it.Errors) / float64(maxVal)
+			b.WriteString(fmt.Sprintf(`<rect class="chart-bar-error" x="%d" y="%d" width="%.1f" height="%d" rx="2"/>`,
+				labelW, y+(rowH-barH)/2, math.Max(ew, 1), barH))
+		}
+		b.WriteString(fmt.Sprintf(`<text class="chart-value" x="%.1f" y="%d">%s</text>`,
+			float64(labelW)+w+6, y+rowH/2+4, render.FormatTokens(it.Value)))
+		b.WriteString(`</g>`)
+		if it.Href != "" {
+			b.WriteString(`</a>`)
+		}
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+// tokenSeries are the stacked layers of the token chart, bottom first
+var tokenSeries = []struct {
+	class, label string
+	value        func(parser.Usage) int
+}{
+	{"series-input", "Input", func(t parser.Usage) int { return t.InputTokens }},
+	{"series-output", "Output", func(t parser.Usage) int { return t.OutputTokens }},
+	{"series-cache-create", "Cache write", func(t parser.Usage) int { return t.CacheCreateTokens }},
+	{"series-cache-read", "Cache read", func(t parser.Usage) int { return t.CacheReadTokens }},
+}
+
+// svgTokenChart draws one stacked column of tokens per day, including
+// the empty days between the first and last
+func svgTokenChart(days []stats.DayStats, href func(date string) string) string {
+	if len(days) == 0 {
+		return `<p class="chart-empty">No data</p>`
+	}
+	byDate := make(map[string]stats.DayStats, len(days))
+	for _, d := range days {
+		byDate[d.Date] = d
+	}
+	first, _ := time.ParseInLocation("2006-01-02", days[0].Date, time.Local)
+	last, _ := time.ParseInLocation("2006-01-02", days[len(days)-1].Date, time.Local)
+	var dates []string
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		dates = append(dates, d.Format("2006-01-02"))
+	}
+
+	const left, top, plotH, bottom = 56, 8, 200, 24
+	maxVal := 1
+	for _, d := range days {
+		maxVal = max(maxVal, d.Tokens.Total())
+	}
+	plotW := float64(chartWidth - left - 8)
+	step := plotW / float64(len(dates))
+	barW := math.Max(step*0.8, 1)
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf(`<svg class="chart" viewBox="0 0 %d %d" role="img">`, chartWidth, top+plotH+bottom))
+	for _, frac := range []float64{0, 0.5, 1} {
+		y := float64(top) + float64(plotH)*(1-frac)
+		b.WriteString(fmt.Sprintf(`<line class="chart-gridline" x1="%d" y1="%.1f" x2="%d" y2="%.1f"/>`, left, y, chartWidth-8, y))
+		b.WriteString(fmt.Sprintf(`<text class="chart-axis" x="%d" y="%.1f" text-anchor="end">%s</text>`,
+			left-6, y+4, render.FormatTokens(int(float64(maxVal)*frac))))
+	}
+
+	for i, date := range dates {
+		x := float64(left) + step*float64(i) + (step-barW)/2
+		d, ok := byDate[date]
+		if !ok || d.Tokens.Total() == 0 {
+			continue
+		}
+		title := fmt.Sprintf("%s: %d sessions, %s tokens, %s est.", date, d.Sessions, render.FormatTokens(d.Tokens.Total()), d.Cost)
+		for _, s := range tokenSeries {
+			title += fmt.Sprintf("\n%s: %s", s.label, render.FormatTokens(s.value(d.Tokens)))
+		}
+		b.WriteString(fmt.Sprintf(`<a href="%s"><g class="chart-col"><title>%s</title>`, html.EscapeString(href(date)), html.EscapeString(title)))
+		b.WriteString(fmt.Sprintf(`<rect class="chart-hit" x="%.1f" y="%d" width="%.1f" height="%d"/>`, float64(left)+step*float64(i), top, step, plotH))
+		y := float64(top + plotH)
+		for _, s := range tokenSeries {
+			h := float64(plotH) * float64(s.value(d.Tokens)) / float64(maxVal)
+			if h <= 0 {
+				continue
+			}
+			y -= h
+			b.WriteString(fmt.Sprintf(`<rect class="%s" x="%.1f" y="%.1f" width="%.1f" height="%.1f"/>`, s.class, x, y, barW, h))
+		}
+		b.WriteString(`</g></a>`)
+	}
+
+	// Label the first, middle and last day
+	labels := []int{0, len(dates) / 2, len(dates) - 1}
+	for j, i := range labels {
+		if j > 0 && i == labels[j-1] {
+			continue
+		}
+		x := float64(left) + step*(float64(i)+0.5)
+		anchor := "middle"
+		if i == 0 {
+			anchor = "start"
+			x = float64(left)
+		} else if i == len(dates)-1 {
+			anchor = "end"
+			x = float64(chartWidth - 8)
+		}
+		b.WriteString(fmt.Sprintf(`<text class="chart-axis" x="%.1f" y="%d" text-anchor="%s">%s</text>`,
+			x, top+plotH+16, anchor, dates[i]))
+	}
+	b.WriteString(`</svg>`)
+
+	b.WriteString(`<div class="chart-legend">`)
+	for _, s := range tokenSeries {
+		b.WriteString(fmt.Sprintf(`<span><i class="legend-swatch %s"></i>%s</span>`, s.class, s.label))
+	}
+	b.WriteString(`</div>`)
+	return b.String()
+}
+
+// svgDonut draws each item's share of the total as a ring segment, with
+// a legend beside it
+func svgDonut(items []chartItem) string {
+	total := 0
+	for _, it := range items {
+		total += it.Value
+	}
+	if total == 0 {
+		return `<p class="chart-empty">No data</p>`
+	}
+	const size, r, stroke = 160, 60, 24
+	circ := 2 * math.Pi * r
+
+	var b strings.Builder
+	b.WriteString(`<div class="donut">`)
+	b.WriteString(fmt.Sprintf(`<svg class="chart donut-chart" viewBox="0 0 %d %d" role="img">`, size, size))
+	b.WriteString(fmt.Sprintf(`<g transform="rotate(-90 %d %d)">`, size/2, size/2))
+	offset := 0.0
+	for i, it := range items {
+		length := circ * float64(it.Value) / float64(total)
+		b.WriteString(fmt.Sprintf(`<a href="%s"><circle class="donut-seg mix-%d" cx="%d" cy="%d" r="%d" stroke-width="%d" stroke-dasharray="%.2f %.2f" stroke-dashoffset="%.2f"><title>%s</title></circle></a>`,
+			html.EscapeString(it.Href), i%6, size/2, size/2, r, stroke, length, circ-length, -offset, html.EscapeString(it.Title)))
+		offset += length
+	}
+	b.WriteString(`</g>`)
+	b.WriteString(fmt.Sprintf(`<text class="donut-total" x="%d" y="%d" text-anchor="middle">%s</text>`, size/2, size/2+6, render.FormatTokens(total)))
+	b.WriteString(`</svg>`)
+
+	b.WriteString(`<ul class="donut-legend">`)
+	for i, it := range items {
+		b.WriteString(fmt.Sprintf(`<li><a href="%s" title="%s"><i class="legend-swatch mix-%d"></i>%s <span class="count">%.1f%%</span></a></li>`,
+			html.EscapeString(it.Href), html.EscapeString(it.Title), i%6, html.EscapeString(it.Label), 100*float64(it.Value)/float64(total)))
+	}
+	b.WriteString(`</ul>`)
+	b.WriteString(`</div>`)
+	return b.String()
+}
+
+// svgHeatmap draws messages by weekday (rows, Monday first) and hour
+func svgHeatmap(grid [7][24]int, href func(day time.Weekday, hour int) string) string {
+	const left, top, cell, gap = 40, 18, 26, 2
+	maxVal := 0
+	for _, row := range grid {
+		for _, n := range row {
+			maxVal = max(maxVal, n)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf(`<svg class="chart" viewBox="0 0 %d %d" role="img">`, left+24*cell, top+7*cell))
+	for hour := 0; hour < 24; hour += 3 {
+		b.WriteString(fmt.Sprintf(`<text class="chart-axis" x="%d" y="%d" text-anchor="middle">%02d</text>`, left+hour*cell+cell/2, top-6, hour))
+	}
+	for row := 0; row < 7; row++ {
+		day := time.Weekday((row + 1) % 7)
+		y := top + row*cell
+		b.WriteString(fmt.Sprintf(`<text class="chart-axis" x="%d" y="%d" text-anchor="end">%s</text>`, left-6, y+cell/2+4, day.String()[:3]))
+		for hour := 0; hour < 24; hour++ {
+			x := left + hour*cell
+			n := grid[day][hour]
+			title := fmt.Sprintf(`<title>%s %02d:00 · %d messages</title>`, day.String()[:3], hour, n)
+			if n == 0 {
+				b.WriteString(fmt.Sprintf(`<rect class="heat-cell heat-empty" x="%d" y="%d" width="%d" height="%d" rx="3">%s</rect>`,
+					x, y, cell-gap, cell-gap, title))
+				continue
+			}
+			opacity := 0.15 + 0.85*float64(n)/float64(maxVal)
+			b.WriteString(fmt.Sprintf(`<a href="%s"><rect class="heat-cell" x="%d" y="%d" width="%d" height="%d" rx="3" fill-opacity="%.2f">%s</rect></a>`,
+				html.EscapeString(href(day, hour)), x, y, cell-gap, cell-gap, opacity, title))
+		}
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+// svgContextSparkline draws the estimated context size after each
+// response, with the heaviest turns marked and linked to their messages
+func svgContextSparkline(points []parser.ContextPoint, heavy map[string]parser.ContextPoint) string {
+	const width, height, pad = 200, 40, 3
+	peak := 1
+	for _, p := range points {
+		peak = max(peak, p.Tokens)
+	}
+	step := float64(width-2*pad) / float64(max(len(points)-1, 1))
+	xy := func(i int, tokens int) (float64, float64) {
+		return pad + step*float64(i), pad + float64(height-2*pad)*(1-float64(tokens)/float64(peak))
+	}
+
+	var line strings.Builder
+	for i, p := range points {
+		x, y := xy(i, p.Tokens)
+		line.WriteString(fmt.Sprintf("%.1f,%.1f ", x, y))
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf(`<svg class="spark" viewBox="0 0 %d %d" role="img">`, width, height))
+	b.WriteString(fmt.Sprintf(`<polyline class="spark-line" points="%s"/>`, strings.TrimSpace(line.String())))
+	for i, p := range points {
+		if _, ok := heavy[p.Message.UUID]; !ok {
+			continue
+		}
+		x, y := xy(i, p.Tokens)
+		b.WriteString(fmt.Sprintf(`<a href="#msg-%s"><circle class="spark-heavy" cx="%.1f" cy="%.1f" r="3"><title>+%s tokens at %s (context %s)</title></circle></a>`,
+			sanitizeID(p.Message.UUID), x, y, render.FormatTokens(p.Added), p.Message.Timestamp.Format("15:04:05"), render.FormatTokens(p.Tokens)))
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
@@ -0,0 +1,272 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+
+	"github.com/thevibeworks/ccx/internal/audit"
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+// defaultCommandsLimit caps the commands listed for a project or all
+// projects unless ?limit= overrides it (0 = no cap); the latest are kept
+const defaultCommandsLimit = 500
+
+// commandsQuery is a parsed /commands or /api/commands request:
+// /commands, /commands/{project} or /commands/{project}/{session}
+type commandsQuery struct {
+	Project string
+	Session string
+	Grep    string
+	Errors  bool
+	Limit   int
+}
+
+func parseCommandsQuery(r *http.Request, prefix string) (commandsQuery, bool) {
+	q := commandsQuery{Grep: r.URL.Query().Get("q"), Errors: r.URL.Query().Get("errors") != "", Limit: defaultCommandsLimit}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			q.Limit = n
+		}
+	}
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	if rest == "" {
+		return q, true
+	}
+	parts := strings.Split(rest, "/")
+	if len(parts) > 2 {
+		return q, false
+	}
+	q.Project = parts[0]
+	if len(parts) == 2 {
+		q.Session = parts[1]
+	}
+	return q, true
+}
+
+// collect lists the query's commands, all of a session's or the latest
+// Limit of a wider scope, and how many matched in total
+func (q commandsQuery) collect() ([]audit.Command, int, error) {
+	var re *regexp.Regexp
+	if q.Grep != "" {
+		var err error
+		if re, err = regexp.Compile("(?i)" + q.Grep); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	var cmds []audit.Command
+	if q.Session != "" {
+		session, err := parser.FindSession(projectsDir, q.Project, q.Session)
+		if err != nil || session == nil {
+			return nil, 0, errNotFound
+		}
+		full, err := parser.ParseSession(session.FilePath)
+		if err != nil {
+			return nil, 0, err
+		}
+		cmds = audit.Filter(audit.Session(q.Project, full), re, q.Errors)
+		return cmds, len(cmds), nil
+	}
+
+	if q.Project != "" {
+		p, err := parser.FindProject(projectsDir, q.Project)
+		if err != nil || p == nil {
+			return nil, 0, errNotFound
+		}
+	}
+	cmds, err := audit.Collect(projectsDir, q.Project)
+	if err != nil {
+		return nil, 0, err
+	}
+	cmds = audit.Filter(cmds, re, q.Errors)
+	total := len(cmds)
+	if q.Limit > 0 && len(cmds) > q.Limit {
+		cmds = cmds[len(cmds)-q.Limit:]
+	}
+	return cmds, total, nil
+}
+
+var errNotFound = errors.New("not found")
+
+// commandsError writes err as a 404 for an unknown project or session, a
+// 400 for a bad pattern, or a 500
+func commandsError(w http.ResponseWriter, r *http.Request, err error) {
+	var syntaxErr *syntax.Error
+	switch {
+	case errors.Is(err, errNotFound):
+		http.NotFound(w, r)
+	case errors.As(err, &syntaxErr):
+		http.Error(w, "invalid pattern: "+err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleCommands serves the shell command log of a session, a project or
+// every project. ?q= filters by regexp, ?errors=1 keeps failed commands.
+func handleCommands(w http.ResponseWriter, r *http.Request) {
+	q, ok := parseCommandsQuery(r, "/commands")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	cmds, total, err := q.collect()
+	if err != nil {
+		commandsError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, renderCommandsPage(q, cmds, total))
+}
+
+// handleAPICommands serves the same lists as JSON
+func handleAPICommands(w http.ResponseWriter, r *http.Request) {
+	q, ok := parseCommandsQuery(r, "/api/commands")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	cmds, total, err := q.collect()
+	if err != nil {
+		commandsError(w, r, err)
+		return
+	}
+	if cmds == nil {
+		cmds = []audit.Command{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"commands": cmds, "total": total})
+}
+
+func renderCommandsPage(q commandsQuery, cmds []audit.Command, total int) string {
+	var b strings.Builder
+
+	action := "/commands"
+	title := "Commands"
+	crumbs := `<a href="/">Projects</a>`
+	if q.Project != "" {
+		action += "/" + url.PathEscape(q.Project)
+		crumbs += fmt.Sprintf(` <span class="sep">/</span> <a href="/project/%s">%s</a>`, html.EscapeString(q.Project), html.EscapeString(parser.GetProjectDisplayName(q.Project)))
+	}
+	if q.Session != "" {
+		action += "/" + url.PathEscape(q.Session)
+		crumbs += fmt.Sprintf(` <span class="sep">/</span> <a href="/session/%s/%s">%s</a>`, html.EscapeString(q.Project), html.EscapeString(q.Session), html.EscapeString(truncate(q.Session, 8)))
+		title = fmt.Sprintf("Commands %s", truncate(q.Session, 8))
+	}
+	crumbs += ` <span class="sep">/</span> <span class="current">Commands</span>`
+
+	b.WriteString(pageHeader(title+" - ccx", "light"))
+	b.WriteString(commandsPageCSS())
+	b.WriteString(renderTopNav(q.Project, q.Session))
+	b.WriteString(`<div class="layout">`)
+	b.WriteString(renderSidebar("commands"))
+	b.WriteString(`<main class="main-content">`)
+	b.WriteString(`<div class="page-header page-header-sessions">`)
+	b.WriteString(fmt.Sprintf(`<div class="breadcrumb">%s</div>`, crumbs))
+	b.WriteString(`<h1>Shell commands</h1>`)
+	count := fmt.Sprintf("%d commands", total)
+	if len(cmds) < total {
+		count = fmt.Sprintf("Latest %d of %d commands", len(cmds), total)
+	}
+	b.WriteString(fmt.Sprintf(`<p class="stats">%s run through the Bash tool, agents and rewound branches included</p>`, count))
+	b.WriteString(`</div>`)
+
+	b.WriteString(fmt.Sprintf(`<form class="cmd-filter" method="get" action="%s">`, html.EscapeString(action)))
+	b.WriteString(fmt.Sprintf(`<input type="text" name="q" class="search-input" placeholder="Regexp, e.g. rm|curl|ssh" value="%s">`, html.EscapeString(q.Grep)))
+	checked := ""
+	if q.Errors {
+		checked = " checked"
+	}
+	b.WriteString(fmt.Sprintf(`<label><input type="checkbox" name="errors" value="1"%s> Failed only</label>`, checked))
+	b.WriteString(`<button type="submit" class="cmd-apply">Filter</button>`)
+	b.WriteString(`</form>`)
+
+	if len(cmds) == 0 {
+		b.WriteString(`<div class="empty">No commands</div>`)
+	}
+	for _, c := range cmds {
+		renderCommand(&b, c, q.Session == "")
+	}
+
+	b.WriteString(`</main>`)
+	b.WriteString(`</div>`)
+	b.WriteString(renderFooter())
+	b.WriteString(pageFooter())
+	return b.String()
+}
+
+func renderCommand(b *strings.Builder, c audit.Command, withSession bool) {
+	b.WriteString(fmt.Sprintf(`<div class="cmd-row cmd-%s">`, c.Status))
+	b.WriteString(`<div class="cmd-head">`)
+	b.WriteString(fmt.Sprintf(`<a class="cmd-time" href="/session/%s/%s#msg-%s">%s</a>`,
+		html.EscapeString(c.Project), html.EscapeString(c.SessionID), sanitizeID(c.MessageUUID), c.Timestamp.Format("2006-01-02 15:04:05")))
+	if withSession {
+		b.WriteString(fmt.Sprintf(`<span class="cmd-session">%s · %s</span>`,
+			html.EscapeString(parser.GetProjectDisplayName(c.Project)), html.EscapeString(truncate(c.SessionID, 8))))
+	}
+	if c.CWD != "" {
+		b.WriteString(fmt.Sprintf(`<code class="cmd-cwd" title="%s">%s</code>`, html.EscapeString(c.CWD), html.EscapeString(truncatePath(c.CWD, 40))))
+	}
+	if c.Agent {
+		b.WriteString(`<span class="cmd-badge">agent</span>`)
+	}
+	if c.Background {
+		b.WriteString(`<span class="cmd-badge">background</span>`)
+	}
+	status := string(c.Status)
+	if c.ExitCode != 0 {
+		status = fmt.Sprintf("exit %d", c.ExitCode)
+	}
+	b.WriteString(fmt.Sprintf(`<span class="cmd-status">%s</span>`, html.EscapeString(status)))
+	b.WriteString(`</div>`)
+
+	b.WriteString(fmt.Sprintf(`<pre class="cmd-line">$ %s</pre>`, html.EscapeString(c.Command)))
+	if c.Description != "" {
+		b.WriteString(fmt.Sprintf(`<div class="cmd-desc">%s</div>`, html.EscapeString(c.Description)))
+	}
+	if out := strings.TrimRight(c.Output, "\n"); out != "" {
+		lines := strings.Count(out, "\n") + 1
+		label := fmt.Sprintf("Output, %d lines", lines)
+		if c.Truncated {
+			label += ", cut"
+		}
+		open := ""
+		if c.Status == audit.StatusError && lines <= 10 {
+			open = " open"
+		}
+		b.WriteString(fmt.Sprintf(`<details class="cmd-output"%s><summary>%s</summary><pre>%s</pre></details>`, open, label, html.EscapeString(out)))
+	}
+	b.WriteString(`</div>`)
+}
+
+func commandsPageCSS() string {
+	return `<style>
+.cmd-filter { display: flex; flex-wrap: wrap; gap: 8px; align-items: center; margin-bottom: 20px; }
+.cmd-filter .search-input { width: 280px; flex: 0 0 auto; }
+.cmd-apply { padding: 6px 14px; border: 1px solid var(--primary); border-radius: var(--radius); background: var(--primary); color: #fff; cursor: pointer; font-size: 13px; }
+.cmd-filter label { font-size: 13px; color: var(--text-muted); }
+.cmd-row { border: 1px solid var(--border); border-left: 3px solid #595; border-radius: var(--radius); margin-bottom: 10px; background: var(--bg); }
+.cmd-error { border-left-color: #c66; }
+.cmd-interrupted, .cmd-unknown { border-left-color: #c93; }
+.cmd-head { display: flex; flex-wrap: wrap; gap: 8px; align-items: baseline; padding: 6px 12px; font-size: 12px; color: var(--text-muted); }
+.cmd-time { font-family: var(--font-mono); color: var(--text-muted); }
+.cmd-cwd { font-family: var(--font-mono); }
+.cmd-status { margin-left: auto; font-weight: 600; }
+.cmd-error .cmd-status { color: #c66; }
+.cmd-line { margin: 0; padding: 4px 12px; font-family: var(--font-mono); font-size: 13px; white-space: pre-wrap; word-break: break-all; }
+.cmd-desc { padding: 0 12px 6px; font-size: 12px; color: var(--text-muted); font-style: italic; }
+.cmd-output { border-top: 1px solid var(--border); }
+.cmd-output summary { cursor: pointer; padding: 4px 12px; font-size: 12px; color: var(--text-muted); }
+.cmd-output pre { margin: 0; padding: 8px 12px; max-height: 320px; overflow: auto; font-family: var(--font-mono); font-size: 12px; background: var(--bg-secondary); white-space: pre-wrap; }
+.cmd-badge { font-size: 10px; padding: 0 6px; border-radius: 8px; border: 1px solid var(--border); color: var(--text-muted); }
+</style>`
+}
@@ -0,0 +1,353 @@
+package web
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/thevibeworks/ccx/internal/compare"
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/pricing"
+	"github.com/thevibeworks/ccx/internal/render"
+)
+
+// handleCompare serves /compare/{project}/{a}/{b}: two sessions of a
+// project, or two branches of one when a and b are the same, lined up by
+// user prompt. ?leaf_a= and ?leaf_b= pick the branch on each side.
+func handleCompare(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/compare/"), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	projectName := parts[0]
+
+	var sessions [2]*parser.Session
+	for i, id := range parts[1:] {
+		if i == 1 && id == parts[1] {
+			sessions[1] = sessions[0]
+			break
+		}
+		s, err := parser.FindSession(projectsDir, projectName, id)
+		if err != nil || s == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if sessions[i], err = parser.ParseSession(s.FilePath); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	q := r.URL.Query()
+	res, err := compare.Sessions(sessions[0], q.Get("leaf_a"), sessions[1], q.Get("leaf_b"), prices)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, renderComparePage(projectName, res))
+}
+
+func renderComparePage(projectName string, res *compare.Result) string {
+	var b strings.Builder
+
+	projDisplay := parser.GetProjectDisplayName(projectName)
+	b.WriteString(pageHeader("Compare - ccx", "light"))
+	b.WriteString(comparePageCSS())
+	b.WriteString(renderTopNav(projectName, ""))
+	b.WriteString(`<div class="layout">`)
+	b.WriteString(renderSidebar("projects"))
+
+	b.WriteString(`<main class="main-content">`)
+	b.WriteString(`<div class="page-header page-header-sessions">`)
+	b.WriteString(fmt.Sprintf(`<div class="breadcrumb"><a href="/">Projects</a> <span class="sep">/</span> <a href="/project/%s">%s</a> <span class="sep">/</span> <span class="current">Compare</span></div>`,
+		html.EscapeString(projectName), html.EscapeString(projDisplay)))
+	if res.A.Session == res.B.Session {
+		b.WriteString(`<h1>Compare branches</h1>`)
+	} else {
+		b.WriteString(`<h1>Compare sessions</h1>`)
+	}
+	b.WriteString(`</div>`)
+
+	// The two sides
+	b.WriteString(`<div class="compare-sides">`)
+	for _, side := range []struct {
+		name string
+		s    *compare.Side
+	}{{"A", res.A}, {"B", res.B}} {
+		b.WriteString(fmt.Sprintf(`<div class="compare-side side-%s">`, strings.ToLower(side.name)))
+		b.WriteString(fmt.Sprintf(`<div class="compare-side-label">%s</div>`, side.name))
+		b.WriteString(fmt.Sprintf(`<a href="/session/%s/%s" class="session-id">%s</a>`,
+			html.EscapeString(projectName), html.EscapeString(side.s.Session.ID), html.EscapeString(truncate(side.s.Session.ID, 8))))
+		if res.A.Session == res.B.Session && side.s.Leaf != "" {
+			b.WriteString(fmt.Sprintf(` <span class="compare-leaf" title="Branch ending at %s">branch %s</span>`,
+				html.EscapeString(side.s.Leaf), html.EscapeString(truncate(side.s.Leaf, 8))))
+		}
+		b.WriteString(fmt.Sprintf(`<div class="session-summary">%s</div>`, html.EscapeString(side.s.Session.Summary)))
+		for _, m := range side.s.Models {
+			b.WriteString(fmt.Sprintf(`<span class="turn-model">%s</span> `, html.EscapeString(m)))
+		}
+		b.WriteString(`</div>`)
+	}
+	b.WriteString(`</div>`)
+
+	// Totals
+	b.WriteString(`<div class="chart-panel"><h2>Totals</h2>`)
+	b.WriteString(`<table class="compare-table"><thead><tr><th></th><th>A</th><th>B</th><th>B − A</th></tr></thead><tbody>`)
+	totals := []struct {
+		label string
+		a, b  int
+		tok   bool
+	}{
+		{"Turns", len(res.A.Turns), len(res.B.Turns), false},
+		{"Responses", res.A.Responses, res.B.Responses, false},
+		{"Tool calls", res.A.ToolCalls(), res.B.ToolCalls(), false},
+		{"Files", len(res.A.Files), len(res.B.Files), false},
+		{"Tokens", res.A.Usage.Total(), res.B.Usage.Total(), true},
+		{"Output tokens", res.A.Usage.OutputTokens, res.B.Usage.OutputTokens, true},
+	}
+	for _, t := range totals {
+		a, bv := fmt.Sprint(t.a), fmt.Sprint(t.b)
+		if t.tok {
+			a, bv = render.FormatTokens(t.a), render.FormatTokens(t.b)
+		}
+		b.WriteString(fmt.Sprintf(`<tr><td>%s</td><td>%s</td><td>%s</td><td class="%s">%s</td></tr>`,
+			t.label, a, bv, deltaClass(t.b-t.a), signedNumber(t.b-t.a)))
+	}
+	b.WriteString(fmt.Sprintf(`<tr><td>Est. cost</td><td>%s</td><td>%s</td><td class="%s">%s</td></tr>`,
+		compareCost(res.A.Cost), compareCost(res.B.Cost), deltaClass(int(100*(res.B.Cost.USD-res.A.Cost.USD))), signedUSD(res.B.Cost.USD-res.A.Cost.USD)))
+	durA, durB := res.A.Duration().Seconds(), res.B.Duration().Seconds()
+	b.WriteString(fmt.Sprintf(`<tr><td>Duration</td><td>%s</td><td>%s</td><td class="%s">%s</td></tr>`,
+		render.FormatDuration(durA), render.FormatDuration(durB), deltaClass(int(durB-durA)), signedDuration(durB-durA)))
+	b.WriteString(`</tbody></table></div>`)
+
+	// Turns, lined up by prompt
+	b.WriteString(`<div class="chart-panel"><h2>Turns`)
+	if res.Diverge >= 0 {
+		b.WriteString(fmt.Sprintf(` <a class="count" href="#diverge">diverge at turn %d</a>`, res.Diverge+1))
+	} else {
+		b.WriteString(` <span class="count">same prompts, tools and files</span>`)
+	}
+	b.WriteString(`</h2>`)
+	b.WriteString(`<table class="compare-table compare-turns"><thead><tr><th>#</th><th>A</th><th>B</th></tr></thead><tbody>`)
+	for i, row := range res.Rows {
+		class, id := compareRowClass(row), ""
+		if i == res.Diverge {
+			class += " compare-diverge"
+			id = ` id="diverge"`
+		}
+		b.WriteString(fmt.Sprintf(`<tr class="%s"%s><td class="compare-num">%d</td>`, class, id, i+1))
+		if row.Shared {
+			b.WriteString(`<td colspan="2">`)
+			renderCompareTurn(&b, projectName, res.A.Session.ID, row.A)
+			b.WriteString(`<span class="compare-shared">same messages on both branches</span></td></tr>`)
+			continue
+		}
+		for _, side := range []struct {
+			id string
+			t  *compare.Turn
+		}{{res.A.Session.ID, row.A}, {res.B.Session.ID, row.B}} {
+			b.WriteString(`<td>`)
+			renderCompareTurn(&b, projectName, side.id, side.t)
+			b.WriteString(`</td>`)
+		}
+		b.WriteString(`</tr>`)
+	}
+	b.WriteString(`</tbody></table></div>`)
+
+	// Tools and files
+	b.WriteString(`<div class="chart-grid">`)
+	b.WriteString(`<div class="chart-panel"><h2>Tools</h2>`)
+	if names := compare.ToolNames(res.A.Tools, res.B.Tools); len(names) > 0 {
+		b.WriteString(`<table class="compare-table"><thead><tr><th>Tool</th><th>A</th><th>B</th><th>B − A</th></tr></thead><tbody>`)
+		for _, name := range names {
+			d := res.B.Tools[name] - res.A.Tools[name]
+			b.WriteString(fmt.Sprintf(`<tr><td>%s</td><td>%d</td><td>%d</td><td class="%s">%s</td></tr>`,
+				html.EscapeString(name), res.A.Tools[name], res.B.Tools[name], deltaClass(d), signedNumber(d)))
+		}
+		b.WriteString(`</tbody></table>`)
+	} else {
+		b.WriteString(`<p class="chart-empty">No tool calls</p>`)
+	}
+	b.WriteString(`</div>`)
+
+	onlyA, onlyB, both := compare.FileDiff(res.A.Files, res.B.Files)
+	b.WriteString(`<div class="chart-panel"><h2>Files</h2>`)
+	if len(onlyA)+len(onlyB)+len(both) == 0 {
+		b.WriteString(`<p class="chart-empty">No files read or changed</p>`)
+	}
+	for _, group := range []struct {
+		label string
+		files []string
+	}{{"Only A", onlyA}, {"Only B", onlyB}, {"Both", both}} {
+		if len(group.files) == 0 {
+			continue
+		}
+		b.WriteString(fmt.Sprintf(`<h3 class="compare-files-label">%s <span class="count">%d</span></h3><ul class="compare-files">`, group.label, len(group.files)))
+		for _, f := range group.files {
+			b.WriteString(fmt.Sprintf(`<li><code title="%s">%s</code>%s%s</li>`, html.EscapeString(f), html.EscapeString(truncatePath(f, 60)),
+				fileUseBadge("A", res.A.Files, f), fileUseBadge("B", res.B.Files, f)))
+		}
+		b.WriteString(`</ul>`)
+	}
+	b.WriteString(`</div>`)
+	b.WriteString(`</div>`)
+
+	b.WriteString(`</main>`)
+	b.WriteString(`</div>`)
+	b.WriteString(renderFooter())
+	b.WriteString(pageFooter())
+
+	return b.String()
+}
+
+func renderCompareTurn(b *strings.Builder, projectName, sessionID string, t *compare.Turn) {
+	if t == nil {
+		b.WriteString(`<span class="compare-missing">—</span>`)
+		return
+	}
+	text := t.Text()
+	if text == "" {
+		text = "(before first prompt)"
+	}
+	anchor := ""
+	if t.Prompt != nil {
+		anchor = "#msg-" + sanitizeID(t.Prompt.UUID)
+	}
+	b.WriteString(fmt.Sprintf(`<a class="compare-prompt" href="/session/%s/%s%s" title="%s">%s</a>`,
+		html.EscapeString(projectName), html.EscapeString(sessionID), anchor, html.EscapeString(text), html.EscapeString(truncate(getFirstLine(text), 90))))
+
+	b.WriteString(`<div class="compare-turn-meta">`)
+	names := make([]string, 0, len(t.Tools))
+	for name := range t.Tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		label := name
+		if n := t.Tools[name]; n > 1 {
+			label = fmt.Sprintf("%s×%d", name, n)
+		}
+		b.WriteString(fmt.Sprintf(`<span class="compare-tool">%s</span>`, html.EscapeString(label)))
+	}
+	if len(t.Files) > 0 {
+		files := make([]string, 0, len(t.Files))
+		for f := range t.Files {
+			files = append(files, f)
+		}
+		sort.Strings(files)
+		b.WriteString(fmt.Sprintf(`<span class="compare-stat" title="%s">%d files</span>`, html.EscapeString(strings.Join(files, "\n")), len(files)))
+	}
+	if total := t.Usage.Total(); total > 0 {
+		b.WriteString(fmt.Sprintf(`<span class="compare-stat">%s tokens</span>`, render.FormatTokens(total)))
+	}
+	if d := t.Duration().Seconds(); d > 0 {
+		b.WriteString(fmt.Sprintf(`<span class="compare-stat">%s</span>`, render.FormatDuration(d)))
+	}
+	b.WriteString(`</div>`)
+}
+
+func compareRowClass(row compare.Row) string {
+	switch {
+	case row.Shared:
+		return "compare-row-shared"
+	case row.B == nil:
+		return "compare-row-only-a"
+	case row.A == nil:
+		return "compare-row-only-b"
+	case row.Differs():
+		return "compare-row-differs"
+	}
+	return "compare-row-same"
+}
+
+func fileUseBadge(side string, files map[string]bool, f string) string {
+	changed, ok := files[f]
+	switch {
+	case !ok:
+		return ""
+	case changed:
+		return fmt.Sprintf(` <span class="compare-file-use changed" title="Changed in %s">%s changed</span>`, side, side)
+	}
+	return fmt.Sprintf(` <span class="compare-file-use" title="Read in %s">%s read</span>`, side, side)
+}
+
+func compareCost(e pricing.Estimate) string {
+	if e.IsZero() {
+		return "-"
+	}
+	return e.String()
+}
+
+func deltaClass(d int) string {
+	switch {
+	case d > 0:
+		return "delta delta-up"
+	case d < 0:
+		return "delta delta-down"
+	}
+	return "delta"
+}
+
+func signedNumber(n int) string {
+	switch {
+	case n > 0:
+		return "+" + render.FormatTokens(n)
+	case n < 0:
+		return "−" + render.FormatTokens(-n)
+	}
+	return "0"
+}
+
+func signedUSD(usd float64) string {
+	if usd < 0 {
+		return "−" + pricing.FormatUSD(-usd)
+	}
+	return "+" + pricing.FormatUSD(usd)
+}
+
+func signedDuration(seconds float64) string {
+	if seconds < 0 {
+		return "−" + render.FormatDuration(-seconds)
+	}
+	return "+" + render.FormatDuration(seconds)
+}
+
+func comparePageCSS() string {
+	return statsPageCSS() + `<style>
+.compare-sides { display: grid; grid-template-columns: 1fr 1fr; gap: 16px; margin-bottom: 16px; }
+.compare-side { background: var(--bg-secondary); border: 1px solid var(--border); border-radius: var(--radius); padding: 12px 14px; border-top: 3px solid var(--accent-project); }
+.compare-side.side-b { border-top-color: var(--primary); }
+.compare-side-label { font-size: 11px; font-weight: 600; color: var(--text-muted); text-transform: uppercase; }
+.compare-side .session-summary { margin: 6px 0; }
+.compare-leaf { font-size: 11px; font-family: var(--font-mono); color: var(--text-muted); }
+.compare-table { width: 100%; border-collapse: collapse; font-size: 13px; }
+.compare-table th { text-align: left; font-weight: 600; color: var(--text-muted); font-size: 12px; padding: 4px 8px; border-bottom: 1px solid var(--border); }
+.compare-table td { padding: 6px 8px; border-bottom: 1px solid var(--border); vertical-align: top; }
+.compare-turns td { width: 48%; }
+.compare-turns td.compare-num { width: 4%; color: var(--text-muted); font-family: var(--font-mono); }
+.compare-row-shared { opacity: 0.6; }
+.compare-row-differs td:not(.compare-num) { background: var(--compacted-bg); }
+.compare-row-only-a td:nth-child(2), .compare-row-only-b td:nth-child(3) { background: var(--error-bg); }
+.compare-diverge td { border-top: 2px solid var(--error-border); }
+.compare-prompt { color: var(--text); text-decoration: none; font-weight: 500; }
+.compare-prompt:hover { color: var(--primary); }
+.compare-missing { color: var(--text-muted); }
+.compare-shared { display: block; font-size: 11px; color: var(--text-muted); margin-top: 2px; }
+.compare-turn-meta { display: flex; flex-wrap: wrap; gap: 4px; margin-top: 4px; }
+.compare-tool { font-size: 11px; font-family: var(--font-mono); background: var(--bg-tertiary); padding: 1px 6px; border-radius: 3px; }
+.compare-stat { font-size: 11px; color: var(--text-muted); margin-left: 4px; }
+.delta { font-family: var(--font-mono); color: var(--text-muted); }
+.delta-up { color: var(--error-border); }
+.delta-down { color: var(--assistant-border); }
+.compare-files-label { font-size: 12px; margin: 8px 0 4px; }
+.compare-files { list-style: none; font-size: 12px; }
+.compare-files li { padding: 2px 0; }
+.compare-file-use { font-size: 10px; color: var(--text-muted); }
+.compare-file-use.changed { color: var(--primary); }
+</style>`
+}
@@ -0,0 +1,350 @@
+package web
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+// idleAfter is how long a session may go unchanged before it is reported idle
+var idleAfter = 2 * time.Minute
+
+// feedInterval batches filesystem events into at most one rescan per project
+const feedInterval = time.Second
+
+// feedSession is the JSON payload of every feed event
+type feedSession struct {
+	Project     string `json:"project"`
+	ProjectName string `json:"project_name"`
+	ID          string `json:"id"`
+	Summary     string `json:"summary"`
+	Branch      string `json:"branch,omitempty"`
+	StartTime   string `json:"start_time"`
+	EndTime     string `json:"end_time"`
+	Messages    int    `json:"messages"`
+	ToolCalls   int    `json:"tool_calls"`
+	Tokens      int    `json:"tokens"`
+	Active      bool   `json:"active"`
+}
+
+// feedEvent is one SSE event on a project or global feed
+type feedEvent struct {
+	Type    string // "session_new", "session_update" or "session_idle"
+	Session feedSession
+}
+
+type sessionState struct {
+	project    string
+	session    *parser.Session
+	lastChange time.Time
+	active     bool
+}
+
+// feedHub watches the projects tree while anyone is subscribed and turns
+// file changes into session lifecycle events
+type feedHub struct {
+	mu       sync.Mutex
+	subs     map[chan feedEvent]string // Subscriber -> project filter, "" for all
+	stop     chan struct{}
+	watcher  *fsnotify.Watcher // nil when falling back to periodic rescans
+	sessions map[string]map[string]*sessionState
+	dirty    map[string]bool
+}
+
+var feeds = &feedHub{subs: make(map[chan feedEvent]string)}
+
+// subscribe starts the hub if needed and returns the feed for project
+// (every project if empty) along with the sessions active right now
+func (h *feedHub) subscribe(project string) (<-chan feedEvent, []feedSession, func()) {
+	ch := make(chan feedEvent, subscriberBuffer)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.stop == nil {
+		h.startLocked()
+	}
+	h.subs[ch] = project
+
+	var active []feedSession
+	for name, sessions := range h.sessions {
+		if project != "" && name != project {
+			continue
+		}
+		for _, st := range sessions {
+			if st.active {
+				active = append(active, st.payload())
+			}
+		}
+	}
+
+	return ch, active, func() { h.unsubscribe(ch) }
+}
+
+func (h *feedHub) unsubscribe(ch chan feedEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[ch]; ok {
+		delete(h.subs, ch)
+		close(ch)
+	}
+	if len(h.subs) == 0 && h.stop != nil {
+		close(h.stop)
+		h.stop = nil
+	}
+}
+
+// startLocked takes the initial snapshot and starts watching
+func (h *feedHub) startLocked() {
+	h.stop = make(chan struct{})
+	h.sessions = make(map[string]map[string]*sessionState)
+	h.dirty = make(map[string]bool)
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("feed: fsnotify unavailable, rescanning every %v: %v", feedInterval, err)
+		w = nil
+	} else if err := w.Add(projectsDir); err != nil {
+		log.Printf("feed: %s: %v, rescanning every %v", projectsDir, err, feedInterval)
+		w.Close()
+		w = nil
+	}
+	h.watcher = w
+
+	// Watches go in before the scan so nothing written in between is missed
+	now := time.Now()
+	if entries, err := os.ReadDir(projectsDir); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() {
+				h.watchLocked(filepath.Join(projectsDir, entry.Name()))
+			}
+		}
+	}
+	projects, _ := parser.DiscoverProjects(projectsDir)
+	for _, p := range projects {
+		known := make(map[string]*sessionState)
+		h.sessions[p.EncodedName] = known
+		for _, s := range p.Sessions {
+			st := &sessionState{project: p.EncodedName, session: s, lastChange: s.EndTime}
+			st.active = now.Sub(s.EndTime) < idleAfter
+			if st.active {
+				h.watchSessionLocked(p.EncodedName, s.ID)
+			}
+			known[s.ID] = st
+		}
+	}
+
+	go h.run(h.stop, w)
+}
+
+func (h *feedHub) watchLocked(dir string) {
+	if h.watcher != nil {
+		_ = h.watcher.Add(dir)
+	}
+}
+
+// watchSessionLocked follows a session's subagents directory, where agent
+// transcripts are written while the parent file stays quiet
+func (h *feedHub) watchSessionLocked(project, id string) {
+	dir := filepath.Join(projectsDir, project, id)
+	if _, err := os.Stat(dir); err != nil {
+		return
+	}
+	h.watchLocked(dir)
+	if _, err := os.Stat(filepath.Join(dir, "subagents")); err == nil {
+		h.watchLocked(filepath.Join(dir, "subagents"))
+	}
+}
+
+func (h *feedHub) run(stop chan struct{}, w *fsnotify.Watcher) {
+	ticker := time.NewTicker(feedInterval)
+	defer ticker.Stop()
+
+	var events chan fsnotify.Event
+	var errs chan error
+	if w != nil {
+		defer w.Close()
+		events, errs = w.Events, w.Errors
+	}
+
+	for {
+		select {
+		case <-stop:
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			h.mu.Lock()
+			if h.stop == stop {
+				h.noteLocked(event)
+			}
+			h.mu.Unlock()
+		case err, ok := <-errs:
+			if !ok {
+				return
+			}
+			// Events may have been dropped; rescan everything on the next tick
+			log.Printf("feed: %v", err)
+			h.mu.Lock()
+			for project := range h.sessions {
+				h.dirty[project] = true
+			}
+			h.mu.Unlock()
+		case <-ticker.C:
+			h.mu.Lock()
+			if h.stop == stop {
+				h.tickLocked(time.Now())
+			}
+			h.mu.Unlock()
+		}
+	}
+}
+
+// noteLocked marks the project an event belongs to for rescanning,
+// forgets removed sessions so a recreated one is new again, and follows
+// new project, session and subagents directories
+func (h *feedHub) noteLocked(event fsnotify.Event) {
+	rel, err := filepath.Rel(projectsDir, event.Name)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	h.dirty[parts[0]] = true
+
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		if len(parts) == 2 && strings.HasSuffix(parts[1], ".jsonl") {
+			delete(h.sessions[parts[0]], strings.TrimSuffix(parts[1], ".jsonl"))
+		}
+		return
+	}
+
+	if !event.Has(fsnotify.Create) || len(parts) > 3 || (len(parts) == 3 && parts[2] != "subagents") {
+		return
+	}
+	if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+		h.watchLocked(event.Name)
+	}
+}
+
+// tickLocked rescans changed projects and reports sessions gone idle
+func (h *feedHub) tickLocked(now time.Time) {
+	if h.watcher == nil {
+		if entries, err := os.ReadDir(projectsDir); err == nil {
+			for _, entry := range entries {
+				if entry.IsDir() {
+					h.dirty[entry.Name()] = true
+				}
+			}
+		}
+	}
+
+	for project := range h.dirty {
+		h.rescanLocked(project, now)
+	}
+	clear(h.dirty)
+
+	for _, sessions := range h.sessions {
+		for _, st := range sessions {
+			if st.active && now.Sub(st.lastChange) >= idleAfter {
+				st.active = false
+				h.broadcastLocked("session_idle", st)
+			}
+		}
+	}
+}
+
+func (h *feedHub) rescanLocked(project string, now time.Time) {
+	sessions, err := parser.DiscoverSessions(filepath.Join(projectsDir, project))
+	if err != nil {
+		delete(h.sessions, project)
+		return
+	}
+
+	known, ok := h.sessions[project]
+	if !ok {
+		known = make(map[string]*sessionState)
+		h.sessions[project] = known
+	}
+	// Without fsnotify, removals are only seen here
+	present := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		present[s.ID] = true
+	}
+	for id := range known {
+		if !present[id] {
+			delete(known, id)
+		}
+	}
+	for _, s := range sessions {
+		st, ok := known[s.ID]
+		if !ok {
+			st = &sessionState{project: project, session: s, lastChange: now, active: true}
+			known[s.ID] = st
+			h.watchSessionLocked(project, s.ID)
+			h.broadcastLocked("session_new", st)
+			continue
+		}
+		if !sessionChanged(st.session, s) {
+			continue
+		}
+		if !st.active {
+			h.watchSessionLocked(project, s.ID)
+		}
+		st.session = s
+		st.lastChange = now
+		st.active = true
+		h.broadcastLocked("session_update", st)
+	}
+}
+
+func sessionChanged(a, b *parser.Session) bool {
+	return a.Summary != b.Summary || !a.EndTime.Equal(b.EndTime) || !reflect.DeepEqual(a.Stats, b.Stats)
+}
+
+// broadcastLocked drops subscribers that fell behind, like watch.Hub does
+func (h *feedHub) broadcastLocked(kind string, st *sessionState) {
+	ev := feedEvent{Type: kind, Session: st.payload()}
+	for ch, project := range h.subs {
+		if project != "" && project != st.project {
+			continue
+		}
+		select {
+		case ch <- ev:
+		default:
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+func (st *sessionState) payload() feedSession {
+	s := st.session
+	return feedSession{
+		Project:     st.project,
+		ProjectName: parser.GetProjectDisplayName(st.project),
+		ID:          s.ID,
+		Summary:     s.Summary,
+		Branch:      s.GitBranch,
+		StartTime:   s.StartTime.Format(time.RFC3339),
+		EndTime:     s.EndTime.Format(time.RFC3339),
+		Messages:    s.Stats.MessageCount,
+		ToolCalls:   s.Stats.ToolCalls,
+		Tokens: parser.Usage{
+			InputTokens:       s.Stats.InputTokens,
+			OutputTokens:      s.Stats.OutputTokens,
+			CacheReadTokens:   s.Stats.CacheReadTokens,
+			CacheCreateTokens: s.Stats.CacheCreateTokens,
+		}.Total(),
+		Active: st.active,
+	}
+}
@@ -0,0 +1,119 @@
+package web
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thevibeworks/ccx/internal/testutil"
+)
+
+func nextFeedEvent(t *testing.T, events <-chan feedEvent) feedEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatal("feed channel closed")
+		}
+		return ev
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for feed event")
+	}
+	return feedEvent{}
+}
+
+func TestFeedHub_SessionLifecycle(t *testing.T) {
+	dir := setupTestDir(t)
+	projectsDir = filepath.Join(dir, "projects")
+	defer func(d time.Duration) { idleAfter = d }(idleAfter)
+	idleAfter = 3 * time.Second
+
+	all, active, cancelAll := feeds.subscribe("")
+	defer cancelAll()
+	if len(active) != 0 {
+		t.Errorf("active = %+v, want none (fixture is from 2024)", active)
+	}
+	other, _, cancelOther := feeds.subscribe("-other-project")
+	defer cancelOther()
+
+	path := filepath.Join(projectsDir, "-test-project", "new-session.jsonl")
+	now := time.Now().UTC().Format(time.RFC3339)
+	line := `{"type":"user","timestamp":"` + now + `","uuid":"n1","message":{"content":"Fix the build"}}` + "\n"
+	if err := os.WriteFile(path, []byte(line), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ev := nextFeedEvent(t, all)
+	if ev.Type != "session_new" || ev.Session.ID != "new-session" || ev.Session.Summary != "Fix the build" || !ev.Session.Active {
+		t.Fatalf("event = %+v, want session_new for new-session", ev)
+	}
+	if ev.Session.Project != "-test-project" {
+		t.Errorf("Project = %q", ev.Session.Project)
+	}
+
+	testutil.AppendFile(t, path, `{"type":"assistant","timestamp":"`+now+`","uuid":"n2","parentUuid":"n1","message":{"usage":{"input_tokens":10,"output_tokens":20,"cache_read_input_tokens":300,"cache_creation_input_tokens":4000},"content":[{"type":"tool_use","id":"t1","name":"Bash","input":{}}]}}`+"\n")
+	ev = nextFeedEvent(t, all)
+	if ev.Type != "session_update" || ev.Session.Messages != 2 || ev.Session.ToolCalls != 1 {
+		t.Fatalf("event = %+v, want session_update with 2 messages", ev)
+	}
+	if ev.Session.Tokens != 4330 {
+		t.Errorf("Tokens = %d, want 4330 including cache tokens", ev.Session.Tokens)
+	}
+
+	ev = nextFeedEvent(t, all)
+	if ev.Type != "session_idle" || ev.Session.ID != "new-session" || ev.Session.Active {
+		t.Fatalf("event = %+v, want session_idle", ev)
+	}
+
+	// A deleted session that comes back is new again
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * feedInterval)
+	if err := os.WriteFile(path, []byte(line), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ev = nextFeedEvent(t, all)
+	if ev.Type != "session_new" || ev.Session.ID != "new-session" {
+		t.Fatalf("event = %+v, want session_new for the recreated session", ev)
+	}
+
+	select {
+	case ev := <-other:
+		t.Errorf("other project's feed got %+v", ev)
+	default:
+	}
+}
+
+func TestHandleWatchFeed(t *testing.T) {
+	dir := setupTestDir(t)
+	projectsDir = filepath.Join(dir, "projects")
+
+	srv := httptest.NewServer(http.HandlerFunc(handleWatchFeed))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/watch/project/-test-project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	first, _ := bufio.NewReader(resp.Body).ReadString('\n')
+	if !strings.HasPrefix(first, "event: connected") {
+		t.Errorf("first line = %q, want connected event", first)
+	}
+
+	req := httptest.NewRequest("GET", "/api/watch/project/nope", nil)
+	w := httptest.NewRecorder()
+	handleWatchFeed(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown project status = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,241 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/thevibeworks/ccx/internal/config"
+	"github.com/thevibeworks/ccx/internal/files"
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+// handleFiles serves /files/{project}/{session}: every file the session
+// changed, with a diff per Write or Edit. ?leaf= picks the branch of a
+// forked session; the latest one is used by default.
+func handleFiles(w http.ResponseWriter, r *http.Request) {
+	session, projectName, leaf, ok := loadFilesSession(w, r, "/files/")
+	if !ok {
+		return
+	}
+	theme := r.URL.Query().Get("theme")
+	if theme == "" {
+		theme = config.Theme()
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, renderFilesPage(session, projectName, leaf, files.History(session, leaf), theme))
+}
+
+// handleAPIFiles serves /api/files/{project}/{session} as JSON
+func handleAPIFiles(w http.ResponseWriter, r *http.Request) {
+	session, _, leaf, ok := loadFilesSession(w, r, "/api/files/")
+	if !ok {
+		return
+	}
+	history := files.History(session, leaf)
+	if history == nil {
+		history = []*files.File{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"session": session.ID,
+		"cwd":     session.CWD,
+		"files":   history,
+	})
+}
+
+func loadFilesSession(w http.ResponseWriter, r *http.Request, prefix string) (*parser.Session, string, string, bool) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		http.NotFound(w, r)
+		return nil, "", "", false
+	}
+	session, err := parser.FindSession(projectsDir, parts[0], parts[1])
+	if err != nil || session == nil {
+		http.NotFound(w, r)
+		return nil, "", "", false
+	}
+	fullSession, err := parser.ParseSession(session.FilePath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, "", "", false
+	}
+	leaf := r.URL.Query().Get("leaf")
+	if leaf != "" && branchIndex(fullSession.Branches, leaf) < 0 {
+		http.Error(w, fmt.Sprintf("no branch ends at %q", leaf), http.StatusBadRequest)
+		return nil, "", "", false
+	}
+	return fullSession, parts[0], leaf, true
+}
+
+func renderFilesPage(session *parser.Session, projectName, leaf string, history []*files.File, theme string) string {
+	var b strings.Builder
+
+	projDisplay := parser.GetProjectDisplayName(projectName)
+	sessionURL := fmt.Sprintf("/session/%s/%s", url.PathEscape(projectName), url.PathEscape(session.ID))
+	if leaf != "" {
+		sessionURL += "?leaf=" + url.QueryEscape(leaf)
+	}
+
+	b.WriteString(pageHeader(fmt.Sprintf("Files %s - ccx", truncate(session.ID, 8)), theme))
+	b.WriteString(filesPageCSS())
+	b.WriteString(renderTopNav(projectName, session.ID))
+	b.WriteString(`<div class="layout">`)
+	b.WriteString(`<main class="main-content files-main">`)
+	b.WriteString(`<div class="page-header page-header-sessions">`)
+	b.WriteString(fmt.Sprintf(`<div class="breadcrumb"><a href="/">Projects</a> <span class="sep">/</span> <a href="/project/%s">%s</a> <span class="sep">/</span> <a href="%s">%s</a> <span class="sep">/</span> <span class="current">Files</span></div>`,
+		html.EscapeString(projectName), html.EscapeString(projDisplay), html.EscapeString(sessionURL), html.EscapeString(truncate(session.ID, 8))))
+	b.WriteString(`<h1>Files changed</h1>`)
+	added, removed, steps := 0, 0, 0
+	for _, f := range history {
+		added += f.Added
+		removed += f.Removed
+		steps += len(f.Steps)
+	}
+	meta := fmt.Sprintf("%d files · %d edits · <span class=\"files-add\">+%d</span> <span class=\"files-del\">−%d</span>", len(history), steps, added, removed)
+	if session.CWD != "" {
+		meta += fmt.Sprintf(" · relative to <code>%s</code>", html.EscapeString(session.CWD))
+	}
+	if len(history) > 0 {
+		patchURL := fmt.Sprintf("/api/export/%s/%s?format=patch", url.PathEscape(projectName), url.PathEscape(session.ID))
+		if leaf != "" {
+			patchURL += "&leaf=" + url.QueryEscape(leaf)
+		}
+		meta += fmt.Sprintf(` · <a href="%s" title="One commit per prompt, for git am">download patch</a>`, html.EscapeString(patchURL))
+	}
+	b.WriteString(fmt.Sprintf(`<p class="files-meta">%s</p>`, meta))
+	b.WriteString(`</div>`)
+
+	if len(history) == 0 {
+		b.WriteString(`<div class="empty">No Write, Edit, MultiEdit or NotebookEdit calls in this session</div>`)
+	}
+	for i, f := range history {
+		rel := files.RelPath(session.CWD, f.Path)
+		b.WriteString(fmt.Sprintf(`<details class="files-file" id="file-%d" open>`, i))
+		b.WriteString(fmt.Sprintf(`<summary><code class="files-path" title="%s">%s</code>`, html.EscapeString(f.Path), html.EscapeString(rel)))
+		if f.Created {
+			b.WriteString(`<span class="files-badge">new</span>`)
+		}
+		b.WriteString(fmt.Sprintf(` <span class="files-add">+%d</span> <span class="files-del">−%d</span> <span class="files-count">%d edits</span></summary>`, f.Added, f.Removed, len(f.Steps)))
+		for _, s := range f.Steps {
+			renderFileStep(&b, s, sessionURL)
+		}
+		b.WriteString(`</details>`)
+	}
+
+	b.WriteString(`</main>`)
+	b.WriteString(`</div>`)
+	b.WriteString(renderFooter())
+	b.WriteString(pageFooter())
+	return b.String()
+}
+
+func renderFileStep(b *strings.Builder, s *files.Step, sessionURL string) {
+	class := "files-step"
+	if s.Failed {
+		class += " files-failed"
+	}
+	b.WriteString(fmt.Sprintf(`<div class="%s">`, class))
+	b.WriteString(fmt.Sprintf(`<div class="files-step-head"><a href="%s#msg-%s">%s</a> <span class="files-tool">%s</span>`,
+		html.EscapeString(sessionURL), sanitizeID(s.MessageUUID), s.Timestamp.Format("15:04:05"), html.EscapeString(s.Tool)))
+	if s.Agent {
+		b.WriteString(`<span class="files-badge">agent</span>`)
+	}
+	if s.Failed {
+		b.WriteString(`<span class="files-badge files-badge-error">failed</span>`)
+	} else {
+		b.WriteString(fmt.Sprintf(` <span class="files-add">+%d</span> <span class="files-del">−%d</span>`, s.Added, s.Removed))
+	}
+	if s.Note != "" {
+		b.WriteString(fmt.Sprintf(` <span class="files-note">%s</span>`, html.EscapeString(s.Note)))
+	}
+	b.WriteString(`</div>`)
+
+	if len(s.Hunks) > 0 {
+		b.WriteString(`<pre class="files-diff">`)
+		for _, h := range s.Hunks {
+			b.WriteString(fmt.Sprintf(`<span class="diff-hunk">%s</span>`+"\n", html.EscapeString(h.Header(s.Exact))))
+			for _, l := range h.Lines {
+				lineClass := "diff-ctx"
+				switch {
+				case strings.HasPrefix(l, "+"):
+					lineClass = "diff-add"
+				case strings.HasPrefix(l, "-"):
+					lineClass = "diff-del"
+				case strings.HasPrefix(l, `\`):
+					lineClass = "diff-hunk"
+				}
+				b.WriteString(fmt.Sprintf(`<span class="%s">%s</span>`+"\n", lineClass, html.EscapeString(l)))
+			}
+		}
+		b.WriteString(`</pre>`)
+	}
+	b.WriteString(`</div>`)
+}
+
+// renderFilesSection lists the changed files in the session info panel
+func renderFilesSection(b *strings.Builder, session *parser.Session, projectName, leaf string) {
+	history := files.History(session, leaf)
+	var changed []*files.File
+	for _, f := range history {
+		if f.Changed() {
+			changed = append(changed, f)
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+	filesURL := fmt.Sprintf("/files/%s/%s", url.PathEscape(projectName), url.PathEscape(session.ID))
+	if leaf != "" {
+		filesURL += "?leaf=" + url.QueryEscape(leaf)
+	}
+
+	b.WriteString(`<div class="info-section info-section-files">`)
+	b.WriteString(fmt.Sprintf(`<div class="info-section-header"><a href="%s">Files changed (%d)</a></div>`, html.EscapeString(filesURL), len(changed)))
+	shown := 0
+	for i, f := range history {
+		if !f.Changed() {
+			continue
+		}
+		if shown == maxInfoFiles {
+			b.WriteString(fmt.Sprintf(`<div class="info-row info-cache"><a href="%s">all %d files…</a></div>`, html.EscapeString(filesURL), len(changed)))
+			break
+		}
+		rel := files.RelPath(session.CWD, f.Path)
+		b.WriteString(fmt.Sprintf(`<div class="info-row info-cache" title="%s"><span class="info-label"><a href="%s#file-%d">%s</a></span><span class="info-value">+%d −%d</span></div>`,
+			html.EscapeString(f.Path), html.EscapeString(filesURL), i, html.EscapeString(truncatePath(rel, 32)), f.Added, f.Removed))
+		shown++
+	}
+	b.WriteString(`</div>`)
+}
+
+func filesPageCSS() string {
+	return `<style>
+.files-main { max-width: 1100px; margin: 0 auto; }
+.files-meta { color: var(--text-muted); font-size: 13px; }
+.files-file { border: 1px solid var(--border); border-radius: var(--radius); margin-bottom: 16px; background: var(--bg); }
+.files-file > summary { cursor: pointer; padding: 8px 12px; background: var(--bg-secondary); border-radius: var(--radius); display: flex; gap: 8px; align-items: baseline; }
+.files-path { font-family: var(--font-mono); font-size: 13px; font-weight: 600; margin-right: auto; }
+.files-count { color: var(--text-muted); font-size: 12px; }
+.files-step { border-top: 1px solid var(--border); }
+.files-step-head { padding: 6px 12px; font-size: 12px; display: flex; gap: 6px; align-items: baseline; }
+.files-step-head a { font-family: var(--font-mono); color: var(--text-muted); }
+.files-tool { font-weight: 600; }
+.files-note { color: var(--text-muted); font-style: italic; }
+.files-badge { font-size: 10px; padding: 0 6px; border-radius: 8px; border: 1px solid var(--border); color: var(--text-muted); }
+.files-badge-error { color: #c66; border-color: #c66; }
+.files-failed .files-step-head { opacity: 0.7; }
+.files-add { color: #595; font-family: var(--font-mono); }
+.files-del { color: #a55; font-family: var(--font-mono); }
+.files-diff { margin: 0; padding: 8px 0; font-family: var(--font-mono); font-size: 12px; overflow-x: auto; background: var(--bg); }
+.files-diff span { display: block; padding: 0 12px; white-space: pre; }
+.files-diff .diff-add { background: rgba(100,255,100,0.1); color: #595; }
+.files-diff .diff-del { background: rgba(255,100,100,0.1); color: #a55; }
+.files-diff .diff-hunk { color: var(--text-muted); background: var(--bg-secondary); }
+[data-theme="dark"] .files-diff .diff-add { background: rgba(100,255,100,0.15); color: #8f8; }
+[data-theme="dark"] .files-diff .diff-del { background: rgba(255,100,100,0.15); color: #f88; }
+</style>`
+}
@@ -0,0 +1,454 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thevibeworks/ccx/internal/config"
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/pricing"
+	"github.com/thevibeworks/ccx/internal/redact"
+	"github.com/thevibeworks/ccx/internal/render"
+)
+
+// publishing is set while Publish renders a site; pages leave out the
+// controls that need the server
+var publishing bool
+
+// PublishOptions configure the site Publish writes
+type PublishOptions struct {
+	Projects []string         // Project names, as ccx projects lists them or part of one; all projects when empty
+	Theme    string           // Page theme: light or dark
+	Redactor *redact.Redactor // Masks everything published; nil for none
+}
+
+// maxSearchText caps the prompt text indexed per session, so the search
+// index stays small enough to load with every page
+const maxSearchText = 4000
+
+// siteEntry is one page in the search index of a published site
+type siteEntry struct {
+	Type    string `json:"type"` // project or session
+	Title   string `json:"title"`
+	Project string `json:"project"`
+	URL     string `json:"url"` // Relative to the site root
+	Time    string `json:"time,omitempty"`
+	Text    string `json:"text,omitempty"` // The session's prompts, searched and quoted in snippets
+}
+
+// Publish writes a static site of the sessions under projDir to outDir: an
+// index of projects, a page listing each project's sessions, a page per
+// session and a search index. Links are relative, so the site works from
+// any static file server or opened from disk. It returns how many sessions
+// were published.
+func Publish(projDir, outDir string, opts PublishOptions) (int, error) {
+	projectsDir = projDir
+	claudeHome = config.ClaudeHome()
+	table, err := config.Pricing()
+	if err != nil {
+		return 0, err
+	}
+	prices = table
+	setRedactor(opts.Redactor)
+	publishing = true
+	defer func() {
+		setRedactor(nil)
+		publishing = false
+	}()
+
+	projects, err := publishedProjects(opts.Projects)
+	if err != nil {
+		return 0, err
+	}
+
+	var index []siteEntry
+	published := 0
+	for _, p := range projects {
+		sort.Slice(p.Sessions, func(i, j int) bool {
+			return p.Sessions[i].EndTime.After(p.Sessions[j].EndTime)
+		})
+		for _, s := range p.Sessions {
+			fullSession, err := parser.ParseSession(s.FilePath)
+			if err != nil {
+				return published, fmt.Errorf("failed to parse session %s: %w", s.ID, err)
+			}
+			page := renderSessionPage(fullSession, p.EncodedName, "", p.Sessions, false, false, true, opts.Theme, loadAnnotations(fullSession.ID))
+			page = withSiteSearch(relink(page, "../"), "../")
+			if err := writeSiteFile(outDir, filepath.Join(p.EncodedName, s.ID+".html"), page); err != nil {
+				return published, err
+			}
+			index = append(index, siteEntry{
+				Type:    "session",
+				Title:   sessionTitle(s),
+				Project: p.Name,
+				URL:     siteSessionPath(p.EncodedName, s.ID),
+				Time:    s.StartTime.Format("2006-01-02 15:04"),
+				Text:    promptText(fullSession),
+			})
+			published++
+		}
+
+		page := withSiteSearch(renderSiteProjectPage(p, projects, opts.Theme), "../")
+		if err := writeSiteFile(outDir, filepath.Join(p.EncodedName, "index.html"), page); err != nil {
+			return published, err
+		}
+		index = append(index, siteEntry{
+			Type:    "project",
+			Title:   p.Name,
+			Project: p.Name,
+			URL:     siteProjectPath(p.EncodedName),
+			Time:    p.LastModified.Format("2006-01-02 15:04"),
+		})
+	}
+
+	if err := writeSiteFile(outDir, "index.html", withSiteSearch(renderSiteIndexPage(projects, published, opts.Theme), "")); err != nil {
+		return published, err
+	}
+
+	// The index is JSON assigned in a script rather than a .json file, as
+	// pages opened from disk can load scripts but not fetch files
+	data, err := json.Marshal(index)
+	if err != nil {
+		return published, fmt.Errorf("failed to build search index: %w", err)
+	}
+	if err := writeSiteFile(outDir, "search-index.js", "window.ccxSearchIndex = "+string(data)+";\n"); err != nil {
+		return published, err
+	}
+	return published, nil
+}
+
+// publishedProjects finds the named projects, newest first, or all of them
+func publishedProjects(names []string) ([]*parser.Project, error) {
+	var projects []*parser.Project
+	if len(names) == 0 {
+		all, err := parser.DiscoverProjects(projectsDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover projects: %w", err)
+		}
+		projects = all
+	}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		p, err := parser.FindProject(projectsDir, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find project: %w", err)
+		}
+		if p == nil {
+			return nil, fmt.Errorf("project not found: %s", name)
+		}
+		if !seen[p.EncodedName] {
+			seen[p.EncodedName] = true
+			projects = append(projects, p)
+		}
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].LastModified.After(projects[j].LastModified)
+	})
+	return projects, nil
+}
+
+func writeSiteFile(outDir, name, content string) error {
+	path := filepath.Join(outDir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// siteProjectPath and siteSessionPath are where a project's session list
+// and a session's page live, relative to the site root
+func siteProjectPath(project string) string {
+	return url.PathEscape(project) + "/index.html"
+}
+
+func siteSessionPath(project, sessionID string) string {
+	return url.PathEscape(project) + "/" + url.PathEscape(sessionID) + ".html"
+}
+
+// serverLink matches the links a page makes to the server's own routes
+var serverLink = regexp.MustCompile(`href="/[^"']*"`)
+
+// relink points a page rendered for the server at the published files,
+// relative to root, the path from the page back to the site root. Links to
+// server pages that aren't published lose their target.
+func relink(page, root string) string {
+	return serverLink.ReplaceAllStringFunc(page, func(attr string) string {
+		u, err := url.Parse(html.UnescapeString(strings.TrimSuffix(strings.TrimPrefix(attr, `href="`), `"`)))
+		if err != nil {
+			return ""
+		}
+		var target string
+		parts := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+		switch {
+		case u.Path == "/":
+			target = "index.html"
+		case len(parts) == 2 && parts[0] == "project":
+			target = siteProjectPath(parts[1])
+		case len(parts) == 3 && parts[0] == "session":
+			target = siteSessionPath(parts[1], parts[2])
+		default:
+			return ""
+		}
+		if u.Fragment != "" {
+			target += "#" + u.EscapedFragment()
+		}
+		return `href="` + html.EscapeString(root+target) + `"`
+	})
+}
+
+// sessionTitle is how a session is listed: its summary, or its short ID
+func sessionTitle(s *parser.Session) string {
+	if s.Summary != "" {
+		return s.Summary
+	}
+	return truncate(s.ID, 8)
+}
+
+// promptText joins a session's prompts for the search index
+func promptText(s *parser.Session) string {
+	var b strings.Builder
+	for _, msg := range parser.Flatten(s.RootMessages) {
+		if msg.Kind != parser.KindUserPrompt {
+			continue
+		}
+		text := strings.TrimSpace(getFirstTextContent(msg))
+		if text == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(text)
+		if b.Len() >= maxSearchText {
+			break
+		}
+	}
+	return strings.ToValidUTF8(truncate(b.String(), maxSearchText), "")
+}
+
+// renderSiteProjectNav lists the published projects beside a site page
+func renderSiteProjectNav(b *strings.Builder, projects []*parser.Project, active, root string) {
+	b.WriteString(`<aside class="panel-nav">`)
+	b.WriteString(fmt.Sprintf(`<div class="panel-header"><a href="%sindex.html">Projects</a></div>`, root))
+	b.WriteString(`<div class="panel-list">`)
+	for _, p := range projects {
+		class := "panel-item"
+		if p.EncodedName == active {
+			class += " active"
+		}
+		b.WriteString(fmt.Sprintf(`<a href="%s%s" class="%s" title="%s">%s</a>`,
+			root, html.EscapeString(siteProjectPath(p.EncodedName)), class, html.EscapeString(p.Name), html.EscapeString(truncate(p.Name, 24))))
+	}
+	b.WriteString(`</div>`)
+	b.WriteString(`</aside>`)
+}
+
+func renderSiteIndexPage(projects []*parser.Project, totalSessions int, theme string) string {
+	var b strings.Builder
+
+	b.WriteString(pageHeader("ccx", theme))
+	b.WriteString(relink(renderTopNav("", ""), ""))
+	b.WriteString(`<div class="layout two-panel">`)
+	renderSiteProjectNav(&b, projects, "", "")
+
+	b.WriteString(`<main class="main-content">`)
+	b.WriteString(`<div class="page-header page-header-projects">`)
+	b.WriteString(`<span class="page-badge badge-project">P</span>`)
+	b.WriteString(`<h1>Projects</h1>`)
+	b.WriteString(fmt.Sprintf(`<div class="stats">%d projects / %d sessions · published %s</div>`, len(projects), totalSessions, time.Now().Format("2006-01-02 15:04")))
+	b.WriteString(`</div>`)
+
+	b.WriteString(`<div class="card-grid">`)
+	for _, p := range projects {
+		sessionsLabel := "sessions"
+		if len(p.Sessions) == 1 {
+			sessionsLabel = "session"
+		}
+		b.WriteString(fmt.Sprintf(`
+<a href="%s" class="card project-card">
+	<div class="card-header">
+		<span class="card-title">%s</span>
+	</div>
+	<div class="card-stats">
+		<span class="stat stat-sessions">◉ %d %s</span>
+		<span class="stat-sep">•</span>
+		<span class="stat stat-age">%s</span>
+	</div>
+</a>`, html.EscapeString(siteProjectPath(p.EncodedName)), html.EscapeString(p.Name), len(p.Sessions), sessionsLabel, p.LastModified.Format("2006-01-02")))
+	}
+	b.WriteString(`</div>`)
+
+	b.WriteString(`</main>`)
+	b.WriteString(`</div>`)
+	b.WriteString(renderFooter())
+	b.WriteString(siteThemeJS())
+	b.WriteString(pageFooter())
+
+	return b.String()
+}
+
+func renderSiteProjectPage(project *parser.Project, projects []*parser.Project, theme string) string {
+	var b strings.Builder
+
+	b.WriteString(pageHeader(project.Name+" - ccx", theme))
+	b.WriteString(relink(renderTopNav(project.EncodedName, ""), "../"))
+	b.WriteString(`<div class="layout two-panel">`)
+	renderSiteProjectNav(&b, projects, project.EncodedName, "../")
+
+	b.WriteString(`<main class="main-content">`)
+	b.WriteString(`<div class="page-header page-header-sessions">`)
+	b.WriteString(fmt.Sprintf(`<div class="breadcrumb"><a href="../index.html">Projects</a> <span class="sep">/</span> <span class="current">%s</span></div>`, html.EscapeString(project.Name)))
+	b.WriteString(`<span class="page-badge badge-session">S</span>`)
+	b.WriteString(fmt.Sprintf(`<h1>%s</h1>`, html.EscapeString(project.Name)))
+	var projectCost pricing.Estimate
+	for _, s := range project.Sessions {
+		projectCost.Add(prices.Session(s.Stats))
+	}
+	if projectCost.IsZero() {
+		b.WriteString(fmt.Sprintf(`<div class="stats">%d sessions</div>`, len(project.Sessions)))
+	} else {
+		b.WriteString(fmt.Sprintf(`<div class="stats">%d sessions · <span title="%s">%s est.</span></div>`, len(project.Sessions), costTitle(projectCost), projectCost))
+	}
+	b.WriteString(`</div>`)
+
+	b.WriteString(`<div class="session-list">`)
+	for _, s := range project.Sessions {
+		totalTokens := s.Stats.InputTokens + s.Stats.OutputTokens
+		tokenDisplay := ""
+		if totalTokens > 0 {
+			tokenDisplay = fmt.Sprintf(`<span class="stat stat-tokens" title="Total tokens"><span class="stat-icon">⧫</span> %s</span>`, render.FormatTokens(totalTokens))
+		}
+		if cost := prices.Session(s.Stats); !cost.IsZero() {
+			tokenDisplay += fmt.Sprintf(`<span class="stat stat-cost" title="%s">%s</span>`, costTitle(cost), cost)
+		}
+		b.WriteString(fmt.Sprintf(`
+<a href="%s.html" class="card session-card">
+	<div class="session-header">
+		<code class="session-id">%s</code>
+		<span class="session-time">%s</span>
+	</div>
+	<div class="session-summary">%s</div>
+	<div class="session-stats">
+		<span class="stat"><span class="stat-icon">M</span> %d</span>
+		<span class="stat"><span class="stat-icon">T</span> %d</span>
+		%s
+	</div>
+</a>`, html.EscapeString(url.PathEscape(s.ID)), html.EscapeString(truncate(s.ID, 8)),
+			s.StartTime.Format("2006-01-02 15:04"), html.EscapeString(s.Summary),
+			s.Stats.MessageCount, s.Stats.ToolCalls, tokenDisplay))
+	}
+	b.WriteString(`</div>`)
+
+	b.WriteString(`</main>`)
+	b.WriteString(`</div>`)
+	b.WriteString(renderFooter())
+	b.WriteString(siteThemeJS())
+	b.WriteString(pageFooter())
+
+	return b.String()
+}
+
+// withSiteSearch adds the search over the site's index to a page; root is
+// the path from the page back to the site root
+func withSiteSearch(page, root string) string {
+	i := strings.LastIndex(page, "</body>")
+	if i < 0 {
+		return page
+	}
+	return page[:i] + siteSearchJS(root) + page[i:]
+}
+
+// siteThemeJS toggles the theme on the listing pages; session pages have
+// their own
+func siteThemeJS() string {
+	return `
+<script>
+const themeToggle = document.getElementById('theme-toggle');
+if (themeToggle) {
+  themeToggle.addEventListener('click', function() {
+    const html = document.documentElement;
+    const current = html.getAttribute('data-theme');
+    html.setAttribute('data-theme', current === 'dark' ? 'light' : 'dark');
+    localStorage.setItem('ccx-theme', html.getAttribute('data-theme'));
+  });
+}
+</script>`
+}
+
+// siteSearchJS searches the site's index from the top nav, matching every
+// word against titles, project names and prompts
+func siteSearchJS(root string) string {
+	return fmt.Sprintf(`
+<script src="%ssearch-index.js"></script>
+<script>
+(function() {
+  const root = %q;
+  const input = document.getElementById('global-search');
+  const results = document.getElementById('search-results');
+  if (!input || !results) return;
+  input.placeholder = 'Search sessions... (press /)';
+
+  function escapeText(s) {
+    if (!s) return '';
+    return s.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;').replace(/"/g, '&quot;').replace(/'/g, '&#39;');
+  }
+
+  function snippet(text, word) {
+    const i = (text || '').toLowerCase().indexOf(word);
+    if (i < 0) return '';
+    const start = Math.max(0, i - 40);
+    return (start > 0 ? '…' : '') + text.slice(start, i + word.length + 80).replace(/\s+/g, ' ');
+  }
+
+  input.addEventListener('input', function() {
+    const query = this.value.trim();
+    const words = query.toLowerCase().split(/\s+/).filter(Boolean);
+    if (!words.length) {
+      results.classList.remove('active');
+      return;
+    }
+    const hits = (window.ccxSearchIndex || []).filter(e => {
+      const text = (e.title + ' ' + e.project + ' ' + (e.text || '')).toLowerCase();
+      return words.every(w => text.includes(w));
+    }).slice(0, 20);
+    if (!hits.length) {
+      results.innerHTML = '<div class="search-empty">No results for "' + escapeText(query) + '"</div>';
+    } else {
+      results.innerHTML = hits.map(e => {
+        const badge = e.type === 'project' ? '<span class="result-badge badge-project">P</span>' : '<span class="result-badge badge-session">S</span>';
+        const snip = snippet(e.text, words[0]);
+        return '<a href="' + escapeText(root + e.url) + '" class="search-result">' + badge +
+          '<div class="result-body">' +
+          '<div class="result-title">' + escapeText(e.title) + '</div>' +
+          '<div class="result-meta">' + escapeText(e.project) + (e.time ? ' · ' + escapeText(e.time) : '') + '</div>' +
+          (snip ? '<div class="result-snippet">' + escapeText(snip) + '</div>' : '') +
+          '</div></a>';
+      }).join('');
+    }
+    results.classList.add('active');
+  });
+
+  input.addEventListener('blur', function() {
+    setTimeout(() => results.classList.remove('active'), 200);
+  });
+
+  document.addEventListener('keydown', function(e) {
+    if (e.key === '/' && !e.target.matches('input, textarea')) {
+      e.preventDefault();
+      input.focus();
+    }
+  });
+})();
+</script>`, html.EscapeString(root), root)
+}
@@ -0,0 +1,104 @@
+package web
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+)
+
+func TestPublish(t *testing.T) {
+	dir := setupTestDir(t)
+	out := filepath.Join(t.TempDir(), "site")
+
+	n, err := Publish(filepath.Join(dir, "projects"), out, PublishOptions{Theme: "light"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("Publish = %d sessions; want 1", n)
+	}
+	if publishing || redactor != nil {
+		t.Error("Publish left the server in publishing mode")
+	}
+
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	session := read("-test-project/test-session-123.html")
+	if !strings.Contains(session, "Hi there!") {
+		t.Error("session page is missing the conversation")
+	}
+	if serverLink.MatchString(session) {
+		t.Error("session page links to the server")
+	}
+	for _, want := range []string{`href="../index.html"`, `href="../-test-project/index.html"`, `src="../search-index.js"`} {
+		if !strings.Contains(session, want) {
+			t.Errorf("session page is missing %s", want)
+		}
+	}
+	for _, server := range []string{`id="tb-watch"`, `id="tb-export"`, `id="tag-input"`} {
+		if strings.Contains(session, server) {
+			t.Errorf("session page has server-only control %s", server)
+		}
+	}
+	if !strings.Contains(session, `<span class="turn-actions"><button class="turn-raw-btn"`) {
+		t.Error("session page turns still offer notes")
+	}
+
+	project := read("-test-project/index.html")
+	if !strings.Contains(project, `href="test-session-123.html"`) {
+		t.Error("project page doesn't link to its session")
+	}
+	if index := read("index.html"); !strings.Contains(index, `href="-test-project/index.html"`) {
+		t.Error("index doesn't link to the project")
+	}
+
+	script := read("search-index.js")
+	var entries []siteEntry
+	if err := json.Unmarshal([]byte(strings.TrimSuffix(strings.TrimPrefix(script, "window.ccxSearchIndex = "), ";\n")), &entries); err != nil {
+		t.Fatalf("search index: %v", err)
+	}
+	found := false
+	for _, e := range entries {
+		if e.Type == "session" && e.URL == "-test-project/test-session-123.html" && strings.Contains(e.Text, "Hello") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("search index has no entry for the session: %v", entries)
+	}
+
+	// The server renders as before once publishing is done
+	parsed, err := parser.ParseSession(filepath.Join(dir, "projects", "-test-project", "test-session-123.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page := renderSessionPage(parsed, "-test-project", "", nil, false, false, true, "light", nil); !strings.Contains(page, `id="tb-export"`) {
+		t.Error("server page lost its export menu after publishing")
+	}
+}
+
+func TestPublish_UnknownProject(t *testing.T) {
+	dir := setupTestDir(t)
+	if _, err := Publish(filepath.Join(dir, "projects"), t.TempDir(), PublishOptions{Projects: []string{"nope"}}); err == nil {
+		t.Error("expected error for unknown project")
+	}
+}
+
+func TestRelink(t *testing.T) {
+	page := `<a href="/">a</a><a href="/project/-p">b</a><a href="/session/-p/s1#msg-u1">c</a><a href="/replay/-p/s1?leaf=x">d</a><a href="#msg-u2">e</a>`
+	want := `<a href="../index.html">a</a><a href="../-p/index.html">b</a><a href="../-p/s1.html#msg-u1">c</a><a >d</a><a href="#msg-u2">e</a>`
+	if got := relink(page, "../"); got != want {
+		t.Errorf("relink = %s; want %s", got, want)
+	}
+}
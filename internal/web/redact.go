@@ -0,0 +1,93 @@
+package web
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/thevibeworks/ccx/internal/db"
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/redact"
+	"github.com/thevibeworks/ccx/internal/search"
+)
+
+// redactor masks everything the server sends when redaction is forced with
+// ccx web --redact or redact.web in config.yaml; nil otherwise
+var redactor *redact.Redactor
+
+// setRedactor forces redaction with r, or turns it off when r is nil.
+// Sessions are masked as they are parsed and message search runs on an
+// index of masked text; what doesn't come from a parsed session is masked
+// where it is served.
+func setRedactor(r *redact.Redactor) {
+	redactor = r
+	search.SetRedactor(r)
+	if r == nil {
+		parser.SetTextFilter(nil)
+		return
+	}
+	parser.SetTextFilter(r.String)
+}
+
+// redactAnnotations masks the quotes and notes of annotations with r,
+// which may be nil
+func redactAnnotations(r *redact.Redactor, annotations []db.Annotation) []db.Annotation {
+	if r == nil {
+		return annotations
+	}
+	out := make([]db.Annotation, len(annotations))
+	for i, a := range annotations {
+		a.Quote = r.String(a.Quote)
+		a.Note = r.String(a.Note)
+		out[i] = a
+	}
+	return out
+}
+
+// redactEnv masks the values of environment settings, reading each as a
+// NAME=value assignment so secrets are caught by their name too
+func redactEnv(env map[string]string) {
+	if redactor == nil {
+		return
+	}
+	for k, v := range env {
+		masked := redactor.String(k + "=" + v)
+		if value, ok := strings.CutPrefix(masked, k+"="); ok {
+			env[k] = value
+		} else {
+			env[k] = redactor.String(v)
+		}
+	}
+}
+
+// redactionReport counts the values masked in a session that was parsed
+// with redaction on
+func redactionReport(s *parser.Session) redact.Report {
+	report := make(redact.Report)
+	tally := func(text string) string {
+		redact.Tally(text, report)
+		return text
+	}
+	s.RewriteText(tally)
+	return report
+}
+
+// renderRedactionInfo adds what was masked to the session info panel
+func renderRedactionInfo(b *strings.Builder, s *parser.Session) {
+	report := redactionReport(s)
+	b.WriteString(`<div class="info-section info-section-redacted">`)
+	b.WriteString(`<div class="info-section-header">Redacted</div>`)
+	if report.Total() == 0 {
+		b.WriteString(`<div class="info-row"><span class="info-label">Nothing masked</span></div>`)
+	}
+	names := make([]string, 0, len(report))
+	for name := range report {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteString(fmt.Sprintf(`<div class="info-row"><span class="info-label">%s</span><span class="info-value">%d</span></div>`, html.EscapeString(name), report[name]))
+	}
+	b.WriteString(`</div>`)
+}
@@ -0,0 +1,369 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/thevibeworks/ccx/internal/config"
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/render"
+)
+
+// replayFrame is one message of a replay as the player sees it
+type replayFrame struct {
+	Offset int64    `json:"t"`    // Milliseconds since the first message, never decreasing
+	Kind   string   `json:"kind"` // prompt, command, response, agent, compact or other
+	Tools  []string `json:"tools,omitempty"`
+	Label  string   `json:"label,omitempty"`
+}
+
+// handleReplay serves /replay/{project}/{session}: the conversation played
+// back one message at a time with its real pacing. ?leaf= picks the branch
+// of a forked session; the latest one plays by default.
+func handleReplay(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/replay/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	projectName := parts[0]
+
+	session, err := parser.FindSession(projectsDir, projectName, parts[1])
+	if err != nil || session == nil {
+		http.NotFound(w, r)
+		return
+	}
+	fullSession, err := parser.ParseSession(session.FilePath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	q := r.URL.Query()
+	leaf := q.Get("leaf")
+	if leaf != "" && branchIndex(fullSession.Branches, leaf) < 0 {
+		http.Error(w, fmt.Sprintf("no branch ends at %q", leaf), http.StatusBadRequest)
+		return
+	}
+	theme := q.Get("theme")
+	if theme == "" {
+		theme = config.Theme()
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, renderReplayPage(fullSession, projectName, leaf, q.Get("thinking") == "1", theme))
+}
+
+// replayMessages returns the messages under roots in playback order, tool
+// results left out since they render with their calls
+func replayMessages(roots []*parser.Message) []*parser.Message {
+	var msgs []*parser.Message
+	for _, msg := range parser.Flatten(roots) {
+		if msg.Kind != parser.KindToolResult {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+func newReplayFrames(msgs []*parser.Message) []replayFrame {
+	frames := make([]replayFrame, 0, len(msgs))
+	var start time.Time
+	var last int64
+	for _, msg := range msgs {
+		if start.IsZero() && !msg.Timestamp.IsZero() {
+			start = msg.Timestamp
+		}
+		f := replayFrame{Offset: last}
+		if !msg.Timestamp.IsZero() {
+			f.Offset = max(last, msg.Timestamp.Sub(start).Milliseconds())
+		}
+		last = f.Offset
+
+		switch {
+		case msg.Kind == parser.KindCompactSummary:
+			f.Kind, f.Label = "compact", "Context compacted"
+		case msg.Kind == parser.KindCommand:
+			f.Kind, f.Label = "command", strings.TrimSpace(msg.CommandName+" "+msg.CommandArgs)
+		case msg.Kind == parser.KindUserPrompt:
+			f.Kind, f.Label = "prompt", truncate(getFirstTextPreview(msg, 0), 80)
+		case msg.IsSidechain:
+			f.Kind = "agent"
+		case msg.Kind == parser.KindAssistant:
+			f.Kind = "response"
+		default:
+			f.Kind = "other"
+		}
+		for _, block := range msg.Content {
+			if block.Type == "tool_use" {
+				f.Tools = append(f.Tools, block.ToolName)
+			}
+		}
+		frames = append(frames, f)
+	}
+	return frames
+}
+
+func renderReplayPage(session *parser.Session, projectName, leaf string, showThinking bool, theme string) string {
+	var b strings.Builder
+
+	// A forked session plays one branch, the latest unless picked
+	roots := session.RootMessages
+	if leaf == "" && len(session.Branches) > 1 {
+		leaf = parser.LatestBranch(session.Branches).LeafUUID
+	}
+	if leaf != "" {
+		roots = parser.BranchRoots(session.RootMessages, leaf)
+	}
+	msgs := replayMessages(roots)
+	frames := newReplayFrames(msgs)
+	heavy := heaviestTurns(parser.ContextTimeline(roots))
+
+	projDisplay := parser.GetProjectDisplayName(projectName)
+	sessionURL := fmt.Sprintf("/session/%s/%s", url.PathEscape(projectName), url.PathEscape(session.ID))
+	if leaf != "" {
+		sessionURL += "?leaf=" + url.QueryEscape(leaf)
+	}
+
+	b.WriteString(pageHeader(fmt.Sprintf("Replay %s - ccx", truncate(session.ID, 8)), theme))
+	b.WriteString(replayPageCSS())
+	b.WriteString(renderTopNav(projectName, session.ID))
+	b.WriteString(`<div class="layout">`)
+	b.WriteString(`<main class="main-content replay-main">`)
+	b.WriteString(`<div class="page-header page-header-sessions">`)
+	b.WriteString(fmt.Sprintf(`<div class="breadcrumb"><a href="/">Projects</a> <span class="sep">/</span> <a href="/project/%s">%s</a> <span class="sep">/</span> <a href="%s">%s</a> <span class="sep">/</span> <span class="current">Replay</span></div>`,
+		html.EscapeString(projectName), html.EscapeString(projDisplay), html.EscapeString(sessionURL), html.EscapeString(truncate(session.ID, 8))))
+	title := session.Summary
+	if title == "" {
+		title = "Session " + truncate(session.ID, 8)
+	}
+	b.WriteString(fmt.Sprintf(`<h1>%s</h1>`, html.EscapeString(title)))
+	var duration time.Duration
+	if len(frames) > 0 {
+		duration = time.Duration(frames[len(frames)-1].Offset) * time.Millisecond
+	}
+	meta := fmt.Sprintf("%d messages · %s", len(frames), render.FormatDuration(duration.Seconds()))
+	if len(session.Branches) > 1 {
+		meta += fmt.Sprintf(" · branch %d of %d", branchIndex(session.Branches, leaf)+1, len(session.Branches))
+	}
+	b.WriteString(fmt.Sprintf(`<p class="replay-meta">%s · <kbd>Space</kbd> play/pause, <kbd>j</kbd>/<kbd>k</kbd> turns, <kbd>←</kbd>/<kbd>→</kbd> messages, <kbd>+</kbd>/<kbd>-</kbd> speed</p>`, meta))
+	b.WriteString(`</div>`)
+
+	b.WriteString(`<div class="messages replay-messages" id="messages">`)
+	toolResults := buildToolResultsMap(parser.Flatten(session.RootMessages))
+	for i, msg := range msgs {
+		level := 0
+		if msg.IsSidechain {
+			level = 2
+		}
+		b.WriteString(fmt.Sprintf(`<div class="replay-frame replay-hidden" data-i="%d">`, i))
+		renderTurnMessage(&b, msg, showThinking, false, level, toolResults, heavy, nil)
+		b.WriteString(`</div>`)
+	}
+	if len(msgs) == 0 {
+		b.WriteString(`<div class="empty">No messages to replay</div>`)
+	}
+	b.WriteString(`</div>`)
+	b.WriteString(`</main>`)
+	b.WriteString(`</div>`)
+
+	// Player controls
+	b.WriteString(`<div class="replay-bar" id="replay-bar">`)
+	b.WriteString(`<button class="dock-btn" id="replay-play" title="Play/pause (Space)"><span class="dock-icon">▶</span></button>`)
+	b.WriteString(`<button class="dock-btn" id="replay-prev" title="Previous turn (k)"><span class="dock-icon">⏮</span></button>`)
+	b.WriteString(`<button class="dock-btn" id="replay-next" title="Next turn (j)"><span class="dock-icon">⏭</span></button>`)
+	b.WriteString(`<div class="replay-scrubber"><div class="replay-track" id="replay-track"></div><input type="range" id="replay-scrub" min="0" max="0" value="0" aria-label="Timeline"></div>`)
+	b.WriteString(`<span class="replay-clock" id="replay-clock"></span>`)
+	b.WriteString(`<select id="replay-speed" title="Speed (+/-)">`)
+	for _, s := range []string{"0.5", "1", "2", "4", "8", "16"} {
+		sel := ""
+		if s == "1" {
+			sel = " selected"
+		}
+		b.WriteString(fmt.Sprintf(`<option value="%s"%s>%s×</option>`, s, sel, s))
+	}
+	b.WriteString(`</select>`)
+	b.WriteString(`<select id="replay-gap" title="Longest pause between messages">`)
+	for _, g := range []struct{ value, label string }{{"0", "Real gaps"}, {"30", "Gaps ≤ 30s"}, {"5", "Gaps ≤ 5s"}, {"1", "Gaps ≤ 1s"}} {
+		sel := ""
+		if g.value == "0" {
+			sel = " selected"
+		}
+		b.WriteString(fmt.Sprintf(`<option value="%s"%s>%s</option>`, g.value, sel, g.label))
+	}
+	b.WriteString(`</select>`)
+	b.WriteString(`</div>`)
+
+	data, _ := json.Marshal(frames)
+	b.WriteString(fmt.Sprintf(`<script type="application/json" id="replay-data">%s</script>`, data))
+	b.WriteString(replayJS())
+	b.WriteString(pageFooter())
+
+	return b.String()
+}
+
+func replayPageCSS() string {
+	return `<style>
+.replay-main { max-width: 960px; margin: 0 auto; padding-bottom: 96px; }
+.replay-meta { color: var(--text-muted); font-size: 13px; }
+.replay-meta kbd { font-family: var(--font-mono); font-size: 11px; padding: 0 4px; border: 1px solid var(--border); border-radius: 3px; }
+.replay-frame.replay-hidden { display: none; }
+.replay-frame.replay-current > .turn { box-shadow: 0 0 0 2px var(--primary); }
+.replay-messages .turn-actions { display: none; }
+.replay-bar { position: fixed; left: 50%; bottom: 16px; transform: translateX(-50%); width: min(960px, calc(100% - 32px)); display: flex; align-items: center; gap: 8px; padding: 8px 12px; background: var(--bg); border: 1px solid var(--border); border-radius: var(--radius); box-shadow: var(--shadow); z-index: 100; }
+.replay-bar select { font-size: 12px; padding: 2px 4px; border: 1px solid var(--border); border-radius: 4px; background: var(--bg); color: var(--text); }
+.replay-scrubber { position: relative; flex: 1; height: 28px; }
+.replay-track { position: absolute; inset: 0; pointer-events: none; }
+.replay-mark { position: absolute; top: 4px; width: 2px; height: 20px; margin-left: -1px; opacity: 0.6; }
+.replay-mark.mark-tool { background: var(--tool-border); height: 8px; top: 16px; }
+.replay-mark.mark-prompt, .replay-mark.mark-command { background: var(--user-border); }
+.replay-mark.mark-compact { background: var(--compacted-border); width: 4px; margin-left: -2px; opacity: 1; }
+#replay-scrub { position: absolute; inset: 0; width: 100%; margin: 0; background: transparent; }
+.replay-clock { font-family: var(--font-mono); font-size: 12px; color: var(--text-muted); white-space: nowrap; }
+</style>`
+}
+
+func replayJS() string {
+	return `
+<script>
+(function() {
+  const frames = JSON.parse(document.getElementById('replay-data').textContent);
+  const els = Array.from(document.querySelectorAll('.replay-frame'));
+  const n = frames.length;
+  if (!n) return;
+
+  const playBtn = document.getElementById('replay-play');
+  const scrub = document.getElementById('replay-scrub');
+  const track = document.getElementById('replay-track');
+  const clock = document.getElementById('replay-clock');
+  const speedSel = document.getElementById('replay-speed');
+  const gapSel = document.getElementById('replay-gap');
+
+  let pos = 0, playing = false, timer = null, at = [];
+
+  // Pause before frame i at 1x, with long idle gaps capped
+  function gap(i) {
+    if (i <= 0) return 0;
+    const cap = parseFloat(gapSel.value) * 1000;
+    const g = frames[i].t - frames[i - 1].t;
+    return cap > 0 ? Math.min(g, cap) : g;
+  }
+
+  function fmt(ms) {
+    const s = Math.floor(ms / 1000), h = Math.floor(s / 3600), m = Math.floor(s / 60) % 60;
+    const ss = String(s % 60).padStart(2, '0');
+    return h ? h + ':' + String(m).padStart(2, '0') + ':' + ss : m + ':' + ss;
+  }
+
+  // Lay the frames out on the playback timeline and mark prompts, tool
+  // calls and compactions on the scrubber
+  function layout() {
+    at = [0];
+    for (let i = 1; i < n; i++) at[i] = at[i - 1] + gap(i);
+    const total = at[n - 1] || 1;
+    scrub.max = at[n - 1];
+    track.innerHTML = '';
+    frames.forEach((f, i) => {
+      let cls = '';
+      if (f.kind === 'prompt' || f.kind === 'command' || f.kind === 'compact') cls = 'mark-' + f.kind;
+      else if (f.tools) cls = 'mark-tool';
+      if (!cls) return;
+      const mark = document.createElement('span');
+      mark.className = 'replay-mark ' + cls;
+      mark.style.left = (at[i] / total * 100) + '%';
+      mark.title = f.label || (f.tools || []).join(', ');
+      track.appendChild(mark);
+    });
+  }
+
+  function show(i, scroll) {
+    pos = Math.max(0, Math.min(n - 1, i));
+    els.forEach((el, j) => {
+      el.classList.toggle('replay-hidden', j > pos);
+      el.classList.toggle('replay-current', j === pos);
+    });
+    scrub.value = at[pos];
+    clock.textContent = fmt(frames[pos].t) + ' / ' + fmt(frames[n - 1].t) + '  ·  ' + (pos + 1) + '/' + n;
+    if (scroll) els[pos].scrollIntoView({ block: 'end', behavior: 'smooth' });
+  }
+
+  function schedule() {
+    clearTimeout(timer);
+    if (!playing) return;
+    if (pos >= n - 1) { pause(); return; }
+    timer = setTimeout(() => { show(pos + 1, true); schedule(); }, gap(pos + 1) / parseFloat(speedSel.value));
+  }
+
+  function play() {
+    if (pos >= n - 1) show(0, true);
+    playing = true;
+    playBtn.querySelector('.dock-icon').textContent = '❚❚';
+    schedule();
+  }
+
+  function pause() {
+    playing = false;
+    clearTimeout(timer);
+    playBtn.querySelector('.dock-icon').textContent = '▶';
+  }
+
+  // A turn ends just before the next prompt or command
+  function turnEnds() {
+    const ends = [];
+    frames.forEach((f, i) => {
+      if (i > 0 && (f.kind === 'prompt' || f.kind === 'command')) ends.push(i - 1);
+    });
+    ends.push(n - 1);
+    return ends;
+  }
+
+  function stepTurn(dir) {
+    const ends = turnEnds();
+    const next = dir > 0 ? ends.find(e => e > pos) : ends.filter(e => e < pos).pop();
+    show(next === undefined ? (dir > 0 ? n - 1 : 0) : next, true);
+    schedule();
+  }
+
+  function stepSpeed(dir) {
+    speedSel.selectedIndex = Math.max(0, Math.min(speedSel.options.length - 1, speedSel.selectedIndex + dir));
+    schedule();
+  }
+
+  playBtn.addEventListener('click', () => playing ? pause() : play());
+  document.getElementById('replay-prev').addEventListener('click', () => stepTurn(-1));
+  document.getElementById('replay-next').addEventListener('click', () => stepTurn(1));
+  speedSel.addEventListener('change', schedule);
+  gapSel.addEventListener('change', () => { layout(); show(pos, false); schedule(); });
+  scrub.addEventListener('input', () => {
+    const t = parseFloat(scrub.value);
+    let i = 0;
+    while (i + 1 < n && at[i + 1] <= t) i++;
+    show(i, true);
+    schedule();
+  });
+
+  document.addEventListener('keydown', e => {
+    if (e.target.matches('input[type=text], textarea, select') || e.metaKey || e.ctrlKey || e.altKey) return;
+    switch (e.key) {
+      case ' ': e.preventDefault(); playing ? pause() : play(); break;
+      case 'j': stepTurn(1); break;
+      case 'k': stepTurn(-1); break;
+      case 'ArrowRight': e.preventDefault(); show(pos + 1, true); schedule(); break;
+      case 'ArrowLeft': e.preventDefault(); show(pos - 1, true); schedule(); break;
+      case '+': case '=': stepSpeed(1); break;
+      case '-': stepSpeed(-1); break;
+      case 'Home': e.preventDefault(); show(0, true); schedule(); break;
+      case 'End': e.preventDefault(); show(n - 1, true); schedule(); break;
+    }
+  });
+
+  layout();
+  show(0, false);
+})();
+</script>`
+}
@@ -7,18 +7,25 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/thevibeworks/ccx/internal/config"
 	"github.com/thevibeworks/ccx/internal/db"
+	"github.com/thevibeworks/ccx/internal/files"
 	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/pricing"
+	"github.com/thevibeworks/ccx/internal/render"
+	"github.com/thevibeworks/ccx/internal/search"
 )
 
 var (
 	projectsDir string
 	claudeHome  string
+	prices      = pricing.Default() // Cost estimate rates; config.yaml overrides are loaded by Serve
 )
 
 type Settings struct {
@@ -50,6 +57,19 @@ type GlobalConfig struct {
 func Serve(addr, projDir string) error {
 	projectsDir = projDir
 	claudeHome = config.ClaudeHome()
+	if table, err := config.Pricing(); err != nil {
+		log.Printf("%v; using built-in rates", err)
+	} else {
+		prices = table
+	}
+	if config.RedactWeb() {
+		r, err := config.Redactor()
+		if err != nil {
+			return err
+		}
+		setRedactor(r)
+		log.Printf("redaction on: secrets and personal data are masked")
+	}
 
 	mux := http.NewServeMux()
 
@@ -59,23 +79,45 @@ func Serve(addr, projDir string) error {
 	mux.HandleFunc("/session/", handleSession)
 	mux.HandleFunc("/settings", handleSettings)
 	mux.HandleFunc("/search", handleSearchPage)
+	mux.HandleFunc("/stats", handleStatsPage)
+	mux.HandleFunc("/stats/sessions", handleStatsSessions)
+	mux.HandleFunc("/compare/", handleCompare)
+	mux.HandleFunc("/replay/", handleReplay)
+	mux.HandleFunc("/files/", handleFiles)
+	mux.HandleFunc("/commands", handleCommands)
+	mux.HandleFunc("/commands/", handleCommands)
 
 	// API
 	mux.HandleFunc("/api/projects", handleAPIProjects)
 	mux.HandleFunc("/api/sessions/", handleAPISessions)
 	mux.HandleFunc("/api/session/", handleAPISession)
 	mux.HandleFunc("/api/stats", handleAPIStats)
+	mux.HandleFunc("/api/stats/report", handleAPIStatsReport)
+	mux.HandleFunc("/api/stats/sessions", handleAPIStatsSessions)
 	mux.HandleFunc("/api/settings", handleAPISettings)
 	mux.HandleFunc("/api/export/", handleAPIExport)
 	mux.HandleFunc("/api/search", handleAPISearch)
+	mux.HandleFunc("/api/files/", handleAPIFiles)
+	mux.HandleFunc("/api/commands", handleAPICommands)
+	mux.HandleFunc("/api/commands/", handleAPICommands)
 
 	// SSE for realtime updates
 	mux.HandleFunc("/api/watch/", handleWatch)
+	mux.HandleFunc("/api/watch/project/", handleWatchFeed)
+	mux.HandleFunc("/api/watch/all", handleWatchFeed)
 
 	// Star/favorite endpoints
 	mux.HandleFunc("/api/star", handleStar)
 	mux.HandleFunc("/api/stars", handleGetStars)
 
+	// Tags
+	mux.HandleFunc("/api/tags", handleAPITags)
+	mux.HandleFunc("/api/tags/", handleAPIItemTags)
+
+	// Annotations API
+	mux.HandleFunc("/api/annotations", handleAPIAnnotations)
+	mux.HandleFunc("/api/annotations/", handleAPIAnnotation)
+
 	// File content API (for agents/skills)
 	mux.HandleFunc("/api/file", handleAPIFile)
 
@@ -90,10 +132,34 @@ func Serve(addr, projDir string) error {
 		IdleTimeout:  120 * time.Second,
 	}
 
+	go warmSearchIndex()
+
 	log.Printf("ccx web server listening on http://%s", addr)
 	return server.ListenAndServe()
 }
 
+// warmSearchIndex brings the message index up to date in the background
+// so the first search doesn't pay for indexing every session
+func warmSearchIndex() {
+	if !db.Available() {
+		return
+	}
+	projects, err := parser.DiscoverProjects(projectsDir)
+	if err != nil {
+		return
+	}
+	start := time.Now()
+	stats, err := search.Sync(projects)
+	if err != nil {
+		log.Printf("search index: %v", err)
+		return
+	}
+	if stats.Indexed > 0 || stats.Removed > 0 {
+		log.Printf("search index: %d sessions indexed, %d removed in %v",
+			stats.Indexed, stats.Removed, time.Since(start).Round(time.Millisecond))
+	}
+}
+
 // logRequest logs HTTP requests with method, path, and duration
 func logRequest(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -137,6 +203,24 @@ func handleIndex(w http.ResponseWriter, r *http.Request) {
 		projects = filtered
 	}
 
+	// A project matches a tag if it or any of its sessions carries it
+	projectTags := loadTags("project")
+	tag := strings.ToLower(q.Get("tag"))
+	if tag != "" {
+		sessionTags := loadTags("session")
+		var filtered []*parser.Project
+		for _, p := range projects {
+			match := slices.Contains(projectTags[p.EncodedName], tag)
+			for _, s := range p.Sessions {
+				match = match || slices.Contains(sessionTags[s.ID], tag)
+			}
+			if match {
+				filtered = append(filtered, p)
+			}
+		}
+		projects = filtered
+	}
+
 	// Sort
 	switch sortBy {
 	case "name":
@@ -160,7 +244,7 @@ func handleIndex(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	fmt.Fprint(w, renderIndexPage(projects, totalSessions, search, sortBy))
+	fmt.Fprint(w, renderIndexPage(projects, totalSessions, search, sortBy, tag, projectTags))
 }
 
 func handleProject(w http.ResponseWriter, r *http.Request) {
@@ -204,6 +288,18 @@ func handleProject(w http.ResponseWriter, r *http.Request) {
 		sessions = filtered
 	}
 
+	sessionTags := loadTags("session")
+	tag := strings.ToLower(q.Get("tag"))
+	if tag != "" {
+		var filtered []*parser.Session
+		for _, s := range sessions {
+			if slices.Contains(sessionTags[s.ID], tag) {
+				filtered = append(filtered, s)
+			}
+		}
+		sessions = filtered
+	}
+
 	// Sort
 	switch sortBy {
 	case "messages":
@@ -217,7 +313,7 @@ func handleProject(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	fmt.Fprint(w, renderProjectPage(project, sessions, allProjects, search, sortBy))
+	fmt.Fprint(w, renderProjectPage(project, sessions, allProjects, search, sortBy, tag, sessionTags))
 }
 
 func handleSession(w http.ResponseWriter, r *http.Request) {
@@ -262,7 +358,7 @@ func handleSession(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	fmt.Fprint(w, renderSessionPage(fullSession, projectName, allSessions, showThinking, showTools, loadAll, theme))
+	fmt.Fprint(w, renderSessionPage(fullSession, projectName, q.Get("leaf"), allSessions, showThinking, showTools, loadAll, theme, nil))
 }
 
 func handleSettings(w http.ResponseWriter, r *http.Request) {
@@ -342,83 +438,89 @@ func handleWatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get initial file size
-	filePath := session.FilePath
-	stat, err := os.Stat(filePath)
+	// Lines appended after this point are streamed; earlier ones were rendered with the page
+	events, cancel, err := watches.Subscribe(session.FilePath, -1)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	lastSize := stat.Size()
+	defer cancel()
 
 	// Send initial connection message
 	fmt.Fprintf(w, "event: connected\ndata: {\"status\":\"watching\"}\n\n")
 	flusher.Flush()
 
-	const maxChunkSize = 1 << 20 // 1MB cap to prevent DoS
-	var partialLine string
-
-	ticker := time.NewTicker(500 * time.Millisecond)
-	defer ticker.Stop()
-
 	for {
 		select {
 		case <-r.Context().Done():
 			return
-		case <-ticker.C:
-			// Check if file has grown
-			stat, err := os.Stat(filePath)
-			if err != nil {
-				continue
+		case ev, ok := <-events:
+			if !ok {
+				// Dropped for falling behind; the client reloads instead of missing lines
+				fmt.Fprintf(w, "event: reset\ndata: {\"reason\":\"overflow\"}\n\n")
+				flusher.Flush()
+				return
 			}
-			newSize := stat.Size()
-			if newSize <= lastSize {
-				continue
+			data := ev.Data
+			if redactor != nil {
+				data = string(redactor.JSON([]byte(data)))
 			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+			flusher.Flush()
+		}
+	}
+}
 
-			// Cap chunk size to prevent huge allocations
-			chunkSize := newSize - lastSize
-			if chunkSize > maxChunkSize {
-				chunkSize = maxChunkSize
-			}
+// handleWatchFeed streams session lifecycle events for one project
+// (/api/watch/project/{name}) or for every project (/api/watch/all)
+func handleWatchFeed(w http.ResponseWriter, r *http.Request) {
+	var project string
+	if name, ok := strings.CutPrefix(r.URL.Path, "/api/watch/project/"); ok {
+		p, err := parser.FindProject(projectsDir, name)
+		if err != nil || p == nil {
+			http.NotFound(w, r)
+			return
+		}
+		project = p.EncodedName
+	}
 
-			// Read new content
-			file, err := os.Open(filePath)
-			if err != nil {
-				continue
-			}
-			if _, err := file.Seek(lastSize, 0); err != nil {
-				file.Close()
-				continue
+	// SSE headers
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "SSE not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, active, cancel := feeds.subscribe(project)
+	defer cancel()
+
+	if active == nil {
+		active = []feedSession{}
+	}
+	data, _ := json.Marshal(map[string]any{"status": "watching", "active": active})
+	fmt.Fprintf(w, "event: connected\ndata: %s\n\n", data)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				fmt.Fprintf(w, "event: reset\ndata: {\"reason\":\"overflow\"}\n\n")
+				flusher.Flush()
+				return
 			}
-			newBytes := make([]byte, chunkSize)
-			n, err := file.Read(newBytes)
-			file.Close()
-			if err != nil || n == 0 {
+			data, err := json.Marshal(ev.Session)
+			if err != nil {
 				continue
 			}
-			lastSize += int64(n)
-
-			// Combine with any partial line from previous read
-			data := partialLine + string(newBytes[:n])
-			partialLine = ""
-
-			// Send each complete line
-			lines := strings.Split(data, "\n")
-			for i, line := range lines {
-				// Last element may be partial if chunk didn't end with newline
-				if i == len(lines)-1 && !strings.HasSuffix(data, "\n") {
-					partialLine = line
-					continue
-				}
-				line = strings.TrimSpace(line)
-				if line == "" {
-					continue
-				}
-				// Send the actual JSONL line
-				fmt.Fprintf(w, "event: line\ndata: %s\n\n", line)
-				flusher.Flush()
-			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+			flusher.Flush()
 		}
 	}
 }
@@ -551,6 +653,7 @@ func loadSettings() *Settings {
 	if err := json.Unmarshal(data, &settings); err != nil {
 		return nil
 	}
+	redactEnv(settings.Env)
 	return &settings
 }
 
@@ -628,6 +731,92 @@ func handleGetStars(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(stars)
 }
 
+// tagItemTypes are the item types that can carry tags
+var tagItemTypes = map[string]bool{"project": true, "session": true, "message": true}
+
+// handleAPITags lists every tag in use with its item count
+func handleAPITags(w http.ResponseWriter, r *http.Request) {
+	counts, err := db.GetTagCounts()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if counts == nil {
+		counts = []db.TagCount{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(counts)
+}
+
+// handleAPIItemTags reads (GET), adds (POST) or removes (DELETE) the tags of
+// one item. ?type= selects project, session (default) or message; POST and
+// DELETE take {"tag": "name"}.
+func handleAPIItemTags(w http.ResponseWriter, r *http.Request) {
+	itemID := strings.TrimPrefix(r.URL.Path, "/api/tags/")
+	itemType := r.URL.Query().Get("type")
+	if itemType == "" {
+		itemType = "session"
+	}
+	if itemID == "" || !tagItemTypes[itemType] {
+		http.Error(w, "invalid item", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost, http.MethodDelete:
+		var req struct {
+			Tag string `json:"tag"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var err error
+		if r.Method == http.MethodPost {
+			if _, err = db.NormalizeTag(req.Tag); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			err = db.TagItemByName(itemType, itemID, req.Tag)
+		} else {
+			err = db.UntagItemByName(itemType, itemID, req.Tag)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tags, err := db.GetItemTags(itemType, itemID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	names := make([]string, 0, len(tags))
+	for _, t := range tags {
+		names = append(names, t.Name)
+	}
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"type": itemType, "id": itemID, "tags": names})
+}
+
+// loadTags returns item ID -> tag names for one item type, or nil when
+// the database is unavailable
+func loadTags(itemType string) map[string][]string {
+	if !db.Available() {
+		return nil
+	}
+	tags, _ := db.GetTagsByType(itemType)
+	return tags
+}
+
 func handleAPIFile(w http.ResponseWriter, r *http.Request) {
 	filePath := r.URL.Query().Get("path")
 	if filePath == "" {
@@ -691,7 +880,7 @@ func handleAPIFile(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]any{
 		"path":    resolvedPath,
-		"content": string(content),
+		"content": redactor.String(string(content)),
 	})
 }
 
@@ -750,6 +939,21 @@ func handleAPIExport(w http.ResponseWriter, r *http.Request) {
 		format = "json"
 	}
 
+	// ?redact=1 masks this export; with redaction forced the session already is
+	annotations := loadAnnotations(fullSession.ID)
+	if redactor != nil || r.URL.Query().Get("redact") != "" {
+		if redactor == nil {
+			rd, err := config.Redactor()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			rd.Session(fullSession)
+			annotations = redactAnnotations(rd, annotations)
+		}
+		w.Header().Set("X-Redacted", redactionReport(fullSession).String())
+	}
+
 	switch format {
 	case "json":
 		w.Header().Set("Content-Type", "application/json")
@@ -758,15 +962,29 @@ func handleAPIExport(w http.ResponseWriter, r *http.Request) {
 	case "html":
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=session-%s.html", truncate(sessionID, 8)))
-		fmt.Fprint(w, renderSessionPage(fullSession, projectName, nil, true, true, true, "light"))
+		fmt.Fprint(w, renderSessionPage(fullSession, projectName, "", nil, true, true, true, "light", annotations))
 	case "md", "markdown":
 		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
 		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=session-%s.md", truncate(sessionID, 8)))
-		fmt.Fprint(w, exportMarkdown(fullSession))
+		fmt.Fprint(w, exportMarkdown(fullSession, annotations))
 	case "org":
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=session-%s.org", truncate(sessionID, 8)))
-		fmt.Fprint(w, exportOrg(fullSession))
+		fmt.Fprint(w, exportOrg(fullSession, annotations))
+	case "patch":
+		leaf := r.URL.Query().Get("leaf")
+		if leaf != "" && branchIndex(fullSession.Branches, leaf) < 0 {
+			http.Error(w, fmt.Sprintf("no branch ends at %q", leaf), http.StatusBadRequest)
+			return
+		}
+		patch := files.Patch(fullSession, leaf, config.PatchAuthor())
+		if patch == "" {
+			http.Error(w, "Session changed no files", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/x-patch; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=session-%s.patch", truncate(sessionID, 8)))
+		fmt.Fprint(w, patch)
 	case "txt", "text":
 		// CLI-style export matching /export format
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
@@ -778,40 +996,67 @@ func handleAPIExport(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// defaultSearchLimit caps /api/search results unless ?limit= overrides it (0 = no cap)
+const defaultSearchLimit = 200
+
+type searchResult struct {
+	URL       string `json:"url"`
+	Summary   string `json:"summary"`
+	Project   string `json:"project"`
+	Time      string `json:"time"`
+	Type      string `json:"type"`
+	Snippet   string `json:"snippet"`
+	MessageID string `json:"message_id,omitempty"`
+	Priority  int    `json:"priority"`
+}
+
 func handleAPISearch(w http.ResponseWriter, r *http.Request) {
-	query := strings.ToLower(r.URL.Query().Get("q"))
-	if query == "" {
+	raw := strings.TrimSpace(r.URL.Query().Get("q"))
+	if raw == "" {
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(map[string]any{"results": []any{}})
 		return
 	}
 
-	projects, err := parser.DiscoverProjects(projectsDir)
+	q, err := search.Parse(raw)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{"results": []any{}, "error": err.Error()})
+		return
+	}
+	if q.IsEmpty() {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"results": []any{}})
 		return
 	}
+	query := q.Text()
 
-	type searchResult struct {
-		URL       string `json:"url"`
-		Summary   string `json:"summary"`
-		Project   string `json:"project"`
-		Time      string `json:"time"`
-		Type      string `json:"type"`
-		Snippet   string `json:"snippet"`
-		MessageID string `json:"message_id,omitempty"`
-		Priority  int    `json:"priority"`
+	limit := defaultSearchLimit
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v >= 0 {
+		limit = v
+	}
+
+	projects, err := parser.DiscoverProjects(projectsDir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	var results []searchResult
-	maxResults := 30
+
+	// Name and ID matching only applies to plain queries
+	plain := query != "" && !q.HasFilters()
 
 	for _, p := range projects {
+		if !q.MatchProject(p) {
+			continue
+		}
 		projDisplay := parser.GetProjectDisplayName(p.EncodedName)
 		projPath := parser.DecodePath(p.EncodedName)
 
 		// Priority 0: Exact session/project ID match
-		if strings.EqualFold(p.EncodedName, query) || strings.Contains(p.EncodedName, query) {
+		if plain && (strings.EqualFold(p.EncodedName, query) || strings.Contains(p.EncodedName, query)) {
 			results = append(results, searchResult{
 				URL:      fmt.Sprintf("/project/%s", p.EncodedName),
 				Summary:  projDisplay,
@@ -822,7 +1067,7 @@ func handleAPISearch(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Priority 1: Project path contains query
-		if strings.Contains(strings.ToLower(projPath), query) && !strings.Contains(p.EncodedName, query) {
+		if plain && strings.Contains(strings.ToLower(projPath), query) && !strings.Contains(p.EncodedName, query) {
 			results = append(results, searchResult{
 				URL:      fmt.Sprintf("/project/%s", p.EncodedName),
 				Summary:  projDisplay,
@@ -835,7 +1080,7 @@ func handleAPISearch(w http.ResponseWriter, r *http.Request) {
 
 		for _, s := range p.Sessions {
 			// Priority 0: Exact session ID match
-			if strings.EqualFold(s.ID, query) || strings.HasPrefix(strings.ToLower(s.ID), query) {
+			if plain && (strings.EqualFold(s.ID, query) || strings.HasPrefix(strings.ToLower(s.ID), query)) {
 				results = append(results, searchResult{
 					URL:      fmt.Sprintf("/session/%s/%s", p.EncodedName, s.ID),
 					Summary:  truncateSummary(s.Summary, 80),
@@ -847,8 +1092,8 @@ func handleAPISearch(w http.ResponseWriter, r *http.Request) {
 				continue
 			}
 
-			// Priority 2: Session summary match
-			if strings.Contains(strings.ToLower(s.Summary), query) {
+			// Priority 2: Session summary and qualifier match
+			if q.MatchSession(p, s) {
 				results = append(results, searchResult{
 					URL:      fmt.Sprintf("/session/%s/%s", p.EncodedName, s.ID),
 					Summary:  truncateSummary(s.Summary, 80),
@@ -857,53 +1102,122 @@ func handleAPISearch(w http.ResponseWriter, r *http.Request) {
 					Type:     "session",
 					Priority: 2,
 				})
-				continue
-			}
-
-			// Priority 3: Deep search - parse session and search content
-			if len(results) < maxResults {
-				fullSession, err := parser.ParseSession(s.FilePath)
-				if err != nil {
-					continue
-				}
-				snippet, msgID := searchSessionContent(fullSession, query)
-				if snippet != "" {
-					url := fmt.Sprintf("/session/%s/%s", p.EncodedName, s.ID)
-					if msgID != "" {
-						url += "#msg-" + msgID
-					}
-					results = append(results, searchResult{
-						URL:       url,
-						Summary:   truncateSummary(s.Summary, 60),
-						Project:   projDisplay,
-						Time:      formatAge(s.StartTime),
-						Type:      "message",
-						Snippet:   snippet,
-						MessageID: msgID,
-						Priority:  3,
-					})
-				}
 			}
 		}
 	}
 
-	// Sort by priority then time
-	sort.Slice(results, func(i, j int) bool {
-		if results[i].Priority != results[j].Priority {
-			return results[i].Priority < results[j].Priority
+	// Priority 3: Message content, ranked by the full-text index.
+	// Falls back to parsing every session when the index is unavailable;
+	// the fallback only understands plain queries.
+	if q.SearchesMessages() {
+		messages, err := indexedMessageResults(projects, q, limit)
+		if err != nil && plain {
+			messages = scanMessageResults(projects, query, limit)
 		}
-		return i < j
+		results = append(results, messages...)
+	}
+
+	// Sort by priority; message hits keep their BM25 order
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Priority < results[j].Priority
 	})
 
 	// Limit results
-	if len(results) > maxResults {
-		results = results[:maxResults]
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	// Message snippets are cut from the masked index, and masking a
+	// fragment would miss what only its context gives away
+	if redactor != nil {
+		for i := range results {
+			results[i].Summary = redactor.String(results[i].Summary)
+			if results[i].Type != "message" {
+				results[i].Snippet = redactor.String(results[i].Snippet)
+			}
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]any{"results": results})
 }
 
+// indexedMessageResults returns every message matching q from the FTS index
+func indexedMessageResults(projects []*parser.Project, q *search.Query, limit int) ([]searchResult, error) {
+	if _, err := search.Sync(projects); err != nil {
+		return nil, err
+	}
+	hits, err := search.Messages(projects, q, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make(map[string]*parser.Session)
+	for _, p := range projects {
+		for _, s := range p.Sessions {
+			sessions[p.EncodedName+"/"+s.ID] = s
+		}
+	}
+
+	results := make([]searchResult, 0, len(hits))
+	for _, h := range hits {
+		s, ok := sessions[h.Project+"/"+h.SessionID]
+		if !ok {
+			continue
+		}
+		url := fmt.Sprintf("/session/%s/%s", h.Project, h.SessionID)
+		if h.UUID != "" {
+			url += "#msg-" + sanitizeID(h.UUID)
+		}
+		results = append(results, searchResult{
+			URL:       url,
+			Summary:   truncateSummary(s.Summary, 60),
+			Project:   parser.GetProjectDisplayName(h.Project),
+			Time:      formatAge(h.Timestamp),
+			Type:      "message",
+			Snippet:   strings.ReplaceAll(h.Snippet, "\n", " "),
+			MessageID: h.UUID,
+			Priority:  3,
+		})
+	}
+	return results, nil
+}
+
+// scanMessageResults parses each session and returns its first matching message
+func scanMessageResults(projects []*parser.Project, query string, limit int) []searchResult {
+	var results []searchResult
+	for _, p := range projects {
+		projDisplay := parser.GetProjectDisplayName(p.EncodedName)
+		for _, s := range p.Sessions {
+			if limit > 0 && len(results) >= limit {
+				return results
+			}
+			fullSession, err := parser.ParseSession(s.FilePath)
+			if err != nil {
+				continue
+			}
+			snippet, msgID := searchSessionContent(fullSession, query)
+			if snippet == "" {
+				continue
+			}
+			url := fmt.Sprintf("/session/%s/%s", p.EncodedName, s.ID)
+			if msgID != "" {
+				url += "#msg-" + msgID
+			}
+			results = append(results, searchResult{
+				URL:       url,
+				Summary:   truncateSummary(s.Summary, 60),
+				Project:   projDisplay,
+				Time:      formatAge(s.StartTime),
+				Type:      "message",
+				Snippet:   snippet,
+				MessageID: msgID,
+				Priority:  3,
+			})
+		}
+	}
+	return results
+}
+
 // No truncation - return full summary
 func truncateSummary(s string, n int) string {
 	return s
@@ -911,7 +1225,7 @@ func truncateSummary(s string, n int) string {
 
 // searchSessionContent returns (snippet, messageID) for first match
 func searchSessionContent(s *parser.Session, query string) (string, string) {
-	allMsgs := flattenMessages(s.RootMessages)
+	allMsgs := parser.Flatten(s.RootMessages)
 	for _, msg := range allMsgs {
 		for _, block := range msg.Content {
 			if block.Type == "text" {
@@ -963,36 +1277,27 @@ func extractSnippet(text, query string, maxLen int) string {
 	return snippet
 }
 
-func flattenMessages(messages []*parser.Message) []*parser.Message {
-	var result []*parser.Message
-	var flatten func(msgs []*parser.Message)
-	flatten = func(msgs []*parser.Message) {
-		for _, msg := range msgs {
-			result = append(result, msg)
-			if len(msg.Children) > 0 {
-				flatten(msg.Children)
-			}
-		}
-	}
-	flatten(messages)
-	return result
-}
-
-func exportMarkdown(s *parser.Session) string {
+func exportMarkdown(s *parser.Session, annotations []db.Annotation) string {
 	var b strings.Builder
 	b.WriteString(fmt.Sprintf("# Session %s\n\n", s.ID))
 	b.WriteString(fmt.Sprintf("**Started:** %s\n\n", s.StartTime.Format("2006-01-02 15:04:05")))
-	b.WriteString(fmt.Sprintf("**Messages:** %d | **Tools:** %d\n\n---\n\n", s.Stats.MessageCount, s.Stats.ToolCalls))
+	b.WriteString(fmt.Sprintf("**Messages:** %d | **Tools:** %d\n\n", s.Stats.MessageCount, s.Stats.ToolCalls))
+	if cost := prices.Session(s.Stats); cost.USD > 0 {
+		b.WriteString(fmt.Sprintf("**Cost:** %s (estimate)\n\n", cost))
+	}
+	b.WriteString("---\n\n")
 
 	// Flatten and export with proper hierarchy based on Kind
-	allMsgs := flattenMessages(s.RootMessages)
+	notes := render.NewFootnotes(annotations)
+	allMsgs := parser.Flatten(s.RootMessages)
 	for _, msg := range allMsgs {
-		exportMessageMd(&b, msg)
+		exportMessageMd(&b, msg, notes)
 	}
+	notes.WriteMarkdown(&b)
 	return b.String()
 }
 
-func exportMessageMd(b *strings.Builder, msg *parser.Message) {
+func exportMessageMd(b *strings.Builder, msg *parser.Message, notes *render.Footnotes) {
 	// Use Kind for proper formatting, not depth-based indentation
 	switch msg.Kind {
 	case parser.KindCompactSummary:
@@ -1015,9 +1320,16 @@ func exportMessageMd(b *strings.Builder, msg *parser.Message) {
 		b.WriteString(fmt.Sprintf("### %s (%s)\n\n", msg.Type, msg.Timestamp.Format("15:04:05")))
 	}
 
+	textIndex := 0
 	for _, block := range msg.Content {
 		switch block.Type {
 		case "text":
+			if block.Text != "" {
+				block.Text = notes.Highlight(msg.UUID, textIndex, block.Text, nil, func(quote string, n int) string {
+					return quote + render.MarkdownRefs([]int{n})
+				})
+				textIndex++
+			}
 			b.WriteString(block.Text + "\n\n")
 		case "thinking":
 			b.WriteString("> *∴ Thinking...*\n\n")
@@ -1028,27 +1340,43 @@ func exportMessageMd(b *strings.Builder, msg *parser.Message) {
 					b.WriteString("```json\n" + string(inputJSON) + "\n```\n\n")
 				}
 			}
+			if block.Sidechain != nil {
+				b.WriteString("<details><summary>Agent transcript</summary>\n\n")
+				for _, agentMsg := range parser.Flatten(block.Sidechain.RootMessages) {
+					exportMessageMd(b, agentMsg, notes)
+				}
+				b.WriteString("</details>\n\n")
+			}
 		case "tool_result":
 			result := fmt.Sprintf("%v", block.ToolResult)
 			b.WriteString("```\n" + result + "\n```\n\n")
 		}
 	}
+	if refs := notes.Remaining(msg.UUID); len(refs) > 0 {
+		b.WriteString("Notes: " + render.MarkdownRefs(refs) + "\n\n")
+	}
 }
 
-func exportOrg(s *parser.Session) string {
+func exportOrg(s *parser.Session, annotations []db.Annotation) string {
 	var b strings.Builder
 	b.WriteString(fmt.Sprintf("#+TITLE: Session %s\n", s.ID))
-	b.WriteString(fmt.Sprintf("#+DATE: %s\n\n", s.StartTime.Format("2006-01-02")))
+	b.WriteString(fmt.Sprintf("#+DATE: %s\n", s.StartTime.Format("2006-01-02")))
+	if cost := prices.Session(s.Stats); cost.USD > 0 {
+		b.WriteString(fmt.Sprintf("#+COST: %s (estimate)\n", cost))
+	}
+	b.WriteString("\n")
 
 	// Flatten and export with proper hierarchy based on Kind
-	allMsgs := flattenMessages(s.RootMessages)
+	notes := render.NewFootnotes(annotations)
+	allMsgs := parser.Flatten(s.RootMessages)
 	for _, msg := range allMsgs {
-		exportMessageOrg(&b, msg)
+		exportMessageOrg(&b, msg, notes)
 	}
+	notes.WriteOrg(&b)
 	return b.String()
 }
 
-func exportMessageOrg(b *strings.Builder, msg *parser.Message) {
+func exportMessageOrg(b *strings.Builder, msg *parser.Message, notes *render.Footnotes) {
 	// Use Kind for proper formatting, max 3 levels
 	switch msg.Kind {
 	case parser.KindCompactSummary:
@@ -1071,9 +1399,16 @@ func exportMessageOrg(b *strings.Builder, msg *parser.Message) {
 		b.WriteString(fmt.Sprintf("** %s [%s]\n", msg.Type, msg.Timestamp.Format("15:04:05")))
 	}
 
+	textIndex := 0
 	for _, block := range msg.Content {
 		switch block.Type {
 		case "text":
+			if block.Text != "" {
+				block.Text = notes.Highlight(msg.UUID, textIndex, block.Text, nil, func(quote string, n int) string {
+					return quote + render.OrgRefs([]int{n})
+				})
+				textIndex++
+			}
 			b.WriteString(block.Text + "\n")
 		case "thinking":
 			b.WriteString("/∴ Thinking.../\n")
@@ -1084,11 +1419,39 @@ func exportMessageOrg(b *strings.Builder, msg *parser.Message) {
 					b.WriteString("#+BEGIN_SRC json\n" + string(inputJSON) + "\n#+END_SRC\n")
 				}
 			}
+			if block.Sidechain != nil {
+				exportSidechainOrg(b, block.Sidechain)
+			}
 		case "tool_result":
 			result := fmt.Sprintf("%v", block.ToolResult)
 			b.WriteString("#+BEGIN_EXAMPLE\n" + result + "\n#+END_EXAMPLE\n")
 		}
 	}
+	if refs := notes.Remaining(msg.UUID); len(refs) > 0 {
+		b.WriteString("Notes: " + render.OrgRefs(refs) + "\n")
+	}
+}
+
+// exportSidechainOrg writes an agent transcript as level-4 headings under its Task call
+func exportSidechainOrg(b *strings.Builder, sc *parser.Sidechain) {
+	for _, msg := range parser.Flatten(sc.RootMessages) {
+		switch msg.Kind {
+		case parser.KindUserPrompt:
+			b.WriteString(fmt.Sprintf("**** ◆ AGENT PROMPT [%s]\n", msg.Timestamp.Format("15:04:05")))
+		case parser.KindAssistant:
+			b.WriteString(fmt.Sprintf("**** ◆ AGENT [%s]\n", msg.Timestamp.Format("15:04:05")))
+		default:
+			continue
+		}
+		for _, block := range msg.Content {
+			switch block.Type {
+			case "text":
+				b.WriteString(block.Text + "\n")
+			case "tool_use":
+				b.WriteString(fmt.Sprintf("- ● %s\n", block.ToolName))
+			}
+		}
+	}
 }
 
 // generateExportFilename creates a filename matching /export CLI style
@@ -1128,7 +1491,7 @@ func exportTxt(s *parser.Session) string {
 	b.WriteString(" *  ▘▘ ▝▝  *\n")
 	b.WriteString("\n")
 
-	allMsgs := flattenMessages(s.RootMessages)
+	allMsgs := parser.Flatten(s.RootMessages)
 	for _, msg := range allMsgs {
 		exportMessageTxt(&b, msg)
 	}
@@ -7,10 +7,13 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/thevibeworks/ccx/internal/db"
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/redact"
 )
 
 func setupTestDir(t *testing.T) string {
@@ -155,6 +158,62 @@ func TestHandleAPIStats(t *testing.T) {
 	}
 }
 
+func TestHandleStatsDashboard(t *testing.T) {
+	dir := setupTestDir(t)
+	projectsDir = filepath.Join(dir, "projects")
+	claudeHome = dir
+
+	// The fixture is from 2024: the default 30 days is empty, since= is all time
+	w := httptest.NewRecorder()
+	handleAPIStatsReport(w, httptest.NewRequest("GET", "/api/stats/report", nil))
+	if !strings.Contains(w.Body.String(), `"sessions":0`) {
+		t.Errorf("default range report = %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handleAPIStatsReport(w, httptest.NewRequest("GET", "/api/stats/report?since=", nil))
+	var report struct {
+		Sessions int `json:"sessions"`
+		Messages int `json:"messages"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to parse report: %v", err)
+	}
+	if report.Sessions != 1 || report.Messages != 2 {
+		t.Errorf("report = %+v, want 1 session, 2 messages", report)
+	}
+
+	w = httptest.NewRecorder()
+	handleStatsPage(w, httptest.NewRequest("GET", "/stats?since=", nil))
+	body := w.Body.String()
+	if w.Code != http.StatusOK || strings.Count(body, "<svg class=\"chart") != 3 {
+		t.Errorf("/stats returned %d with %d charts", w.Code, strings.Count(body, "<svg class=\"chart"))
+	}
+	if !strings.Contains(body, `/stats/sessions?project=-test-project`) {
+		t.Error("project chart should drill down to the project's sessions")
+	}
+
+	w = httptest.NewRecorder()
+	handleAPIStatsSessions(w, httptest.NewRequest("GET", "/api/stats/sessions?since=&day=2024-01-01", nil))
+	if !strings.Contains(w.Body.String(), `"id":"test-session-123"`) {
+		t.Errorf("drill-down = %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handleStatsSessions(w, httptest.NewRequest("GET", "/stats/sessions?since=&day=2024-01-02", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "No matching sessions") {
+		t.Errorf("empty drill-down returned %d", w.Code)
+	}
+
+	for _, query := range []string{"since=yesterday", "weekday=7&hour=1", "hour=3"} {
+		w = httptest.NewRecorder()
+		handleAPIStatsSessions(w, httptest.NewRequest("GET", "/api/stats/sessions?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s returned %d, want 400", query, w.Code)
+		}
+	}
+}
+
 func TestHandleAPISearch(t *testing.T) {
 	dir := setupTestDir(t)
 	projectsDir = filepath.Join(dir, "projects")
@@ -254,6 +313,302 @@ func TestHandleSession(t *testing.T) {
 	}
 }
 
+func TestHandleSession_ContextGrowth(t *testing.T) {
+	dir := setupTestDir(t)
+	projectsDir = filepath.Join(dir, "projects")
+	claudeHome = dir
+
+	content := `{"type":"user","timestamp":"2024-01-02T10:00:00Z","uuid":"u1","message":{"content":"Hello"}}
+{"type":"assistant","timestamp":"2024-01-02T10:00:01Z","uuid":"a1","parentUuid":"u1","message":{"usage":{"input_tokens":5000,"output_tokens":100},"content":"Hi"}}
+{"type":"user","timestamp":"2024-01-02T10:00:02Z","uuid":"u2","parentUuid":"a1","message":{"content":"Read the log"}}
+{"type":"assistant","timestamp":"2024-01-02T10:00:03Z","uuid":"a2","parentUuid":"u2","message":{"usage":{"input_tokens":10,"cache_read_input_tokens":5100,"cache_creation_input_tokens":40000,"output_tokens":100},"content":"Done"}}
+`
+	sessionFile := filepath.Join(projectsDir, "-test-project", "ctx-session.jsonl")
+	if err := os.WriteFile(sessionFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/session/-test-project/ctx-session", nil)
+	w := httptest.NewRecorder()
+	handleSession(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("handleSession returned %d, want %d", w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`class="spark"`,
+		`<a href="#msg-a2"><circle class="spark-heavy"`, // Heaviest turn marked on the sparkline
+		`class="turn-heavy"`,
+		`45.2k ctx`, // Context after a2
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("session page missing %q", want)
+		}
+	}
+}
+
+func TestHandleCompare(t *testing.T) {
+	dir := setupTestDir(t)
+	projectsDir = filepath.Join(dir, "projects")
+	claudeHome = dir
+
+	content := `{"type":"user","timestamp":"2024-01-02T10:00:00Z","uuid":"v1","message":{"content":"Hello"}}
+{"type":"assistant","timestamp":"2024-01-02T10:00:01Z","uuid":"b1","parentUuid":"v1","message":{"content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/src/main.go"}}]}}
+`
+	if err := os.WriteFile(filepath.Join(projectsDir, "-test-project", "other-session.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/compare/-test-project/test-session-123/other", nil)
+	w := httptest.NewRecorder()
+	handleCompare(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("handleCompare returned %d, want %d", w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"Compare sessions",
+		`id="diverge"`, // Same prompt, but only B read a file
+		"compare-row-differs",
+		"/src/main.go",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("compare page missing %q", want)
+		}
+	}
+
+	for path, code := range map[string]int{
+		"/compare/-test-project/test-session-123":                             http.StatusNotFound,
+		"/compare/-test-project/test-session-123/missing":                     http.StatusNotFound,
+		"/compare/-test-project/test-session-123/test-session-123?leaf_a=zzz": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		handleCompare(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != code {
+			t.Errorf("%s returned %d, want %d", path, w.Code, code)
+		}
+	}
+}
+
+func TestHandleSession_Branches(t *testing.T) {
+	dir := setupTestDir(t)
+	projectsDir = filepath.Join(dir, "projects")
+	claudeHome = dir
+
+	// u3 rewinds to a1; each branch ends in a different answer
+	content := `{"type":"summary","summary":"Picked Go","leafUuid":"a2"}
+{"type":"user","timestamp":"2024-01-02T10:00:00Z","uuid":"u1","message":{"content":"Plan it"}}
+{"type":"assistant","timestamp":"2024-01-02T10:00:01Z","uuid":"a1","parentUuid":"u1","message":{"content":"Plan"}}
+{"type":"user","timestamp":"2024-01-02T10:01:00Z","uuid":"u2","parentUuid":"a1","message":{"content":"Use Go"}}
+{"type":"assistant","timestamp":"2024-01-02T10:01:01Z","uuid":"a2","parentUuid":"u2","message":{"content":"Answer in Go"}}
+{"type":"user","timestamp":"2024-01-02T10:02:00Z","uuid":"u3","parentUuid":"a1","message":{"content":"Use Rust"}}
+{"type":"assistant","timestamp":"2024-01-02T10:02:01Z","uuid":"a3","parentUuid":"u3","message":{"content":"Answer in Rust"}}
+`
+	if err := os.WriteFile(filepath.Join(projectsDir, "-test-project", "forked-session.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	handleSession(w, httptest.NewRequest("GET", "/session/-test-project/forked-session", nil))
+	body := w.Body.String()
+	for _, want := range []string{`class="branch-bar"`, "2 branches", "Picked Go", "Use Rust", "?leaf=a3", "Answer in Go", "Answer in Rust"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("session page missing %q", want)
+		}
+	}
+	if strings.Contains(body, `class="branch-compare"`) {
+		t.Error("compare links shown with no branch selected")
+	}
+
+	w = httptest.NewRecorder()
+	handleSession(w, httptest.NewRequest("GET", "/session/-test-project/forked-session?leaf=a3", nil))
+	body = w.Body.String()
+	if strings.Contains(body, "Answer in Go") {
+		t.Error("?leaf=a3 still shows the other branch")
+	}
+	for _, want := range []string{"Answer in Rust", `class="branch-pill active" title="Use Rust"`, "leaf_a=a3&amp;leaf_b=a2"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("branch page missing %q", want)
+		}
+	}
+}
+
+func TestHandleReplay(t *testing.T) {
+	dir := setupTestDir(t)
+	projectsDir = filepath.Join(dir, "projects")
+	claudeHome = dir
+
+	content := `{"type":"user","timestamp":"2024-01-02T10:00:00Z","uuid":"u1","message":{"content":"Fix it"}}
+{"type":"assistant","timestamp":"2024-01-02T10:00:05Z","uuid":"a1","parentUuid":"u1","message":{"content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/src/main.go"}}]}}
+{"type":"user","timestamp":"2024-01-02T10:00:06Z","uuid":"r1","parentUuid":"a1","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"package main"}]}}
+{"type":"assistant","timestamp":"2024-01-02T10:00:04Z","uuid":"a2","parentUuid":"r1","message":{"content":"Fixed"}}
+`
+	if err := os.WriteFile(filepath.Join(projectsDir, "-test-project", "replay-session.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	handleReplay(w, httptest.NewRequest("GET", "/replay/-test-project/replay-session", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("handleReplay returned %d, want %d", w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	// The tool result renders with its call; the out-of-order timestamp
+	// doesn't move the clock backwards
+	want := `[{"t":0,"kind":"prompt","label":"Fix it"},{"t":5000,"kind":"response","tools":["Read"]},{"t":5000,"kind":"response"}]`
+	if !strings.Contains(body, want) {
+		t.Errorf("replay data missing %s", want)
+	}
+	if n := strings.Count(body, `class="replay-frame `); n != 3 {
+		t.Errorf("rendered %d frames, want 3", n)
+	}
+
+	for path, code := range map[string]int{
+		"/replay/-test-project":                        http.StatusNotFound,
+		"/replay/-test-project/missing":                http.StatusNotFound,
+		"/replay/-test-project/replay-session?leaf=u1": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		handleReplay(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != code {
+			t.Errorf("%s returned %d, want %d", path, w.Code, code)
+		}
+	}
+}
+
+func TestHandleFiles(t *testing.T) {
+	dir := setupTestDir(t)
+	projectsDir = filepath.Join(dir, "projects")
+	claudeHome = dir
+
+	content := `{"type":"user","timestamp":"2024-01-02T10:00:00Z","uuid":"u1","cwd":"/src","message":{"content":"Add main"}}
+{"type":"assistant","timestamp":"2024-01-02T10:00:01Z","uuid":"a1","parentUuid":"u1","message":{"content":[{"type":"tool_use","id":"t1","name":"Write","input":{"file_path":"/src/main.go","content":"package main\n"}}]}}
+{"type":"user","timestamp":"2024-01-02T10:00:02Z","uuid":"r1","parentUuid":"a1","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"File created successfully at: /src/main.go"}]}}
+{"type":"assistant","timestamp":"2024-01-02T10:00:03Z","uuid":"a2","parentUuid":"r1","message":{"content":[{"type":"tool_use","id":"t2","name":"Edit","input":{"file_path":"/src/main.go","old_string":"package main\n","new_string":"package main\n\nfunc main() {}\n"}}]}}
+{"type":"user","timestamp":"2024-01-02T10:00:04Z","uuid":"r2","parentUuid":"a2","message":{"content":[{"type":"tool_result","tool_use_id":"t2","content":"ok"}]}}
+`
+	if err := os.WriteFile(filepath.Join(projectsDir, "-test-project", "files-session.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	handleFiles(w, httptest.NewRequest("GET", "/files/-test-project/files-session", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("handleFiles returned %d, want %d", w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"main.go", "2 edits", `<span class="diff-add">+func main() {}</span>`, "@@ -1 +1,3 @@", "#msg-a2"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("files page missing %q", want)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handleAPIFiles(w, httptest.NewRequest("GET", "/api/files/-test-project/files-session", nil))
+	var resp struct {
+		CWD   string `json:"cwd"`
+		Files []struct {
+			Path    string `json:"path"`
+			Created bool   `json:"created"`
+			Added   int    `json:"added"`
+		} `json:"files"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.CWD != "/src" || len(resp.Files) != 1 || resp.Files[0].Path != "/src/main.go" || !resp.Files[0].Created || resp.Files[0].Added != 3 {
+		t.Errorf("unexpected files response: %+v", resp)
+	}
+
+	// The session page links to the change log
+	w = httptest.NewRecorder()
+	handleSession(w, httptest.NewRequest("GET", "/session/-test-project/files-session", nil))
+	if !strings.Contains(w.Body.String(), `href="/files/-test-project/files-session#file-0"`) {
+		t.Error("session info panel missing files changed")
+	}
+
+	for path, code := range map[string]int{
+		"/files/-test-project":                       http.StatusNotFound,
+		"/files/-test-project/missing":               http.StatusNotFound,
+		"/files/-test-project/files-session?leaf=u1": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		handleFiles(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != code {
+			t.Errorf("%s returned %d, want %d", path, w.Code, code)
+		}
+	}
+}
+
+func TestHandleCommands(t *testing.T) {
+	dir := setupTestDir(t)
+	projectsDir = filepath.Join(dir, "projects")
+	claudeHome = dir
+
+	content := `{"type":"user","timestamp":"2024-01-02T10:00:00Z","uuid":"u1","cwd":"/src","message":{"content":"Clean up"}}
+{"type":"assistant","timestamp":"2024-01-02T10:00:01Z","uuid":"a1","parentUuid":"u1","message":{"content":[{"type":"tool_use","id":"b1","name":"Bash","input":{"command":"rm -rf build","description":"Remove build output"}},{"type":"tool_use","id":"b2","name":"Bash","input":{"command":"make <all>"}}]}}
+{"type":"user","timestamp":"2024-01-02T10:00:02Z","uuid":"r1","parentUuid":"a1","message":{"content":[{"type":"tool_result","tool_use_id":"b1","content":""},{"type":"tool_result","tool_use_id":"b2","content":"Error: Exit code 2\nmake: *** No rule","is_error":true}]}}
+`
+	if err := os.WriteFile(filepath.Join(projectsDir, "-test-project", "commands-session.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	handleCommands(w, httptest.NewRequest("GET", "/commands/-test-project/commands-session", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("handleCommands returned %d, want %d", w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"$ rm -rf build", "Remove build output", "$ make &lt;all&gt;", "exit 2", `href="/session/-test-project/commands-session#msg-a1"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("commands page missing %q", want)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handleAPICommands(w, httptest.NewRequest("GET", "/api/commands?q=RM&errors=", nil))
+	var resp struct {
+		Commands []struct {
+			Command string `json:"command"`
+			Status  string `json:"status"`
+			CWD     string `json:"cwd"`
+		} `json:"commands"`
+		Total int `json:"total"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Total != 1 || len(resp.Commands) != 1 || resp.Commands[0].Command != "rm -rf build" || resp.Commands[0].CWD != "/src" {
+		t.Errorf("unexpected commands response: %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	handleAPICommands(w, httptest.NewRequest("GET", "/api/commands/-test-project?errors=1", nil))
+	resp.Commands = nil
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Commands) != 1 || resp.Commands[0].Status != "error" {
+		t.Errorf("errors=1 returned %+v", resp.Commands)
+	}
+
+	for path, code := range map[string]int{
+		"/commands/missing":               http.StatusNotFound,
+		"/commands/-test-project/missing": http.StatusNotFound,
+		"/commands/-test-project/a/b":     http.StatusNotFound,
+		"/commands?q=(":                   http.StatusBadRequest,
+		"/commands":                       http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		handleCommands(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != code {
+			t.Errorf("%s returned %d, want %d", path, w.Code, code)
+		}
+	}
+}
+
 func TestHandleSession_NotFound(t *testing.T) {
 	dir := setupTestDir(t)
 	projectsDir = filepath.Join(dir, "projects")
@@ -309,6 +664,125 @@ func TestHandleAPIExport_Markdown(t *testing.T) {
 	}
 }
 
+func TestHandleAPIExport_Patch(t *testing.T) {
+	dir := setupTestDir(t)
+	projectsDir = filepath.Join(dir, "projects")
+	claudeHome = dir
+
+	content := `{"type":"user","timestamp":"2024-01-02T10:00:00Z","uuid":"u1","cwd":"/src","message":{"content":"Add main"}}
+{"type":"assistant","timestamp":"2024-01-02T10:00:01Z","uuid":"a1","parentUuid":"u1","message":{"content":[{"type":"tool_use","id":"t1","name":"Write","input":{"file_path":"/src/main.go","content":"package main\n"}}]}}
+{"type":"user","timestamp":"2024-01-02T10:00:02Z","uuid":"r1","parentUuid":"a1","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"File created successfully at: /src/main.go"}]}}
+`
+	if err := os.WriteFile(filepath.Join(projectsDir, "-test-project", "patch-session.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	handleAPIExport(w, httptest.NewRequest("GET", "/api/export/-test-project/patch-session?format=patch", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("handleAPIExport returned %d, want %d", w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"Subject: [PATCH] Add main\n", "diff --git a/main.go b/main.go\nnew file mode 100644\n", "+package main\n"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("patch missing %q:\n%s", want, body)
+		}
+	}
+
+	// A session without file changes has nothing to export
+	w = httptest.NewRecorder()
+	handleAPIExport(w, httptest.NewRequest("GET", "/api/export/-test-project/test-session-123?format=patch", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("patch of a session without changes returned %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandleAPIExport_Redacted(t *testing.T) {
+	dir := setupTestDir(t)
+	projectsDir = filepath.Join(dir, "projects")
+	claudeHome = dir
+
+	content := `{"type":"user","timestamp":"2024-01-02T10:00:00Z","uuid":"u1","message":{"content":"Mail ops@example.com the report"}}
+{"type":"assistant","timestamp":"2024-01-02T10:00:01Z","uuid":"a1","parentUuid":"u1","message":{"content":"Sent to ops@example.com"}}
+`
+	if err := os.WriteFile(filepath.Join(projectsDir, "-test-project", "secret-session.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	handleAPIExport(w, httptest.NewRequest("GET", "/api/export/-test-project/secret-session?format=md", nil))
+	if !strings.Contains(w.Body.String(), "ops@example.com") || w.Header().Get("X-Redacted") != "" {
+		t.Error("export without redact=1 was masked")
+	}
+
+	w = httptest.NewRecorder()
+	handleAPIExport(w, httptest.NewRequest("GET", "/api/export/-test-project/secret-session?format=md&redact=1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("handleAPIExport returned %d, want %d", w.Code, http.StatusOK)
+	}
+	if body := w.Body.String(); strings.Contains(body, "ops@example.com") || !strings.Contains(body, "[REDACTED:email]") {
+		t.Errorf("redacted export not masked:\n%s", body)
+	}
+	// Both messages and the summary taken from the prompt
+	if got := w.Header().Get("X-Redacted"); got != "3 email" {
+		t.Errorf("X-Redacted = %q, want %q", got, "3 email")
+	}
+}
+
+func TestRedactionForced(t *testing.T) {
+	dir := setupTestDir(t)
+	projectsDir = filepath.Join(dir, "projects")
+	claudeHome = dir
+
+	content := `{"type":"user","timestamp":"2024-01-02T10:00:00Z","uuid":"u1","message":{"content":"Deploy with ` + "ghp_" + `a1B2c3D4e5F6g7H8i9J0k1L2m3N4o5P6q7R8"}}
+{"type":"assistant","timestamp":"2024-01-02T10:00:01Z","uuid":"a1","parentUuid":"u1","message":{"content":[{"type":"tool_use","id":"b1","name":"Bash","input":{"command":"curl -u ops@example.com https://example.com"}}]}}
+`
+	if err := os.WriteFile(filepath.Join(projectsDir, "-test-project", "secret-session.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := redact.New(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setRedactor(r)
+	t.Cleanup(func() { setRedactor(nil) })
+
+	for _, path := range []string{
+		"/session/-test-project/secret-session",
+		"/project/-test-project",
+		"/commands/-test-project/secret-session",
+		"/api/export/-test-project/secret-session?format=json",
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		switch {
+		case strings.HasPrefix(path, "/session/"):
+			handleSession(w, req)
+		case strings.HasPrefix(path, "/project/"):
+			handleProject(w, req)
+		case strings.HasPrefix(path, "/commands/"):
+			handleCommands(w, req)
+		default:
+			handleAPIExport(w, req)
+		}
+		if w.Code != http.StatusOK {
+			t.Errorf("%s returned %d, want %d", path, w.Code, http.StatusOK)
+			continue
+		}
+		body := w.Body.String()
+		if strings.Contains(body, "a1B2c3D4e5F6") || strings.Contains(body, "ops@example.com") {
+			t.Errorf("%s leaks a secret", path)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handleSession(w, httptest.NewRequest("GET", "/session/-test-project/secret-session", nil))
+	if body := w.Body.String(); !strings.Contains(body, `info-section-redacted`) || !strings.Contains(body, "github-token") {
+		t.Error("session info panel doesn't report what was masked")
+	}
+}
+
 func TestHandleAPIExport_NotFound(t *testing.T) {
 	dir := setupTestDir(t)
 	projectsDir = filepath.Join(dir, "projects")
@@ -483,3 +957,255 @@ func TestHandleAPIFile_DeniesSymlinkEscape(t *testing.T) {
 		t.Fatalf("handleAPIFile returned %d, want %d. Body: %s", w.Code, http.StatusForbidden, w.Body.String())
 	}
 }
+
+func TestHandleAPISearch_IndexedMessages(t *testing.T) {
+	dir := setupTestDir(t)
+	projectsDir = filepath.Join(dir, "projects")
+	claudeHome = dir
+
+	if err := db.Init(filepath.Join(dir, "data")); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	req := httptest.NewRequest("GET", "/api/search?q=there", nil)
+	w := httptest.NewRecorder()
+
+	handleAPISearch(w, req)
+
+	var result struct {
+		Results []searchResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(result.Results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(result.Results))
+	}
+	r := result.Results[0]
+	if r.Type != "message" || r.MessageID != "a1" {
+		t.Errorf("result = %+v, want message hit on a1", r)
+	}
+	if !strings.HasSuffix(r.URL, "#msg-a1") {
+		t.Errorf("URL = %q, want anchor to a1", r.URL)
+	}
+}
+
+func TestHandleAPISearch_Qualifiers(t *testing.T) {
+	dir := setupTestDir(t)
+	projectsDir = filepath.Join(dir, "projects")
+	claudeHome = dir
+
+	if err := db.Init(filepath.Join(dir, "data")); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	req := httptest.NewRequest("GET", "/api/search?q="+url.QueryEscape("kind:assistant -nothing"), nil)
+	w := httptest.NewRecorder()
+	handleAPISearch(w, req)
+
+	var result struct {
+		Results []searchResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(result.Results) == 0 {
+		t.Fatal("expected message results for kind:assistant")
+	}
+	for _, r := range result.Results {
+		if r.Type != "message" {
+			t.Errorf("result = %+v, want only messages", r)
+		}
+	}
+
+	// Invalid qualifiers are reported rather than silently ignored
+	req = httptest.NewRequest("GET", "/api/search?q=kind:robot", nil)
+	w = httptest.NewRecorder()
+	handleAPISearch(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("handleAPISearch returned %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if !strings.Contains(w.Body.String(), "unknown kind") {
+		t.Errorf("body = %s, want error message", w.Body.String())
+	}
+}
+
+func TestHandleAPIItemTags(t *testing.T) {
+	dir := setupTestDir(t)
+	projectsDir = filepath.Join(dir, "projects")
+	claudeHome = dir
+
+	if err := db.Init(filepath.Join(dir, "data")); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tagRequest := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/tags/test-session-123", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handleAPIItemTags(w, req)
+		return w
+	}
+
+	w := tagRequest("POST", `{"tag":"Incident"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST returned %d: %s", w.Code, w.Body.String())
+	}
+	var item struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &item); err != nil {
+		t.Fatal(err)
+	}
+	if len(item.Tags) != 1 || item.Tags[0] != "incident" {
+		t.Errorf("tags = %v, want [incident]", item.Tags)
+	}
+
+	if w := tagRequest("POST", `{"tag":"two words"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid tag returned %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	// Tag list and chips on the project page
+	w = httptest.NewRecorder()
+	handleAPITags(w, httptest.NewRequest("GET", "/api/tags", nil))
+	if !strings.Contains(w.Body.String(), `{"name":"incident","count":1}`) {
+		t.Errorf("/api/tags = %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handleProject(w, httptest.NewRequest("GET", "/project/-test-project?tag=incident", nil))
+	if !strings.Contains(w.Body.String(), `data-tag="incident"`) || !strings.Contains(w.Body.String(), `data-session="test-session-123"`) {
+		t.Error("project page filtered by tag is missing the tagged session")
+	}
+	w = httptest.NewRecorder()
+	handleProject(w, httptest.NewRequest("GET", "/project/-test-project?tag=other", nil))
+	if strings.Contains(w.Body.String(), `data-session="test-session-123"`) {
+		t.Error("project page filtered by another tag still lists the session")
+	}
+
+	w = tagRequest("DELETE", `{"tag":"incident"}`)
+	if err := json.Unmarshal(w.Body.Bytes(), &item); err != nil {
+		t.Fatal(err)
+	}
+	if len(item.Tags) != 0 {
+		t.Errorf("tags after DELETE = %v, want none", item.Tags)
+	}
+	if counts, _ := db.GetTagCounts(); len(counts) != 0 {
+		t.Errorf("unused tag kept: %+v", counts)
+	}
+}
+
+func TestHandleAPIAnnotations(t *testing.T) {
+	dir := setupTestDir(t)
+	projectsDir = filepath.Join(dir, "projects")
+	claudeHome = dir
+
+	if err := db.Init(filepath.Join(dir, "data")); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handleAPIAnnotations(w, httptest.NewRequest("POST", "/api/annotations", strings.NewReader(body)))
+		return w
+	}
+
+	w := post(`{"project":"-test-project","session_id":"test-session-123","message_uuid":"a1","block":0,"start":0,"end":5,"quote":"Hi th","note":"greeting","author":"alice"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST returned %d: %s", w.Code, w.Body.String())
+	}
+	var highlight db.Annotation
+	if err := json.Unmarshal(w.Body.Bytes(), &highlight); err != nil {
+		t.Fatal(err)
+	}
+	if highlight.ID == 0 || highlight.Author != "alice" || highlight.CreatedAt.IsZero() {
+		t.Errorf("created annotation = %+v", highlight)
+	}
+
+	if w := post(`{"project":"-test-project","session_id":"test-session-123","message_uuid":"u1","block":-1,"note":"first prompt"}`); w.Code != http.StatusCreated {
+		t.Fatalf("second POST returned %d: %s", w.Code, w.Body.String())
+	}
+	if w := post(`{"project":"-test-project","session_id":"test-session-123","message_uuid":"u1","block":-1,"note":"  "}`); w.Code != http.StatusBadRequest {
+		t.Errorf("empty note returned %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := post(`{"project":"-test-project","session_id":"test-session-123","message_uuid":"elsewhere","block":-1,"note":"x"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("message from another session returned %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := post(`{"session_id":"nope","message_uuid":"u1","note":"x"}`); w.Code != http.StatusNotFound {
+		t.Errorf("unknown session returned %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	w = httptest.NewRecorder()
+	handleAPIAnnotations(w, httptest.NewRequest("GET", "/api/annotations?session=test-session-123", nil))
+	var list struct {
+		Annotations []db.Annotation `json:"annotations"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Annotations) != 2 || list.Annotations[0].ID != highlight.ID {
+		t.Fatalf("annotations = %+v, want 2 oldest first", list.Annotations)
+	}
+
+	w = httptest.NewRecorder()
+	handleAPIAnnotation(w, httptest.NewRequest("PATCH", "/api/annotations/"+strconv.FormatInt(highlight.ID, 10), strings.NewReader(`{"note":"a greeting"}`)))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"note":"a greeting"`) {
+		t.Errorf("PATCH returned %d: %s", w.Code, w.Body.String())
+	}
+
+	// Single annotations are masked like the list when redaction is forced
+	r, err := redact.New(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setRedactor(r)
+	url := "/api/annotations/" + strconv.FormatInt(highlight.ID, 10)
+	w = httptest.NewRecorder()
+	handleAPIAnnotation(w, httptest.NewRequest("PATCH", url, strings.NewReader(`{"note":"ask ops@example.com"}`)))
+	if strings.Contains(w.Body.String(), "ops@example.com") {
+		t.Errorf("PATCH leaks the note: %s", w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	handleAPIAnnotation(w, httptest.NewRequest("GET", url, nil))
+	if body := w.Body.String(); strings.Contains(body, "ops@example.com") || !strings.Contains(body, "[REDACTED:email]") {
+		t.Errorf("GET leaks the note: %s", body)
+	}
+	setRedactor(nil)
+	handleAPIAnnotation(httptest.NewRecorder(), httptest.NewRequest("PATCH", url, strings.NewReader(`{"note":"a greeting"}`)))
+
+	// Exports carry the annotations as footnotes
+	session, err := parser.ParseSession(filepath.Join(projectsDir, "-test-project", "test-session-123.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := exportMarkdown(session, loadAnnotations(session.ID))
+	for _, want := range []string{"Notes: [^1]", "Hi th[^2]ere!", "[^1]: first prompt", "[^2]: > Hi th\n    a greeting\n    *alice, "} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown export missing %q:\n%s", want, md)
+		}
+	}
+	if org := exportOrg(session, loadAnnotations(session.ID)); !strings.Contains(org, "Hi th[fn:2]ere!") || !strings.Contains(org, "[fn:1] first prompt") {
+		t.Errorf("org export is missing footnotes:\n%s", org)
+	}
+	page := renderSessionPage(session, "-test-project", "", nil, true, true, true, "light", loadAnnotations(session.ID))
+	for _, want := range []string{`id="fnref-1"`, `<li id="fn-1">`, `<a href="#fnref-2">↩</a>`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("html export missing %q", want)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handleAPIAnnotation(w, httptest.NewRequest("DELETE", "/api/annotations/"+strconv.FormatInt(highlight.ID, 10), nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("DELETE returned %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	handleAPIAnnotation(w, httptest.NewRequest("DELETE", "/api/annotations/"+strconv.FormatInt(highlight.ID, 10), nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("second DELETE returned %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,444 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/render"
+	"github.com/thevibeworks/ccx/internal/stats"
+)
+
+// statsParams are the dashboard's filter fields as given in the query.
+// Since defaults to the last 30 days; an explicit empty since is all time.
+type statsParams struct {
+	Since, Until, Project, Branch string
+}
+
+func parseStatsParams(q url.Values) statsParams {
+	p := statsParams{Since: "30d", Until: q.Get("until"), Project: q.Get("project"), Branch: q.Get("branch")}
+	if q.Has("since") {
+		p.Since = q.Get("since")
+	}
+	return p
+}
+
+func (p statsParams) filter() (stats.Filter, error) {
+	f := stats.Filter{Project: p.Project, Branch: p.Branch}
+	var err error
+	if p.Since != "" {
+		if f.After, err = stats.ParseDate(p.Since, false); err != nil {
+			return f, err
+		}
+	}
+	if p.Until != "" {
+		if f.Before, err = stats.ParseDate(p.Until, true); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+// query encodes the params, then extra key/value pairs
+func (p statsParams) query(extra ...string) string {
+	v := url.Values{}
+	v.Set("since", p.Since)
+	for key, value := range map[string]string{"until": p.Until, "project": p.Project, "branch": p.Branch} {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		v.Set(extra[i], extra[i+1])
+	}
+	return v.Encode()
+}
+
+// parseStatsMatch reads the drill-down fields: day, model, tool, and
+// weekday (0 = Sunday) with hour
+func parseStatsMatch(q url.Values) (stats.Match, error) {
+	m := stats.Match{Day: q.Get("day"), Model: q.Get("model"), Tool: q.Get("tool")}
+	if q.Get("weekday") != "" || q.Get("hour") != "" {
+		day, err1 := strconv.Atoi(q.Get("weekday"))
+		hour, err2 := strconv.Atoi(q.Get("hour"))
+		if err1 != nil || err2 != nil || day < 0 || day > 6 || hour < 0 || hour > 23 {
+			return m, fmt.Errorf("invalid weekday/hour")
+		}
+		m.Slot = &stats.Slot{Weekday: time.Weekday(day), Hour: hour}
+	}
+	return m, nil
+}
+
+// handleStatsPage renders the statistics dashboard
+func handleStatsPage(w http.ResponseWriter, r *http.Request) {
+	p := parseStatsParams(r.URL.Query())
+	f, err := p.filter()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	report, err := stats.Collect(projectsDir, f, prices)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, renderStatsPage(p, f, report))
+}
+
+// handleStatsSessions renders the sessions behind one chart element
+func handleStatsSessions(w http.ResponseWriter, r *http.Request) {
+	p, f, m, ok := statsRequest(w, r)
+	if !ok {
+		return
+	}
+	a, err := stats.Aggregate(projectsDir, f, prices)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, renderStatsSessionsPage(p, f, m, a.Sessions(m)))
+}
+
+// handleAPIStatsReport returns the full report for the query's filter
+func handleAPIStatsReport(w http.ResponseWriter, r *http.Request) {
+	p := parseStatsParams(r.URL.Query())
+	f, err := p.filter()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	report, err := stats.Collect(projectsDir, f, prices)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// handleAPIStatsSessions lists the sessions matching the query's filter
+// and drill-down fields
+func handleAPIStatsSessions(w http.ResponseWriter, r *http.Request) {
+	_, f, m, ok := statsRequest(w, r)
+	if !ok {
+		return
+	}
+	a, err := stats.Aggregate(projectsDir, f, prices)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"sessions": a.Sessions(m)})
+}
+
+// statsRequest parses a drill-down query, answering 400 when it's invalid
+func statsRequest(w http.ResponseWriter, r *http.Request) (statsParams, stats.Filter, stats.Match, bool) {
+	q := r.URL.Query()
+	p := parseStatsParams(q)
+	f, err := p.filter()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return p, f, stats.Match{}, false
+	}
+	m, err := parseStatsMatch(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return p, f, m, false
+	}
+	return p, f, m, true
+}
+
+func renderStatsPage(p statsParams, f stats.Filter, r *stats.Report) string {
+	var b strings.Builder
+
+	b.WriteString(pageHeader("Statistics - ccx", "light"))
+	b.WriteString(statsPageCSS())
+	b.WriteString(renderTopNav("", ""))
+	b.WriteString(`<div class="layout">`)
+	b.WriteString(renderSidebar("stats"))
+
+	b.WriteString(`<main class="main-content">`)
+	b.WriteString(`<div class="page-header">`)
+	b.WriteString(`<h1>Statistics</h1>`)
+	b.WriteString(fmt.Sprintf(`<p class="stats">%d sessions in %d projects, %s</p>`, r.Sessions, r.Projects, html.EscapeString(f.Scope())))
+	b.WriteString(`</div>`)
+	renderStatsFilter(&b, p)
+
+	drill := func(extra ...string) string { return "/stats/sessions?" + p.query(extra...) }
+
+	b.WriteString(`<div class="stats-cards">`)
+	cards := []struct{ label, value, title string }{
+		{"Sessions", strconv.Itoa(r.Sessions), ""},
+		{"Messages", strconv.Itoa(r.Messages), fmt.Sprintf("%d prompts", r.UserPrompts)},
+		{"Tokens", render.FormatTokens(r.Tokens.Total()), fmt.Sprintf("%d input, %d output, %d cache read, %d cache write",
+			r.Tokens.InputTokens, r.Tokens.OutputTokens, r.Tokens.CacheReadTokens, r.Tokens.CacheCreateTokens)},
+		{"Tool calls", strconv.Itoa(r.ToolCalls), fmt.Sprintf("%d failed", r.ToolErrors)},
+		{"Est. cost", r.Cost.String(), "Estimated from the pricing table; Claude Code does not record cost"},
+		{"Avg session", render.FormatDuration(r.AvgDurationSeconds), ""},
+		{"Compactions", fmt.Sprintf("%.2f", r.CompactionsPerSession()), fmt.Sprintf("%d compactions in %d sessions", r.Compactions, r.CompactedSessions)},
+	}
+	for _, c := range cards {
+		b.WriteString(fmt.Sprintf(`<div class="stats-card" title="%s"><div class="stats-card-value">%s</div><div class="stats-card-label">%s</div></div>`,
+			html.EscapeString(c.title), html.EscapeString(c.value), c.label))
+	}
+	b.WriteString(`</div>`)
+
+	b.WriteString(`<section class="chart-panel"><h2>Tokens over time</h2>`)
+	b.WriteString(svgTokenChart(r.ByDay, func(date string) string { return drill("day", date) }))
+	b.WriteString(`</section>`)
+
+	b.WriteString(`<div class="chart-grid">`)
+	var projects []chartItem
+	for _, ps := range r.ByProject {
+		projects = append(projects, chartItem{
+			Label: ps.Name,
+			Value: ps.Sessions,
+			Title: fmt.Sprintf("%s: %d sessions, %d messages, %s tokens, %s est.", ps.Name, ps.Sessions, ps.Messages, render.FormatTokens(ps.Tokens.Total()), ps.Cost),
+			Href:  "/stats/sessions?" + statsParams{Since: p.Since, Until: p.Until, Project: ps.Project, Branch: p.Branch}.query(),
+		})
+	}
+	b.WriteString(`<section class="chart-panel"><h2>Sessions per project</h2>`)
+	b.WriteString(svgBarChart(topItems(projects, 12)))
+	b.WriteString(`</section>`)
+
+	var models []chartItem
+	for _, ms := range r.ByModel {
+		models = append(models, chartItem{
+			Label: ms.Model,
+			Value: ms.Tokens.Total(),
+			Title: fmt.Sprintf("%s: %d responses, %s tokens, %s est.", ms.Model, ms.Messages, render.FormatTokens(ms.Tokens.Total()), ms.Cost),
+			Href:  drill("model", ms.Model),
+		})
+	}
+	b.WriteString(`<section class="chart-panel"><h2>Model mix <span class="count">by tokens</span></h2>`)
+	b.WriteString(svgDonut(models))
+	b.WriteString(`</section>`)
+	b.WriteString(`</div>`)
+
+	var tools []chartItem
+	for _, ts := range r.ByTool {
+		tools = append(tools, chartItem{
+			Label:  ts.Name,
+			Value:  ts.Calls,
+			Errors: ts.Errors,
+			Title:  fmt.Sprintf("%s: %d calls, %d failed (%.1f%%)", ts.Name, ts.Calls, ts.Errors, 100*ts.ErrorRate),
+			Href:   drill("tool", ts.Name),
+		})
+	}
+	b.WriteString(`<section class="chart-panel"><h2>Tool usage <span class="count"><i class="legend-swatch chart-bar-error"></i>failed</span></h2>`)
+	b.WriteString(svgBarChart(topItems(tools, 15)))
+	b.WriteString(`</section>`)
+
+	b.WriteString(`<section class="chart-panel"><h2>Activity <span class="count">messages by hour and weekday</span></h2>`)
+	b.WriteString(svgHeatmap(r.Activity, func(day time.Weekday, hour int) string {
+		return drill("weekday", strconv.Itoa(int(day)), "hour", strconv.Itoa(hour))
+	}))
+	b.WriteString(`</section>`)
+
+	b.WriteString(`</main>`)
+	b.WriteString(`</div>`)
+	b.WriteString(renderFooter())
+	b.WriteString(pageFooter())
+
+	return b.String()
+}
+
+// renderStatsFilter renders the project/branch/date form with quick ranges
+func renderStatsFilter(b *strings.Builder, p statsParams) {
+	b.WriteString(`<form class="stats-filter" method="get" action="/stats">`)
+	fields := []struct{ name, value, placeholder string }{
+		{"project", p.Project, "Project"},
+		{"branch", p.Branch, "Branch"},
+		{"since", p.Since, "Since (YYYY-MM-DD or 30d)"},
+		{"until", p.Until, "Until"},
+	}
+	for _, fld := range fields {
+		b.WriteString(fmt.Sprintf(`<input type="text" name="%s" class="search-input" placeholder="%s" value="%s">`,
+			fld.name, fld.placeholder, html.EscapeString(fld.value)))
+	}
+	b.WriteString(`<button type="submit" class="stats-apply">Apply</button>`)
+	b.WriteString(`<span class="stats-ranges">`)
+	for _, rng := range []struct{ since, label string }{{"7d", "7d"}, {"30d", "30d"}, {"90d", "90d"}, {"", "All"}} {
+		class := "stats-range"
+		if p.Since == rng.since && p.Until == "" {
+			class += " active"
+		}
+		q := statsParams{Since: rng.since, Project: p.Project, Branch: p.Branch}.query()
+		b.WriteString(fmt.Sprintf(`<a href="/stats?%s" class="%s">%s</a>`, html.EscapeString(q), class, rng.label))
+	}
+	b.WriteString(`</span>`)
+	b.WriteString(`</form>`)
+}
+
+// topItems keeps the first n items, folding the rest into one "others" row
+func topItems(items []chartItem, n int) []chartItem {
+	if len(items) <= n {
+		return items
+	}
+	rest := chartItem{}
+	for _, it := range items[n-1:] {
+		rest.Value += it.Value
+		rest.Errors += it.Errors
+	}
+	rest.Label = fmt.Sprintf("%d others", len(items)-n+1)
+	rest.Title = rest.Label
+	return append(items[:n-1:n-1], rest)
+}
+
+// describeStatsMatch is the heading of a drill-down list
+func describeStatsMatch(p statsParams, m stats.Match) string {
+	var parts []string
+	if p.Project != "" {
+		name := p.Project
+		if strings.HasPrefix(name, "-") {
+			name = parser.GetProjectDisplayName(name)
+		}
+		parts = append(parts, "in "+name)
+	}
+	if p.Branch != "" {
+		parts = append(parts, "on branch "+p.Branch)
+	}
+	if m.Day != "" {
+		parts = append(parts, "started "+m.Day)
+	}
+	if m.Model != "" {
+		parts = append(parts, "using "+m.Model)
+	}
+	if m.Tool != "" {
+		parts = append(parts, "calling "+m.Tool)
+	}
+	if m.Slot != nil {
+		parts = append(parts, fmt.Sprintf("active %ss %02d:00-%02d:59", m.Slot.Weekday, m.Slot.Hour, m.Slot.Hour))
+	}
+	if len(parts) == 0 {
+		return "All sessions"
+	}
+	return "Sessions " + strings.Join(parts, ", ")
+}
+
+func renderStatsSessionsPage(p statsParams, f stats.Filter, m stats.Match, sessions []stats.SessionSummary) string {
+	var b strings.Builder
+
+	title := describeStatsMatch(p, m)
+	b.WriteString(pageHeader(title+" - ccx", "light"))
+	b.WriteString(statsPageCSS())
+	b.WriteString(renderTopNav("", ""))
+	b.WriteString(`<div class="layout">`)
+	b.WriteString(renderSidebar("stats"))
+
+	b.WriteString(`<main class="main-content">`)
+	b.WriteString(`<div class="page-header page-header-sessions">`)
+	b.WriteString(fmt.Sprintf(`<div class="breadcrumb"><a href="/stats?%s">Statistics</a> <span class="sep">/</span> <span class="current">Sessions</span></div>`,
+		html.EscapeString(p.query())))
+	b.WriteString(fmt.Sprintf(`<h1>%s</h1>`, html.EscapeString(title)))
+	b.WriteString(fmt.Sprintf(`<div class="stats">%d sessions, %s</div>`, len(sessions), html.EscapeString(f.Scope())))
+	b.WriteString(`</div>`)
+
+	b.WriteString(`<div class="session-list">`)
+	if len(sessions) == 0 {
+		b.WriteString(`<p class="chart-empty">No matching sessions</p>`)
+	}
+	for _, s := range sessions {
+		tokenDisplay := ""
+		if total := s.Tokens.Total(); total > 0 {
+			tokenDisplay = fmt.Sprintf(`<span class="stat stat-tokens" title="Tokens in range"><span class="stat-icon">⧫</span> %s</span>`, render.FormatTokens(total))
+		}
+		if !s.Cost.IsZero() {
+			tokenDisplay += fmt.Sprintf(`<span class="stat stat-cost" title="Estimated cost in range">%s</span>`, s.Cost)
+		}
+		b.WriteString(fmt.Sprintf(`
+<a href="/session/%s/%s" class="card session-card">
+	<div class="session-header">
+		<code class="session-id">%s</code>
+		<span class="session-project">%s</span>
+		<span class="session-time" title="%s">%s</span>
+	</div>
+	<div class="session-summary">%s</div>
+	<div class="session-stats">
+		<span class="stat"><span class="stat-icon">M</span> %d</span>
+		<span class="stat"><span class="stat-icon">T</span> %d</span>
+		%s
+	</div>
+</a>`, html.EscapeString(s.Project), html.EscapeString(s.ID),
+			html.EscapeString(truncate(s.ID, 8)),
+			html.EscapeString(s.ProjectName),
+			s.StartTime.Local().Format("2006-01-02 15:04"),
+			formatRelativeTime(s.StartTime),
+			html.EscapeString(s.Summary),
+			s.Messages, s.ToolCalls, tokenDisplay))
+	}
+	b.WriteString(`</div>`)
+
+	b.WriteString(`</main>`)
+	b.WriteString(`</div>`)
+	b.WriteString(renderFooter())
+	b.WriteString(pageFooter())
+
+	return b.String()
+}
+
+func statsPageCSS() string {
+	return `<style>
+.stats-filter { display: flex; flex-wrap: wrap; gap: 8px; align-items: center; margin-bottom: 20px; }
+.stats-filter .search-input { width: 160px; flex: 0 0 auto; }
+.stats-apply { padding: 6px 14px; border: 1px solid var(--primary); border-radius: var(--radius); background: var(--primary); color: #fff; cursor: pointer; font-size: 13px; }
+.stats-apply:hover { background: var(--primary-hover); }
+.stats-ranges { display: flex; gap: 4px; margin-left: auto; }
+.stats-range { padding: 4px 10px; border: 1px solid var(--border); border-radius: var(--radius); color: var(--text-muted); text-decoration: none; font-size: 13px; }
+.stats-range:hover, .stats-range.active { border-color: var(--primary); color: var(--primary); }
+.stats-cards { display: grid; grid-template-columns: repeat(auto-fit, minmax(130px, 1fr)); gap: 12px; margin-bottom: 20px; }
+.stats-card { background: var(--bg-secondary); border: 1px solid var(--border); border-radius: var(--radius); padding: 12px 14px; }
+.stats-card-value { font-size: 1.4rem; font-weight: 600; font-family: var(--font-mono); }
+.stats-card-label { font-size: 12px; color: var(--text-muted); }
+.chart-grid { display: grid; grid-template-columns: repeat(auto-fit, minmax(360px, 1fr)); gap: 16px; }
+.chart-panel { background: var(--bg-secondary); border: 1px solid var(--border); border-radius: var(--radius); padding: 14px 16px; margin-bottom: 16px; }
+.chart-panel h2 { font-size: 14px; margin-bottom: 10px; }
+.chart-panel .count { color: var(--text-muted); font-weight: normal; font-size: 12px; margin-left: 6px; }
+.chart { width: 100%; height: auto; display: block; font-family: var(--font-sans); }
+.chart-empty { color: var(--text-muted); font-size: 13px; padding: 12px 0; }
+.chart-label, .chart-value, .chart-axis { font-size: 11px; fill: var(--text-muted); }
+.chart-label { fill: var(--text); }
+.chart-gridline { stroke: var(--border); stroke-width: 1; }
+.chart-hit { fill: transparent; }
+.chart-row:hover .chart-hit, .chart-col:hover .chart-hit { fill: var(--bg-tertiary); }
+.chart-bar { fill: var(--primary); }
+.chart-bar-error { fill: var(--error-border); background: var(--error-border); }
+.series-input { fill: var(--accent-project); background: var(--accent-project); }
+.series-output { fill: var(--primary); background: var(--primary); }
+.series-cache-create { fill: var(--accent-session); background: var(--accent-session); }
+.series-cache-read { fill: var(--accent-conversation); background: var(--accent-conversation); }
+.chart-legend { display: flex; gap: 14px; font-size: 12px; color: var(--text-muted); margin-top: 6px; }
+.legend-swatch { display: inline-block; width: 10px; height: 10px; border-radius: 2px; margin-right: 5px; vertical-align: middle; }
+.donut { display: flex; align-items: center; gap: 20px; }
+.donut-chart { width: 160px; flex-shrink: 0; }
+.donut-seg { fill: none; }
+.donut-seg:hover { opacity: 0.8; }
+.donut-total { font-size: 16px; font-weight: 600; fill: var(--text); font-family: var(--font-mono); }
+.donut-legend { list-style: none; font-size: 13px; min-width: 0; }
+.donut-legend a { color: var(--text); text-decoration: none; display: block; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+.donut-legend a:hover { color: var(--primary); }
+.mix-0 { stroke: var(--primary); background: var(--primary); }
+.mix-1 { stroke: var(--accent-project); background: var(--accent-project); }
+.mix-2 { stroke: var(--accent-session); background: var(--accent-session); }
+.mix-3 { stroke: var(--accent-conversation); background: var(--accent-conversation); }
+.mix-4 { stroke: var(--assistant-border); background: var(--assistant-border); }
+.mix-5 { stroke: var(--compacted-border); background: var(--compacted-border); }
+.heat-cell { fill: var(--primary); }
+.heat-cell.heat-empty { fill: var(--bg-tertiary); }
+a:hover .heat-cell { stroke: var(--text); stroke-width: 1; }
+.session-project { font-size: 12px; color: var(--accent-project); flex: 1; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+</style>`
+}
@@ -4,12 +4,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"html"
+	"net/url"
 	"regexp"
-	"sort"
 	"strings"
 	"time"
 
+	"github.com/thevibeworks/ccx/internal/db"
 	"github.com/thevibeworks/ccx/internal/parser"
+	"github.com/thevibeworks/ccx/internal/pricing"
+	"github.com/thevibeworks/ccx/internal/render"
 )
 
 var idSanitizer = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
@@ -24,7 +27,7 @@ func isSafeURL(url string) bool {
 	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
 }
 
-func renderIndexPage(projects []*parser.Project, totalSessions int, search, sortBy string) string {
+func renderIndexPage(projects []*parser.Project, totalSessions int, search, sortBy, tag string, tags map[string][]string) string {
 	var b strings.Builder
 
 	b.WriteString(pageHeader("ccx", "light"))
@@ -52,6 +55,12 @@ func renderIndexPage(projects []*parser.Project, totalSessions int, search, sort
 		<option value="sessions"%s>Sessions</option>
 	</select>`, selected(sortBy, "time"), selected(sortBy, "name"), selected(sortBy, "sessions")))
 	b.WriteString(`</div>`)
+	b.WriteString(renderTagFilter(tag))
+	b.WriteString(`</div>`)
+
+	b.WriteString(`<div class="live-panel" id="live-panel" hidden>`)
+	b.WriteString(`<div class="live-panel-header"><span class="live-dot"></span> Active now <span class="live-count" id="live-count"></span></div>`)
+	b.WriteString(`<div class="live-list" id="live-list"></div>`)
 	b.WriteString(`</div>`)
 
 	b.WriteString(`<div class="card-grid" id="results">`)
@@ -62,16 +71,18 @@ func renderIndexPage(projects []*parser.Project, totalSessions int, search, sort
 		}
 		displayName := parser.GetProjectDisplayName(p.EncodedName)
 		b.WriteString(fmt.Sprintf(`
-<a href="/project/%s" class="card project-card">
+<a href="/project/%s" class="card project-card" data-project="%s" data-sessions="%d">
 	<div class="card-header">
 		<span class="card-title">%s</span>
 	</div>
 	<div class="card-stats">
-		<span class="stat">◉ %d %s</span>
+		<span class="stat stat-sessions">◉ %d %s</span>
 		<span class="stat-sep">•</span>
-		<span class="stat">%s</span>
-	</div>
-</a>`, html.EscapeString(p.EncodedName), html.EscapeString(displayName), len(p.Sessions), sessionsLabel, formatAge(p.LastModified)))
+		<span class="stat stat-age">%s</span>
+	</div>%s
+</a>`, html.EscapeString(p.EncodedName), html.EscapeString(p.EncodedName), len(p.Sessions),
+			html.EscapeString(displayName), len(p.Sessions), sessionsLabel, formatAge(p.LastModified),
+			renderTagChips(tags[p.EncodedName])))
 	}
 	b.WriteString(`</div>`)
 
@@ -79,12 +90,13 @@ func renderIndexPage(projects []*parser.Project, totalSessions int, search, sort
 	b.WriteString(`</div>`)
 	b.WriteString(renderFooter())
 	b.WriteString(indexJS())
+	b.WriteString(liveFeedJS("/api/watch/all", ""))
 	b.WriteString(pageFooter())
 
 	return b.String()
 }
 
-func renderProjectPage(project *parser.Project, sessions []*parser.Session, allProjects []*parser.Project, search, sortBy string) string {
+func renderProjectPage(project *parser.Project, sessions []*parser.Session, allProjects []*parser.Project, search, sortBy, tag string, tags map[string][]string) string {
 	var b strings.Builder
 
 	b.WriteString(pageHeader(project.Name+" - ccx", "light"))
@@ -112,7 +124,15 @@ func renderProjectPage(project *parser.Project, sessions []*parser.Session, allP
 	b.WriteString(fmt.Sprintf(`<div class="breadcrumb"><a href="/">Projects</a> <span class="sep">/</span> <span class="current">%s</span></div>`, html.EscapeString(project.Name)))
 	b.WriteString(`<span class="page-badge badge-session">S</span>`)
 	b.WriteString(fmt.Sprintf(`<h1>%s</h1>`, html.EscapeString(project.Name)))
-	b.WriteString(fmt.Sprintf(`<div class="stats">%d sessions</div>`, len(sessions)))
+	var projectCost pricing.Estimate
+	for _, s := range sessions {
+		projectCost.Add(prices.Session(s.Stats))
+	}
+	if projectCost.IsZero() {
+		b.WriteString(fmt.Sprintf(`<div class="stats">%d sessions</div>`, len(sessions)))
+	} else {
+		b.WriteString(fmt.Sprintf(`<div class="stats">%d sessions · <span title="%s">%s est.</span></div>`, len(sessions), costTitle(projectCost), projectCost))
+	}
 	b.WriteString(`</div>`)
 
 	b.WriteString(`<div class="controls">`)
@@ -127,6 +147,7 @@ func renderProjectPage(project *parser.Project, sessions []*parser.Session, allP
 		<option value="messages"%s>Messages</option>
 	</select>`, selected(sortBy, "time"), selected(sortBy, "messages")))
 	b.WriteString(`</div>`)
+	b.WriteString(renderTagFilter(tag))
 	b.WriteString(`</div>`)
 
 	b.WriteString(`<div class="session-list" id="results">`)
@@ -135,10 +156,13 @@ func renderProjectPage(project *parser.Project, sessions []*parser.Session, allP
 		totalTokens := s.Stats.InputTokens + s.Stats.OutputTokens
 		tokenDisplay := ""
 		if totalTokens > 0 {
-			tokenDisplay = fmt.Sprintf(`<span class="stat stat-tokens" title="Total tokens"><span class="stat-icon">⧫</span> %s</span>`, formatTokens(totalTokens))
+			tokenDisplay = fmt.Sprintf(`<span class="stat stat-tokens" title="Total tokens"><span class="stat-icon">⧫</span> %s</span>`, render.FormatTokens(totalTokens))
+		}
+		if cost := prices.Session(s.Stats); !cost.IsZero() {
+			tokenDisplay += fmt.Sprintf(`<span class="stat stat-cost" title="%s">%s</span>`, costTitle(cost), cost)
 		}
 		b.WriteString(fmt.Sprintf(`
-<a href="/session/%s/%s" class="card session-card">
+<a href="/session/%s/%s" class="card session-card" data-session="%s">
 	<div class="session-header">
 		<code class="session-id">%s</code>
 		<span class="session-time" title="%s">%s</span>
@@ -148,13 +172,13 @@ func renderProjectPage(project *parser.Project, sessions []*parser.Session, allP
 		<span class="stat"><span class="stat-icon">M</span> %d</span>
 		<span class="stat"><span class="stat-icon">T</span> %d</span>
 		%s
-	</div>
-</a>`, html.EscapeString(project.EncodedName), html.EscapeString(s.ID),
+	</div>%s
+</a>`, html.EscapeString(project.EncodedName), html.EscapeString(s.ID), html.EscapeString(s.ID),
 			html.EscapeString(truncate(s.ID, 8)),
 			s.StartTime.Format("2006-01-02 15:04"),
 			formatRelativeTime(s.StartTime),
 			html.EscapeString(summary),
-			s.Stats.MessageCount, s.Stats.ToolCalls, tokenDisplay))
+			s.Stats.MessageCount, s.Stats.ToolCalls, tokenDisplay, renderTagChips(tags[s.ID])))
 	}
 	b.WriteString(`</div>`)
 
@@ -162,15 +186,48 @@ func renderProjectPage(project *parser.Project, sessions []*parser.Session, allP
 	b.WriteString(`</div>`)
 	b.WriteString(renderFooter())
 	b.WriteString(indexJS())
+	b.WriteString(liveFeedJS("/api/watch/project/"+url.PathEscape(project.EncodedName), project.EncodedName))
 	b.WriteString(pageFooter())
 
 	return b.String()
 }
 
-func renderSessionPage(session *parser.Session, projectName string, allSessions []*parser.Session, showThinking, showTools, loadAll bool, theme string) string {
+// renderTagChips renders an item's tags; clicking one filters the listing by it
+func renderTagChips(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
 	var b strings.Builder
+	b.WriteString(`<div class="card-tags">`)
+	for _, t := range tags {
+		b.WriteString(fmt.Sprintf(`<span class="tag-chip" data-tag="%s">#%s</span>`, html.EscapeString(t), html.EscapeString(t)))
+	}
+	b.WriteString(`</div>`)
+	return b.String()
+}
+
+// renderTagFilter shows the active ?tag= filter with a button to clear it
+func renderTagFilter(tag string) string {
+	if tag == "" {
+		return ""
+	}
+	return fmt.Sprintf(`<button class="tag-filter" id="tag-filter" title="Clear tag filter">#%s ✕</button>`, html.EscapeString(tag))
+}
+
+func renderSessionPage(session *parser.Session, projectName, leaf string, allSessions []*parser.Session, showThinking, showTools, loadAll bool, theme string, footnotes []db.Annotation) string {
+	var b strings.Builder
+
+	// A selected branch narrows the whole page to its one path
+	roots := session.RootMessages
+	if branchIndex(session.Branches, leaf) >= 0 {
+		roots = parser.BranchRoots(session.RootMessages, leaf)
+	} else {
+		leaf = ""
+	}
 
-	title := fmt.Sprintf("Session %s - ccx", session.ID[:8])
+	title := fmt.Sprintf("Session %s - ccx", truncate(session.ID, 8))
+	contextPoints := parser.ContextTimeline(roots)
+	heavy := heaviestTurns(contextPoints)
 	b.WriteString(pageHeader(title, theme))
 	b.WriteString(renderTopNav(projectName, session.ID))
 	b.WriteString(`<div class="layout session-layout">`)
@@ -205,8 +262,18 @@ func renderSessionPage(session *parser.Session, projectName string, allSessions
 	b.WriteString(`<span id="toggle-icon">◀</span>`)
 	b.WriteString(`</button>`)
 	b.WriteString(`</div>`)
+	if len(contextPoints) > 1 {
+		peak := 0
+		for _, p := range contextPoints {
+			peak = max(peak, p.Tokens)
+		}
+		b.WriteString(`<div class="context-spark" title="Estimated context size after each response; dots mark the turns that grew it most">`)
+		b.WriteString(fmt.Sprintf(`<div class="context-spark-label"><span>Context</span><span>peak %s</span></div>`, render.FormatTokens(peak)))
+		b.WriteString(svgContextSparkline(contextPoints, heavy))
+		b.WriteString(`</div>`)
+	}
 	b.WriteString(`<div class="nav-list" id="nav-list">`)
-	renderConversationNav(&b, session.RootMessages)
+	renderConversationNav(&b, roots)
 	b.WriteString(`</div>`)
 	b.WriteString(`</aside>`)
 
@@ -225,9 +292,14 @@ func renderSessionPage(session *parser.Session, projectName string, allSessions
 	b.WriteString(fmt.Sprintf(`<input type="checkbox" id="show-thinking" style="display:none" %s>`, thinkingChecked))
 	b.WriteString(fmt.Sprintf(`<input type="checkbox" id="show-tools" style="display:none" %s>`, toolsChecked))
 
+	if !publishing {
+		renderBranchBar(&b, session, projectName, leaf)
+	}
 	b.WriteString(`<div class="messages" id="messages">`)
-	renderMessages(&b, session.RootMessages, 0, showThinking, showTools, loadAll)
+	notes := render.NewFootnotes(footnotes)
+	renderMessages(&b, roots, 0, showThinking, showTools, loadAll, heavy, notes)
 	b.WriteString(`</div>`)
+	notes.WriteHTML(&b)
 
 	// Tail spinner for watch mode
 	b.WriteString(`<div class="tail-spinner"><span class="cli-spinner-char"></span> Tailing session...</div>`)
@@ -258,22 +330,37 @@ func renderSessionPage(session *parser.Session, projectName string, allSessions
 	b.WriteString(fmt.Sprintf(`<button class="dock-btn toggle%s" id="tb-thinking" title="Thinking (t)"><span class="dock-icon">∴</span><span class="dock-label">Think</span></button>`, thinkingActive))
 	b.WriteString(fmt.Sprintf(`<button class="dock-btn toggle%s" id="tb-tools" title="Tools (o)"><span class="dock-icon">◎</span><span class="dock-label">Tools</span></button>`, toolsActive))
 	b.WriteString(`</div>`)
-	b.WriteString(`<div class="dock-sep"></div>`)
-	b.WriteString(`<div class="dock-group dock-live">`)
-	b.WriteString(`<button class="dock-btn live-btn" id="tb-watch" title="Watch live (w)"><span class="dock-icon">◉</span><span class="dock-label">Live</span></button>`)
-	b.WriteString(`</div>`)
+	// A published page has no server to watch, replay or export from
+	if !publishing {
+		b.WriteString(`<div class="dock-sep"></div>`)
+		b.WriteString(`<div class="dock-group dock-live">`)
+		b.WriteString(`<button class="dock-btn live-btn" id="tb-watch" title="Watch live (w)"><span class="dock-icon">◉</span><span class="dock-label">Live</span></button>`)
+		replayURL := fmt.Sprintf("/replay/%s/%s", url.PathEscape(projectName), url.PathEscape(session.ID))
+		if leaf != "" {
+			replayURL += "?leaf=" + url.QueryEscape(leaf)
+		}
+		b.WriteString(fmt.Sprintf(`<a class="dock-btn" id="tb-replay" href="%s" title="Replay with real timing"><span class="dock-icon">▷</span><span class="dock-label">Replay</span></a>`, html.EscapeString(replayURL)))
+		b.WriteString(fmt.Sprintf(`<a class="dock-btn" id="tb-commands" href="/commands/%s/%s" title="Shell commands run in this session"><span class="dock-icon">$</span><span class="dock-label">Commands</span></a>`, html.EscapeString(url.PathEscape(projectName)), html.EscapeString(url.PathEscape(session.ID))))
+		b.WriteString(`</div>`)
+	}
 	b.WriteString(`<div class="dock-sep"></div>`)
 	b.WriteString(`<div class="dock-group dock-actions">`)
-	b.WriteString(`<div class="dock-dropdown">`)
-	b.WriteString(`<button class="dock-btn" id="tb-export" title="Export"><span class="dock-icon"><svg width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2"><path d="M21 15v4a2 2 0 01-2 2H5a2 2 0 01-2-2v-4M7 10l5 5 5-5M12 15V3"/></svg></span><span class="dock-label">Export</span></button>`)
-	b.WriteString(`<div class="dock-menu" id="toolbar-export-menu">`)
-	b.WriteString(fmt.Sprintf(`<a href="/api/export/%s/%s?format=html">HTML</a>`, html.EscapeString(projectName), html.EscapeString(session.ID)))
-	b.WriteString(fmt.Sprintf(`<a href="/api/export/%s/%s?format=md">Markdown</a>`, html.EscapeString(projectName), html.EscapeString(session.ID)))
-	b.WriteString(fmt.Sprintf(`<a href="/api/export/%s/%s?format=org">Org</a>`, html.EscapeString(projectName), html.EscapeString(session.ID)))
-	b.WriteString(fmt.Sprintf(`<a href="/api/export/%s/%s?format=txt">Text</a>`, html.EscapeString(projectName), html.EscapeString(session.ID)))
-	b.WriteString(fmt.Sprintf(`<a href="/api/export/%s/%s?format=json">JSON</a>`, html.EscapeString(projectName), html.EscapeString(session.ID)))
-	b.WriteString(`</div>`)
-	b.WriteString(`</div>`)
+	if !publishing {
+		b.WriteString(`<div class="dock-dropdown">`)
+		b.WriteString(`<button class="dock-btn" id="tb-export" title="Export"><span class="dock-icon"><svg width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2"><path d="M21 15v4a2 2 0 01-2 2H5a2 2 0 01-2-2v-4M7 10l5 5 5-5M12 15V3"/></svg></span><span class="dock-label">Export</span></button>`)
+		b.WriteString(`<div class="dock-menu" id="toolbar-export-menu">`)
+		b.WriteString(fmt.Sprintf(`<a href="/api/export/%s/%s?format=html">HTML</a>`, html.EscapeString(projectName), html.EscapeString(session.ID)))
+		b.WriteString(fmt.Sprintf(`<a href="/api/export/%s/%s?format=md">Markdown</a>`, html.EscapeString(projectName), html.EscapeString(session.ID)))
+		b.WriteString(fmt.Sprintf(`<a href="/api/export/%s/%s?format=org">Org</a>`, html.EscapeString(projectName), html.EscapeString(session.ID)))
+		b.WriteString(fmt.Sprintf(`<a href="/api/export/%s/%s?format=txt">Text</a>`, html.EscapeString(projectName), html.EscapeString(session.ID)))
+		b.WriteString(fmt.Sprintf(`<a href="/api/export/%s/%s?format=json">JSON</a>`, html.EscapeString(projectName), html.EscapeString(session.ID)))
+		b.WriteString(fmt.Sprintf(`<a href="/api/export/%s/%s?format=patch" title="File changes as a git patch series">Patch</a>`, html.EscapeString(projectName), html.EscapeString(session.ID)))
+		if redactor == nil {
+			b.WriteString(fmt.Sprintf(`<a href="/api/export/%s/%s?format=md&amp;redact=1" title="Markdown with secrets and personal data masked">Markdown, redacted</a>`, html.EscapeString(projectName), html.EscapeString(session.ID)))
+		}
+		b.WriteString(`</div>`)
+		b.WriteString(`</div>`)
+	}
 	b.WriteString(`<button class="dock-btn" id="tb-search" title="Search (/ or f)"><span class="dock-icon"><svg width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2"><circle cx="11" cy="11" r="8"/><path d="M21 21l-4.35-4.35"/></svg></span><span class="dock-label">Find</span></button>`)
 	b.WriteString(`<button class="dock-btn" id="tb-refresh" title="Refresh (r)"><span class="dock-icon"><svg width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2"><path d="M23 4v6h-6M1 20v-6h6"/><path d="M3.51 9a9 9 0 0114.85-3.36L23 10M1 14l4.64 4.36A9 9 0 0020.49 15"/></svg></span></button>`)
 	b.WriteString(`<button class="dock-btn" id="tb-info" title="Info (i)"><span class="dock-icon">ⓘ</span></button>`)
@@ -323,13 +410,20 @@ func renderSessionPage(session *parser.Session, projectName string, allSessions
 		b.WriteString(fmt.Sprintf(`<div class="info-row"><span class="info-label">CWD</span><code class="info-cwd" title="%s">%s</code></div>`,
 			html.EscapeString(session.CWD), html.EscapeString(truncatePath(session.CWD, 40))))
 	}
+	if !publishing {
+		b.WriteString(fmt.Sprintf(`<form class="info-row" onsubmit="location.href='/compare/%s/%s/'+encodeURIComponent(this.other.value.trim());return false">`,
+			html.EscapeString(url.PathEscape(projectName)), html.EscapeString(url.PathEscape(session.ID))))
+		b.WriteString(`<span class="info-label">Compare</span><input type="text" class="tag-input" name="other" placeholder="with session…" title="Session ID or prefix to compare with (Enter)"></form>`)
+		b.WriteString(`<div class="info-row info-tags"><span class="info-label">Tags</span><span class="session-tags" id="session-tags"></span>`)
+		b.WriteString(`<input type="text" class="tag-input" id="tag-input" placeholder="+ tag" title="Add a tag (Enter)"></div>`)
+	}
 	b.WriteString(`</div>`)
 
 	// Time section
 	b.WriteString(`<div class="info-section">`)
 	b.WriteString(`<div class="info-section-header">Time</div>`)
 	b.WriteString(fmt.Sprintf(`<div class="info-row"><span class="info-label">Started</span><span class="info-value">%s</span></div>`, session.StartTime.Format("2006-01-02 15:04")))
-	b.WriteString(fmt.Sprintf(`<div class="info-row"><span class="info-label">Duration</span><span class="info-value">%s</span></div>`, formatDuration(session.Stats.DurationSeconds)))
+	b.WriteString(fmt.Sprintf(`<div class="info-row"><span class="info-label">Duration</span><span class="info-value">%s</span></div>`, render.FormatDuration(session.Stats.DurationSeconds)))
 	b.WriteString(`</div>`)
 
 	// Activity section
@@ -348,21 +442,56 @@ func renderSessionPage(session *parser.Session, projectName string, allSessions
 	if totalTokens > 0 {
 		b.WriteString(`<div class="info-section info-section-tokens">`)
 		b.WriteString(`<div class="info-section-header">Tokens</div>`)
-		b.WriteString(fmt.Sprintf(`<div class="info-row" title="Fresh tokens sent to API (not from cache)"><span class="info-label">Input</span><span class="info-value">%s</span></div>`, formatTokens(session.Stats.InputTokens)))
-		b.WriteString(fmt.Sprintf(`<div class="info-row" title="Tokens generated by Claude"><span class="info-label">Output</span><span class="info-value">%s</span></div>`, formatTokens(session.Stats.OutputTokens)))
+		b.WriteString(fmt.Sprintf(`<div class="info-row" title="Fresh tokens sent to API (not from cache)"><span class="info-label">Input</span><span class="info-value">%s</span></div>`, render.FormatTokens(session.Stats.InputTokens)))
+		b.WriteString(fmt.Sprintf(`<div class="info-row" title="Tokens generated by Claude"><span class="info-label">Output</span><span class="info-value">%s</span></div>`, render.FormatTokens(session.Stats.OutputTokens)))
 		// Show cache stats if present
 		if session.Stats.CacheReadTokens > 0 || session.Stats.CacheCreateTokens > 0 {
 			if session.Stats.CacheReadTokens > 0 {
-				b.WriteString(fmt.Sprintf(`<div class="info-row info-cache" title="Tokens read from prompt cache (90%% cheaper)"><span class="info-label">↩ Cache read</span><span class="info-value">%s</span></div>`, formatTokens(session.Stats.CacheReadTokens)))
+				b.WriteString(fmt.Sprintf(`<div class="info-row info-cache" title="Tokens read from prompt cache (90%% cheaper)"><span class="info-label">↩ Cache read</span><span class="info-value">%s</span></div>`, render.FormatTokens(session.Stats.CacheReadTokens)))
 			}
 			if session.Stats.CacheCreateTokens > 0 {
-				b.WriteString(fmt.Sprintf(`<div class="info-row info-cache" title="Tokens written to prompt cache"><span class="info-label">↪ Cache write</span><span class="info-value">%s</span></div>`, formatTokens(session.Stats.CacheCreateTokens)))
+				b.WriteString(fmt.Sprintf(`<div class="info-row info-cache" title="Tokens written to prompt cache"><span class="info-label">↪ Cache write</span><span class="info-value">%s</span></div>`, render.FormatTokens(session.Stats.CacheCreateTokens)))
 			}
 		}
-		b.WriteString(fmt.Sprintf(`<div class="info-row info-total" title="Input + Output tokens"><span class="info-label">Total</span><span class="info-value"><strong>%s</strong></span></div>`, formatTokens(totalTokens)))
+		b.WriteString(fmt.Sprintf(`<div class="info-row info-total" title="Input + Output tokens"><span class="info-label">Total</span><span class="info-value"><strong>%s</strong></span></div>`, render.FormatTokens(totalTokens)))
 		b.WriteString(`</div>`)
 	}
 
+	// Context window: size after the latest response and the turns that
+	// grew it most, estimated from per-response usage
+	if len(contextPoints) > 0 {
+		last := contextPoints[len(contextPoints)-1]
+		b.WriteString(`<div class="info-section info-section-context">`)
+		b.WriteString(`<div class="info-section-header">Context window</div>`)
+		b.WriteString(fmt.Sprintf(`<div class="info-row" title="Prompt and output of the latest response"><span class="info-label">Latest</span><span class="info-value">%s</span></div>`, render.FormatTokens(last.Tokens)))
+		for _, p := range parser.HeaviestTurns(contextPoints, maxHeavyTurns) {
+			b.WriteString(fmt.Sprintf(`<div class="info-row info-cache" title="Context grew to %s tokens"><span class="info-label"><a href="#msg-%s">%s</a></span><span class="info-value">+%s</span></div>`,
+				render.FormatTokens(p.Tokens), sanitizeID(p.Message.UUID), p.Message.Timestamp.Format("15:04:05"), render.FormatTokens(p.Added)))
+		}
+		b.WriteString(`</div>`)
+	}
+
+	renderFilesSection(&b, session, projectName, leaf)
+
+	// Cost estimate from the pricing table, with a per-day breakdown for
+	// sessions that span days
+	if cost := prices.Session(session.Stats); !cost.IsZero() {
+		b.WriteString(`<div class="info-section info-section-cost">`)
+		b.WriteString(`<div class="info-section-header">Estimated cost</div>`)
+		b.WriteString(fmt.Sprintf(`<div class="info-row info-total" title="%s"><span class="info-label">Session</span><span class="info-value"><strong>%s</strong></span></div>`,
+			costTitle(cost), cost))
+		if days := prices.ByDay(session.Stats); len(days) > 1 {
+			for _, d := range days {
+				b.WriteString(fmt.Sprintf(`<div class="info-row info-cache"><span class="info-label">%s</span><span class="info-value">%s</span></div>`, d.Date, d.Estimate))
+			}
+		}
+		b.WriteString(`</div>`)
+	}
+
+	if redactor != nil {
+		renderRedactionInfo(&b, session)
+	}
+
 	b.WriteString(`</div>`)
 
 	b.WriteString(`</div>`)
@@ -378,8 +507,96 @@ const (
 	initialContextSections   = 3   // Number of compact sections to show initially
 	maxProjectsInitial       = 50  // Max projects to show initially (future: load more)
 	maxSessionsInitial       = 100 // Max sessions per project initially (future: load more)
+	maxHeavyTurns            = 5   // Turns flagged for growing the context most
+	maxInfoFiles             = 8   // Changed files listed in the info panel
 )
 
+// heaviestTurns indexes the turns that grew the context most by UUID
+func heaviestTurns(points []parser.ContextPoint) map[string]parser.ContextPoint {
+	heavy := make(map[string]parser.ContextPoint)
+	for _, p := range parser.HeaviestTurns(points, maxHeavyTurns) {
+		heavy[p.Message.UUID] = p
+	}
+	return heavy
+}
+
+// branchIndex finds the branch ending at leaf, or -1
+func branchIndex(branches []parser.Branch, leaf string) int {
+	if leaf == "" {
+		return -1
+	}
+	for i, br := range branches {
+		if br.LeafUUID == leaf {
+			return i
+		}
+	}
+	return -1
+}
+
+// renderBranchBar lists the paths of a forked session so each can be read
+// on its own; with one selected, the others link to a comparison with it
+func renderBranchBar(b *strings.Builder, session *parser.Session, projectName, leaf string) {
+	if len(session.Branches) < 2 {
+		return
+	}
+	base := fmt.Sprintf("/session/%s/%s", url.PathEscape(projectName), url.PathEscape(session.ID))
+
+	b.WriteString(`<nav class="branch-bar" aria-label="Branches">`)
+	b.WriteString(fmt.Sprintf(`<span class="branch-bar-label">%d branches</span>`, len(session.Branches)))
+	active := ""
+	if leaf == "" {
+		active = " active"
+	}
+	b.WriteString(fmt.Sprintf(`<a href="%s" class="branch-pill%s" title="Show every branch as one tree">All</a>`, html.EscapeString(base), active))
+
+	for i, br := range session.Branches {
+		active = ""
+		if br.LeafUUID == leaf {
+			active = " active"
+		}
+		label := branchLabel(session.RootMessages, br)
+		b.WriteString(`<span class="branch-item">`)
+		b.WriteString(fmt.Sprintf(`<a href="%s?leaf=%s" class="branch-pill%s" title="%s">`,
+			html.EscapeString(base), html.EscapeString(url.QueryEscape(br.LeafUUID)), active, html.EscapeString(label)))
+		b.WriteString(fmt.Sprintf(`<span class="branch-num">%d</span>`, i+1))
+		b.WriteString(fmt.Sprintf(`<span class="branch-label">%s</span>`, html.EscapeString(truncate(label, 40))))
+		b.WriteString(fmt.Sprintf(`<span class="branch-meta">%d msgs · %s</span>`, br.Messages, formatRelativeTime(br.EndTime)))
+		b.WriteString(`</a>`)
+		if leaf != "" && br.LeafUUID != leaf {
+			compareURL := fmt.Sprintf("/compare/%s/%s/%s?leaf_a=%s&leaf_b=%s",
+				url.PathEscape(projectName), url.PathEscape(session.ID), url.PathEscape(session.ID),
+				url.QueryEscape(leaf), url.QueryEscape(br.LeafUUID))
+			b.WriteString(fmt.Sprintf(`<a href="%s" class="branch-compare" title="Compare with the selected branch">⇄</a>`, html.EscapeString(compareURL)))
+		}
+		b.WriteString(`</span>`)
+	}
+	b.WriteString(`</nav>`)
+}
+
+// branchLabel is the branch's summary, or else the first prompt after it
+// forks off
+func branchLabel(roots []*parser.Message, br parser.Branch) string {
+	if br.Summary != "" {
+		return br.Summary
+	}
+	forked := false
+	for _, msg := range parser.Path(roots, br.LeafUUID) {
+		forked = forked || msg.UUID == br.ForkUUID
+		if !forked {
+			continue
+		}
+		switch msg.Kind {
+		case parser.KindUserPrompt:
+			if text := getFirstTextPreview(msg, 0); text != "" {
+				return text
+			}
+		case parser.KindCommand:
+			return strings.TrimSpace(msg.CommandName + " " + msg.CommandArgs)
+		}
+	}
+	return truncate(br.LeafUUID, 8)
+}
+
 // splitByCompactBoundaries splits messages into sections delimited by compact summaries
 func splitByCompactBoundaries(messages []*parser.Message) [][]*parser.Message {
 	var sections [][]*parser.Message
@@ -420,12 +637,12 @@ func splitByUserPrompts(messages []*parser.Message, chunkSize int) [][]*parser.M
 	return sections
 }
 
-func renderMessages(b *strings.Builder, messages []*parser.Message, depth int, showThinking, showTools, loadAll bool) {
-	allMsgs := flattenMessages(messages)
+func renderMessages(b *strings.Builder, messages []*parser.Message, depth int, showThinking, showTools, loadAll bool, heavy map[string]parser.ContextPoint, notes *render.Footnotes) {
+	allMsgs := parser.Flatten(messages)
 
 	// Check if progressive loading is needed (unless loadAll is requested)
 	if !loadAll && len(allMsgs) > progressiveLoadThreshold {
-		renderMessagesProgressive(b, allMsgs, showThinking, showTools)
+		renderMessagesProgressive(b, allMsgs, showThinking, showTools, heavy, notes)
 		return
 	}
 
@@ -447,7 +664,7 @@ func renderMessages(b *strings.Builder, messages []*parser.Message, depth int, s
 		if isAnchor {
 			// Close previous thread if any
 			if inThread && len(currentThread) > 0 {
-				renderThread(b, currentThread, showThinking, showTools, toolResults)
+				renderThread(b, currentThread, showThinking, showTools, toolResults, heavy, notes)
 			}
 			// Start new thread
 			currentThread = []*parser.Message{msg}
@@ -456,18 +673,18 @@ func renderMessages(b *strings.Builder, messages []*parser.Message, depth int, s
 			currentThread = append(currentThread, msg)
 		} else {
 			// Messages before first anchor - render directly
-			renderTurnMessage(b, msg, showThinking, showTools, 0, toolResults)
+			renderTurnMessage(b, msg, showThinking, showTools, 0, toolResults, heavy, notes)
 		}
 	}
 
 	// Close final thread
 	if inThread && len(currentThread) > 0 {
-		renderThread(b, currentThread, showThinking, showTools, toolResults)
+		renderThread(b, currentThread, showThinking, showTools, toolResults, heavy, notes)
 	}
 }
 
 // renderMessagesProgressive renders large conversations with lazy loading
-func renderMessagesProgressive(b *strings.Builder, allMsgs []*parser.Message, showThinking, showTools bool) {
+func renderMessagesProgressive(b *strings.Builder, allMsgs []*parser.Message, showThinking, showTools bool, heavy map[string]parser.ContextPoint, notes *render.Footnotes) {
 	sections := splitByCompactBoundaries(allMsgs)
 
 	// If no compact boundaries, fall back to splitting by user prompts
@@ -518,19 +735,19 @@ func renderMessagesProgressive(b *strings.Builder, allMsgs []*parser.Message, sh
 
 		if isAnchor {
 			if inThread && len(currentThread) > 0 {
-				renderThread(b, currentThread, showThinking, showTools, toolResults)
+				renderThread(b, currentThread, showThinking, showTools, toolResults, heavy, notes)
 			}
 			currentThread = []*parser.Message{msg}
 			inThread = true
 		} else if inThread {
 			currentThread = append(currentThread, msg)
 		} else {
-			renderTurnMessage(b, msg, showThinking, showTools, 0, toolResults)
+			renderTurnMessage(b, msg, showThinking, showTools, 0, toolResults, heavy, notes)
 		}
 	}
 
 	if inThread && len(currentThread) > 0 {
-		renderThread(b, currentThread, showThinking, showTools, toolResults)
+		renderThread(b, currentThread, showThinking, showTools, toolResults, heavy, notes)
 	}
 }
 
@@ -548,7 +765,7 @@ func buildToolResultsMap(messages []*parser.Message) map[string]parser.ContentBl
 }
 
 // renderThread renders a conversation thread anchored by a USER message
-func renderThread(b *strings.Builder, thread []*parser.Message, showThinking, showTools bool, toolResults map[string]parser.ContentBlock) {
+func renderThread(b *strings.Builder, thread []*parser.Message, showThinking, showTools bool, toolResults map[string]parser.ContentBlock, heavy map[string]parser.ContextPoint, notes *render.Footnotes) {
 	if len(thread) == 0 {
 		return
 	}
@@ -561,7 +778,7 @@ func renderThread(b *strings.Builder, thread []*parser.Message, showThinking, sh
 
 	// Render anchor (USER prompt or Command)
 	b.WriteString(`<div class="thread-anchor">`)
-	renderTurnMessage(b, anchor, showThinking, showTools, 0, toolResults)
+	renderTurnMessage(b, anchor, showThinking, showTools, 0, toolResults, heavy, notes)
 	b.WriteString(`</div>`)
 
 	// Render responses with indent
@@ -572,7 +789,7 @@ func renderThread(b *strings.Builder, thread []*parser.Message, showThinking, sh
 			if msg.IsSidechain {
 				level = 2
 			}
-			renderTurnMessage(b, msg, showThinking, showTools, level, toolResults)
+			renderTurnMessage(b, msg, showThinking, showTools, level, toolResults, heavy, notes)
 		}
 		b.WriteString(`</div>`)
 	}
@@ -580,7 +797,7 @@ func renderThread(b *strings.Builder, thread []*parser.Message, showThinking, sh
 	b.WriteString(`</div>`)
 }
 
-func renderTurnMessage(b *strings.Builder, msg *parser.Message, showThinking, showTools bool, level int, toolResults map[string]parser.ContentBlock) {
+func renderTurnMessage(b *strings.Builder, msg *parser.Message, showThinking, showTools bool, level int, toolResults map[string]parser.ContentBlock, heavy map[string]parser.ContextPoint, notes *render.Footnotes) {
 	// Level class for indentation
 	levelClass := ""
 	if level > 0 {
@@ -614,7 +831,7 @@ func renderTurnMessage(b *strings.Builder, msg *parser.Message, showThinking, sh
 		b.WriteString(`<summary class="turn-header"><span class="turn-icon">▽</span> System Instructions</summary>`)
 		b.WriteString(`<div class="turn-body">`)
 		for _, block := range msg.Content {
-			renderBlock(b, block, showThinking, showTools, toolResults)
+			renderBlock(b, block, showThinking, showTools, toolResults, notes)
 		}
 		b.WriteString(`</div></details>`)
 		return
@@ -634,9 +851,10 @@ func renderTurnMessage(b *strings.Builder, msg *parser.Message, showThinking, sh
 		// Show command args if present
 		if msg.CommandArgs != "" {
 			b.WriteString(`<div class="turn-body command-args">`)
-			b.WriteString(renderMarkdown(msg.CommandArgs))
+			b.WriteString(render.MarkdownHTML(msg.CommandArgs))
 			b.WriteString(`</div>`)
 		}
+		renderNoteRefs(b, msg.UUID, notes)
 		b.WriteString(`</div>`)
 		return
 	}
@@ -677,12 +895,13 @@ func renderTurnMessage(b *strings.Builder, msg *parser.Message, showThinking, sh
 		b.WriteString(fmt.Sprintf(`<span class="turn-role">%s</span>`, role))
 		b.WriteString(fmt.Sprintf(`<span class="turn-preview">%s</span>`, html.EscapeString(preview)))
 		b.WriteString(fmt.Sprintf(`<span class="turn-time">%s</span>`, msg.Timestamp.Format("15:04:05")))
-		b.WriteString(`<span class="turn-actions"><button class="turn-raw-btn" onclick="toggleTurnRaw(event,this)">raw</button><button class="turn-copy-btn" onclick="copyTurn(event,this)">copy</button></span>`)
+		b.WriteString(turnActions())
 		b.WriteString(`</summary>`)
 		b.WriteString(fmt.Sprintf(`<div class="turn-body" data-raw="%s">`, html.EscapeString(rawContent)))
 		for _, block := range msg.Content {
-			renderBlock(b, block, showThinking, showTools, toolResults)
+			renderBlock(b, block, showThinking, showTools, toolResults, notes)
 		}
+		renderNoteRefs(b, msg.UUID, notes)
 		b.WriteString(`</div>`)
 		b.WriteString(`</details>`)
 		return
@@ -697,24 +916,51 @@ func renderTurnMessage(b *strings.Builder, msg *parser.Message, showThinking, sh
 	if msg.Model != "" {
 		b.WriteString(fmt.Sprintf(`<span class="turn-model">%s</span>`, html.EscapeString(msg.Model)))
 	}
-	b.WriteString(`<span class="turn-actions"><button class="turn-raw-btn" onclick="toggleTurnRaw(event,this)">raw</button><button class="turn-copy-btn" onclick="copyTurn(event,this)">copy</button></span>`)
+	if cost := prices.Message(msg); !cost.IsZero() {
+		b.WriteString(fmt.Sprintf(`<span class="turn-cost" title="%s">%s</span>`, costTitle(cost), cost))
+	}
+	if msg.Usage != nil && msg.ContextTokens > 0 {
+		b.WriteString(fmt.Sprintf(`<span class="turn-context" title="Estimated context after this response: %d tokens">%s ctx</span>`, msg.ContextTokens, render.FormatTokens(msg.ContextTokens)))
+	}
+	if p, ok := heavy[msg.UUID]; ok {
+		b.WriteString(fmt.Sprintf(`<span class="turn-heavy" title="One of the turns that grew the context most">▲ +%s</span>`, render.FormatTokens(p.Added)))
+	}
+	b.WriteString(turnActions())
 	b.WriteString(`</div>`)
 
 	b.WriteString(fmt.Sprintf(`<div class="turn-body" data-raw="%s">`, html.EscapeString(rawContent)))
 	for _, block := range msg.Content {
-		renderBlock(b, block, showThinking, showTools, toolResults)
+		renderBlock(b, block, showThinking, showTools, toolResults, notes)
 	}
+	renderNoteRefs(b, msg.UUID, notes)
 	b.WriteString(`</div>`)
 
 	b.WriteString(`</div>`)
 }
 
-func renderBlock(b *strings.Builder, block parser.ContentBlock, showThinking, showTools bool, toolResults map[string]parser.ContentBlock) {
+// renderNoteRefs links a message to the footnotes of its annotations
+func renderNoteRefs(b *strings.Builder, uuid string, notes *render.Footnotes) {
+	if refs := notes.Remaining(uuid); len(refs) > 0 {
+		b.WriteString(fmt.Sprintf(`<p class="footnote-refs">Notes: %s</p>`, render.HTMLRefs(refs)))
+	}
+}
+
+// turnActions are the buttons in a turn's header; a published page can't
+// save notes, so it has none
+func turnActions() string {
+	note := `<button class="turn-note-btn" onclick="noteTurn(event,this)">note</button>`
+	if publishing {
+		note = ""
+	}
+	return `<span class="turn-actions">` + note + `<button class="turn-raw-btn" onclick="toggleTurnRaw(event,this)">raw</button><button class="turn-copy-btn" onclick="copyTurn(event,this)">copy</button></span>`
+}
+
+func renderBlock(b *strings.Builder, block parser.ContentBlock, showThinking, showTools bool, toolResults map[string]parser.ContentBlock, notes *render.Footnotes) {
 	switch block.Type {
 	case "text":
 		if block.Text != "" {
 			b.WriteString(`<div class="block-text">`)
-			b.WriteString(renderMarkdown(block.Text))
+			b.WriteString(render.MarkdownHTML(block.Text))
 			b.WriteString(`</div>`)
 		}
 
@@ -736,7 +982,7 @@ func renderBlock(b *strings.Builder, block parser.ContentBlock, showThinking, sh
 			openAttr = " open"
 		}
 		// Compact preview for common tools
-		preview := compactToolPreview(block.ToolName, block.ToolInput)
+		preview := render.ToolPreview(block.ToolName, block.ToolInput)
 		b.WriteString(fmt.Sprintf(`<details class="block-tool" id="tool-%s" data-tool-id="%s"%s>`, sanitizeID(block.ToolID), html.EscapeString(block.ToolID), openAttr))
 		b.WriteString(fmt.Sprintf(`<summary><span class="block-icon">●</span> %s<span class="tool-preview">%s</span><span class="tool-actions"><button class="raw-toggle">raw</button><button class="copy-btn">copy</button></span></summary>`,
 			html.EscapeString(block.ToolName), html.EscapeString(preview)))
@@ -776,6 +1022,10 @@ func renderBlock(b *strings.Builder, block parser.ContentBlock, showThinking, sh
 			b.WriteString(`</div>`)
 		}
 
+		if block.Sidechain != nil {
+			renderSidechain(b, block.Sidechain, showThinking, showTools, notes)
+		}
+
 		b.WriteString(`</details>`)
 
 	case "tool_result":
@@ -790,105 +1040,32 @@ func renderBlock(b *strings.Builder, block parser.ContentBlock, showThinking, sh
 	}
 }
 
-// compactToolPreview returns a short preview for the tool call
-func compactToolPreview(toolName string, input any) string {
-	m, ok := input.(map[string]any)
-	if !ok {
-		return ""
-	}
+// renderSidechain renders a subagent transcript inline under its Task call
+func renderSidechain(b *strings.Builder, sc *parser.Sidechain, showThinking, showTools bool, notes *render.Footnotes) {
+	msgs := parser.Flatten(sc.RootMessages)
+	toolResults := buildToolResultsMap(msgs)
 
-	switch toolName {
-	case "Read":
-		if fp, ok := m["file_path"].(string); ok {
-			return fp
-		}
-	case "Write":
-		if fp, ok := m["file_path"].(string); ok {
-			return fp
-		}
-	case "Edit":
-		if fp, ok := m["file_path"].(string); ok {
-			return fp
-		}
-	case "Grep":
-		if p, ok := m["pattern"].(string); ok {
-			return "/" + p + "/"
-		}
-	case "Glob":
-		if p, ok := m["pattern"].(string); ok {
-			return p
-		}
-	case "Bash":
-		if cmd, ok := m["command"].(string); ok {
-			if len(cmd) > 50 {
-				return "$ " + cmd[:50] + "..."
-			}
-			return "$ " + cmd
-		}
-	case "Task":
-		var parts []string
-		if agent, ok := m["subagent_type"].(string); ok && agent != "" {
-			parts = append(parts, "["+agent+"]")
-		}
-		if desc, ok := m["description"].(string); ok {
-			parts = append(parts, desc)
-		}
-		if len(parts) > 0 {
-			return strings.Join(parts, " ")
-		}
-	case "Skill":
-		if skill, ok := m["skill"].(string); ok {
-			return "/" + skill
-		}
-	case "WebSearch":
-		if q, ok := m["query"].(string); ok {
-			if len(q) > 50 {
-				return q[:50] + "..."
-			}
-			return q
-		}
-	case "WebFetch":
-		if url, ok := m["url"].(string); ok {
-			return url
-		}
-	case "AskUserQuestion":
-		if questions, ok := m["questions"].([]any); ok && len(questions) > 0 {
-			if q, ok := questions[0].(map[string]any); ok {
-				if header, ok := q["header"].(string); ok {
-					return header
-				}
-			}
-		}
-	case "LSP":
-		if op, ok := m["operation"].(string); ok {
-			if fp, ok := m["filePath"].(string); ok {
-				// Just show filename, not full path
-				parts := strings.Split(fp, "/")
-				return op + " " + parts[len(parts)-1]
-			}
-			return op
-		}
-	case "TaskOutput":
-		if id, ok := m["task_id"].(string); ok {
-			return id
-		}
-	case "KillShell":
-		if id, ok := m["shell_id"].(string); ok {
-			return id
-		}
+	b.WriteString(fmt.Sprintf(`<details class="tool-section agent-transcript" data-agent-id="%s">`, html.EscapeString(sc.AgentID)))
+	b.WriteString(`<summary class="section-label">`)
+	b.WriteString(fmt.Sprintf(`agent transcript · %d messages · %d tool calls · %s tokens`,
+		sc.Stats.MessageCount, sc.Stats.ToolCalls, render.FormatTokens(sc.Stats.InputTokens+sc.Stats.OutputTokens)))
+	if sc.Stats.DurationSeconds > 0 {
+		b.WriteString(" · " + render.FormatDuration(sc.Stats.DurationSeconds))
 	}
-
-	// Fallback: show first key=value (sorted for determinism)
-	if len(m) > 0 {
-		keys := make([]string, 0, len(m))
-		for k := range m {
-			keys = append(keys, k)
+	b.WriteString(`</summary>`)
+	b.WriteString(`<div class="agent-transcript-body">`)
+	for i, msg := range msgs {
+		// The opening prompt repeats the Task input shown above
+		if i == 0 && msg.Kind == parser.KindUserPrompt {
+			continue
+		}
+		if msg.Kind == parser.KindToolResult {
+			continue
 		}
-		sort.Strings(keys)
-		k := keys[0]
-		return fmt.Sprintf("%s=%v", k, m[k])
+		renderTurnMessage(b, msg, showThinking, showTools, 2, toolResults, nil, notes)
 	}
-	return ""
+	b.WriteString(`</div>`)
+	b.WriteString(`</details>`)
 }
 
 // isActiveTool returns true for tools that modify state (should be expanded by default)
@@ -994,7 +1171,7 @@ func renderToolInput(b *strings.Builder, toolName string, input any) {
 		}
 		if prompt, ok := m["prompt"].(string); ok {
 			b.WriteString(`<div class="task-prompt">`)
-			b.WriteString(renderMarkdown(prompt))
+			b.WriteString(render.MarkdownHTML(prompt))
 			b.WriteString(`</div>`)
 		}
 		b.WriteString(`</div>`)
@@ -1112,168 +1289,6 @@ func renderToolInput(b *strings.Builder, toolName string, input any) {
 	b.WriteString(fmt.Sprintf(`<pre class="tool-input">%s</pre>`, html.EscapeString(string(inputJSON))))
 }
 
-func renderMarkdown(text string) string {
-	var b strings.Builder
-	lines := strings.Split(text, "\n")
-	inCodeBlock := false
-	codeBlockLang := ""
-	var codeLines []string
-	inTable := false
-	var tableRows []string
-
-	for i, line := range lines {
-		if strings.HasPrefix(line, "```") {
-			if inCodeBlock {
-				b.WriteString(fmt.Sprintf(`<pre class="code-block"><code class="lang-%s">%s</code></pre>`,
-					html.EscapeString(codeBlockLang), html.EscapeString(strings.Join(codeLines, "\n"))))
-				codeLines = nil
-				inCodeBlock = false
-			} else {
-				inCodeBlock = true
-				codeBlockLang = strings.TrimPrefix(line, "```")
-				if codeBlockLang == "" {
-					codeBlockLang = "text"
-				}
-			}
-			continue
-		}
-
-		if inCodeBlock {
-			codeLines = append(codeLines, line)
-			continue
-		}
-
-		// Table detection: line starts with | and contains |
-		isTableLine := strings.HasPrefix(strings.TrimSpace(line), "|") && strings.Contains(line, "|")
-		isSeparatorLine := isTableLine && strings.Contains(line, "---")
-
-		if isTableLine {
-			if !inTable {
-				inTable = true
-				tableRows = nil
-			}
-			if !isSeparatorLine {
-				tableRows = append(tableRows, line)
-			}
-			// Check if next line is not a table line
-			if i+1 >= len(lines) || !strings.HasPrefix(strings.TrimSpace(lines[i+1]), "|") {
-				// End of table, render it
-				b.WriteString(renderTable(tableRows))
-				inTable = false
-				tableRows = nil
-			}
-			continue
-		}
-
-		if strings.TrimSpace(line) == "" {
-			b.WriteString(`<br>`)
-			continue
-		}
-
-		// Process inline formatting
-		escaped := html.EscapeString(line)
-		escaped = processInlineCode(escaped)
-		escaped = processBold(escaped)
-
-		b.WriteString(`<p>` + escaped + `</p>`)
-	}
-
-	if inCodeBlock {
-		b.WriteString(fmt.Sprintf(`<pre class="code-block"><code class="lang-%s">%s</code></pre>`,
-			html.EscapeString(codeBlockLang), html.EscapeString(strings.Join(codeLines, "\n"))))
-	}
-
-	return b.String()
-}
-
-// renderTable converts markdown table rows to HTML table
-func renderTable(rows []string) string {
-	if len(rows) == 0 {
-		return ""
-	}
-	var b strings.Builder
-	b.WriteString(`<table class="md-table">`)
-
-	for i, row := range rows {
-		cells := parseTableRow(row)
-		if i == 0 {
-			b.WriteString(`<thead><tr>`)
-			for _, cell := range cells {
-				escaped := html.EscapeString(strings.TrimSpace(cell))
-				escaped = processInlineCode(escaped)
-				escaped = processBold(escaped)
-				b.WriteString(`<th>` + escaped + `</th>`)
-			}
-			b.WriteString(`</tr></thead><tbody>`)
-		} else {
-			b.WriteString(`<tr>`)
-			for _, cell := range cells {
-				escaped := html.EscapeString(strings.TrimSpace(cell))
-				escaped = processInlineCode(escaped)
-				escaped = processBold(escaped)
-				b.WriteString(`<td>` + escaped + `</td>`)
-			}
-			b.WriteString(`</tr>`)
-		}
-	}
-	b.WriteString(`</tbody></table>`)
-	return b.String()
-}
-
-// parseTableRow splits a markdown table row into cells
-func parseTableRow(row string) []string {
-	row = strings.TrimSpace(row)
-	row = strings.Trim(row, "|")
-	return strings.Split(row, "|")
-}
-
-// processInlineCode converts `code` to <code>code</code>
-func processInlineCode(s string) string {
-	var result strings.Builder
-	inCode := false
-	for i := 0; i < len(s); i++ {
-		if s[i] == '`' {
-			if inCode {
-				result.WriteString("</code>")
-			} else {
-				result.WriteString("<code>")
-			}
-			inCode = !inCode
-		} else {
-			result.WriteByte(s[i])
-		}
-	}
-	// Close unclosed code tag
-	if inCode {
-		result.WriteString("</code>")
-	}
-	return result.String()
-}
-
-// processBold converts **text** to <strong>text</strong>
-func processBold(s string) string {
-	var result strings.Builder
-	inBold := false
-	for i := 0; i < len(s); i++ {
-		if i+1 < len(s) && s[i] == '*' && s[i+1] == '*' {
-			if inBold {
-				result.WriteString("</strong>")
-			} else {
-				result.WriteString("<strong>")
-			}
-			inBold = !inBold
-			i++ // skip second *
-		} else {
-			result.WriteByte(s[i])
-		}
-	}
-	// Close unclosed bold tag
-	if inBold {
-		result.WriteString("</strong>")
-	}
-	return result.String()
-}
-
 // getFirstLine returns first line of text (no truncation)
 func getFirstLine(text string) string {
 	text = strings.TrimSpace(text)
@@ -1329,27 +1344,6 @@ func formatRelativeTime(t time.Time) string {
 	}
 }
 
-func formatDuration(seconds float64) string {
-	if seconds <= 0 {
-		return "-"
-	}
-	d := time.Duration(seconds * float64(time.Second))
-	if d < time.Minute {
-		return fmt.Sprintf("%ds", int(d.Seconds()))
-	}
-	if d < time.Hour {
-		m := int(d.Minutes())
-		s := int(d.Seconds()) % 60
-		if s > 0 {
-			return fmt.Sprintf("%dm %ds", m, s)
-		}
-		return fmt.Sprintf("%dm", m)
-	}
-	h := int(d.Hours())
-	m := int(d.Minutes()) % 60
-	return fmt.Sprintf("%dh %dm", h, m)
-}
-
 func truncatePath(path string, maxLen int) string {
 	if len(path) <= maxLen {
 		return path
@@ -1358,23 +1352,18 @@ func truncatePath(path string, maxLen int) string {
 	return "..." + path[len(path)-maxLen+3:]
 }
 
-func formatTokens(n int) string {
-	if n >= 1000000 {
-		return fmt.Sprintf("%.1fM", float64(n)/1000000)
-	}
-	if n >= 1000 {
-		k := float64(n) / 1000
-		if k >= 999.95 {
-			return fmt.Sprintf("%.1fM", float64(n)/1000000)
-		}
-		return fmt.Sprintf("%.1fk", k)
+// costTitle is the tooltip of a cost estimate
+func costTitle(e pricing.Estimate) string {
+	title := "Estimated from the pricing table; Claude Code does not record cost"
+	if e.Unpriced > 0 {
+		title += fmt.Sprintf(". %s tokens from models without a rate are not included", render.FormatTokens(e.Unpriced))
 	}
-	return fmt.Sprintf("%d", n)
+	return title
 }
 
 // renderConversationNav renders a collapsible tree navigation
 func renderConversationNav(b *strings.Builder, messages []*parser.Message) {
-	allMsgs := flattenMessages(messages)
+	allMsgs := parser.Flatten(messages)
 
 	// Group messages: each user prompt starts a new group
 	type navGroup struct {
@@ -1482,7 +1471,7 @@ func renderNavChild(b *strings.Builder, msg *parser.Message) {
 			if block.Type == "tool_use" {
 				hasTool = true
 				toolName = block.ToolName
-				toolPreview = compactToolPreview(block.ToolName, block.ToolInput)
+				toolPreview = render.ToolPreview(block.ToolName, block.ToolInput)
 				break
 			}
 		}
@@ -1527,6 +1516,7 @@ func renderSearchPage(projectsDir, query string) string {
 	b.WriteString(`<span class="search-spinner" id="search-spinner"></span>`)
 	b.WriteString(`</div>`)
 	b.WriteString(`</div>`)
+	b.WriteString(`<p class="search-syntax">Qualifiers: <code>project:</code> <code>branch:</code> <code>tag:</code> <code>model:</code> <code>tool:</code> <code>kind:</code> <code>is:error</code> <code>after:YYYY-MM-DD</code> <code>before:</code> · <code>"exact phrase"</code> · <code>-exclude</code></p>`)
 
 	b.WriteString(`<div id="search-results" class="search-results"></div>`)
 
@@ -1554,9 +1544,13 @@ async function doSearch(query) {
   }
   spinner.classList.add('loading');
   try {
-    const resp = await fetch('/api/search?q=' + encodeURIComponent(query));
-    const results = await resp.json();
-    renderResults(results);
+    const resp = await fetch('/api/search?q=' + encodeURIComponent(query) + '&limit=0');
+    const data = await resp.json();
+    if (data.error) {
+      resultsDiv.innerHTML = '<p class="search-error">' + escapeHtml(data.error) + '</p>';
+    } else {
+      renderResults(data.results || []);
+    }
   } catch (e) {
     resultsDiv.innerHTML = '<p class="search-error">Search failed</p>';
   }
@@ -1573,13 +1567,12 @@ function renderResults(results) {
     const badge = r.type === 'project' ? '<span class="result-badge badge-project">P</span>' :
                   r.type === 'session' ? '<span class="result-badge badge-session">S</span>' :
                   '<span class="result-badge badge-message">M</span>';
-    const url = r.type === 'project' ? '/project/' + r.project_encoded :
-                '/session/' + r.project_encoded + '/' + r.session_id;
+    const url = (r.url && r.url[0] === '/' && r.url[1] !== '/') ? escapeHtml(r.url) : '#';
     html += '<a href="' + url + '" class="search-result">';
     html += badge;
     html += '<div class="result-body">';
     html += '<div class="result-title">' + escapeHtml(r.title || r.summary || 'Untitled') + '</div>';
-    html += '<div class="result-meta">' + escapeHtml(r.project_name || '') + (r.time ? ' · ' + escapeHtml(r.time) : '') + '</div>';
+    html += '<div class="result-meta">' + escapeHtml(r.project || '') + (r.time ? ' · ' + escapeHtml(r.time) : '') + '</div>';
     if (r.snippet) {
       html += '<div class="result-snippet">' + escapeHtml(r.snippet) + '</div>';
     }
@@ -1590,7 +1583,8 @@ function renderResults(results) {
 }
 
 function escapeHtml(s) {
-  return s.replace(/&/g,'&amp;').replace(/</g,'&lt;').replace(/>/g,'&gt;');
+  if (!s) return '';
+  return s.replace(/&/g,'&amp;').replace(/</g,'&lt;').replace(/>/g,'&gt;').replace(/"/g,'&quot;');
 }
 
 searchInput.addEventListener('input', function(e) {
@@ -1625,6 +1619,8 @@ if (%q) doSearch(%q);
 <style>
 .search-results { margin-top: 20px; }
 .search-hint, .search-empty, .search-error { color: var(--text-muted); font-size: 13px; }
+.search-syntax { color: var(--text-muted); font-size: 12px; margin-top: 8px; }
+.search-syntax code { font-size: 11px; }
 .search-error { color: var(--error-border); }
 .search-list { display: flex; flex-direction: column; gap: 8px; }
 .search-list .search-result {
@@ -1964,7 +1960,9 @@ func renderTopNav(projectName, sessionID string) string {
 	b.WriteString(`<a href="https://x.com/ericwang42" target="_blank" rel="noopener noreferrer" class="icon-btn" title="@ericwang42"><svg width="14" height="14" viewBox="0 0 24 24" fill="currentColor"><path d="M18.244 2.25h3.308l-7.227 8.26 8.502 11.24H16.17l-5.214-6.817L4.99 21.75H1.68l7.73-8.835L1.254 2.25H8.08l4.713 6.231zm-1.161 17.52h1.833L7.084 4.126H5.117z"/></svg></a>`)
 	b.WriteString(`<a href="https://github.com/thevibeworks/ccx" target="_blank" rel="noopener noreferrer" class="icon-btn" title="GitHub"><svg width="14" height="14" viewBox="0 0 16 16" fill="currentColor"><path d="M8 0C3.58 0 0 3.58 0 8c0 3.54 2.29 6.53 5.47 7.59.4.07.55-.17.55-.38 0-.19-.01-.82-.01-1.49-2.01.37-2.53-.49-2.69-.94-.09-.23-.48-.94-.82-1.13-.28-.15-.68-.52-.01-.53.63-.01 1.08.58 1.23.82.72 1.21 1.87.87 2.33.66.07-.52.28-.87.51-1.07-1.78-.2-3.64-.89-3.64-3.95 0-.87.31-1.59.82-2.15-.08-.2-.36-1.02.08-2.12 0 0 .67-.21 2.2.82.64-.18 1.32-.27 2-.27.68 0 1.36.09 2 .27 1.53-1.04 2.2-.82 2.2-.82.44 1.1.16 1.92.08 2.12.51.56.82 1.27.82 2.15 0 3.07-1.87 3.75-3.65 3.95.29.25.54.73.54 1.48 0 1.07-.01 1.93-.01 2.2 0 .21.15.46.55.38A8.013 8.013 0 0016 8c0-4.42-3.58-8-8-8z"/></svg></a>`)
 	b.WriteString(`<button class="icon-btn" id="theme-toggle" title="Toggle theme (d)">◐</button>`)
-	b.WriteString(`<a href="/settings" class="icon-btn" title="Settings">◎</a>`)
+	if !publishing {
+		b.WriteString(`<a href="/settings" class="icon-btn" title="Settings">◎</a>`)
+	}
 	b.WriteString(`</div>`)
 	b.WriteString(`</div>`)
 	b.WriteString(`</header>`)
@@ -1992,6 +1990,8 @@ func renderSidebar(active string) string {
 	}{
 		{"/", "Projects", "projects"},
 		{"/search", "Search", "search"},
+		{"/stats", "Statistics", "stats"},
+		{"/commands", "Commands", "commands"},
 		{"/settings", "Settings", "settings"},
 	}
 
@@ -2693,6 +2693,140 @@ code, pre, .session-id, .model-badge {
 
 .session-card { border-left: 3px solid var(--accent-session); }
 
+/* Tags */
+.card-tags { display: flex; flex-wrap: wrap; gap: 4px; margin-top: 6px; }
+.tag-chip {
+  font-size: 11px;
+  padding: 1px 7px;
+  border-radius: 10px;
+  background: var(--bg-tertiary);
+  color: var(--text-muted);
+  cursor: pointer;
+}
+.tag-chip:hover { background: var(--primary); color: white; }
+.tag-chip a { color: inherit; text-decoration: none; }
+.session-tags { display: flex; flex-wrap: wrap; gap: 4px; }
+.session-tags .tag-chip:hover { background: var(--bg-tertiary); color: var(--text); }
+.tag-remove { border: none; background: none; color: var(--text-muted); cursor: pointer; margin-left: 3px; font-size: 11px; }
+.tag-remove:hover { color: var(--error-border); }
+.tag-input {
+  width: 70px;
+  font-size: 11px;
+  padding: 1px 6px;
+  border: 1px solid var(--border);
+  border-radius: 10px;
+  background: var(--bg);
+  color: var(--text);
+  margin-left: auto;
+}
+.tag-input.invalid { border-color: var(--error-border); }
+
+/* Annotations: notes in the margin, highlights in the text */
+.turn { position: relative; }
+.annotation-mark { background: rgba(255, 213, 79, 0.45); color: inherit; border-radius: 2px; cursor: pointer; }
+.annotation-mark.focus { background: rgba(255, 179, 0, 0.7); }
+.annotations { display: flex; flex-direction: column; gap: 6px; margin: 6px 0 10px 22px; }
+.annotation, .annotation-form {
+  font-size: 12px;
+  padding: 6px 8px;
+  border-left: 3px solid #ffd54f;
+  border-radius: 0 var(--radius) var(--radius) 0;
+  background: var(--bg-secondary);
+}
+.annotation.focus { border-left-color: #ffb300; }
+.annotation blockquote { margin: 0 0 4px; color: var(--text-muted); font-style: italic; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+.annotation-note { white-space: pre-wrap; word-break: break-word; }
+.annotation-meta { display: flex; gap: 6px; align-items: center; margin-top: 4px; font-size: 10px; color: var(--text-muted); }
+.annotation-meta button, .annotation-form button {
+  border: none;
+  background: none;
+  color: var(--text-muted);
+  cursor: pointer;
+  font-size: 10px;
+  padding: 0;
+}
+.annotation-meta button:first-of-type { margin-left: auto; }
+.annotation-meta button:hover, .annotation-form button:hover { color: var(--text); }
+.annotation-form textarea {
+  width: 100%;
+  min-height: 48px;
+  font: inherit;
+  padding: 4px;
+  border: 1px solid var(--border);
+  border-radius: var(--radius);
+  background: var(--bg);
+  color: var(--text);
+  resize: vertical;
+}
+.annotation-form-actions { display: flex; gap: 8px; justify-content: flex-end; margin-top: 4px; }
+.highlight-btn {
+  position: absolute;
+  z-index: 50;
+  padding: 2px 8px;
+  font-size: 11px;
+  border: 1px solid var(--border);
+  border-radius: var(--radius);
+  background: var(--bg);
+  color: var(--text);
+  cursor: pointer;
+  box-shadow: 0 2px 6px rgba(0, 0, 0, 0.15);
+}
+.footnotes { margin-top: 32px; padding-top: 12px; border-top: 1px solid var(--border); font-size: 13px; }
+.footnotes li { margin: 0 0 10px 20px; }
+.footnotes blockquote { border-left: 3px solid #ffd54f; padding-left: 8px; color: var(--text-muted); }
+.footnote-by { font-size: 11px; color: var(--text-muted); }
+.footnote-refs { font-size: 11px; color: var(--text-muted); }
+@media (min-width: 1500px) {
+  .session-main.has-annotations { max-width: 1180px; padding-right: 312px; }
+  .session-main.has-annotations .annotations { position: absolute; top: 0; left: calc(100% + 24px); width: 256px; margin: 0; }
+}
+.tag-filter {
+  font-size: 12px;
+  padding: 3px 10px;
+  border-radius: 12px;
+  border: 1px solid var(--primary);
+  background: transparent;
+  color: var(--primary);
+  cursor: pointer;
+}
+
+/* Live feed: sessions written to in the last few minutes */
+.live-dot {
+  display: inline-block;
+  width: 8px;
+  height: 8px;
+  border-radius: 50%;
+  background: #4c4;
+  flex-shrink: 0;
+  animation: live-pulse 1.5s infinite;
+}
+.card.live { border-color: #4c4; }
+.session-card.live { border-left-color: #4c4; }
+.live-panel {
+  border: 1px solid var(--border);
+  border-left: 3px solid #4c4;
+  border-radius: var(--radius);
+  padding: 10px 14px;
+  margin-bottom: 16px;
+}
+.live-panel-header { display: flex; align-items: center; gap: 8px; font-weight: 600; font-size: 13px; margin-bottom: 6px; }
+.live-count { color: var(--text-muted); font-weight: 400; }
+.live-list { display: flex; flex-direction: column; gap: 4px; }
+.live-item {
+  display: flex;
+  align-items: center;
+  gap: 10px;
+  font-size: 13px;
+  color: var(--text);
+  text-decoration: none;
+  padding: 4px 6px;
+  border-radius: 4px;
+}
+.live-item:hover { background: var(--bg-secondary); }
+.live-project { font-weight: 600; white-space: nowrap; }
+.live-summary { flex: 1; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; color: var(--text-muted); }
+.live-stats { font-size: 11px; color: var(--text-muted); white-space: nowrap; }
+
 .session-header {
   display: flex;
   justify-content: space-between;
@@ -2939,6 +3073,24 @@ code, pre, .session-id, .model-badge {
 
 .nav-list { padding: 4px; }
 
+.branch-bar { display: flex; flex-wrap: wrap; align-items: center; gap: 6px; margin-bottom: 16px; padding: 8px 10px; border: 1px solid var(--border); border-radius: var(--radius); background: var(--bg-secondary); font-size: 12px; }
+.branch-bar-label { color: var(--text-muted); margin-right: 4px; }
+.branch-item { display: inline-flex; align-items: center; gap: 2px; }
+.branch-pill { display: inline-flex; align-items: center; gap: 6px; padding: 3px 10px; border: 1px solid var(--border); border-radius: 12px; color: var(--text); text-decoration: none; max-width: 360px; }
+.branch-pill:hover { border-color: var(--primary); }
+.branch-pill.active { background: var(--primary); border-color: var(--primary); color: #fff; }
+.branch-num { font-weight: 600; }
+.branch-label { overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+.branch-meta { opacity: 0.7; font-size: 11px; white-space: nowrap; }
+.branch-compare { padding: 2px 4px; color: var(--text-muted); text-decoration: none; }
+.branch-compare:hover { color: var(--primary); }
+.context-spark { padding: 8px 12px 4px; border-bottom: 1px solid var(--border); }
+.context-spark-label { display: flex; justify-content: space-between; font-size: 10px; color: var(--text-muted); margin-bottom: 2px; }
+.spark { width: 100%; height: 40px; display: block; }
+.spark-line { fill: none; stroke: var(--accent-conversation); stroke-width: 1.5; stroke-linejoin: round; }
+.spark-heavy { fill: var(--error-border); cursor: pointer; }
+.session-layout.sidebar-collapsed .nav-sidebar .context-spark { display: none; }
+
 .nav-item {
   display: flex;
   align-items: center;
@@ -3237,6 +3389,27 @@ details.turn-user[open] .turn-role { display: none; }
   font-family: var(--font-mono);
   color: var(--text-muted);
 }
+.turn-cost {
+  font-size: 9px;
+  font-family: var(--font-mono);
+  color: var(--text-muted);
+  cursor: help;
+}
+.turn-context {
+  font-size: 9px;
+  font-family: var(--font-mono);
+  color: var(--text-muted);
+  cursor: help;
+}
+.turn-heavy {
+  background: var(--error-bg);
+  color: var(--error-border);
+  padding: 1px 5px;
+  border-radius: 3px;
+  font-size: 9px;
+  font-family: var(--font-mono);
+  cursor: help;
+}
 .turn-actions {
   display: flex;
   gap: 4px;
@@ -3245,7 +3418,7 @@ details.turn-user[open] .turn-role { display: none; }
   transition: opacity 0.15s;
 }
 .turn:hover .turn-actions, details.turn-user:hover .turn-actions { opacity: 1; }
-.turn-raw-btn, .turn-copy-btn {
+.turn-raw-btn, .turn-copy-btn, .turn-note-btn {
   padding: 1px 6px;
   font-size: 9px;
   border: 1px solid var(--border);
@@ -3255,7 +3428,7 @@ details.turn-user[open] .turn-role { display: none; }
   cursor: pointer;
   font-family: var(--font-mono);
 }
-.turn-raw-btn:hover, .turn-copy-btn:hover { background: var(--bg-secondary); color: var(--text); }
+.turn-raw-btn:hover, .turn-copy-btn:hover, .turn-note-btn:hover { background: var(--bg-secondary); color: var(--text); }
 .turn-raw-btn.active { background: var(--primary); color: white; border-color: var(--primary); }
 .turn-body.raw-mode { background: var(--bg-tertiary); border-radius: var(--radius); }
 .turn-body.raw-mode pre.raw-content { margin: 0; padding: 8px; font-size: 10px; line-height: 1.4; white-space: pre-wrap; word-break: break-word; }
@@ -3716,6 +3889,10 @@ body.watching .tail-spinner { display: flex; align-items: center; gap: 8px; }
   border-left: 3px solid var(--primary);
 }
 
+.agent-transcript > summary { cursor: pointer; }
+.agent-transcript-body { margin-top: 8px; border-left: 2px solid #86c; }
+.agent-transcript-body .turn-agent { margin-left: 0; border-left: none; }
+
 /* Skill tool */
 .skill-call { font-family: var(--font-mono); font-size: 12px; }
 .skill-name { color: var(--primary); font-weight: 600; }
@@ -3868,6 +4045,20 @@ if (searchInput) {
   });
 }
 
+document.addEventListener('click', function(e) {
+  const chip = e.target.closest('.tag-chip');
+  if (chip) {
+    e.preventDefault();
+    const url = new URL(window.location);
+    url.searchParams.set('tag', chip.dataset.tag);
+    window.location = url;
+  } else if (e.target.closest('#tag-filter')) {
+    const url = new URL(window.location);
+    url.searchParams.delete('tag');
+    window.location = url;
+  }
+});
+
 if (sortSelect) {
   sortSelect.addEventListener('change', function(e) {
     const url = new URL(window.location);
@@ -3972,14 +4163,403 @@ if (backTop) {
 `
 }
 
+// liveFeedJS keeps the index and project pages current from a session feed.
+// On a project page (project set) session cards are updated and new ones added;
+// on the index, project cards and the "Active now" panel are.
+func liveFeedJS(feedURL, project string) string {
+	return fmt.Sprintf(`
+<script>
+(function() {
+  if (!window.EventSource) return;
+  const feedProject = %q;
+  const active = new Map();
+  const results = document.getElementById('results');
+  const filtered = new URL(window.location).searchParams.has('q');
+
+  function fmtTokens(n) {
+    if (n >= 1000000) return (n / 1000000).toFixed(1) + 'M';
+    if (n >= 1000) return (n / 1000).toFixed(1) + 'K';
+    return String(n);
+  }
+
+  function sessionURL(s) {
+    return '/session/' + encodeURIComponent(s.project) + '/' + encodeURIComponent(s.id);
+  }
+
+  function sessionCardHTML(s) {
+    const start = new Date(s.start_time);
+    const tokens = s.tokens > 0 ? '<span class="stat stat-tokens" title="Total tokens"><span class="stat-icon">⧫</span> ' + fmtTokens(s.tokens) + '</span>' : '';
+    return '<div class="session-header">' +
+        '<code class="session-id">' + escapeHtml(s.id.slice(0, 8)) + '</code>' +
+        '<span class="session-time" title="' + escapeHtml(start.toLocaleString()) + '">' + escapeHtml(start.toLocaleString()) + '</span>' +
+      '</div>' +
+      '<div class="session-summary">' + escapeHtml(s.summary) + '</div>' +
+      '<div class="session-stats">' +
+        '<span class="stat"><span class="stat-icon">M</span> ' + s.messages + '</span>' +
+        '<span class="stat"><span class="stat-icon">T</span> ' + s.tool_calls + '</span>' +
+        tokens +
+      '</div>';
+  }
+
+  function updateSessionCard(s, isNew) {
+    if (!results) return;
+    let card = results.querySelector('[data-session="' + CSS.escape(s.id) + '"]');
+    if (!card) {
+      if (!isNew || filtered) return;
+      card = document.createElement('a');
+      card.className = 'card session-card';
+      card.href = sessionURL(s);
+      card.dataset.session = s.id;
+      results.prepend(card);
+    }
+    const chips = card.querySelector('.card-tags');
+    card.innerHTML = sessionCardHTML(s);
+    if (chips) card.appendChild(chips);
+    card.classList.toggle('live', s.active);
+  }
+
+  function updateProjectCard(s, isNew) {
+    const card = results && results.querySelector('[data-project="' + CSS.escape(s.project) + '"]');
+    if (!card) return;
+    if (isNew) {
+      const n = parseInt(card.dataset.sessions || '0', 10) + 1;
+      card.dataset.sessions = n;
+      const el = card.querySelector('.stat-sessions');
+      if (el) el.textContent = '◉ ' + n + (n === 1 ? ' session' : ' sessions');
+    }
+    if (s.active) {
+      const age = card.querySelector('.stat-age');
+      if (age) age.textContent = 'just now';
+    }
+    let live = false;
+    active.forEach(a => { if (a.project === s.project) live = true; });
+    card.classList.toggle('live', live);
+  }
+
+  function renderActivePanel() {
+    const panel = document.getElementById('live-panel');
+    const list = document.getElementById('live-list');
+    if (!panel || !list) return;
+    panel.hidden = active.size === 0;
+    document.getElementById('live-count').textContent = active.size;
+    const sessions = Array.from(active.values()).sort((a, b) => b.end_time.localeCompare(a.end_time));
+    list.innerHTML = sessions.map(s =>
+      '<a class="live-item" href="' + escapeHtml(sessionURL(s)) + '">' +
+        '<span class="live-dot"></span>' +
+        '<span class="live-project">' + escapeHtml(s.project_name) + '</span>' +
+        '<span class="live-summary">' + escapeHtml(s.summary) + '</span>' +
+        '<span class="live-stats">' + s.messages + ' msgs · ' + s.tool_calls + ' tools · ' + fmtTokens(s.tokens) + '</span>' +
+      '</a>').join('');
+  }
+
+  function apply(s, isNew) {
+    const key = s.project + '/' + s.id;
+    if (s.active) active.set(key, s); else active.delete(key);
+    if (feedProject) {
+      updateSessionCard(s, isNew);
+    } else {
+      updateProjectCard(s, isNew);
+      renderActivePanel();
+    }
+  }
+
+  const source = new EventSource(%q);
+  source.addEventListener('connected', function(e) {
+    active.clear();
+    JSON.parse(e.data).active.forEach(s => apply(s, false));
+  });
+  source.addEventListener('session_new', e => apply(JSON.parse(e.data), true));
+  source.addEventListener('session_update', e => apply(JSON.parse(e.data), false));
+  source.addEventListener('session_idle', e => apply(JSON.parse(e.data), false));
+  source.addEventListener('reset', function() {
+    source.close();
+    location.reload();
+  });
+})();
+</script>
+`, project, feedURL)
+}
+
 func sessionJS(projectName, sessionID string) string {
 	return fmt.Sprintf(`
 <script>
 const projectName = %q;
 const sessionID = %q;
+const published = %t; // a static page from ccx publish: no server to call
 let eventSource = null;
 let autoScroll = false;
 
+// Session tags (info panel)
+const tagsURL = '/api/tags/' + encodeURIComponent(sessionID) + '?type=session';
+
+function renderSessionTags(tags) {
+  const el = document.getElementById('session-tags');
+  if (!el) return;
+  el.innerHTML = tags.map(t =>
+    '<span class="tag-chip"><a href="/project/' + encodeURIComponent(projectName) + '?tag=' + encodeURIComponent(t) + '">#' + escapeHtml(t) + '</a>' +
+    '<button class="tag-remove" data-tag="' + escapeHtml(t) + '" title="Remove tag">×</button></span>').join('');
+}
+
+async function updateSessionTag(method, tag) {
+  try {
+    const res = await fetch(tagsURL, {
+      method: method,
+      headers: {'Content-Type': 'application/json'},
+      body: method === 'GET' ? undefined : JSON.stringify({tag: tag})
+    });
+    const input = document.getElementById('tag-input');
+    if (!res.ok) {
+      input?.classList.add('invalid');
+      if (input) input.title = (await res.text()).trim() || 'Tag update failed';
+      return;
+    }
+    input?.classList.remove('invalid');
+    renderSessionTags((await res.json()).tags);
+  } catch (err) {
+    console.error('Tags error:', err);
+  }
+}
+
+document.getElementById('tag-input')?.addEventListener('keydown', function(e) {
+  if (e.key === 'Enter' && this.value.trim()) {
+    updateSessionTag('POST', this.value.trim());
+    this.value = '';
+  }
+  e.stopPropagation();
+});
+document.getElementById('session-tags')?.addEventListener('click', function(e) {
+  const btn = e.target.closest('.tag-remove');
+  if (btn) updateSessionTag('DELETE', btn.dataset.tag);
+});
+if (!published) updateSessionTag('GET');
+
+// Annotations: margin notes and highlights, anchored by message UUID.
+// Highlights are offsets into the text of one of the turn's text blocks.
+let annotations = [];
+
+function annotationTurn(uuid) {
+  return document.getElementById('msg-' + sanitizeID(uuid));
+}
+
+function turnTextBlocks(turn) {
+  return Array.from(turn.querySelectorAll(':scope > .turn-body > .block-text'));
+}
+
+function textOffset(block, node, offset) {
+  const r = document.createRange();
+  r.selectNodeContents(block);
+  r.setEnd(node, offset);
+  return r.toString().length;
+}
+
+function markRange(block, start, end, id) {
+  const walker = document.createTreeWalker(block, NodeFilter.SHOW_TEXT);
+  const targets = [];
+  let pos = 0;
+  while (walker.nextNode() && pos < end) {
+    const node = walker.currentNode;
+    const s = Math.max(start, pos), e = Math.min(end, pos + node.nodeValue.length);
+    if (s < e) targets.push([node, s - pos, e - pos]);
+    pos += node.nodeValue.length;
+  }
+  targets.forEach(([node, s, e]) => {
+    const r = document.createRange();
+    r.setStart(node, s);
+    r.setEnd(node, e);
+    const mark = document.createElement('mark');
+    mark.className = 'annotation-mark';
+    mark.dataset.annotation = id;
+    r.surroundContents(mark);
+  });
+}
+
+function clearAnnotations() {
+  document.querySelectorAll('.annotations').forEach(el => el.remove());
+  document.querySelectorAll('mark.annotation-mark').forEach(mark => {
+    const parent = mark.parentNode;
+    while (mark.firstChild) parent.insertBefore(mark.firstChild, mark);
+    parent.removeChild(mark);
+    parent.normalize();
+  });
+}
+
+function annotationsFor(turn) {
+  let box = turn.querySelector(':scope > .annotations');
+  if (!box) {
+    box = document.createElement('div');
+    box.className = 'annotations';
+    turn.appendChild(box);
+  }
+  return box;
+}
+
+function renderAnnotation(a) {
+  const when = new Date(a.created_at).toLocaleString();
+  const edited = a.updated_at !== a.created_at ? ' (edited)' : '';
+  return '<div class="annotation" data-id="' + a.id + '">' +
+    (a.quote ? '<blockquote title="' + escapeHtml(a.quote) + '">' + escapeHtml(a.quote) + '</blockquote>' : '') +
+    (a.note ? '<div class="annotation-note">' + escapeHtml(a.note) + '</div>' : '') +
+    '<div class="annotation-meta"><span>' + escapeHtml(a.author || 'anonymous') + ' · ' + escapeHtml(when) + edited + '</span>' +
+    '<button data-action="edit">edit</button><button data-action="delete">delete</button></div></div>';
+}
+
+function renderAnnotations() {
+  clearAnnotations();
+  document.querySelector('.session-main')?.classList.toggle('has-annotations', annotations.length > 0);
+  annotations.forEach(a => {
+    const turn = annotationTurn(a.message_uuid);
+    if (!turn) return;
+    annotationsFor(turn).insertAdjacentHTML('beforeend', renderAnnotation(a));
+    if (a.block < 0 || a.end <= a.start) return;
+    const block = turnTextBlocks(turn)[a.block];
+    if (!block) return;
+    let start = a.start, end = a.end;
+    const text = block.textContent;
+    if (a.quote && text.slice(start, end) !== a.quote) {
+      // The rendering changed since the highlight was made; find the quote instead
+      start = text.indexOf(a.quote);
+      if (start < 0) return;
+      end = start + a.quote.length;
+    }
+    markRange(block, start, end, a.id);
+  });
+}
+
+async function loadAnnotations() {
+  try {
+    const res = await fetch('/api/annotations?session=' + encodeURIComponent(sessionID));
+    if (!res.ok) return;
+    annotations = (await res.json()).annotations || [];
+    renderAnnotations();
+  } catch (err) {
+    console.error('Annotations error:', err);
+  }
+}
+
+async function saveAnnotation(url, method, body) {
+  const res = await fetch(url, {
+    method: method,
+    headers: {'Content-Type': 'application/json'},
+    body: body ? JSON.stringify(body) : undefined
+  });
+  if (!res.ok) throw new Error((await res.text()).trim() || 'Annotation update failed');
+  await loadAnnotations();
+}
+
+// openNoteForm shows an editor in the turn's margin; onSave receives the note text
+function openNoteForm(turn, initial, onSave, before) {
+  document.querySelectorAll('.annotation-form').forEach(el => el.remove());
+  const form = document.createElement('div');
+  form.className = 'annotation-form';
+  form.innerHTML = '<textarea placeholder="Add a note... (Ctrl+Enter to save)"></textarea>' +
+    '<div class="annotation-form-actions"><button data-action="cancel">cancel</button><button data-action="save">save</button></div>';
+  const box = annotationsFor(turn);
+  if (before) box.insertBefore(form, before); else box.appendChild(form);
+  const textarea = form.querySelector('textarea');
+  textarea.value = initial || '';
+  textarea.focus();
+
+  const close = () => { form.remove(); if (!box.children.length) box.remove(); };
+  const save = async () => {
+    try {
+      await onSave(textarea.value.trim());
+    } catch (err) {
+      textarea.classList.add('invalid');
+      textarea.title = err.message;
+    }
+  };
+  form.addEventListener('click', e => {
+    e.stopPropagation();
+    if (e.target.dataset.action === 'cancel') close();
+    if (e.target.dataset.action === 'save') save();
+  });
+  textarea.addEventListener('keydown', e => {
+    e.stopPropagation();
+    if (e.key === 'Escape') close();
+    if (e.key === 'Enter' && (e.ctrlKey || e.metaKey)) save();
+  });
+}
+
+function noteTurn(e, btn) {
+  e.stopPropagation();
+  e.preventDefault();
+  const turn = btn.closest('.turn');
+  if (!turn || !turn.id) return;
+  const uuid = turn.id.slice(4);
+  openNoteForm(turn, '', note => saveAnnotation('/api/annotations', 'POST', {
+    project: projectName, session_id: sessionID, message_uuid: uuid, block: -1, note: note
+  }));
+}
+
+// Selecting text inside a turn's text block offers to highlight it
+const highlightBtn = document.createElement('button');
+highlightBtn.className = 'highlight-btn';
+highlightBtn.textContent = '✎ highlight';
+highlightBtn.style.display = 'none';
+document.body.appendChild(highlightBtn);
+let pendingHighlight = null;
+
+document.getElementById('messages')?.addEventListener('mouseup', function() {
+  if (published) return;
+  setTimeout(() => {
+    const sel = window.getSelection();
+    pendingHighlight = null;
+    highlightBtn.style.display = 'none';
+    if (!sel || sel.isCollapsed || !sel.rangeCount) return;
+    const range = sel.getRangeAt(0);
+    const start = range.startContainer.nodeType === 1 ? range.startContainer : range.startContainer.parentElement;
+    const block = start?.closest('.block-text');
+    if (!block || !block.contains(range.endContainer)) return;
+    const turn = block.closest('.turn');
+    const index = turn ? turnTextBlocks(turn).indexOf(block) : -1;
+    if (index < 0 || !turn.id) return;
+    const from = textOffset(block, range.startContainer, range.startOffset);
+    const to = textOffset(block, range.endContainer, range.endOffset);
+    const quote = block.textContent.slice(from, to);
+    if (!quote.trim()) return;
+    pendingHighlight = {turn: turn, uuid: turn.id.slice(4), block: index, start: from, end: to, quote: quote};
+    const rect = range.getBoundingClientRect();
+    highlightBtn.style.top = (window.scrollY + rect.bottom + 6) + 'px';
+    highlightBtn.style.left = (window.scrollX + rect.left) + 'px';
+    highlightBtn.style.display = 'block';
+  }, 0);
+});
+
+highlightBtn.addEventListener('mousedown', e => e.preventDefault());
+highlightBtn.addEventListener('click', function() {
+  const h = pendingHighlight;
+  highlightBtn.style.display = 'none';
+  if (!h) return;
+  window.getSelection()?.removeAllRanges();
+  openNoteForm(h.turn, '', note => saveAnnotation('/api/annotations', 'POST', {
+    project: projectName, session_id: sessionID, message_uuid: h.uuid,
+    block: h.block, start: h.start, end: h.end, quote: h.quote, note: note
+  }));
+});
+
+document.getElementById('messages')?.addEventListener('click', function(e) {
+  const mark = e.target.closest('mark.annotation-mark');
+  const note = e.target.closest('.annotation');
+  const id = mark?.dataset.annotation || note?.dataset.id;
+  if (!id) return;
+
+  const action = e.target.dataset.action;
+  if (action === 'delete') {
+    if (confirm('Delete this note?')) saveAnnotation('/api/annotations/' + id, 'DELETE').catch(err => console.error(err));
+    return;
+  }
+  if (action === 'edit') {
+    const a = annotations.find(x => String(x.id) === id);
+    openNoteForm(note.closest('.turn'), a?.note, text => saveAnnotation('/api/annotations/' + id, 'PATCH', {note: text}), note);
+    note.style.display = 'none';
+    return;
+  }
+  document.querySelectorAll('.annotation.focus, mark.annotation-mark.focus').forEach(el => el.classList.remove('focus'));
+  document.querySelectorAll('[data-annotation="' + id + '"], .annotation[data-id="' + id + '"]').forEach(el => el.classList.add('focus'));
+});
+
+if (!published) loadAnnotations();
+
 // Progressive loading - load all earlier messages
 function loadEarlierMessages() {
   const btn = document.querySelector('.load-earlier');
@@ -4039,7 +4619,7 @@ const searchResults = document.getElementById('search-results');
 let globalSearchTimeout;
 let searchAbort = null;
 
-if (globalSearchInput && searchResults) {
+if (!published && globalSearchInput && searchResults) {
   globalSearchInput.addEventListener('input', function(e) {
     clearTimeout(globalSearchTimeout);
     if (searchAbort) { searchAbort.abort(); searchAbort = null; }
@@ -4362,6 +4942,12 @@ function startWatch() {
     }
   });
 
+  // File was truncated, replaced, or we fell behind: the rendered page is stale
+  eventSource.addEventListener('reset', function() {
+    stopWatch();
+    location.reload();
+  });
+
   eventSource.addEventListener('error', function() {
     stopWatch();
   });
@@ -4409,7 +4995,7 @@ function appendTailMessage(data) {
         '<span class="turn-role">USER</span>' +
         '<span class="turn-preview">' + escapeHtml(preview) + '</span>' +
         '<span class="turn-time">' + timestamp + '</span>' +
-        '<span class="turn-actions"><button class="turn-raw-btn" onclick="toggleTurnRaw(event,this)">raw</button><button class="turn-copy-btn" onclick="copyTurn(event,this)">copy</button></span>' +
+        '<span class="turn-actions"><button class="turn-note-btn" onclick="noteTurn(event,this)">note</button><button class="turn-raw-btn" onclick="toggleTurnRaw(event,this)">raw</button><button class="turn-copy-btn" onclick="copyTurn(event,this)">copy</button></span>' +
       '</summary>' +
       '<div class="turn-body" data-rawb64="' + rawB64 + '">' +
         renderContentBlocks(content) +
@@ -4429,7 +5015,7 @@ function appendTailMessage(data) {
         '<span class="turn-icon">○</span>' +
         '<span class="turn-role">' + escapeHtml(resultToolName) + '</span>' +
         '<span class="turn-time">' + timestamp + '</span>' +
-        '<span class="turn-actions"><button class="turn-raw-btn" onclick="toggleTurnRaw(event,this)">raw</button><button class="turn-copy-btn" onclick="copyTurn(event,this)">copy</button></span>' +
+        '<span class="turn-actions"><button class="turn-note-btn" onclick="noteTurn(event,this)">note</button><button class="turn-raw-btn" onclick="toggleTurnRaw(event,this)">raw</button><button class="turn-copy-btn" onclick="copyTurn(event,this)">copy</button></span>' +
       '</div>' +
       '<div class="turn-body" data-rawb64="' + rawB64 + '">' +
         renderContentBlocks(content) +
@@ -4450,7 +5036,7 @@ function appendTailMessage(data) {
         '<span class="turn-role">' + role + '</span>' +
         '<span class="turn-time">' + timestamp + '</span>' +
         (model ? '<span class="turn-model">' + escapeHtml(model) + '</span>' : '') +
-        '<span class="turn-actions"><button class="turn-raw-btn" onclick="toggleTurnRaw(event,this)">raw</button><button class="turn-copy-btn" onclick="copyTurn(event,this)">copy</button></span>' +
+        '<span class="turn-actions"><button class="turn-note-btn" onclick="noteTurn(event,this)">note</button><button class="turn-raw-btn" onclick="toggleTurnRaw(event,this)">raw</button><button class="turn-copy-btn" onclick="copyTurn(event,this)">copy</button></span>' +
       '</div>' +
       '<div class="turn-body" data-rawb64="' + rawB64 + '">' +
         renderContentBlocks(content) +
@@ -5344,5 +5930,5 @@ document.addEventListener('keydown', function(e) {
   25%%, 75%% { background: rgba(218, 119, 86, 0.15); box-shadow: 0 0 0 2px var(--primary); }
 }
 </style>
-`, projectName, sessionID)
+`, projectName, sessionID, publishing)
 }
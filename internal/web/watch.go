@@ -0,0 +1,10 @@
+package web
+
+import "github.com/thevibeworks/ccx/internal/watch"
+
+// subscriberBuffer is how many events a slow client may fall behind before
+// it is dropped and told to reload
+const subscriberBuffer = 1024
+
+// watches shares one tail per session file across all SSE clients
+var watches = watch.NewHub(subscriberBuffer)